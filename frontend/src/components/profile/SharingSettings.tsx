@@ -5,7 +5,9 @@ import { toast } from "sonner";
 import { MaterialIcon } from "@/components/ui/MaterialIcon";
 import { cn } from "@/lib/utils";
 import {
+    acceptInvitationOpts,
     createSharingGrantOpts,
+    declineInvitationOpts,
     getSharedWithMeOpts,
     getSharingGrantsOpts,
     getSharingInvitationsOpts,
@@ -48,6 +50,8 @@ export function SharingSettings() {
     const createMutation = useMutation(createSharingGrantOpts);
     const revokeMutation = useMutation(revokeSharingGrantOpts);
     const markReadMutation = useMutation(markInvitationReadOpts);
+    const acceptMutation = useMutation(acceptInvitationOpts);
+    const declineMutation = useMutation(declineInvitationOpts);
     const unreadCount = (invitationsQuery.data ?? []).filter((inv) => !inv.read_at).length;
     const users = usersQuery.data ?? [];
 
@@ -70,7 +74,7 @@ export function SharingSettings() {
                     toast.error(error.message || "No se pudo compartir acceso");
                 },
                 onSuccess: () => {
-                    toast.success("Permiso compartido");
+                    toast.success("Invitación enviada");
                     setRecipient("");
                     setAccessLevel("view");
                 },
@@ -220,7 +224,21 @@ export function SharingSettings() {
                     <InvitationRow
                         invitation={inv}
                         isMarking={markReadMutation.isPending}
+                        isResponding={acceptMutation.isPending || declineMutation.isPending}
                         key={inv.id}
+                        onAccept={(id) =>
+                            acceptMutation.mutate(id, {
+                                onError: (error) =>
+                                    toast.error(error.message || "No se pudo aceptar la invitación"),
+                                onSuccess: () => toast.success("Invitación aceptada"),
+                            })
+                        }
+                        onDecline={(id) =>
+                            declineMutation.mutate(id, {
+                                onError: (error) =>
+                                    toast.error(error.message || "No se pudo rechazar la invitación"),
+                            })
+                        }
                         onMarkRead={(id) =>
                             markReadMutation.mutate(id, {
                                 onError: (error) =>
@@ -272,6 +290,7 @@ function SharingGrantRow({
                 </p>
                 <p className="mt-1 truncate text-xs text-on-surface-variant">
                     @{grant.grantee_username} · {formatAccessLevel(grant.access_level)}
+                    {grant.accepted_at ? null : " · pendiente"}
                 </p>
             </div>
             <button
@@ -312,13 +331,20 @@ function ReceivedGrantRow({ grant }: { grant: SharingGrant }) {
 function InvitationRow({
     invitation,
     isMarking,
+    isResponding,
+    onAccept,
+    onDecline,
     onMarkRead,
 }: {
     invitation: SharingInvitation;
     isMarking: boolean;
+    isResponding: boolean;
+    onAccept: (id: string) => void;
+    onDecline: (id: string) => void;
     onMarkRead: (id: string) => void;
 }) {
     const isUnread = !invitation.read_at;
+    const isPending = invitation.status === "pending";
     const ownerName = invitation.owner_fullname || `@${invitation.owner_username}`;
     return (
         <article
@@ -334,19 +360,40 @@ function InvitationRow({
                     {ownerName}
                 </p>
                 <p className="mt-0.5 truncate text-xs text-on-surface-variant">
-                    @{invitation.owner_username} · {formatAccessLevel(invitation.access_level as SharingAccessLevel)} · compartió contigo
+                    @{invitation.owner_username} · {formatAccessLevel(invitation.access_level as SharingAccessLevel)} · {isPending ? "te invitó" : "compartió contigo"}
                 </p>
             </div>
             <div className="flex shrink-0 items-center gap-2">
-                <Link
-                    className="inline-flex items-center gap-1 rounded-full border border-primary/20 bg-primary/10 px-3 py-1 font-label text-[10px] font-bold uppercase tracking-widest text-primary transition-all duration-300 hover:bg-primary/20 active:scale-95"
-                    params={{ userId: invitation.owner_user_id }}
-                    to="/shared/$userId"
-                >
-                    <MaterialIcon name="open_in_new" className="text-xs" />
-                    Ver tareas
-                </Link>
-                {isUnread ? (
+                {isPending ? (
+                    <>
+                        <button
+                            className="rounded-full border border-primary/20 bg-primary/10 px-3 py-1 font-label text-[10px] font-bold uppercase tracking-widest text-primary transition-all duration-300 hover:bg-primary/20 active:scale-95 disabled:opacity-50"
+                            disabled={isResponding}
+                            onClick={() => onAccept(invitation.id)}
+                            type="button"
+                        >
+                            Aceptar
+                        </button>
+                        <button
+                            className="rounded-full border border-error/20 bg-error/10 px-3 py-1 font-label text-[10px] font-bold uppercase tracking-widest text-error transition-all duration-300 hover:bg-error/20 active:scale-95 disabled:opacity-50"
+                            disabled={isResponding}
+                            onClick={() => onDecline(invitation.id)}
+                            type="button"
+                        >
+                            Rechazar
+                        </button>
+                    </>
+                ) : (
+                    <Link
+                        className="inline-flex items-center gap-1 rounded-full border border-primary/20 bg-primary/10 px-3 py-1 font-label text-[10px] font-bold uppercase tracking-widest text-primary transition-all duration-300 hover:bg-primary/20 active:scale-95"
+                        params={{ userId: invitation.owner_user_id }}
+                        to="/shared/$userId"
+                    >
+                        <MaterialIcon name="open_in_new" className="text-xs" />
+                        Ver tareas
+                    </Link>
+                )}
+                {isUnread && !isPending ? (
                     <button
                         className="rounded-full border border-outline-variant/20 bg-surface-container-highest px-2 py-1 font-label text-[10px] font-bold uppercase tracking-widest text-on-surface-variant transition-all duration-300 hover:bg-surface-container-high active:scale-95 disabled:opacity-50"
                         disabled={isMarking}
@@ -59,15 +59,38 @@ self.addEventListener("push", (event) => {
     event.waitUntil(self.registration.showNotification(payload.title || "Routine Ritual", options));
 });
 
+const TASK_ACTIONS = new Set(["complete", "snooze", "skip"]);
+
 self.addEventListener("notificationclick", (event) => {
     event.notification.close();
 
-    const notificationData = event.notification.data as { url?: string } | undefined;
+    const notificationData = event.notification.data as { action_token?: string; url?: string } | undefined;
     const targetURL = notificationData?.url || "/tasks";
 
+    if (TASK_ACTIONS.has(event.action) && notificationData?.action_token) {
+        event.waitUntil(sendTaskAction(event.action, notificationData.action_token, targetURL));
+        return;
+    }
+
     event.waitUntil(openOrFocusClient(targetURL));
 });
 
+async function sendTaskAction(action: string, token: string, fallbackURL: string) {
+    try {
+        const response = await fetch("/api/v1/notifications/actions", {
+            body: JSON.stringify({ action, token }),
+            headers: { "Content-Type": "application/json" },
+            method: "POST",
+        });
+        if (response.ok || response.status === 409) {
+            return;
+        }
+    } catch (error) {
+        // Network failure: fall through and let the user finish in the app.
+    }
+    return openOrFocusClient(fallbackURL);
+}
+
 function readPushPayload(event: PushEvent): PushPayload {
     if (!event.data) {
         return {};
@@ -0,0 +1,59 @@
+import { mutationOptions, queryOptions } from "@tanstack/react-query";
+import type { ApiResponse } from "@/types/api";
+import { getApiError } from "@/types/api";
+import type { Insight } from "@/types/insight";
+import { NotificationsQueryKeys } from "./notifications";
+import { queryClient } from "./queryClient";
+
+interface InsightsData {
+    insights: Insight[];
+}
+
+export const InsightsQueryKeys = {
+    all: () => ["insights"] as const,
+    listing: (includeDismissed: boolean) => [...InsightsQueryKeys.all(), "listing", includeDismissed] as const,
+} as const;
+
+export function getInsightsOpts(includeDismissed = false) {
+    return queryOptions({
+        queryKey: InsightsQueryKeys.listing(includeDismissed),
+        queryFn: () => getInsights(includeDismissed),
+        staleTime: 5 * 60 * 1000,
+    });
+}
+
+/** Dismissing also archives the inbox notice of the insight. */
+export const dismissInsightOpts = mutationOptions({
+    mutationFn: dismissInsight,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: InsightsQueryKeys.all() });
+        void queryClient.invalidateQueries({ queryKey: NotificationsQueryKeys.inbox() });
+    },
+});
+
+export async function getInsights(includeDismissed = false): Promise<Insight[]> {
+    const params = new URLSearchParams();
+    if (includeDismissed) {
+        params.set("include_dismissed", "true");
+    }
+    const response = await fetch(`/api/v1/insights?${params.toString()}`, {
+        method: "GET",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<InsightsData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Error al obtener los hallazgos"));
+    }
+    return data.data?.insights ?? [];
+}
+
+export async function dismissInsight(id: string): Promise<void> {
+    const response = await fetch(`/api/v1/insights/${id}/dismiss`, {
+        method: "POST",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<unknown>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo descartar el hallazgo"));
+    }
+}
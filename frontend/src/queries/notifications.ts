@@ -27,7 +27,7 @@ export const markNotificationReadOpts = mutationOptions({
 });
 
 export async function getNotificationInbox(): Promise<NotificationInboxItem[]> {
-    const response = await fetch("/api/v1/notifications/inbox", {
+    const response = await fetch("/api/v1/notifications/inbox?type=ping", {
         credentials: "include",
         method: "GET",
     });
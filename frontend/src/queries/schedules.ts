@@ -1,6 +1,7 @@
 import { mutationOptions, queryOptions } from "@tanstack/react-query";
 import {
     cancelSchedule,
+    getScheduleStats,
     listSchedules,
     pauseSchedule,
     resumeSchedule,
@@ -15,6 +16,14 @@ export const getSchedulesOpts = queryOptions({
     staleTime: 30_000,
 });
 
+export function getScheduleStatsOpts(id: string, from: string, to: string) {
+    return queryOptions({
+        queryKey: [...schedulesQueryKey, id, "stats", from, to] as const,
+        queryFn: () => getScheduleStats(id, from, to),
+        staleTime: 60_000,
+    });
+}
+
 function invalidateSchedules() {
     void queryClient.invalidateQueries({ queryKey: schedulesQueryKey });
 }
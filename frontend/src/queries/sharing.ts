@@ -2,8 +2,15 @@ import { mutationOptions, queryOptions } from "@tanstack/react-query";
 import type { ApiResponse } from "@/types/api";
 import { getApiError } from "@/types/api";
 import type {
+    ChangeRequest,
+    ChangeRequestStatus,
+    CreatePublicShareLinkInput,
+    CreatedPublicShareLink,
     CreateSharingGrantInput,
     PingTaskResult,
+    PublicShare,
+    PublicShareLink,
+    SetAccountabilityInput,
     SharingGrant,
     SharingInvitation,
     SharingUser,
@@ -22,6 +29,18 @@ interface GrantData {
     grant: SharingGrant;
 }
 
+interface ChangeRequestsData {
+    change_requests: ChangeRequest[];
+}
+
+interface ChangeRequestData {
+    change_request: ChangeRequest;
+}
+
+interface PublicShareLinksData {
+    links: PublicShareLink[];
+}
+
 interface UsersData {
     users: SharingUser[];
 }
@@ -34,6 +53,10 @@ export const SharingQueryKeys = {
     sharedWithMe: () => [...SharingQueryKeys.all(), "shared-with-me"] as const,
     invitations: () => [...SharingQueryKeys.all(), "invitations"] as const,
     users: (query: string) => [...SharingQueryKeys.all(), "users", query] as const,
+    changeRequests: (role: ChangeRequestRole, status: ChangeRequestStatus | "") =>
+        [...SharingQueryKeys.all(), "change-requests", role, status] as const,
+    publicLinks: () => [...SharingQueryKeys.all(), "public-links"] as const,
+    publicShare: (token: string) => [...SharingQueryKeys.all(), "public-share", token] as const,
 } as const;
 
 export const getSharingGrantsOpts = queryOptions({
@@ -70,6 +93,92 @@ export const revokeSharingGrantOpts = mutationOptions({
     },
 });
 
+export const setGrantAccountabilityOpts = mutationOptions({
+    mutationFn: setGrantAccountability,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.grants() });
+    },
+});
+
+export const removeGrantAccountabilityOpts = mutationOptions({
+    mutationFn: removeGrantAccountability,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.all() });
+    },
+});
+
+export const setGrantAnalyticsOpts = mutationOptions({
+    mutationFn: setGrantAnalytics,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.grants() });
+    },
+});
+
+export const setGrantApprovalOpts = mutationOptions({
+    mutationFn: setGrantApproval,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.grants() });
+    },
+});
+
+export type ChangeRequestRole = "incoming" | "sent";
+
+export function getChangeRequestsOpts(role: ChangeRequestRole, status: ChangeRequestStatus | "" = "pending") {
+    return queryOptions({
+        queryKey: SharingQueryKeys.changeRequests(role, status),
+        queryFn: () => getChangeRequests(role, status),
+        staleTime: 30 * 1000,
+    });
+}
+
+export const approveChangeRequestOpts = mutationOptions({
+    mutationFn: approveChangeRequest,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: [...SharingQueryKeys.all(), "change-requests"] });
+    },
+});
+
+export const rejectChangeRequestOpts = mutationOptions({
+    mutationFn: rejectChangeRequest,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: [...SharingQueryKeys.all(), "change-requests"] });
+    },
+});
+
+export const cancelChangeRequestOpts = mutationOptions({
+    mutationFn: cancelChangeRequest,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: [...SharingQueryKeys.all(), "change-requests"] });
+    },
+});
+
+export const getPublicShareLinksOpts = queryOptions({
+    queryKey: SharingQueryKeys.publicLinks(),
+    queryFn: getPublicShareLinks,
+});
+
+export const createPublicShareLinkOpts = mutationOptions({
+    mutationFn: createPublicShareLink,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.publicLinks() });
+    },
+});
+
+export const revokePublicShareLinkOpts = mutationOptions({
+    mutationFn: revokePublicShareLink,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.publicLinks() });
+    },
+});
+
+export function getPublicShareOpts(token: string) {
+    return queryOptions({
+        queryKey: SharingQueryKeys.publicShare(token),
+        queryFn: () => getPublicShare(token),
+        retry: false,
+    });
+}
+
 export const getSharingInvitationsOpts = queryOptions({
     queryKey: SharingQueryKeys.invitations(),
     queryFn: getSharingInvitations,
@@ -83,6 +192,21 @@ export const markInvitationReadOpts = mutationOptions({
     },
 });
 
+export const acceptInvitationOpts = mutationOptions({
+    mutationFn: acceptInvitation,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.invitations() });
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.sharedWithMe() });
+    },
+});
+
+export const declineInvitationOpts = mutationOptions({
+    mutationFn: declineInvitation,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SharingQueryKeys.invitations() });
+    },
+});
+
 export const pingTaskOpts = mutationOptions({
     mutationFn: pingTask,
 });
@@ -156,6 +280,217 @@ export async function revokeSharingGrant(grantId: string): Promise<void> {
     }
 }
 
+export async function setGrantAccountability({
+    grantId,
+    ...settings
+}: SetAccountabilityInput): Promise<SharingGrant> {
+    const response = await fetch(`/api/v1/sharing/grants/${grantId}/accountability`, {
+        body: JSON.stringify(settings),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<GrantData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo configurar el compañero de responsabilidad"));
+    }
+    if (!data.data?.grant) {
+        throw new Error("La respuesta no incluyó el permiso");
+    }
+    return data.data.grant;
+}
+
+/** Ends the partnership; both the owner and the partner may call it. */
+export async function removeGrantAccountability(grantId: string): Promise<void> {
+    const response = await fetch(`/api/v1/sharing/grants/${grantId}/accountability`, {
+        credentials: "include",
+        method: "DELETE",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo terminar la responsabilidad compartida"));
+    }
+}
+
+export async function setGrantAnalytics({
+    grantId,
+    shareAnalytics,
+}: {
+    grantId: string;
+    shareAnalytics: boolean;
+}): Promise<SharingGrant> {
+    const response = await fetch(`/api/v1/sharing/grants/${grantId}/analytics`, {
+        body: JSON.stringify({ share_analytics: shareAnalytics }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<GrantData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo actualizar el permiso"));
+    }
+    if (!data.data?.grant) {
+        throw new Error("La respuesta no incluyó el permiso");
+    }
+    return data.data.grant;
+}
+
+export async function setGrantApproval({
+    grantId,
+    requiresApproval,
+}: {
+    grantId: string;
+    requiresApproval: boolean;
+}): Promise<SharingGrant> {
+    const response = await fetch(`/api/v1/sharing/grants/${grantId}/approval`, {
+        body: JSON.stringify({ requires_approval: requiresApproval }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<GrantData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo actualizar el permiso"));
+    }
+    if (!data.data?.grant) {
+        throw new Error("La respuesta no incluyó el permiso");
+    }
+    return data.data.grant;
+}
+
+export async function getChangeRequests(
+    role: ChangeRequestRole,
+    status: ChangeRequestStatus | "",
+): Promise<ChangeRequest[]> {
+    const params = new URLSearchParams({ role, status });
+    const response = await fetch(`/api/v1/sharing/change-requests?${params.toString()}`, {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<ChangeRequestsData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron cargar las solicitudes"));
+    }
+    return data.data?.change_requests ?? [];
+}
+
+export async function approveChangeRequest(requestId: string): Promise<ChangeRequest> {
+    const response = await fetch(`/api/v1/sharing/change-requests/${requestId}/approve`, {
+        credentials: "include",
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<ChangeRequestData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo aprobar la solicitud"));
+    }
+    if (!data.data?.change_request) {
+        throw new Error("La respuesta no incluyó la solicitud");
+    }
+    return data.data.change_request;
+}
+
+export async function rejectChangeRequest({
+    note,
+    requestId,
+}: {
+    note?: string;
+    requestId: string;
+}): Promise<ChangeRequest> {
+    const response = await fetch(`/api/v1/sharing/change-requests/${requestId}/reject`, {
+        body: JSON.stringify({ note: note?.trim() ?? "" }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<ChangeRequestData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo rechazar la solicitud"));
+    }
+    if (!data.data?.change_request) {
+        throw new Error("La respuesta no incluyó la solicitud");
+    }
+    return data.data.change_request;
+}
+
+/** Withdraws a pending request; only its requester may call it. */
+export async function cancelChangeRequest(requestId: string): Promise<void> {
+    const response = await fetch(`/api/v1/sharing/change-requests/${requestId}`, {
+        credentials: "include",
+        method: "DELETE",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo cancelar la solicitud"));
+    }
+}
+
+export async function getPublicShareLinks(): Promise<PublicShareLink[]> {
+    const response = await fetch("/api/v1/sharing/public-links", {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<PublicShareLinksData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron cargar los enlaces"));
+    }
+    return data.data?.links ?? [];
+}
+
+export async function createPublicShareLink(
+    input: CreatePublicShareLinkInput,
+): Promise<CreatedPublicShareLink> {
+    const response = await fetch("/api/v1/sharing/public-links", {
+        body: JSON.stringify(input),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<CreatedPublicShareLink>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo crear el enlace"));
+    }
+    if (!data.data?.token) {
+        throw new Error("La respuesta no incluyó el enlace");
+    }
+    return data.data;
+}
+
+export async function revokePublicShareLink(linkId: string): Promise<void> {
+    const response = await fetch(`/api/v1/sharing/public-links/${linkId}`, {
+        credentials: "include",
+        method: "DELETE",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo revocar el enlace"));
+    }
+}
+
+/** Opens a public link; it needs no session. */
+export async function getPublicShare(token: string): Promise<PublicShare> {
+    const response = await fetch(`/api/v1/public/share/${encodeURIComponent(token)}`, {
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<PublicShare>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Enlace no disponible"));
+    }
+    if (!data.data) {
+        throw new Error("La respuesta no incluyó el enlace");
+    }
+    return data.data;
+}
+
 export async function getSharingInvitations(): Promise<SharingInvitation[]> {
     const response = await fetch("/api/v1/sharing/invitations", {
         credentials: "include",
@@ -179,6 +514,28 @@ export async function markInvitationRead(invitationId: string): Promise<void> {
     }
 }
 
+export async function acceptInvitation(invitationId: string): Promise<void> {
+    const response = await fetch(`/api/v1/sharing/invitations/${invitationId}/accept`, {
+        credentials: "include",
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo aceptar la invitación"));
+    }
+}
+
+export async function declineInvitation(invitationId: string): Promise<void> {
+    const response = await fetch(`/api/v1/sharing/invitations/${invitationId}/decline`, {
+        credentials: "include",
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo rechazar la invitación"));
+    }
+}
+
 export async function pingTask({
     message,
     taskId,
@@ -0,0 +1,315 @@
+import { mutationOptions, queryOptions } from "@tanstack/react-query";
+import type { ApiResponse } from "@/types/api";
+import { getApiError } from "@/types/api";
+import type {
+    Space,
+    SpaceDayProgress,
+    SpaceDayTasks,
+    SpaceInput,
+    SpaceMember,
+    SpaceRole,
+} from "@/types/space";
+import type { Task } from "@/types/task";
+import { queryClient } from "./queryClient";
+
+interface SpacesData {
+    spaces: Space[];
+}
+
+interface SpaceData {
+    space: Space;
+    members?: SpaceMember[];
+}
+
+interface MembersData {
+    members: SpaceMember[];
+}
+
+interface ProgressData {
+    progress: SpaceDayProgress;
+}
+
+interface TaskData {
+    task: Task;
+}
+
+export const SpaceQueryKeys = {
+    all: () => ["spaces"] as const,
+    list: () => [...SpaceQueryKeys.all(), "list"] as const,
+    detail: (spaceId: string) => [...SpaceQueryKeys.all(), spaceId] as const,
+    tasks: (spaceId: string, date: string) => [...SpaceQueryKeys.detail(spaceId), "tasks", date] as const,
+    progress: (spaceId: string, date: string) =>
+        [...SpaceQueryKeys.detail(spaceId), "progress", date] as const,
+} as const;
+
+export const getSpacesOpts = queryOptions({
+    queryKey: SpaceQueryKeys.list(),
+    queryFn: getSpaces,
+});
+
+export function getSpaceOpts(spaceId: string) {
+    return queryOptions({
+        queryKey: SpaceQueryKeys.detail(spaceId),
+        queryFn: () => getSpace(spaceId),
+    });
+}
+
+export function getSpaceDayTasksOpts(spaceId: string, date: string) {
+    return queryOptions({
+        queryKey: SpaceQueryKeys.tasks(spaceId, date),
+        queryFn: () => getSpaceDayTasks(spaceId, date),
+        staleTime: 30 * 1000,
+    });
+}
+
+export function getSpaceProgressOpts(spaceId: string, date: string) {
+    return queryOptions({
+        queryKey: SpaceQueryKeys.progress(spaceId, date),
+        queryFn: () => getSpaceProgress(spaceId, date),
+        staleTime: 30 * 1000,
+    });
+}
+
+export const createSpaceOpts = mutationOptions({
+    mutationFn: createSpace,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.list() });
+    },
+});
+
+export const deleteSpaceOpts = mutationOptions({
+    mutationFn: deleteSpace,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.all() });
+    },
+});
+
+export const addSpaceMemberOpts = mutationOptions({
+    mutationFn: addSpaceMember,
+    onSuccess: (_data, { spaceId }) => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.detail(spaceId) });
+    },
+});
+
+export const updateSpaceMemberOpts = mutationOptions({
+    mutationFn: updateSpaceMember,
+    onSuccess: (_data, { spaceId }) => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.detail(spaceId) });
+    },
+});
+
+export const removeSpaceMemberOpts = mutationOptions({
+    mutationFn: removeSpaceMember,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.all() });
+    },
+});
+
+export const setScheduleSpaceOpts = mutationOptions({
+    mutationFn: setScheduleSpace,
+    onSuccess: (_data, { spaceId }) => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.detail(spaceId) });
+    },
+});
+
+export const assignSpaceTaskOpts = mutationOptions({
+    mutationFn: assignSpaceTask,
+    onSuccess: (_data, { spaceId }) => {
+        void queryClient.invalidateQueries({ queryKey: SpaceQueryKeys.detail(spaceId) });
+    },
+});
+
+export async function getSpaces(): Promise<Space[]> {
+    const response = await fetch("/api/v1/spaces", {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<SpacesData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron cargar los espacios"));
+    }
+    return data.data?.spaces ?? [];
+}
+
+export async function getSpace(spaceId: string): Promise<{ space: Space; members: SpaceMember[] }> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}`, {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<SpaceData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo cargar el espacio"));
+    }
+    if (!data.data?.space) {
+        throw new Error("La respuesta no incluyó el espacio");
+    }
+    return { space: data.data.space, members: data.data.members ?? [] };
+}
+
+export async function createSpace(input: SpaceInput): Promise<Space> {
+    const response = await fetch("/api/v1/spaces", {
+        body: JSON.stringify(input),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<SpaceData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo crear el espacio"));
+    }
+    if (!data.data?.space) {
+        throw new Error("La respuesta no incluyó el espacio");
+    }
+    return data.data.space;
+}
+
+export async function deleteSpace(spaceId: string): Promise<void> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}`, {
+        credentials: "include",
+        method: "DELETE",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo eliminar el espacio"));
+    }
+}
+
+export async function addSpaceMember({
+    role,
+    spaceId,
+    user,
+}: {
+    role: SpaceRole;
+    spaceId: string;
+    user: string;
+}): Promise<SpaceMember[]> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}/members`, {
+        body: JSON.stringify({ role, user: user.trim() }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<MembersData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo agregar al miembro"));
+    }
+    return data.data?.members ?? [];
+}
+
+export async function updateSpaceMember({
+    role,
+    spaceId,
+    userId,
+}: {
+    role: SpaceRole;
+    spaceId: string;
+    userId: string;
+}): Promise<SpaceMember[]> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}/members/${userId}`, {
+        body: JSON.stringify({ role }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<MembersData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo actualizar el rol"));
+    }
+    return data.data?.members ?? [];
+}
+
+export async function removeSpaceMember({
+    spaceId,
+    userId,
+}: {
+    spaceId: string;
+    userId: string;
+}): Promise<void> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}/members/${userId}`, {
+        credentials: "include",
+        method: "DELETE",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, never>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo eliminar al miembro"));
+    }
+}
+
+/** Moves a schedule into the space, or out of it when `remove` is set. */
+export async function setScheduleSpace({
+    remove,
+    scheduleId,
+    spaceId,
+}: {
+    remove?: boolean;
+    scheduleId: string;
+    spaceId: string;
+}): Promise<void> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}/schedules/${scheduleId}`, {
+        credentials: "include",
+        method: remove ? "DELETE" : "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<Record<string, unknown>>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo actualizar la agenda del espacio"));
+    }
+}
+
+export async function getSpaceDayTasks(spaceId: string, date: string): Promise<SpaceDayTasks> {
+    const params = new URLSearchParams({ date });
+    const response = await fetch(`/api/v1/spaces/${spaceId}/tasks?${params.toString()}`, {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<SpaceDayTasks>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron cargar las tareas del espacio"));
+    }
+    return data.data ?? { tasks: [], date, space_id: spaceId };
+}
+
+export async function getSpaceProgress(spaceId: string, date: string): Promise<SpaceDayProgress | null> {
+    const params = new URLSearchParams({ date });
+    const response = await fetch(`/api/v1/spaces/${spaceId}/progress?${params.toString()}`, {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<ProgressData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo cargar el progreso del espacio"));
+    }
+    return data.data?.progress ?? null;
+}
+
+export async function assignSpaceTask({
+    spaceId,
+    taskId,
+    userId,
+}: {
+    spaceId: string;
+    taskId: string;
+    userId: string | null;
+}): Promise<Task> {
+    const response = await fetch(`/api/v1/spaces/${spaceId}/tasks/${taskId}/assignee`, {
+        body: JSON.stringify({ user_id: userId }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<TaskData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo asignar la tarea"));
+    }
+    if (!data.data?.task) {
+        throw new Error("La respuesta no incluyó la tarea");
+    }
+    return data.data.task;
+}
@@ -1,15 +1,24 @@
 import type { TTask } from "@/lib/schemas/task";
 import type {
+    Task,
+    TaskBreakdownDimension,
     TaskFeedItem,
+    TaskHeatmap,
+    TaskHeatmapInput,
     TaskHistoryRange,
     TaskMetricsRange,
+    TaskScoreWeights,
     TaskStatsRangeInput,
+    TaskStreak,
+    TimeAccountingRange,
+    TimeGroupingInput,
     UpdateTaskInput,
 } from "@/types/task";
 import { mutationOptions, queryOptions } from "@tanstack/react-query";
 import { queryClient } from "./queryClient";
 import type { ApiResponse } from "@/types/api";
 import { getApiError } from "@/types/api";
+import type { ChangeRequest } from "@/types/sharing";
 
 export type UpdateTaskPayload = UpdateTaskInput;
 
@@ -17,6 +26,12 @@ interface TaskData {
     task: Partial<TTask>;
 }
 
+/** Grantees in approval mode get a pending change request instead of the task. */
+interface UpdateTaskData {
+    task?: Partial<TTask>;
+    change_request?: ChangeRequest;
+}
+
 interface TasksData {
     date?: string;
     tasks: RawTaskResponse[];
@@ -64,6 +79,7 @@ export interface DayProgress {
     failed: number;
     in_progress: number;
     percentage: number;
+    score: number;
 }
 
 interface DayProgressData {
@@ -78,6 +94,42 @@ interface TaskMetricsData {
     metrics: TaskMetricsRange;
 }
 
+interface TaskHeatmapData {
+    heatmap: TaskHeatmap;
+}
+
+interface TimeAccountingData {
+    time: TimeAccountingRange;
+}
+
+interface TaskStreakData {
+    streak: TaskStreak;
+}
+
+interface RestWeekdaysData {
+    rest_weekdays: number[];
+}
+
+interface ScoreWeightsData {
+    score_weights: TaskScoreWeights;
+}
+
+export interface CoachedOwner {
+    owner_user_id: string;
+    username: string;
+    fullname: string;
+    grant_id: string;
+    today: DayProgress;
+    history: TaskHistoryRange;
+    metrics: TaskMetricsRange;
+}
+
+interface CoachDashboardData {
+    owners: CoachedOwner[];
+    from: string;
+    to: string;
+}
+
 export const getTasksOpts = queryOptions({
     queryKey: TasksQueryKeys.all(),
     queryFn: getTasks,
@@ -143,22 +195,162 @@ export async function getDayTasks(date: string) {
     return { feedItems, date: data.data?.date ?? date };
 }
 
-export function getTaskHistoryOpts(range: TaskStatsRangeInput) {
+export function getTaskHistoryOpts(
+    range: TaskStatsRangeInput,
+    breakdown?: TaskBreakdownDimension,
+    grouping: TimeGroupingInput = {},
+) {
     return queryOptions({
-        queryKey: [...TasksQueryKeys.history(), range.from, range.to] as const,
-        queryFn: () => getTaskHistory(range),
+        queryKey: [
+            ...TasksQueryKeys.history(),
+            range.from,
+            range.to,
+            range.ownerUserId ?? "",
+            breakdown ?? "",
+            grouping.granularity ?? "day",
+            grouping.weekStart ?? "",
+        ] as const,
+        queryFn: () => getTaskHistory(range, breakdown, grouping),
         staleTime: 60 * 1000,
     });
 }
 
 export function getTaskMetricsOpts(range: TaskStatsRangeInput) {
     return queryOptions({
-        queryKey: [...TasksQueryKeys.progress(), "metrics", range.from, range.to] as const,
+        queryKey: [...TasksQueryKeys.progress(), "metrics", range.from, range.to, range.ownerUserId ?? ""] as const,
         queryFn: () => getTaskMetrics(range),
         staleTime: 60 * 1000,
     });
 }
 
+export function getTaskHeatmapOpts(input: TaskHeatmapInput = {}) {
+    return queryOptions({
+        queryKey: [
+            ...TasksQueryKeys.progress(),
+            "heatmap",
+            input.year ?? "",
+            input.category ?? "",
+            input.scheduleId ?? "",
+            input.ownerUserId ?? "",
+        ] as const,
+        queryFn: () => getTaskHeatmap(input),
+        staleTime: 5 * 60 * 1000,
+    });
+}
+
+export function getTaskTimeAccountingOpts(range: TaskStatsRangeInput, grouping: TimeGroupingInput = {}) {
+    return queryOptions({
+        queryKey: [
+            ...TasksQueryKeys.progress(),
+            "time",
+            range.from,
+            range.to,
+            range.ownerUserId ?? "",
+            grouping.granularity ?? "day",
+            grouping.weekStart ?? "",
+        ] as const,
+        queryFn: () => getTaskTimeAccounting(range, grouping),
+        staleTime: 60 * 1000,
+    });
+}
+
+export function getTaskStreakOpts(range: TaskStatsRangeInput) {
+    return queryOptions({
+        queryKey: [...TasksQueryKeys.progress(), "streak", range.from, range.to, range.ownerUserId ?? ""] as const,
+        queryFn: () => getTaskStreak(range),
+        staleTime: 60 * 1000,
+    });
+}
+
+export const setStreakRestDaysOpts = mutationOptions({
+    mutationFn: setStreakRestDays,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: TasksQueryKeys.progress() });
+    },
+});
+
+export async function getTaskStreak(range: TaskStatsRangeInput): Promise<TaskStreak> {
+    const params = taskStatsParams(range);
+    const response = await fetch(`/api/v1/tasks/streak?${params.toString()}`, {
+        method: "GET",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<TaskStreakData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Error al obtener la racha"));
+    }
+    if (!data.data?.streak) {
+        throw new Error("La respuesta no incluyó la racha");
+    }
+    return data.data.streak;
+}
+
+export async function setStreakRestDays(restWeekdays: number[]): Promise<number[]> {
+    const response = await fetch("/api/v1/tasks/streak/rest-days", {
+        body: JSON.stringify({ rest_weekdays: restWeekdays }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<RestWeekdaysData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron guardar los días de descanso"));
+    }
+    return data.data?.rest_weekdays ?? [];
+}
+
+export function getScoreWeightsOpts() {
+    return queryOptions({
+        queryKey: [...TasksQueryKeys.progress(), "score-weights"] as const,
+        queryFn: getScoreWeights,
+        staleTime: 5 * 60 * 1000,
+    });
+}
+
+export const setScoreWeightsOpts = mutationOptions({
+    mutationFn: setScoreWeights,
+    onSuccess: () => {
+        void queryClient.invalidateQueries({ queryKey: TasksQueryKeys.progress() });
+    },
+});
+
+export async function getScoreWeights(): Promise<TaskScoreWeights> {
+    const response = await fetch("/api/v1/tasks/score-weights", {
+        method: "GET",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<ScoreWeightsData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Error al obtener los pesos de la puntuación"));
+    }
+    if (!data.data?.score_weights) {
+        throw new Error("La respuesta no incluyó los pesos de la puntuación");
+    }
+    return data.data.score_weights;
+}
+
+/** Fields left out keep their current value. */
+export async function setScoreWeights(weights: Partial<TaskScoreWeights>): Promise<TaskScoreWeights> {
+    const response = await fetch("/api/v1/tasks/score-weights", {
+        body: JSON.stringify(weights),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<ScoreWeightsData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron guardar los pesos de la puntuación"));
+    }
+    if (!data.data?.score_weights) {
+        throw new Error("La respuesta no incluyó los pesos de la puntuación");
+    }
+    return data.data.score_weights;
+}
+
 export async function getTodayProgress(): Promise<DayProgress> {
     const response = await fetch("/api/v1/tasks/progress", {
         method: "GET",
@@ -174,11 +366,64 @@ export async function getTodayProgress(): Promise<DayProgress> {
     return data.data.progress;
 }
 
-export async function getTaskHistory(range: TaskStatsRangeInput): Promise<TaskHistoryRange> {
+function taskStatsParams(range: TaskStatsRangeInput) {
     const params = new URLSearchParams({
         from: range.from,
         to: range.to,
     });
+    if (range.ownerUserId) {
+        params.set("owner_user_id", range.ownerUserId);
+    }
+    return params;
+}
+
+function setTimeGroupingParams(params: URLSearchParams, grouping: TimeGroupingInput) {
+    if (grouping.granularity) {
+        params.set("granularity", grouping.granularity);
+    }
+    if (grouping.weekStart !== undefined) {
+        params.set("week_start", String(grouping.weekStart));
+    }
+}
+
+export function getCoachDashboardOpts(range: TaskStatsRangeInput, ownerUserIds: string[] = []) {
+    return queryOptions({
+        queryKey: [...TasksQueryKeys.progress(), "coach", range.from, range.to, ...ownerUserIds] as const,
+        queryFn: () => getCoachDashboard(range, ownerUserIds),
+        staleTime: 60 * 1000,
+    });
+}
+
+/** Compares the owners that share their analytics with the current user. */
+export async function getCoachDashboard(
+    range: TaskStatsRangeInput,
+    ownerUserIds: string[] = [],
+): Promise<CoachedOwner[]> {
+    const params = taskStatsParams(range);
+    if (ownerUserIds.length > 0) {
+        params.set("owner_user_ids", ownerUserIds.join(","));
+    }
+    const response = await fetch(`/api/v1/tasks/coach?${params.toString()}`, {
+        method: "GET",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<CoachDashboardData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Error al obtener el panel"));
+    }
+    return data.data?.owners ?? [];
+}
+
+export async function getTaskHistory(
+    range: TaskStatsRangeInput,
+    breakdown?: TaskBreakdownDimension,
+    grouping: TimeGroupingInput = {},
+): Promise<TaskHistoryRange> {
+    const params = taskStatsParams(range);
+    if (breakdown) {
+        params.set("breakdown", breakdown);
+    }
+    setTimeGroupingParams(params, grouping);
     const response = await fetch(`/api/v1/tasks/history?${params.toString()}`, {
         method: "GET",
         credentials: "include",
@@ -194,10 +439,7 @@ export async function getTaskHistory(range: TaskStatsRangeInput): Promise<TaskHi
 }
 
 export async function getTaskMetrics(range: TaskStatsRangeInput): Promise<TaskMetricsRange> {
-    const params = new URLSearchParams({
-        from: range.from,
-        to: range.to,
-    });
+    const params = taskStatsParams(range);
     const response = await fetch(`/api/v1/tasks/metrics?${params.toString()}`, {
         method: "GET",
         credentials: "include",
@@ -212,6 +454,54 @@ export async function getTaskMetrics(range: TaskStatsRangeInput): Promise<TaskMe
     return data.data.metrics;
 }
 
+export async function getTaskHeatmap(input: TaskHeatmapInput = {}): Promise<TaskHeatmap> {
+    const params = new URLSearchParams();
+    if (input.year) {
+        params.set("year", String(input.year));
+    }
+    if (input.category) {
+        params.set("category", input.category);
+    }
+    if (input.scheduleId) {
+        params.set("schedule_id", input.scheduleId);
+    }
+    if (input.ownerUserId) {
+        params.set("owner_user_id", input.ownerUserId);
+    }
+    const response = await fetch(`/api/v1/tasks/heatmap?${params.toString()}`, {
+        method: "GET",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<TaskHeatmapData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Error al obtener el mapa de actividad"));
+    }
+    if (!data.data?.heatmap) {
+        throw new Error("La respuesta no incluyó el mapa de actividad");
+    }
+    return data.data.heatmap;
+}
+
+export async function getTaskTimeAccounting(
+    range: TaskStatsRangeInput,
+    grouping: TimeGroupingInput = {},
+): Promise<TimeAccountingRange> {
+    const params = taskStatsParams(range);
+    setTimeGroupingParams(params, grouping);
+    const response = await fetch(`/api/v1/tasks/time?${params.toString()}`, {
+        method: "GET",
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<TimeAccountingData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "Error al obtener el tiempo"));
+    }
+    if (!data.data?.time) {
+        throw new Error("La respuesta no incluyó el tiempo");
+    }
+    return data.data.time;
+}
+
 export function getTaskByIdOpts(taskId: string) {
     return queryOptions({
         queryKey: TasksQueryKeys.byId(taskId),
@@ -526,7 +816,7 @@ export async function updateTask({
             title,
         }),
     });
-    const data = (await response.json()) as ApiResponse<TaskData>;
+    const data = (await response.json()) as ApiResponse<UpdateTaskData>;
     if (!response.ok) {
         throw new Error(getApiError(data, "Error al actualizar tarea"));
     }
@@ -607,3 +897,84 @@ function dateToTimeString(value: Date | null | undefined) {
     }
     return `${String(value.getHours()).padStart(2, "0")}:${String(value.getMinutes()).padStart(2, "0")}`;
 }
+
+interface AssignedTasksData {
+    tasks: Task[];
+}
+
+interface AssignedTaskData {
+    task: Task;
+}
+
+export const TaskAssignmentQueryKeys = {
+    all: () => [...TasksQueryKeys.all(), "assignments"] as const,
+} as const;
+
+export const getTaskAssignmentsOpts = queryOptions({
+    queryKey: TaskAssignmentQueryKeys.all(),
+    queryFn: getTaskAssignments,
+});
+
+export async function getTaskAssignments(): Promise<Task[]> {
+    const response = await fetch("/api/v1/tasks/assigned", {
+        credentials: "include",
+        method: "GET",
+    });
+    const data = (await response.json()) as ApiResponse<AssignedTasksData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron cargar las tareas asignadas"));
+    }
+    return data.data?.tasks ?? [];
+}
+
+/** Hands the task to another user, by id or username/email. A null user takes it back. */
+export async function delegateTask({
+    taskId,
+    user,
+    userId,
+}: {
+    taskId: string;
+    user?: string | null;
+    userId?: string | null;
+}): Promise<Task> {
+    const response = await fetch(`/api/v1/tasks/${taskId}/assignee`, {
+        body: JSON.stringify({ user: user ?? "", user_id: userId ?? "" }),
+        credentials: "include",
+        headers: {
+            "Content-Type": "application/json",
+        },
+        method: "PUT",
+    });
+    const data = (await response.json()) as ApiResponse<AssignedTaskData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo asignar la tarea"));
+    }
+    if (!data.data?.task) {
+        throw new Error("La respuesta no incluyó la tarea");
+    }
+    return data.data.task;
+}
+
+export async function acceptTaskAssignment({ taskId }: { taskId: string }): Promise<Task> {
+    return respondToTaskAssignment(taskId, "accept");
+}
+
+export async function declineTaskAssignment({ taskId }: { taskId: string }): Promise<Task> {
+    return respondToTaskAssignment(taskId, "decline");
+}
+
+async function respondToTaskAssignment(taskId: string, answer: "accept" | "decline"): Promise<Task> {
+    const response = await fetch(`/api/v1/tasks/${taskId}/assignment/${answer}`, {
+        credentials: "include",
+        method: "POST",
+    });
+    const data = (await response.json()) as ApiResponse<AssignedTaskData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudo responder la asignación"));
+    }
+    if (!data.data?.task) {
+        throw new Error("La respuesta no incluyó la tarea");
+    }
+    queryClient.invalidateQueries({ queryKey: TasksQueryKeys.all() });
+    return data.data.task;
+}
@@ -1,6 +1,6 @@
 import type { ApiResponse } from "@/types/api";
 import { getApiError } from "@/types/api";
-import type { CreateScheduleInput, Schedule } from "@/types/schedule";
+import type { CreateScheduleInput, Schedule, ScheduleStats } from "@/types/schedule";
 
 interface ScheduleData {
     schedule: Schedule;
@@ -10,6 +10,10 @@ interface SchedulesData {
     schedules: Schedule[];
 }
 
+interface ScheduleStatsData {
+    stats: ScheduleStats;
+}
+
 export async function createSchedule(payload: CreateScheduleInput): Promise<Schedule> {
     const response = await fetch("/api/v1/schedules", {
         body: JSON.stringify(payload),
@@ -73,3 +77,18 @@ export async function cancelSchedule(id: string): Promise<void> {
         throw new Error(getApiError(data ?? ({} as ApiResponse<unknown>), "No se pudo cancelar la rutina"));
     }
 }
+
+export async function getScheduleStats(id: string, from: string, to: string): Promise<ScheduleStats> {
+    const params = new URLSearchParams({ from, to });
+    const response = await fetch(`/api/v1/schedules/${id}/stats?${params.toString()}`, {
+        credentials: "include",
+    });
+    const data = (await response.json()) as ApiResponse<ScheduleStatsData>;
+    if (!response.ok) {
+        throw new Error(getApiError(data, "No se pudieron cargar las estadísticas"));
+    }
+    if (!data.data?.stats) {
+        throw new Error("La respuesta no incluyó las estadísticas");
+    }
+    return data.data.stats;
+}
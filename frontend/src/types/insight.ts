@@ -0,0 +1,76 @@
+export type InsightKind = "weekday_skip" | "completion_decline" | "late_start" | "late_finish";
+
+/** Rates are percentages of missed tasks. */
+export interface WeekdaySkipEvidence {
+    schedule_title: string;
+    from: string;
+    to: string;
+    /** 0 = Sunday. */
+    weekday: number;
+    weekday_total: number;
+    weekday_missed: number;
+    weekday_rate: number;
+    other_total: number;
+    other_missed: number;
+    other_rate: number;
+    missed_dates: string[];
+}
+
+export interface CompletionWeek {
+    start: string;
+    total: number;
+    completed: number;
+    percentage: number;
+}
+
+export interface CompletionDeclineEvidence {
+    schedule_title: string;
+    previous_from: string;
+    previous_to: string;
+    previous_total: number;
+    previous_completed: number;
+    previous_percentage: number;
+    recent_from: string;
+    recent_to: string;
+    recent_total: number;
+    recent_completed: number;
+    recent_percentage: number;
+    /** Relative change of the percentage, negative for a drop. */
+    change: number;
+    weeks: CompletionWeek[];
+}
+
+/** Minutes after the plan; early starts or finishes are negative. */
+export interface LatenessSample {
+    date: string;
+    minutes: number;
+}
+
+export interface LatenessEvidence {
+    schedule_title: string;
+    from: string;
+    to: string;
+    samples: number;
+    late_samples: number;
+    median_minutes: number;
+    average_minutes: number;
+    delays: LatenessSample[];
+}
+
+interface InsightBase {
+    id: string;
+    user_id: string;
+    schedule_task_id: string;
+    /** Tells apart insights of the same kind, like the weekday of a skip pattern. */
+    subject?: string;
+    title: string;
+    body: string;
+    detected_at: string;
+    updated_at: string;
+    dismissed_at?: string;
+}
+
+export type Insight =
+    | (InsightBase & { kind: "weekday_skip"; evidence: WeekdaySkipEvidence })
+    | (InsightBase & { kind: "completion_decline"; evidence: CompletionDeclineEvidence })
+    | (InsightBase & { kind: "late_start" | "late_finish"; evidence: LatenessEvidence });
@@ -38,6 +38,7 @@ export interface Schedule {
     frequency: ScheduleFrequency;
     frequencyConfig: Record<string, unknown>;
     category: string;
+    spaceId?: string;
     status: ScheduleStatus;
     priority: LegacyPriority;
     createdAt: string;
@@ -69,3 +70,30 @@ export interface CreateScheduleInput {
 }
 
 export type UpdateScheduleInput = Partial<CreateScheduleInput>;
+
+export type ScheduleDayStatus = "completed" | "missed" | "pending";
+
+/** A day the schedule was due; days it does not repeat on are omitted. */
+export interface ScheduleStatsDay {
+    date: string;
+    status: ScheduleDayStatus;
+    current_count: number;
+}
+
+export interface ScheduleStats {
+    schedule_id: string;
+    from: string;
+    to: string;
+    scheduled_days: number;
+    completed: number;
+    missed: number;
+    /** Completed over settled days; today is not settled until completed. */
+    completion_rate: number;
+    current_streak: number;
+    longest_streak: number;
+    target_count?: number;
+    average_count: number;
+    /** Average count as a percentage of target_count. */
+    target_rate?: number;
+    days: ScheduleStatsDay[];
+}
@@ -9,6 +9,10 @@ export interface SharingGrant {
     can_create: boolean;
     can_edit_tasks: boolean;
     can_ping: boolean;
+    scope_categories: string[];
+    scope_schedule_ids: string[];
+    expires_at?: string | null;
+    accepted_at?: string | null;
     owner_username?: string;
     owner_fullname?: string;
     owner_email?: string;
@@ -18,6 +22,24 @@ export interface SharingGrant {
     created_at: string;
     updated_at: string;
     revoked_at?: string | null;
+    /** Grantee edits and new schedules wait for the owner's approval. */
+    requires_approval: boolean;
+    /** The grantee may read the owner's progress, history and metrics. */
+    share_analytics: boolean;
+    accountability?: AccountabilitySettings | null;
+}
+
+/** Present on grants whose grantee is an accountability partner. */
+export interface AccountabilitySettings {
+    grace_minutes: number;
+    weekly_summary: boolean;
+    created_at: string;
+}
+
+export interface SetAccountabilityInput {
+    grantId: string;
+    grace_minutes?: number;
+    weekly_summary?: boolean;
 }
 
 export interface SharingUser {
@@ -30,6 +52,115 @@ export interface SharingUser {
 export interface CreateSharingGrantInput {
     access_level: SharingAccessLevel;
     grantee: string;
+    scope_categories?: string[];
+    scope_schedule_ids?: string[];
+    expires_at?: string;
+    requires_approval?: boolean;
+    share_analytics?: boolean;
+}
+
+export type ChangeRequestKind = "task_update" | "schedule_create";
+export type ChangeRequestStatus = "pending" | "approved" | "rejected" | "cancelled";
+
+/** One changed field; `from` is null for schedules that do not exist yet. */
+export interface ChangeRequestField {
+    field: string;
+    from: string | number | boolean | null;
+    to: string | number | boolean | null;
+}
+
+export interface ChangeRequest {
+    id: string;
+    grant_id?: string;
+    owner_user_id: string;
+    owner_username: string;
+    owner_fullname: string;
+    requester_user_id: string;
+    requester_username: string;
+    requester_fullname: string;
+    kind: ChangeRequestKind;
+    task_id?: string;
+    task_title?: string;
+    diff: ChangeRequestField[];
+    status: ChangeRequestStatus;
+    review_note?: string;
+    created_at: string;
+    resolved_at?: string | null;
+}
+
+export type PublicShareScope = "day" | "range" | "category";
+
+/** A tokenized read-only link; `from` and `to` are YYYY-MM-DD dates. */
+export interface PublicShareLink {
+    id: string;
+    owner_user_id: string;
+    label?: string;
+    scope: PublicShareScope;
+    from: string;
+    to: string;
+    category?: string;
+    expires_at?: string | null;
+    revoked_at?: string | null;
+    access_count: number;
+    last_accessed_at?: string | null;
+    created_at: string;
+}
+
+export interface CreatePublicShareLinkInput {
+    scope: PublicShareScope;
+    label?: string;
+    /** Required for day links. */
+    date?: string;
+    /** Required for range and category links. */
+    from?: string;
+    to?: string;
+    category?: string;
+    /** Defaults to 30 days from now. */
+    expires_at?: string;
+}
+
+/** Returned only once, when the link is created. */
+export interface CreatedPublicShareLink {
+    link: PublicShareLink;
+    token: string;
+    url: string;
+}
+
+export interface PublicShareTask {
+    date: string;
+    title: string;
+    description?: string;
+    category?: string;
+    priority_level: string;
+    status_level: string;
+    is_required: boolean;
+    schedule_start_time?: string;
+    schedule_end_time?: string;
+    duration_minutes?: number;
+    target_count?: number;
+    current_count: number;
+    completed_at?: string | null;
+}
+
+export interface PublicShareDay {
+    date: string;
+    total: number;
+    completed: number;
+    percentage: number;
+    tasks: PublicShareTask[];
+}
+
+export interface PublicShare {
+    link: {
+        label?: string;
+        owner_name: string;
+        scope: PublicShareScope;
+        from: string;
+        to: string;
+        category?: string;
+        expires_at?: string | null;
+    };
+    days: PublicShareDay[];
 }
 
 export interface TaskPing {
@@ -43,14 +174,22 @@ export interface TaskPing {
     read_at?: string | null;
 }
 
+export type SharingInvitationStatus = "pending" | "accepted" | "declined" | "cancelled" | "expired";
+
 export interface SharingInvitation {
     id: string;
     grant_id: string;
     owner_user_id: string;
     owner_username: string;
     owner_fullname: string;
+    grantee_user_id: string;
+    grantee_username: string;
+    grantee_fullname: string;
     access_level: string;
+    status: SharingInvitationStatus;
     read_at?: string | null;
+    responded_at?: string | null;
+    expires_at?: string | null;
     created_at: string;
 }
 
@@ -0,0 +1,52 @@
+import type { Task } from "./task";
+
+export type SpaceRole = "owner" | "manager" | "member" | "viewer";
+
+export interface Space {
+    id: string;
+    name: string;
+    description?: string;
+    created_by: string;
+    role: SpaceRole;
+    member_count: number;
+    created_at: string;
+    updated_at: string;
+}
+
+export interface SpaceMember {
+    space_id: string;
+    user_id: string;
+    username: string;
+    fullname: string;
+    role: SpaceRole;
+    created_at: string;
+}
+
+export interface SpaceInput {
+    name: string;
+    description?: string;
+}
+
+export interface SpaceMemberProgress {
+    user_id: string;
+    username: string;
+    fullname: string;
+    total: number;
+    completed: number;
+    percentage: number;
+}
+
+export interface SpaceDayProgress {
+    space_id: string;
+    date: string;
+    total: number;
+    completed: number;
+    percentage: number;
+    members: SpaceMemberProgress[];
+}
+
+export interface SpaceDayTasks {
+    tasks: Task[];
+    date: string;
+    space_id: string;
+}
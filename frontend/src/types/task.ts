@@ -5,6 +5,8 @@ export type Priority = LegacyPriority;
 
 export type TaskStatus = "pending" | "in_progress" | "completed" | "skipped" | "failed";
 
+export type TaskAssignmentStatus = "pending" | "accepted" | "declined";
+
 export interface Task {
     id: string;
     userId: string;
@@ -27,6 +29,10 @@ export interface Task {
     notes: string | null;
     frequency: ScheduleFrequency | null;
     category: string | null;
+    spaceId?: string;
+    assigneeUserId?: string;
+    assignmentStatus?: TaskAssignmentStatus;
+    assignedByUserId?: string;
     required: boolean;
     isRequired: boolean;
     canEdit?: boolean;
@@ -50,10 +56,15 @@ export interface TaskFeedItem {
     status_level: TaskStatus;
     target_count?: number | null;
     title: string;
+    owner_user_id?: string;
+    assignee_user_id?: string;
+    assignment_status?: TaskAssignmentStatus;
 }
 
+/** One day, or the bucket from date to end_date in a grouped history. */
 export interface TaskHistoryDay {
     date: string;
+    end_date?: string;
     total: number;
     completed: number;
     pending: number;
@@ -61,14 +72,48 @@ export interface TaskHistoryDay {
     failed: number;
     in_progress: number;
     percentage: number;
+    /** Weighted percentage, see TaskScoreWeights. */
+    score: number;
+    /** Present when the history was split by a dimension; missing on empty days. */
+    breakdown?: TaskBreakdownCount[];
 }
 
 export interface TaskHistoryRange {
     from: string;
     to: string;
+    granularity?: TimeGranularity;
+    /** First day of the week (0 = Sunday) when grouped by week. */
+    week_start?: number;
+    breakdown?: TaskBreakdownDimension;
     days: TaskHistoryDay[];
 }
 
+export type TaskBreakdownDimension = "category" | "priority" | "required";
+
+/** Key is the category (empty when uncategorized), the priority, or "required"/"optional". */
+export interface TaskBreakdownCount {
+    key: string;
+    total: number;
+    completed: number;
+    percentage: number;
+}
+
+export interface TaskBreakdownSlice extends TaskBreakdownCount {
+    previous_total: number;
+    previous_completed: number;
+    previous_percentage: number;
+    /** Percentage points vs the previous period; absent when it had no tasks. */
+    trend?: number;
+}
+
+export interface TaskBreakdowns {
+    previous_from: string;
+    previous_to: string;
+    category: TaskBreakdownSlice[];
+    priority: TaskBreakdownSlice[];
+    required: TaskBreakdownSlice[];
+}
+
 export interface TaskMetricsBestDay {
     date: string;
     percentage: number;
@@ -84,16 +129,129 @@ export interface TaskMetricsRange {
     failed: number;
     in_progress: number;
     percentage: number;
+    score: number;
     days_count: number;
     completions_count: number;
     active_days: number;
     best_day?: TaskMetricsBestDay | null;
     current_streak: number;
+    longest_streak: number;
+    /** Missed days in the range covered by a streak freeze. */
+    frozen_days: number;
+    breakdowns: TaskBreakdowns;
+    score_weights: TaskScoreWeights;
+}
+
+/**
+ * A task weighs its required or optional weight times its urgent or medium
+ * weight. With partial_credit unfinished counters earn the share reached.
+ */
+export interface TaskScoreWeights {
+    required_weight: number;
+    optional_weight: number;
+    urgent_weight: number;
+    medium_weight: number;
+    partial_credit: boolean;
+}
+
+export type TimeGranularity = "day" | "week" | "month";
+
+/** Grouping of history and time accounting; weeks start on Monday by default. */
+export interface TimeGroupingInput {
+    granularity?: TimeGranularity;
+    weekStart?: number;
+}
+
+export interface TimeCategoryMinutes {
+    category: string;
+    planned_minutes: number;
+    focused_minutes: number;
+}
+
+/** Planned vs tracked time between start and end (inclusive). */
+export interface TimeBucket {
+    start: string;
+    end: string;
+    tasks: number;
+    tracked_tasks: number;
+    planned_minutes: number;
+    focused_minutes: number;
+    overrun_minutes: number;
+    underrun_minutes: number;
+    /** Tasks with both a scheduled and an actual start. */
+    timed_starts: number;
+    on_time_starts: number;
+    late_starts: number;
+    early_starts: number;
+    average_start_delay_minutes: number;
+    categories: TimeCategoryMinutes[];
+}
+
+export interface TimeAccountingRange {
+    from: string;
+    to: string;
+    granularity: TimeGranularity;
+    totals: TimeBucket;
+    buckets: TimeBucket[];
+}
+
+/** One heatmap cell; level runs from 0 (nothing) to 4. */
+export interface TaskHeatmapDay {
+    date: string;
+    total: number;
+    completed: number;
+    completions: number;
+    percentage: number;
+    level: number;
+}
+
+export interface TaskHeatmap {
+    year: number;
+    from: string;
+    to: string;
+    category?: string;
+    schedule_id?: string;
+    max_completions: number;
+    active_days: number;
+    completions: number;
+    days: TaskHeatmapDay[];
+}
+
+export interface TaskHeatmapInput {
+    year?: number;
+    category?: string;
+    scheduleId?: string;
+    ownerUserId?: string;
+}
+
+export type StreakFreezeKind = "earned" | "used";
+
+export interface StreakFreezeEvent {
+    id: string;
+    date: string;
+    kind: StreakFreezeKind;
+    streak: number;
+    created_at: string;
+}
+
+/** Running streak; rest weekdays (0 = Sunday) neither break nor extend it. */
+export interface TaskStreak {
+    user_id: string;
+    rest_weekdays: number[];
+    current_streak: number;
+    longest_streak: number;
+    freezes_available: number;
+    evaluated_through?: string;
+    from: string;
+    to: string;
+    freezes: StreakFreezeEvent[];
 }
 
 export interface TaskStatsRangeInput {
     from: string;
     to: string;
+    /** Reads a sharing owner's stats; the grant must share analytics. */
+    ownerUserId?: string;
 }
 
 export interface UpdateTaskInput {
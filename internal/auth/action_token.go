@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// NotificationActionTokenTTL bounds how long after a reminder is sent its
+// action buttons keep working.
+const NotificationActionTokenTTL = 12 * time.Hour
+
+const notificationActionPurpose = "notification_action"
+
+var ErrInvalidActionToken = errors.New("invalid notification action token")
+
+// NotificationActionClaims authorize a single action on a single task on
+// behalf of the task owner, without a session cookie. The token ID (jti) is
+// recorded on use so each token can be redeemed only once.
+type NotificationActionClaims struct {
+	Purpose string `json:"purpose"`
+	UserID  string `json:"user_id"`
+	TaskID  string `json:"task_id"`
+	jwt.RegisteredClaims
+}
+
+func CreateNotificationActionToken(userID string, taskID string) (string, error) {
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256, NotificationActionClaims{
+		Purpose: notificationActionPurpose,
+		UserID:  userID,
+		TaskID:  taskID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.Must(uuid.NewV7()).String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(NotificationActionTokenTTL)),
+		},
+	})
+	return t.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// ParseNotificationActionToken verifies the signature, expiry and purpose of
+// an action token. Session tokens are rejected even though they share the
+// signing key.
+func ParseNotificationActionToken(tokenStr string) (*NotificationActionClaims, error) {
+	claims := &NotificationActionClaims{}
+	t, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidActionToken, err)
+	}
+	if !t.Valid || claims.Purpose != notificationActionPurpose || claims.ID == "" ||
+		claims.UserID == "" || claims.TaskID == "" {
+		return nil, ErrInvalidActionToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestNotificationActionTokenRoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("a", 32))
+
+	token, err := CreateNotificationActionToken("user-1", "task-1")
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	claims, err := ParseNotificationActionToken(token)
+	if err != nil {
+		t.Fatalf("parse token: %v", err)
+	}
+	if claims.UserID != "user-1" || claims.TaskID != "task-1" || claims.ID == "" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if claims.ExpiresAt == nil || time.Until(claims.ExpiresAt.Time) > NotificationActionTokenTTL {
+		t.Fatalf("unexpected expiry %v", claims.ExpiresAt)
+	}
+}
+
+func TestNotificationActionTokenRejectsSessionToken(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("a", 32))
+
+	session, err := CreateToken(&db.User{ID: "user-1", Username: "user", Fullname: "User", Role: db.RoleUser})
+	if err != nil {
+		t.Fatalf("create session token: %v", err)
+	}
+	if _, err := ParseNotificationActionToken(session); !errors.Is(err, ErrInvalidActionToken) {
+		t.Fatalf("expected ErrInvalidActionToken for session token, got %v", err)
+	}
+}
+
+func TestNotificationActionTokenRejectsExpiredAndForeignKey(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("a", 32))
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, NotificationActionClaims{
+		Purpose: notificationActionPurpose,
+		UserID:  "user-1",
+		TaskID:  "task-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	})
+	signed, err := expired.SignedString([]byte(strings.Repeat("a", 32)))
+	if err != nil {
+		t.Fatalf("sign expired token: %v", err)
+	}
+	if _, err := ParseNotificationActionToken(signed); !errors.Is(err, ErrInvalidActionToken) {
+		t.Fatalf("expected ErrInvalidActionToken for expired token, got %v", err)
+	}
+
+	valid, err := CreateNotificationActionToken("user-1", "task-1")
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	t.Setenv("JWT_SECRET", strings.Repeat("b", 32))
+	if _, err := ParseNotificationActionToken(valid); !errors.Is(err, ErrInvalidActionToken) {
+		t.Fatalf("expected ErrInvalidActionToken for token signed with another key, got %v", err)
+	}
+}
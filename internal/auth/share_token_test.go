@@ -0,0 +1,24 @@
+package auth
+
+import "testing"
+
+func TestNewPublicShareToken(t *testing.T) {
+	token, hash, err := NewPublicShareToken()
+	if err != nil {
+		t.Fatalf("NewPublicShareToken: %v", err)
+	}
+	if len(token) != 32 {
+		t.Fatalf("expected a 32 character token, got %q", token)
+	}
+	if hash != HashPublicShareToken(token) || hash == token {
+		t.Fatalf("expected the stored hash to be derived from the token")
+	}
+
+	other, _, err := NewPublicShareToken()
+	if err != nil {
+		t.Fatalf("NewPublicShareToken: %v", err)
+	}
+	if other == token {
+		t.Fatalf("expected distinct tokens")
+	}
+}
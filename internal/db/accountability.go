@@ -0,0 +1,274 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type AccountabilityAlertKind string
+
+const (
+	AccountabilityAlertMissedTask    AccountabilityAlertKind = "missed_task"
+	AccountabilityAlertStreakBroken  AccountabilityAlertKind = "streak_broken"
+	AccountabilityAlertWeeklySummary AccountabilityAlertKind = "weekly_summary"
+)
+
+const (
+	DefaultAccountabilityGraceMinutes = 120
+	MaxAccountabilityGraceMinutes     = 2880
+)
+
+// AccountabilitySettings turns the grantee of a grant into an accountability
+// partner. A day is judged once GraceMinutes have passed after it ended.
+type AccountabilitySettings struct {
+	GraceMinutes  int       `json:"grace_minutes"`
+	WeeklySummary bool      `json:"weekly_summary"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Grace returns the grace period as a duration.
+func (settings *AccountabilitySettings) Grace() time.Duration {
+	return time.Duration(settings.GraceMinutes) * time.Minute
+}
+
+// SetAccountabilityPartner makes the grantee of an active or pending grant
+// owned by ownerUserID an accountability partner, or updates the settings of
+// an existing partnership. It returns false when the grant is not the
+// owner's, has lapsed or does not let the grantee view tasks.
+func SetAccountabilityPartner(ctx context.Context, ownerUserID string, grantID string, graceMinutes int, weeklySummary bool) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`INSERT INTO accountability_partners (grant_id, grace_minutes, weekly_summary)
+		 SELECT g.id, $3, $4
+		 FROM task_access_grants g
+		 WHERE g.id = $1 AND g.owner_user_id = $2 AND g.can_view AND `+openGrantSQL+`
+		 ON CONFLICT (grant_id) DO UPDATE
+		 SET grace_minutes = EXCLUDED.grace_minutes, weekly_summary = EXCLUDED.weekly_summary`,
+		grantID,
+		ownerUserID,
+		graceMinutes,
+		weeklySummary,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RemoveAccountabilityPartner ends the partnership on a grant. Either the
+// owner or the partner may end it; the grant itself stays in place.
+func RemoveAccountabilityPartner(ctx context.Context, userID string, grantID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`DELETE FROM accountability_partners ap
+		 USING task_access_grants g
+		 WHERE ap.grant_id = g.id AND g.id = $1
+		   AND (g.owner_user_id = $2 OR g.grantee_user_id = $2)`,
+		grantID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListAccountabilityPartnerships returns every accepted, live grant whose
+// grantee is an accountability partner.
+func ListAccountabilityPartnerships(ctx context.Context) ([]*TaskAccessGrant, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		taskAccessGrantSelectSQL()+`
+		WHERE ap.grant_id IS NOT NULL AND g.can_view AND `+activeGrantSQL+`
+		ORDER BY g.owner_user_id, g.created_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []*TaskAccessGrant{}
+	for rows.Next() {
+		grant, err := scanTaskAccessGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ListUnreportedMissedTasks returns the owner's required tasks between from
+// and to that did not end completed and that the partner on grantID has not
+// been alerted about. Tasks the owner delegated to someone else are left out.
+func ListUnreportedMissedTasks(ctx context.Context, grantID string, ownerUserID string, from time.Time, to time.Time) ([]*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		detailedTaskSelectSQL()+`
+		WHERE user_id = $1
+		  AND `+taskResponsibleUserSQL+` = $1
+		  AND is_required
+		  AND status <> 'completed'
+		  AND DATE(date) BETWEEN $2::date AND $3::date
+		  AND NOT EXISTS (
+			SELECT 1 FROM accountability_alerts aa
+			WHERE aa.grant_id = $4 AND aa.kind = 'missed_task' AND aa.subject = detailed_tasks.id::text
+		  )
+		ORDER BY date ASC, start_time ASC NULLS LAST`,
+		ownerUserID,
+		from,
+		to,
+		grantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDetailedTasks(rows)
+}
+
+// GetUserAccountableTasks returns the owner's tasks between from and to that
+// count for the owner, for the weekly summary.
+func GetUserAccountableTasks(ctx context.Context, ownerUserID string, from time.Time, to time.Time) ([]*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		detailedTaskSelectSQL()+`
+		WHERE user_id = $1
+		  AND `+taskResponsibleUserSQL+` = $1
+		  AND DATE(date) BETWEEN $2::date AND $3::date
+		ORDER BY date ASC, start_time ASC NULLS LAST`,
+		ownerUserID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDetailedTasks(rows)
+}
+
+// GetUserBrokenStreak returns the length of the streak that `day` broke, or
+// zero when day kept the streak, was a rest day or was covered by a freeze,
+// or there was no streak to break.
+func GetUserBrokenStreak(ctx context.Context, userID string, day time.Time) (int, error) {
+	from := day.AddDate(0, 0, -89)
+	history, err := GetUserTaskHistory(ctx, userID, from, day)
+	if err != nil {
+		return 0, err
+	}
+	rules, err := getUserStreakRules(ctx, userID, from, day)
+	if err != nil {
+		return 0, err
+	}
+
+	days := history.Days
+	if len(days) == 0 {
+		return 0, nil
+	}
+	last := days[len(days)-1]
+	if isPerfectDay(last) || rules.skips(last) {
+		return 0, nil
+	}
+	streak, _ := calculateStreaks(days[:len(days)-1], rules)
+	return streak, nil
+}
+
+// RecordAccountabilityAlert claims an alert for the partner on grantID. It
+// returns false when the alert was already sent.
+func RecordAccountabilityAlert(ctx context.Context, grantID string, kind AccountabilityAlertKind, subject string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`INSERT INTO accountability_alerts (grant_id, kind, subject)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT DO NOTHING`,
+		grantID,
+		string(kind),
+		subject,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReleaseAccountabilityAlert drops the claim RecordAccountabilityAlert took,
+// so an alert that could not be sent is retried on the next check.
+func ReleaseAccountabilityAlert(ctx context.Context, grantID string, kind AccountabilityAlertKind, subject string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`DELETE FROM accountability_alerts WHERE grant_id = $1 AND kind = $2 AND subject = $3`,
+		grantID,
+		string(kind),
+		subject,
+	)
+	return err
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskBreakdownDimension is a schedule field history and metrics can be
+// split by.
+type TaskBreakdownDimension string
+
+const (
+	TaskBreakdownCategory TaskBreakdownDimension = "category"
+	TaskBreakdownPriority TaskBreakdownDimension = "priority"
+	TaskBreakdownRequired TaskBreakdownDimension = "required"
+)
+
+// Keys of the required breakdown. Uncategorized tasks have an empty
+// category key.
+const (
+	TaskBreakdownKeyRequired = "required"
+	TaskBreakdownKeyOptional = "optional"
+)
+
+func ParseTaskBreakdownDimension(value string) (TaskBreakdownDimension, bool) {
+	switch dimension := TaskBreakdownDimension(strings.ToLower(strings.TrimSpace(value))); dimension {
+	case TaskBreakdownCategory, TaskBreakdownPriority, TaskBreakdownRequired:
+		return dimension, true
+	}
+	return "", false
+}
+
+// TaskBreakdownCount is the completion of the tasks sharing one Key.
+type TaskBreakdownCount struct {
+	Key        string  `json:"key"`
+	Total      int     `json:"total"`
+	Completed  int     `json:"completed"`
+	Percentage float64 `json:"percentage"`
+}
+
+// TaskBreakdownSlice compares a TaskBreakdownCount with the previous period
+// of the same length. Trend is the change in percentage points, and is nil
+// when the slice had no tasks in the previous period.
+type TaskBreakdownSlice struct {
+	TaskBreakdownCount
+	PreviousTotal      int      `json:"previous_total"`
+	PreviousCompleted  int      `json:"previous_completed"`
+	PreviousPercentage float64  `json:"previous_percentage"`
+	Trend              *float64 `json:"trend,omitempty"`
+}
+
+type TaskBreakdowns struct {
+	PreviousFrom string               `json:"previous_from"`
+	PreviousTo   string               `json:"previous_to"`
+	Category     []TaskBreakdownSlice `json:"category"`
+	Priority     []TaskBreakdownSlice `json:"priority"`
+	Required     []TaskBreakdownSlice `json:"required"`
+}
+
+// taskBreakdownKeySQL returns the key of dimension for a row of
+// schedule_tasks aliased st.
+func taskBreakdownKeySQL(dimension TaskBreakdownDimension) string {
+	switch dimension {
+	case TaskBreakdownPriority:
+		return `st.priority_level`
+	case TaskBreakdownRequired:
+		return `CASE WHEN st.is_required THEN '` + TaskBreakdownKeyRequired + `' ELSE '` + TaskBreakdownKeyOptional + `' END`
+	default:
+		return `COALESCE(LOWER(TRIM(st.category)), '')`
+	}
+}
+
+// previousPeriod returns the range of the same length that ends the day
+// before from.
+func previousPeriod(from time.Time, to time.Time) (time.Time, time.Time) {
+	days := int(math.Round(to.Sub(from).Hours()/24)) + 1
+	return from.AddDate(0, 0, -days), from.AddDate(0, 0, -1)
+}
+
+// GetUserTaskBreakdowns splits the tasks of userID between from and to by
+// category, priority and required, comparing each slice with the previous
+// period of the same length.
+func GetUserTaskBreakdowns(ctx context.Context, userID string, from time.Time, to time.Time) (*TaskBreakdowns, error) {
+	previousFrom, previousTo := previousPeriod(from, to)
+	breakdowns := &TaskBreakdowns{
+		PreviousFrom: previousFrom.Format("2006-01-02"),
+		PreviousTo:   previousTo.Format("2006-01-02"),
+	}
+	for _, dimension := range []TaskBreakdownDimension{TaskBreakdownCategory, TaskBreakdownPriority, TaskBreakdownRequired} {
+		slices, err := getUserTaskBreakdown(ctx, userID, dimension, previousFrom, from, to)
+		if err != nil {
+			return nil, err
+		}
+		switch dimension {
+		case TaskBreakdownCategory:
+			breakdowns.Category = slices
+		case TaskBreakdownPriority:
+			breakdowns.Priority = slices
+		case TaskBreakdownRequired:
+			breakdowns.Required = slices
+		}
+	}
+	return breakdowns, nil
+}
+
+func getUserTaskBreakdown(ctx context.Context, userID string, dimension TaskBreakdownDimension, previousFrom time.Time, from time.Time, to time.Time) ([]TaskBreakdownSlice, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			`+taskBreakdownKeySQL(dimension)+` AS key,
+			COALESCE(SUM(r.total) FILTER (WHERE r.day >= $3::date), 0),
+			COALESCE(SUM(r.completed) FILTER (WHERE r.day >= $3::date), 0),
+			COALESCE(SUM(r.total) FILTER (WHERE r.day < $3::date), 0),
+			COALESCE(SUM(r.completed) FILTER (WHERE r.day < $3::date), 0)
+		FROM task_daily_rollups r
+		INNER JOIN schedule_tasks st ON st.id = r.schedule_task_id
+		WHERE r.user_id = $1
+			AND r.day BETWEEN $2::date AND $4::date
+		GROUP BY key`,
+		userID,
+		previousFrom,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slices := []TaskBreakdownSlice{}
+	for rows.Next() {
+		var slice TaskBreakdownSlice
+		if err := rows.Scan(&slice.Key, &slice.Total, &slice.Completed, &slice.PreviousTotal, &slice.PreviousCompleted); err != nil {
+			return nil, err
+		}
+		slices = append(slices, slice)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return finishTaskBreakdownSlices(slices), nil
+}
+
+// finishTaskBreakdownSlices fills in percentages and trends, drops slices
+// without tasks in the current period and sorts the rest by total, largest
+// first.
+func finishTaskBreakdownSlices(slices []TaskBreakdownSlice) []TaskBreakdownSlice {
+	finished := make([]TaskBreakdownSlice, 0, len(slices))
+	for _, slice := range slices {
+		if slice.Total == 0 {
+			continue
+		}
+		slice.Percentage = completionPercentage(slice.Completed, slice.Total)
+		if slice.PreviousTotal > 0 {
+			slice.PreviousPercentage = completionPercentage(slice.PreviousCompleted, slice.PreviousTotal)
+			trend := math.Round((slice.Percentage-slice.PreviousPercentage)*10) / 10
+			slice.Trend = &trend
+		}
+		finished = append(finished, slice)
+	}
+	sort.SliceStable(finished, func(i, j int) bool {
+		if finished[i].Total != finished[j].Total {
+			return finished[i].Total > finished[j].Total
+		}
+		return finished[i].Key < finished[j].Key
+	})
+	return finished
+}
+
+// GetUserTaskHistoryBreakdown splits every day between from and to by
+// dimension. Days without tasks are missing from the result.
+func GetUserTaskHistoryBreakdown(ctx context.Context, userID string, from time.Time, to time.Time, dimension TaskBreakdownDimension) (map[string][]TaskBreakdownCount, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			r.day,
+			`+taskBreakdownKeySQL(dimension)+` AS key,
+			SUM(r.total),
+			SUM(r.completed)
+		FROM task_daily_rollups r
+		INNER JOIN schedule_tasks st ON st.id = r.schedule_task_id
+		WHERE r.user_id = $1
+			AND r.day BETWEEN $2::date AND $3::date
+			AND r.total > 0
+		GROUP BY r.day, key
+		ORDER BY r.day ASC, SUM(r.total) DESC, key ASC`,
+		userID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[string][]TaskBreakdownCount{}
+	for rows.Next() {
+		var day time.Time
+		var count TaskBreakdownCount
+		if err := rows.Scan(&day, &count.Key, &count.Total, &count.Completed); err != nil {
+			return nil, err
+		}
+		count.Percentage = completionPercentage(count.Completed, count.Total)
+		key := day.Format("2006-01-02")
+		days[key] = append(days[key], count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFinishTaskBreakdownSlices(t *testing.T) {
+	slices := finishTaskBreakdownSlices([]TaskBreakdownSlice{
+		{TaskBreakdownCount: TaskBreakdownCount{Key: "gym", Total: 2, Completed: 1}},
+		{TaskBreakdownCount: TaskBreakdownCount{Key: "study", Total: 4, Completed: 2}, PreviousTotal: 5, PreviousCompleted: 5},
+		{TaskBreakdownCount: TaskBreakdownCount{Key: "gone"}, PreviousTotal: 3, PreviousCompleted: 1},
+	})
+	if len(slices) != 2 || slices[0].Key != "study" || slices[1].Key != "gym" {
+		t.Fatalf("slices = %+v, want study then gym", slices)
+	}
+	study := slices[0]
+	if study.Percentage != 50 || study.PreviousPercentage != 100 || study.Trend == nil || *study.Trend != -50 {
+		t.Fatalf("study = %+v, want 50%% down 50 points from 100%%", study)
+	}
+	if slices[1].Trend != nil {
+		t.Fatalf("gym trend = %v, want nil without a previous period", *slices[1].Trend)
+	}
+}
+
+func TestPreviousPeriod(t *testing.T) {
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(2026, 3, 31, 12, 0, 0, 0, time.Local)
+	previousFrom, previousTo := previousPeriod(from, to)
+	if got := previousFrom.Format("2006-01-02"); got != "2026-01-29" {
+		t.Fatalf("previousFrom = %s, want 2026-01-29", got)
+	}
+	if got := previousTo.Format("2006-01-02"); got != "2026-02-28" {
+		t.Fatalf("previousTo = %s, want 2026-02-28", got)
+	}
+}
+
+func TestParseTaskBreakdownDimension(t *testing.T) {
+	if dimension, ok := ParseTaskBreakdownDimension(" Category "); !ok || dimension != TaskBreakdownCategory {
+		t.Fatalf("ParseTaskBreakdownDimension = %q, %v", dimension, ok)
+	}
+	if _, ok := ParseTaskBreakdownDimension("status"); ok {
+		t.Fatal("status should not be a breakdown dimension")
+	}
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// TimeGranularity is the size of the buckets a range is grouped in.
+type TimeGranularity string
+
+const (
+	TimeGranularityDay   TimeGranularity = "day"
+	TimeGranularityWeek  TimeGranularity = "week"
+	TimeGranularityMonth TimeGranularity = "month"
+)
+
+func ParseTimeGranularity(value string) (TimeGranularity, bool) {
+	switch granularity := TimeGranularity(strings.ToLower(strings.TrimSpace(value))); granularity {
+	case TimeGranularityDay, TimeGranularityWeek, TimeGranularityMonth:
+		return granularity, true
+	case "":
+		return TimeGranularityDay, true
+	}
+	return "", false
+}
+
+// bucketStart returns the first day of the bucket holding day. Weeks start
+// on weekStart.
+func bucketStart(day time.Time, granularity TimeGranularity, weekStart time.Weekday) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.Local)
+	switch granularity {
+	case TimeGranularityWeek:
+		offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+		return day.AddDate(0, 0, -offset)
+	case TimeGranularityMonth:
+		return time.Date(day.Year(), day.Month(), 1, 12, 0, 0, 0, time.Local)
+	}
+	return day
+}
+
+// bucketEnd returns the last day of the bucket starting on start.
+func bucketEnd(start time.Time, granularity TimeGranularity) time.Time {
+	switch granularity {
+	case TimeGranularityWeek:
+		return start.AddDate(0, 0, 6)
+	case TimeGranularityMonth:
+		return start.AddDate(0, 1, -1)
+	}
+	return start
+}
+
+// GroupTaskHistory sums the days of history into buckets of granularity,
+// clipped to the range. Each bucket is dated by its first day, with EndDate
+// its last, and its percentages come from the summed counts rather than an
+// average of the daily ones.
+func GroupTaskHistory(history *TaskHistoryRange, granularity TimeGranularity, weekStart time.Weekday) *TaskHistoryRange {
+	history.Granularity = granularity
+	if granularity == TimeGranularityDay || granularity == "" {
+		history.Granularity = TimeGranularityDay
+		return history
+	}
+	if granularity == TimeGranularityWeek {
+		start := int(weekStart)
+		history.WeekStart = &start
+	}
+
+	buckets := []TaskHistoryDay{}
+	index := map[string]int{}
+	for _, day := range history.Days {
+		date, err := time.ParseInLocation("2006-01-02", day.Date, time.Local)
+		if err != nil {
+			continue
+		}
+		key := bucketStart(date, granularity, weekStart).Format("2006-01-02")
+		i, ok := index[key]
+		if !ok {
+			i = len(buckets)
+			index[key] = i
+			buckets = append(buckets, TaskHistoryDay{Date: day.Date})
+		}
+		bucket := &buckets[i]
+		bucket.EndDate = day.Date
+		bucket.Total += day.Total
+		bucket.Completed += day.Completed
+		bucket.Pending += day.Pending
+		bucket.Skipped += day.Skipped
+		bucket.Failed += day.Failed
+		bucket.InProgress += day.InProgress
+		bucket.scoreTally.add(day.scoreTally)
+		bucket.Breakdown = mergeTaskBreakdownCounts(bucket.Breakdown, day.Breakdown)
+	}
+
+	for i := range buckets {
+		buckets[i].Percentage = completionPercentage(buckets[i].Completed, buckets[i].Total)
+		buckets[i].Score = buckets[i].scoreTally.score()
+		for j := range buckets[i].Breakdown {
+			count := &buckets[i].Breakdown[j]
+			count.Percentage = completionPercentage(count.Completed, count.Total)
+		}
+		sort.SliceStable(buckets[i].Breakdown, func(a, b int) bool {
+			left, right := buckets[i].Breakdown[a], buckets[i].Breakdown[b]
+			if left.Total != right.Total {
+				return left.Total > right.Total
+			}
+			return left.Key < right.Key
+		})
+	}
+	history.Days = buckets
+	return history
+}
+
+func mergeTaskBreakdownCounts(counts []TaskBreakdownCount, more []TaskBreakdownCount) []TaskBreakdownCount {
+	for _, count := range more {
+		merged := false
+		for i := range counts {
+			if counts[i].Key == count.Key {
+				counts[i].Total += count.Total
+				counts[i].Completed += count.Completed
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			counts = append(counts, TaskBreakdownCount{Key: count.Key, Total: count.Total, Completed: count.Completed})
+		}
+	}
+	return counts
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketStart(t *testing.T) {
+	day := time.Date(2026, 10, 15, 12, 0, 0, 0, time.Local) // Thursday
+	if got := bucketStart(day, TimeGranularityWeek, time.Monday).Format("2006-01-02"); got != "2026-10-12" {
+		t.Fatalf("week from Monday = %s, want 2026-10-12", got)
+	}
+	if got := bucketStart(day, TimeGranularityWeek, time.Sunday).Format("2006-01-02"); got != "2026-10-11" {
+		t.Fatalf("week from Sunday = %s, want 2026-10-11", got)
+	}
+	start := bucketStart(day, TimeGranularityMonth, time.Monday)
+	if got := bucketEnd(start, TimeGranularityMonth).Format("2006-01-02"); got != "2026-10-31" {
+		t.Fatalf("month end = %s, want 2026-10-31", got)
+	}
+}
+
+func TestGroupTaskHistory(t *testing.T) {
+	history := &TaskHistoryRange{
+		From: "2026-10-03",
+		To:   "2026-10-06",
+		Days: []TaskHistoryDay{
+			// Saturday and Sunday fall in the week starting Monday 2026-09-28.
+			{Date: "2026-10-03", Total: 1, Completed: 1, Breakdown: []TaskBreakdownCount{{Key: "gym", Total: 1, Completed: 1}}},
+			{Date: "2026-10-04", Total: 3, Completed: 0, Pending: 3, Breakdown: []TaskBreakdownCount{{Key: "study", Total: 2}, {Key: "gym", Total: 1}}},
+			{Date: "2026-10-05", Total: 2, Completed: 2},
+			{Date: "2026-10-06"},
+		},
+	}
+	grouped := GroupTaskHistory(history, TimeGranularityWeek, time.Monday)
+
+	if grouped.Granularity != TimeGranularityWeek || grouped.WeekStart == nil || *grouped.WeekStart != 1 {
+		t.Fatalf("granularity = %q, week start = %v", grouped.Granularity, grouped.WeekStart)
+	}
+	if len(grouped.Days) != 2 {
+		t.Fatalf("days = %+v, want 2 weeks", grouped.Days)
+	}
+	first := grouped.Days[0]
+	// 1 of 4 tasks, not the 50% average of the daily 100% and 0%.
+	if first.Date != "2026-10-03" || first.EndDate != "2026-10-04" || first.Total != 4 || first.Pending != 3 || first.Percentage != 25 {
+		t.Fatalf("first = %+v, want 2026-10-03..04 at 25%%", first)
+	}
+	if len(first.Breakdown) != 2 || first.Breakdown[0].Key != "gym" || first.Breakdown[0].Percentage != 50 || first.Breakdown[1].Total != 2 {
+		t.Fatalf("first breakdown = %+v", first.Breakdown)
+	}
+	if second := grouped.Days[1]; second.Date != "2026-10-05" || second.EndDate != "2026-10-06" || second.Percentage != 100 {
+		t.Fatalf("second = %+v, want 2026-10-05..06 at 100%%", second)
+	}
+
+	daily := GroupTaskHistory(&TaskHistoryRange{Days: []TaskHistoryDay{{Date: "2026-10-05"}}}, TimeGranularityDay, time.Monday)
+	if daily.Granularity != TimeGranularityDay || daily.WeekStart != nil || len(daily.Days) != 1 {
+		t.Fatalf("daily = %+v, want days untouched", daily)
+	}
+}
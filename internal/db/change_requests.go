@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type ChangeRequestKind string
+
+const (
+	ChangeRequestTaskUpdate     ChangeRequestKind = "task_update"
+	ChangeRequestScheduleCreate ChangeRequestKind = "schedule_create"
+)
+
+type ChangeRequestStatus string
+
+const (
+	ChangeRequestPending   ChangeRequestStatus = "pending"
+	ChangeRequestApproved  ChangeRequestStatus = "approved"
+	ChangeRequestRejected  ChangeRequestStatus = "rejected"
+	ChangeRequestCancelled ChangeRequestStatus = "cancelled"
+)
+
+const MaxChangeRequestNoteLength = 500
+
+// ChangeRequestField is one line of a change request diff. From is nil for
+// fields of a schedule that does not exist yet.
+type ChangeRequestField struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// ChangeRequest is an edit or a new schedule that a grantee in approval mode
+// proposed to the owner. Payload is what gets applied on approval.
+type ChangeRequest struct {
+	ID                string               `json:"id"`
+	GrantID           string               `json:"grant_id,omitempty"`
+	OwnerUserID       string               `json:"owner_user_id"`
+	OwnerUsername     string               `json:"owner_username"`
+	OwnerFullname     string               `json:"owner_fullname"`
+	RequesterUserID   string               `json:"requester_user_id"`
+	RequesterUsername string               `json:"requester_username"`
+	RequesterFullname string               `json:"requester_fullname"`
+	Kind              ChangeRequestKind    `json:"kind"`
+	TaskID            string               `json:"task_id,omitempty"`
+	TaskTitle         string               `json:"task_title,omitempty"`
+	Payload           json.RawMessage      `json:"-"`
+	Diff              []ChangeRequestField `json:"diff"`
+	Status            ChangeRequestStatus  `json:"status"`
+	ReviewNote        string               `json:"review_note,omitempty"`
+	CreatedAt         time.Time            `json:"created_at"`
+	ResolvedAt        *time.Time           `json:"resolved_at,omitempty"`
+}
+
+// NormalizeChangeRequestStatus validates a status filter. An empty value
+// means any status.
+func NormalizeChangeRequestStatus(value string) (ChangeRequestStatus, bool) {
+	switch status := ChangeRequestStatus(value); status {
+	case "", ChangeRequestPending, ChangeRequestApproved, ChangeRequestRejected, ChangeRequestCancelled:
+		return status, true
+	}
+	return "", false
+}
+
+func CreateChangeRequest(ctx context.Context, request *ChangeRequest) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	diff, err := json.Marshal(request.Diff)
+	if err != nil {
+		return err
+	}
+
+	request.Status = ChangeRequestPending
+	return conn.QueryRow(
+		ctx,
+		`INSERT INTO sharing_change_requests (grant_id, owner_user_id, requester_user_id, kind, task_id, payload, diff)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING id, created_at`,
+		nullableString(request.GrantID),
+		request.OwnerUserID,
+		request.RequesterUserID,
+		string(request.Kind),
+		nullableString(request.TaskID),
+		request.Payload,
+		diff,
+	).Scan(&request.ID, &request.CreatedAt)
+}
+
+func GetChangeRequest(ctx context.Context, id string) (*ChangeRequest, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return scanChangeRequest(conn.QueryRow(ctx, changeRequestSelectSQL()+` WHERE r.id = $1`, id))
+}
+
+// ListChangeRequestsForOwner returns the requests sent to ownerUserID, newest
+// first. An empty status lists every request.
+func ListChangeRequestsForOwner(ctx context.Context, ownerUserID string, status ChangeRequestStatus) ([]*ChangeRequest, error) {
+	return listChangeRequests(ctx, `r.owner_user_id = $1`, ownerUserID, status)
+}
+
+// ListChangeRequestsByRequester returns the requests requesterUserID sent,
+// newest first. An empty status lists every request.
+func ListChangeRequestsByRequester(ctx context.Context, requesterUserID string, status ChangeRequestStatus) ([]*ChangeRequest, error) {
+	return listChangeRequests(ctx, `r.requester_user_id = $1`, requesterUserID, status)
+}
+
+func listChangeRequests(ctx context.Context, where string, userID string, status ChangeRequestStatus) ([]*ChangeRequest, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		changeRequestSelectSQL()+` WHERE `+where+` AND ($2 = '' OR r.status = $2) ORDER BY r.created_at DESC LIMIT 200`,
+		userID,
+		string(status),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []*ChangeRequest{}
+	for rows.Next() {
+		request, err := scanChangeRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ResolveChangeRequest moves a pending request of ownerUserID to status. It
+// returns false when the request is no longer pending, so two reviews of the
+// same request cannot both apply it.
+func ResolveChangeRequest(ctx context.Context, id string, ownerUserID string, status ChangeRequestStatus, note string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE sharing_change_requests
+		 SET status = $3, review_note = $4, resolved_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND owner_user_id = $2 AND status = 'pending'`,
+		id,
+		ownerUserID,
+		string(status),
+		nullableString(note),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReopenChangeRequest puts an approved request back to pending when applying
+// it failed.
+func ReopenChangeRequest(ctx context.Context, id string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`UPDATE sharing_change_requests
+		 SET status = 'pending', review_note = NULL, resolved_at = NULL
+		 WHERE id = $1 AND status = 'approved'`,
+		id,
+	)
+	return err
+}
+
+// CancelChangeRequest withdraws a pending request sent by requesterUserID.
+func CancelChangeRequest(ctx context.Context, id string, requesterUserID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE sharing_change_requests
+		 SET status = 'cancelled', resolved_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND requester_user_id = $2 AND status = 'pending'`,
+		id,
+		requesterUserID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func changeRequestSelectSQL() string {
+	return `SELECT
+		r.id,
+		r.grant_id::text,
+		r.owner_user_id,
+		owner.username,
+		owner.fullname,
+		r.requester_user_id,
+		requester.username,
+		requester.fullname,
+		r.kind,
+		r.task_id::text,
+		dt.title,
+		r.payload,
+		r.diff,
+		r.status,
+		r.review_note,
+		r.created_at,
+		r.resolved_at
+	FROM sharing_change_requests r
+	INNER JOIN users owner ON owner.id = r.owner_user_id
+	INNER JOIN users requester ON requester.id = r.requester_user_id
+	LEFT JOIN detailed_tasks dt ON dt.id = r.task_id`
+}
+
+func scanChangeRequest(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*ChangeRequest, error) {
+	var request ChangeRequest
+	var grantID, taskID, taskTitle, reviewNote sql.NullString
+	var resolvedAt sql.NullTime
+	var diff []byte
+	err := scanner.Scan(
+		&request.ID,
+		&grantID,
+		&request.OwnerUserID,
+		&request.OwnerUsername,
+		&request.OwnerFullname,
+		&request.RequesterUserID,
+		&request.RequesterUsername,
+		&request.RequesterFullname,
+		&request.Kind,
+		&taskID,
+		&taskTitle,
+		&request.Payload,
+		&diff,
+		&request.Status,
+		&reviewNote,
+		&request.CreatedAt,
+		&resolvedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	request.GrantID = grantID.String
+	request.TaskID = taskID.String
+	request.TaskTitle = taskTitle.String
+	request.ReviewNote = reviewNote.String
+	if resolvedAt.Valid {
+		request.ResolvedAt = &resolvedAt.Time
+	}
+	if err := json.Unmarshal(diff, &request.Diff); err != nil {
+		return nil, err
+	}
+	if request.Diff == nil {
+		request.Diff = []ChangeRequestField{}
+	}
+	return &request, nil
+}
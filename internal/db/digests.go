@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DigestKind string
+
+const (
+	DigestKindMorningAgenda  DigestKind = "morning_agenda"
+	DigestKindEveningSummary DigestKind = "evening_summary"
+)
+
+const (
+	DefaultMorningDigestTime = "07:00"
+	DefaultEveningDigestTime = "21:00"
+)
+
+// DigestSettings controls when (and whether) a user receives the morning
+// agenda and the evening summary. Times are "HH:MM" in the server timezone.
+type DigestSettings struct {
+	UserID         string `json:"user_id"`
+	MorningEnabled bool   `json:"morning_enabled"`
+	MorningTime    string `json:"morning_time"`
+	EveningEnabled bool   `json:"evening_enabled"`
+	EveningTime    string `json:"evening_time"`
+}
+
+func DefaultDigestSettings(userID string) *DigestSettings {
+	return &DigestSettings{
+		UserID:         userID,
+		MorningEnabled: true,
+		MorningTime:    DefaultMorningDigestTime,
+		EveningEnabled: true,
+		EveningTime:    DefaultEveningDigestTime,
+	}
+}
+
+// GetDigestSettings returns the stored settings for the user, or the defaults
+// when the user never changed them.
+func GetDigestSettings(ctx context.Context, userID string) (*DigestSettings, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	settings := &DigestSettings{UserID: userID}
+	err = conn.QueryRow(
+		ctx,
+		`SELECT
+			morning_enabled,
+			to_char(morning_time, 'HH24:MI'),
+			evening_enabled,
+			to_char(evening_time, 'HH24:MI')
+		 FROM user_digest_settings
+		 WHERE user_id = $1`,
+		userID,
+	).Scan(
+		&settings.MorningEnabled,
+		&settings.MorningTime,
+		&settings.EveningEnabled,
+		&settings.EveningTime,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultDigestSettings(userID), nil
+		}
+		return nil, err
+	}
+	return settings, nil
+}
+
+func UpsertDigestSettings(ctx context.Context, settings *DigestSettings) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`INSERT INTO user_digest_settings (
+			user_id, morning_enabled, morning_time, evening_enabled, evening_time
+		) VALUES (
+			@userID, @morningEnabled, @morningTime::time, @eveningEnabled, @eveningTime::time
+		)
+		ON CONFLICT (user_id) DO UPDATE SET
+			morning_enabled = @morningEnabled,
+			morning_time = @morningTime::time,
+			evening_enabled = @eveningEnabled,
+			evening_time = @eveningTime::time`,
+		pgx.NamedArgs{
+			"userID":         settings.UserID,
+			"morningEnabled": settings.MorningEnabled,
+			"morningTime":    settings.MorningTime,
+			"eveningEnabled": settings.EveningEnabled,
+			"eveningTime":    settings.EveningTime,
+		},
+	)
+	return err
+}
+
+// ListDigestRecipients returns the digest settings of every user that has at
+// least one push subscription. Users without a settings row get the defaults.
+func ListDigestRecipients(ctx context.Context) ([]*DigestSettings, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			u.user_id,
+			COALESCE(s.morning_enabled, TRUE),
+			COALESCE(to_char(s.morning_time, 'HH24:MI'), $1),
+			COALESCE(s.evening_enabled, TRUE),
+			COALESCE(to_char(s.evening_time, 'HH24:MI'), $2)
+		 FROM (SELECT DISTINCT user_id FROM push_subscriptions) u
+		 LEFT JOIN user_digest_settings s ON s.user_id = u.user_id
+		 ORDER BY u.user_id`,
+		DefaultMorningDigestTime,
+		DefaultEveningDigestTime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []*DigestSettings{}
+	for rows.Next() {
+		var settings DigestSettings
+		if err := rows.Scan(
+			&settings.UserID,
+			&settings.MorningEnabled,
+			&settings.MorningTime,
+			&settings.EveningEnabled,
+			&settings.EveningTime,
+		); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, &settings)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+func DigestAlreadySent(ctx context.Context, userID string, kind DigestKind, day time.Time) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var exists bool
+	err = conn.QueryRow(
+		ctx,
+		`SELECT EXISTS (
+			SELECT 1
+			FROM digest_notifications
+			WHERE user_id = $1 AND kind = $2 AND digest_date = DATE($3::timestamptz)
+		)`,
+		userID,
+		string(kind),
+		day,
+	).Scan(&exists)
+	return exists, err
+}
+
+// MarkDigestSent records that the digest was processed for the user and day.
+// outboxID is empty when there was nothing to report.
+func MarkDigestSent(ctx context.Context, userID string, kind DigestKind, day time.Time, outboxID string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`INSERT INTO digest_notifications (user_id, kind, digest_date, outbox_id)
+		 VALUES ($1, $2, DATE($3::timestamptz), $4)
+		 ON CONFLICT (user_id, kind, digest_date) DO NOTHING`,
+		userID,
+		string(kind),
+		day,
+		nullableString(outboxID),
+	)
+	return err
+}
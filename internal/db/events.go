@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+)
+
+// UserEventsChannel is the NOTIFY channel fed by the notify_user_event
+// triggers.
+const UserEventsChannel = "user_events"
+
+// UserEvent announces that a row relevant to UserID changed. Type is one of
+// "task", "schedule", "inbox" or "sharing_invitation"; Op is "insert",
+// "update" or "delete".
+type UserEvent struct {
+	UserID string `json:"user_id"`
+	Type   string `json:"type"`
+	Op     string `json:"op"`
+	ID     string `json:"id"`
+}
+
+// ListenUserEvents takes a connection out of the pool, puts it in LISTEN mode
+// and calls handle for every event until ctx is cancelled or the connection
+// fails. The connection is closed rather than returned so no pooled
+// connection is left subscribed to the channel.
+func ListenUserEvents(ctx context.Context, handle func(UserEvent)) error {
+	pooled, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+UserEventsChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event UserEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Printf("invalid user event payload %q: %v", notification.Payload, err)
+			continue
+		}
+		handle(event)
+	}
+}
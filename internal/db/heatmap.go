@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// MaxHeatmapLevel is the darkest cell of the heatmap; days with nothing to
+// show are level 0.
+const MaxHeatmapLevel = 4
+
+// TaskHeatmapFilter narrows a heatmap to one category or one schedule. Empty
+// fields do not filter.
+type TaskHeatmapFilter struct {
+	Category   string
+	ScheduleID string
+}
+
+type TaskHeatmapDay struct {
+	Date        string  `json:"date"`
+	Total       int     `json:"total"`
+	Completed   int     `json:"completed"`
+	Completions int     `json:"completions"`
+	Percentage  float64 `json:"percentage"`
+	Level       int     `json:"level"`
+}
+
+// TaskHeatmap has one cell per day of Year, January 1 first.
+type TaskHeatmap struct {
+	Year           int              `json:"year"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	Category       string           `json:"category,omitempty"`
+	ScheduleID     string           `json:"schedule_id,omitempty"`
+	MaxCompletions int              `json:"max_completions"`
+	ActiveDays     int              `json:"active_days"`
+	Completions    int              `json:"completions"`
+	Days           []TaskHeatmapDay `json:"days"`
+}
+
+func GetUserTaskHeatmap(ctx context.Context, userID string, year int, filter TaskHeatmapFilter) (*TaskHeatmap, error) {
+	filter.Category = normalizeScopeCategory(filter.Category)
+	from := time.Date(year, time.January, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(year, time.December, 31, 12, 0, 0, 0, time.Local)
+
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT day, SUM(total), SUM(completed), SUM(completions)
+		FROM task_daily_rollups
+		WHERE user_id = $1
+			AND day BETWEEN $2::date AND $3::date
+			AND ($4::text = '' OR category = $4::text)
+			AND ($5::text = '' OR schedule_task_id = NULLIF($5::text, '')::uuid)
+		GROUP BY day`,
+		userID,
+		from,
+		to,
+		filter.Category,
+		filter.ScheduleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[string]TaskHeatmapDay{}
+	for rows.Next() {
+		var date time.Time
+		var day TaskHeatmapDay
+		if err := rows.Scan(&date, &day.Total, &day.Completed, &day.Completions); err != nil {
+			return nil, err
+		}
+		days[date.Format("2006-01-02")] = day
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	heatmap := BuildTaskHeatmap(year, days)
+	heatmap.Category = filter.Category
+	heatmap.ScheduleID = filter.ScheduleID
+	return heatmap, nil
+}
+
+// BuildTaskHeatmap lays counts keyed by date over every day of year and
+// assigns each day its level.
+func BuildTaskHeatmap(year int, counts map[string]TaskHeatmapDay) *TaskHeatmap {
+	from := time.Date(year, time.January, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(year, time.December, 31, 12, 0, 0, 0, time.Local)
+	heatmap := &TaskHeatmap{
+		Year: year,
+		From: from.Format("2006-01-02"),
+		To:   to.Format("2006-01-02"),
+		Days: make([]TaskHeatmapDay, 0, 366),
+	}
+	for _, day := range counts {
+		heatmap.MaxCompletions = max(heatmap.MaxCompletions, day.Completions)
+	}
+
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		key := date.Format("2006-01-02")
+		day := counts[key]
+		day.Date = key
+		day.Percentage = completionPercentage(day.Completed, day.Total)
+		day.Level = heatmapLevel(day, heatmap.MaxCompletions)
+		if day.Completions > 0 || day.Completed > 0 {
+			heatmap.ActiveDays++
+		}
+		heatmap.Completions += day.Completions
+		heatmap.Days = append(heatmap.Days, day)
+	}
+	return heatmap
+}
+
+// heatmapLevel blends how much of the day was done with how busy it was
+// compared with the busiest day of the year, in equal parts. Days without
+// tasks due are judged on their completions alone.
+func heatmapLevel(day TaskHeatmapDay, maxCompletions int) int {
+	volume := 0.0
+	if maxCompletions > 0 {
+		volume = float64(day.Completions) / float64(maxCompletions)
+	}
+	score := volume
+	if day.Total > 0 {
+		score = (day.Percentage/100 + volume) / 2
+	}
+	if score <= 0 {
+		return 0
+	}
+	return min(MaxHeatmapLevel, int(math.Ceil(score*MaxHeatmapLevel)))
+}
@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestBuildTaskHeatmap(t *testing.T) {
+	heatmap := BuildTaskHeatmap(2024, map[string]TaskHeatmapDay{
+		"2024-01-01": {Total: 4, Completed: 4, Completions: 4},
+		"2024-02-29": {Total: 4, Completed: 1, Completions: 1},
+		"2024-03-10": {Completions: 2},
+		"2024-12-31": {Total: 3},
+	})
+
+	if len(heatmap.Days) != 366 || heatmap.From != "2024-01-01" || heatmap.To != "2024-12-31" {
+		t.Fatalf("days = %d from %s to %s, want a leap year", len(heatmap.Days), heatmap.From, heatmap.To)
+	}
+	if heatmap.MaxCompletions != 4 || heatmap.ActiveDays != 3 || heatmap.Completions != 7 {
+		t.Fatalf("summary = %d max, %d active, %d completions", heatmap.MaxCompletions, heatmap.ActiveDays, heatmap.Completions)
+	}
+
+	levels := map[string]int{}
+	for _, day := range heatmap.Days {
+		levels[day.Date] = day.Level
+	}
+	want := map[string]int{
+		"2024-01-01": 4, // everything done on the busiest day
+		"2024-02-29": 1, // 25% done, a quarter of the busiest day
+		"2024-03-10": 2, // nothing due, half the busiest day
+		"2024-12-31": 0, // nothing done
+		"2024-06-15": 0, // nothing at all
+	}
+	for date, level := range want {
+		if levels[date] != level {
+			t.Fatalf("level on %s = %d, want %d", date, levels[date], level)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type InboxItemType string
+
+const (
+	InboxItemTypePing       InboxItemType = "ping"
+	InboxItemTypeInvitation InboxItemType = "invitation"
+	InboxItemTypeSystem     InboxItemType = "system"
+	InboxItemTypeDigest     InboxItemType = "digest"
+	InboxItemTypeComment    InboxItemType = "comment"
+)
+
+// InboxReadState filters inbox items by whether they were read.
+type InboxReadState string
+
+const (
+	InboxReadStateAll    InboxReadState = "all"
+	InboxReadStateUnread InboxReadState = "unread"
+	InboxReadStateRead   InboxReadState = "read"
+)
+
+const (
+	DefaultInboxPageSize = 20
+	MaxInboxPageSize     = 100
+)
+
+var ErrInvalidInboxCursor = errors.New("invalid inbox cursor")
+
+// InboxItem is one entry of the unified inbox. Ping and invitation items keep
+// the sender and task fields of their source rows; system and digest notices
+// carry their own title, body and data.
+type InboxItem struct {
+	ID               string            `json:"id"`
+	Type             InboxItemType     `json:"type"`
+	Title            string            `json:"title"`
+	Body             string            `json:"body,omitempty"`
+	URL              string            `json:"url,omitempty"`
+	Data             map[string]string `json:"data,omitempty"`
+	TaskID           string            `json:"task_id,omitempty"`
+	TaskTitle        string            `json:"task_title,omitempty"`
+	SenderUserID     string            `json:"sender_user_id,omitempty"`
+	SenderUsername   string            `json:"sender_username,omitempty"`
+	SenderFullname   string            `json:"sender_fullname,omitempty"`
+	Message          string            `json:"message,omitempty"`
+	GrantID          string            `json:"grant_id,omitempty"`
+	AccessLevel      string            `json:"access_level,omitempty"`
+	NotificationSent bool              `json:"notification_sent"`
+	CreatedAt        time.Time         `json:"created_at"`
+	ReadAt           *time.Time        `json:"read_at,omitempty"`
+	ArchivedAt       *time.Time        `json:"archived_at,omitempty"`
+}
+
+type InboxFilter struct {
+	Types     []InboxItemType
+	ReadState InboxReadState
+	Archived  bool
+	Cursor    string
+	Limit     int
+}
+
+type InboxPage struct {
+	Items      []*InboxItem `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+type InboxUnreadCount struct {
+	Total  int                   `json:"total"`
+	ByType map[InboxItemType]int `json:"by_type"`
+}
+
+func NormalizeInboxItemType(value string) (InboxItemType, bool) {
+	switch InboxItemType(strings.TrimSpace(value)) {
+	case InboxItemTypePing:
+		return InboxItemTypePing, true
+	case InboxItemTypeInvitation:
+		return InboxItemTypeInvitation, true
+	case InboxItemTypeSystem:
+		return InboxItemTypeSystem, true
+	case InboxItemTypeDigest:
+		return InboxItemTypeDigest, true
+	case InboxItemTypeComment:
+		return InboxItemTypeComment, true
+	}
+	return "", false
+}
+
+// ListInboxItems returns one page of the user's inbox, newest first. Pages are
+// keyed by (created_at, id) so new items do not shift later pages.
+func ListInboxItems(ctx context.Context, userID string, filter InboxFilter) (*InboxPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultInboxPageSize
+	}
+	if limit > MaxInboxPageSize {
+		limit = MaxInboxPageSize
+	}
+
+	args := pgx.NamedArgs{
+		"userID": userID,
+		"limit":  limit + 1,
+	}
+	where := []string{"user_id = @userID"}
+	if filter.Archived {
+		where = append(where, "archived_at IS NOT NULL")
+	} else {
+		where = append(where, "archived_at IS NULL")
+	}
+	switch filter.ReadState {
+	case InboxReadStateUnread:
+		where = append(where, "read_at IS NULL")
+	case InboxReadStateRead:
+		where = append(where, "read_at IS NOT NULL")
+	}
+	if len(filter.Types) > 0 {
+		types := make([]string, 0, len(filter.Types))
+		for _, itemType := range filter.Types {
+			types = append(types, string(itemType))
+		}
+		where = append(where, "type = ANY(@types)")
+		args["types"] = types
+	}
+	if filter.Cursor != "" {
+		cursorTime, cursorID, err := DecodeInboxCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "(created_at, id) < (@cursorTime, @cursorID::uuid)")
+		args["cursorTime"] = cursorTime
+		args["cursorID"] = cursorID
+	}
+
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			id, type, task_id, task_title, sender_user_id, sender_username, sender_fullname,
+			message, grant_id, access_level, title, body, url, data,
+			notification_sent, read_at, archived_at, created_at
+		 FROM inbox_items
+		 WHERE `+strings.Join(where, " AND ")+`
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT @limit`,
+		args,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &InboxPage{Items: []*InboxItem{}}
+	for rows.Next() {
+		item, err := scanInboxItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Items = append(page.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = EncodeInboxCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+func CountUnreadInboxItems(ctx context.Context, userID string) (*InboxUnreadCount, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT type, COUNT(*)
+		 FROM inbox_items
+		 WHERE user_id = $1 AND read_at IS NULL AND archived_at IS NULL
+		 GROUP BY type`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	count := &InboxUnreadCount{ByType: map[InboxItemType]int{}}
+	for rows.Next() {
+		var itemType InboxItemType
+		var n int
+		if err := rows.Scan(&itemType, &n); err != nil {
+			return nil, err
+		}
+		count.ByType[itemType] = n
+		count.Total += n
+	}
+	return count, rows.Err()
+}
+
+// MarkInboxItemRead marks any inbox item of the user as read.
+func MarkInboxItemRead(ctx context.Context, userID string, itemID string) (bool, error) {
+	return updateInboxItem(ctx, userID, itemID, "read_at = COALESCE(read_at, CURRENT_TIMESTAMP)")
+}
+
+// ArchiveInboxItem hides the item from the default inbox view. Archived items
+// count as read.
+func ArchiveInboxItem(ctx context.Context, userID string, itemID string) (bool, error) {
+	return updateInboxItem(ctx, userID, itemID,
+		"archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP), read_at = COALESCE(read_at, CURRENT_TIMESTAMP)")
+}
+
+// DeleteInboxItem removes the item from the recipient's inbox. Source rows are
+// soft-deleted so the sender's ping history is preserved.
+func DeleteInboxItem(ctx context.Context, userID string, itemID string) (bool, error) {
+	return updateInboxItem(ctx, userID, itemID, "deleted_at = CURRENT_TIMESTAMP")
+}
+
+// updateInboxItem applies set to whichever source table owns itemID. Item ids
+// are UUIDs, so at most one of the three updates matches.
+func updateInboxItem(ctx context.Context, userID string, itemID string, set string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var updated int
+	err = conn.QueryRow(
+		ctx,
+		`WITH pings AS (
+			UPDATE task_pings SET `+set+`
+			WHERE id = $1 AND recipient_user_id = $2 AND deleted_at IS NULL
+			RETURNING 1
+		), invitations AS (
+			UPDATE sharing_invitations SET `+set+`
+			WHERE id = $1 AND grantee_user_id = $2 AND deleted_at IS NULL
+			RETURNING 1
+		), notices AS (
+			UPDATE inbox_notices SET `+set+`
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM pings)
+			+ (SELECT COUNT(*) FROM invitations)
+			+ (SELECT COUNT(*) FROM notices)`,
+		itemID,
+		userID,
+	).Scan(&updated)
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
+
+// MarkAllInboxItemsRead marks every unread, non-archived item of the given
+// types as read. No types means all types. It returns the number of items
+// updated.
+func MarkAllInboxItemsRead(ctx context.Context, userID string, types []InboxItemType) (int, error) {
+	includes := func(itemType InboxItemType) bool {
+		if len(types) == 0 {
+			return true
+		}
+		for _, t := range types {
+			if t == itemType {
+				return true
+			}
+		}
+		return false
+	}
+	noticeKinds := []string{}
+	for _, kind := range []InboxItemType{InboxItemTypeSystem, InboxItemTypeDigest, InboxItemTypeComment} {
+		if includes(kind) {
+			noticeKinds = append(noticeKinds, string(kind))
+		}
+	}
+
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var updated int
+	err = conn.QueryRow(
+		ctx,
+		`WITH pings AS (
+			UPDATE task_pings SET read_at = CURRENT_TIMESTAMP
+			WHERE @pings AND recipient_user_id = @userID
+			  AND read_at IS NULL AND archived_at IS NULL AND deleted_at IS NULL
+			RETURNING 1
+		), invitations AS (
+			UPDATE sharing_invitations SET read_at = CURRENT_TIMESTAMP
+			WHERE @invitations AND grantee_user_id = @userID
+			  AND read_at IS NULL AND archived_at IS NULL AND deleted_at IS NULL
+			RETURNING 1
+		), notices AS (
+			UPDATE inbox_notices SET read_at = CURRENT_TIMESTAMP
+			WHERE kind = ANY(@noticeKinds) AND user_id = @userID
+			  AND read_at IS NULL AND archived_at IS NULL AND deleted_at IS NULL
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM pings)
+			+ (SELECT COUNT(*) FROM invitations)
+			+ (SELECT COUNT(*) FROM notices)`,
+		pgx.NamedArgs{
+			"userID":      userID,
+			"pings":       includes(InboxItemTypePing),
+			"invitations": includes(InboxItemTypeInvitation),
+			"noticeKinds": noticeKinds,
+		},
+	).Scan(&updated)
+	return updated, err
+}
+
+// CreateInboxNotice stores a system, digest or comment notice for the user and returns
+// its id.
+func CreateInboxNotice(ctx context.Context, userID string, kind InboxItemType, title string, body string, url string, data map[string]string) (string, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var dataJSON []byte
+	if len(data) > 0 {
+		dataJSON, err = json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var id string
+	err = conn.QueryRow(
+		ctx,
+		`INSERT INTO inbox_notices (user_id, kind, title, body, url, data)
+		 VALUES (@userID, @kind, @title, @body, @url, @data)
+		 RETURNING id`,
+		pgx.NamedArgs{
+			"userID": userID,
+			"kind":   string(kind),
+			"title":  title,
+			"body":   nullableString(body),
+			"url":    nullableString(url),
+			"data":   dataJSON,
+		},
+	).Scan(&id)
+	return id, err
+}
+
+// EncodeInboxCursor and DecodeInboxCursor wrap the (created_at, id) position
+// of the last item of a page in an opaque token.
+func EncodeInboxCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeInboxCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidInboxCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return time.Time{}, "", ErrInvalidInboxCursor
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidInboxCursor
+	}
+	return parsed, id, nil
+}
+
+func scanInboxItem(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*InboxItem, error) {
+	var item InboxItem
+	var taskID, taskTitle, senderUserID, senderUsername, senderFullname sql.NullString
+	var message, grantID, accessLevel, title, body, url sql.NullString
+	var data []byte
+	var readAt, archivedAt sql.NullTime
+	err := scanner.Scan(
+		&item.ID,
+		&item.Type,
+		&taskID,
+		&taskTitle,
+		&senderUserID,
+		&senderUsername,
+		&senderFullname,
+		&message,
+		&grantID,
+		&accessLevel,
+		&title,
+		&body,
+		&url,
+		&data,
+		&item.NotificationSent,
+		&readAt,
+		&archivedAt,
+		&item.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	item.TaskID = taskID.String
+	item.TaskTitle = taskTitle.String
+	item.SenderUserID = senderUserID.String
+	item.SenderUsername = senderUsername.String
+	item.SenderFullname = senderFullname.String
+	item.Message = message.String
+	item.GrantID = grantID.String
+	item.AccessLevel = accessLevel.String
+	item.Title = title.String
+	item.Body = body.String
+	item.URL = url.String
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &item.Data); err != nil {
+			return nil, err
+		}
+	}
+	if readAt.Valid {
+		item.ReadAt = &readAt.Time
+	}
+	if archivedAt.Valid {
+		item.ArchivedAt = &archivedAt.Time
+	}
+	item.fillSourceText()
+	return &item, nil
+}
+
+// fillSourceText gives pings and invitations the same title/body/url shape as
+// notices so clients can render every item the same way.
+func (item *InboxItem) fillSourceText() {
+	sender := item.SenderFullname
+	if sender == "" && item.SenderUsername != "" {
+		sender = "@" + item.SenderUsername
+	}
+	switch item.Type {
+	case InboxItemTypePing:
+		item.Title = sender + " te recordó " + item.TaskTitle
+		item.Body = item.Message
+		item.URL = "/tasks/" + item.TaskID
+	case InboxItemTypeInvitation:
+		if item.Data["status"] == string(SharingInvitationAccepted) {
+			item.Title = "Nuevo acceso compartido"
+			item.Body = sender + " te compartió acceso a sus tareas."
+			item.URL = "/shared/" + item.SenderUserID
+			return
+		}
+		// Pending invitations are answered from the sharing settings.
+		item.Title = "Nueva invitación"
+		item.Body = sender + " te invitó a acceder a sus tareas."
+		item.URL = "/profile"
+	}
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInboxCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 10, 18, 14, 5, 6, 123456000, time.FixedZone("CST", -6*3600))
+	id := "0192a4f0-0000-7000-8000-000000000001"
+
+	gotTime, gotID, err := DecodeInboxCursor(EncodeInboxCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("decode cursor: %v", err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != id {
+		t.Fatalf("round trip = (%v, %q), want (%v, %q)", gotTime, gotID, createdAt, id)
+	}
+}
+
+func TestDecodeInboxCursorRejectsGarbage(t *testing.T) {
+	for _, cursor := range []string{"garbage!", "bm8tc2VwYXJhdG9y", "bm90LWEtdGltZXxpZA"} {
+		if _, _, err := DecodeInboxCursor(cursor); !errors.Is(err, ErrInvalidInboxCursor) {
+			t.Fatalf("DecodeInboxCursor(%q) err = %v, want ErrInvalidInboxCursor", cursor, err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type InsightKind string
+
+const (
+	InsightKindWeekdaySkip       InsightKind = "weekday_skip"
+	InsightKindCompletionDecline InsightKind = "completion_decline"
+	InsightKindLateStart         InsightKind = "late_start"
+	InsightKindLateFinish        InsightKind = "late_finish"
+)
+
+// InsightDismissCooldownDays is how long a dismissed insight stays hidden
+// while the engine keeps detecting it.
+const InsightDismissCooldownDays = 30
+
+// Insight is a finding of the insights engine about one schedule of a user.
+// Evidence holds the numbers behind it, shaped by Kind.
+type Insight struct {
+	ID             string          `json:"id"`
+	UserID         string          `json:"user_id"`
+	ScheduleTaskID string          `json:"schedule_task_id"`
+	Kind           InsightKind     `json:"kind"`
+	Subject        string          `json:"subject,omitempty"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Evidence       json.RawMessage `json:"evidence"`
+	DetectedAt     time.Time       `json:"detected_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DismissedAt    *time.Time      `json:"dismissed_at,omitempty"`
+}
+
+// ScheduleObservation is one task of a schedule as the insights engine sees
+// it. Planned times are nil when the schedule has none, ActualStart and
+// FinishedAt when nobody recorded them; only completed tasks finish.
+type ScheduleObservation struct {
+	ScheduleTaskID string
+	Title          string
+	Date           time.Time
+	Status         TaskStatus
+	PlannedStart   *time.Time
+	PlannedEnd     *time.Time
+	ActualStart    *time.Time
+	FinishedAt     *time.Time
+}
+
+// ListInsightUserIDs returns the users with tasks since since.
+func ListInsightUserIDs(ctx context.Context, since time.Time) ([]string, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT DISTINCT user_id FROM task_daily_rollups WHERE day >= $1::date AND total > 0`,
+		since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// ListUserScheduleObservations loads the tasks userID is responsible for
+// between from and to, by schedule and then by date.
+func ListUserScheduleObservations(ctx context.Context, userID string, from time.Time, to time.Time) ([]ScheduleObservation, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			t.schedule_task_id,
+			st.title,
+			DATE(t.date),
+			t.status_level,
+			to_char(st.schedule_start_time, 'HH24:MI'),
+			to_char(st.schedule_end_time, 'HH24:MI'),
+			COALESCE(st.duration_minutes, 0),
+			COALESCE(c.first_start, t.actual_start),
+			CASE WHEN t.status_level = 'completed' THEN COALESCE(t.actual_end, t.completed_at) END
+		FROM (
+			SELECT id, date, schedule_task_id, status_level, actual_start, actual_end, completed_at
+			FROM tasks
+			WHERE `+taskResponsibleUserSQL+` = $1
+				AND DATE(date) BETWEEN $2::date AND $3::date
+		) t
+		INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
+		LEFT JOIN LATERAL (
+			SELECT MIN(actual_start) AS first_start
+			FROM task_completions
+			WHERE task_id = t.id
+		) c ON TRUE
+		ORDER BY t.schedule_task_id, t.date`,
+		userID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	observations := []ScheduleObservation{}
+	for rows.Next() {
+		var observation ScheduleObservation
+		var day time.Time
+		var startClock, endClock sql.NullString
+		var durationMinutes int
+		var actualStart, finishedAt sql.NullTime
+		if err := rows.Scan(
+			&observation.ScheduleTaskID,
+			&observation.Title,
+			&day,
+			&observation.Status,
+			&startClock,
+			&endClock,
+			&durationMinutes,
+			&actualStart,
+			&finishedAt,
+		); err != nil {
+			return nil, err
+		}
+		observation.Date = time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, time.Local)
+		if startClock.Valid {
+			start := clockOnDay(day, startClock.String)
+			observation.PlannedStart = &start
+		}
+		if endClock.Valid {
+			end := clockOnDay(day, endClock.String)
+			observation.PlannedEnd = &end
+		} else if observation.PlannedStart != nil && durationMinutes > 0 {
+			end := observation.PlannedStart.Add(time.Duration(durationMinutes) * time.Minute)
+			observation.PlannedEnd = &end
+		}
+		if actualStart.Valid {
+			start := actualStart.Time
+			observation.ActualStart = &start
+		}
+		if finishedAt.Valid {
+			finished := finishedAt.Time
+			observation.FinishedAt = &finished
+		}
+		observations = append(observations, observation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return observations, nil
+}
+
+// SaveInsight stores insight, or refreshes the text and evidence of the same
+// finding when it exists, and fills in its id and dates. It reports whether
+// the insight surfaced: it is new, it was resolved and came back, or its
+// dismissal is older than InsightDismissCooldownDays.
+func SaveInsight(ctx context.Context, insight *Insight) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	evidence := insight.Evidence
+	if len(evidence) == 0 {
+		evidence = json.RawMessage(`{}`)
+	}
+
+	// CURRENT_TIMESTAMP is fixed for the statement, so detected_at matches it
+	// exactly when the row was inserted or brought back.
+	var surfaced bool
+	err = conn.QueryRow(
+		ctx,
+		`INSERT INTO insights (user_id, schedule_task_id, kind, subject, title, body, evidence)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT ON CONSTRAINT insights_key DO UPDATE SET
+			title = EXCLUDED.title,
+			body = EXCLUDED.body,
+			evidence = EXCLUDED.evidence,
+			detected_at = CASE
+				WHEN insights.resolved_at IS NOT NULL
+					OR insights.dismissed_at < CURRENT_TIMESTAMP - make_interval(days => $8::int)
+					THEN CURRENT_TIMESTAMP
+				ELSE insights.detected_at
+			END,
+			dismissed_at = CASE
+				WHEN insights.dismissed_at < CURRENT_TIMESTAMP - make_interval(days => $8::int) THEN NULL
+				ELSE insights.dismissed_at
+			END,
+			resolved_at = NULL
+		 RETURNING id, detected_at, updated_at, dismissed_at, detected_at = CURRENT_TIMESTAMP AND dismissed_at IS NULL`,
+		insight.UserID,
+		insight.ScheduleTaskID,
+		string(insight.Kind),
+		insight.Subject,
+		insight.Title,
+		insight.Body,
+		evidence,
+		InsightDismissCooldownDays,
+	).Scan(&insight.ID, &insight.DetectedAt, &insight.UpdatedAt, &insight.DismissedAt, &surfaced)
+	if err != nil {
+		return false, err
+	}
+	insight.Evidence = evidence
+	return surfaced, nil
+}
+
+// ResolveUserInsights resolves the open insights of userID other than
+// keepIDs, the ones the engine still detects.
+func ResolveUserInsights(ctx context.Context, userID string, keepIDs []string) (int64, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if keepIDs == nil {
+		keepIDs = []string{}
+	}
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE insights
+		 SET resolved_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1
+			AND resolved_at IS NULL
+			AND NOT (id = ANY($2::uuid[]))`,
+		userID,
+		keepIDs,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListUserInsights returns the open insights of userID, newest first.
+// Dismissed ones are only included when includeDismissed is set.
+func ListUserInsights(ctx context.Context, userID string, includeDismissed bool) ([]*Insight, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT id, user_id, schedule_task_id, kind, subject, title, body, evidence, detected_at, updated_at, dismissed_at
+		FROM insights
+		WHERE user_id = $1
+			AND resolved_at IS NULL
+			AND ($2::bool OR dismissed_at IS NULL)
+		ORDER BY detected_at DESC, id`,
+		userID,
+		includeDismissed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	insights := []*Insight{}
+	for rows.Next() {
+		insight := &Insight{}
+		if err := rows.Scan(
+			&insight.ID,
+			&insight.UserID,
+			&insight.ScheduleTaskID,
+			&insight.Kind,
+			&insight.Subject,
+			&insight.Title,
+			&insight.Body,
+			&insight.Evidence,
+			&insight.DetectedAt,
+			&insight.UpdatedAt,
+			&insight.DismissedAt,
+		); err != nil {
+			return nil, err
+		}
+		insights = append(insights, insight)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return insights, nil
+}
+
+// DismissInsight hides an insight of userID and archives the inbox notice
+// that announced it. It reports false when userID has no such insight.
+func DismissInsight(ctx context.Context, userID string, insightID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE insights
+		 SET dismissed_at = COALESCE(dismissed_at, CURRENT_TIMESTAMP)
+		 WHERE id = $1 AND user_id = $2`,
+		insightID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE inbox_notices
+		 SET archived_at = COALESCE(archived_at, CURRENT_TIMESTAMP)
+		 WHERE user_id = $1 AND data->>'insight_id' = $2`,
+		userID,
+		insightID,
+	); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RedeemNotificationActionToken records the use of a signed notification
+// action token. It returns false when the token was already redeemed, which
+// makes every token single-use even though it is verified statelessly.
+func RedeemNotificationActionToken(ctx context.Context, tokenID string, userID string, taskID string, action string, expiresAt time.Time) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`INSERT INTO notification_action_tokens (token_id, user_id, task_id, action, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (token_id) DO NOTHING`,
+		tokenID,
+		userID,
+		taskID,
+		action,
+		expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReleaseNotificationActionToken forgets the redemption of a token whose
+// action failed, so the user can try it again.
+func ReleaseNotificationActionToken(ctx context.Context, tokenID string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(ctx, `DELETE FROM notification_action_tokens WHERE token_id = $1`, tokenID)
+	return err
+}
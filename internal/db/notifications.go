@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"time"
 
@@ -9,11 +10,17 @@ import (
 )
 
 type PushSubscription struct {
-	ID       string    `json:"id" db:"id"`
-	UserID   string    `json:"user_id" db:"user_id"`
-	Endpoint string    `json:"endpoint" db:"endpoint"`
-	Keys     Keys      `json:"keys" db:"keys"`
-	Created  time.Time `json:"created_at" db:"created_at"`
+	ID            string     `json:"id" db:"id"`
+	UserID        string     `json:"user_id" db:"user_id"`
+	Endpoint      string     `json:"endpoint" db:"endpoint"`
+	Keys          Keys       `json:"keys" db:"keys"`
+	Label         string     `json:"label,omitempty" db:"label"`
+	UserAgent     string     `json:"user_agent,omitempty" db:"user_agent"`
+	LastSeenAt    time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty" db:"last_success_at"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty" db:"last_failure_at"`
+	FailureCount  int        `json:"failure_count" db:"failure_count"`
+	Created       time.Time  `json:"created_at" db:"created_at"`
 }
 
 type Keys struct {
@@ -33,21 +40,34 @@ func SaveSubscription(ctx context.Context, userID string, sub *PushSubscription)
 		return err
 	}
 
+	// Re-registering an endpoint refreshes its keys and marks the device as
+	// seen. A label is only overwritten when the client sends a new one.
 	args := pgx.NamedArgs{
-		"userID":   userID,
-		"endpoint": sub.Endpoint,
-		"keys":     keysJSON,
+		"userID":    userID,
+		"endpoint":  sub.Endpoint,
+		"keys":      keysJSON,
+		"label":     nullableString(sub.Label),
+		"userAgent": nullableString(sub.UserAgent),
 	}
-	_, err = conn.Exec(
+	saved, err := scanPushSubscription(conn.QueryRow(
 		ctx,
-		`INSERT INTO push_subscriptions (user_id, endpoint, keys) 
-		 VALUES (@userID, @endpoint, @keys)
-		 ON CONFLICT (user_id, endpoint) DO UPDATE 
-		 SET keys = @keys, updated_at = CURRENT_TIMESTAMP`,
+		`INSERT INTO push_subscriptions (user_id, endpoint, keys, label, user_agent)
+		 VALUES (@userID, @endpoint, @keys, @label, @userAgent)
+		 ON CONFLICT (user_id, endpoint) DO UPDATE
+		 SET keys = @keys,
+		     label = COALESCE(EXCLUDED.label, push_subscriptions.label),
+		     user_agent = COALESCE(EXCLUDED.user_agent, push_subscriptions.user_agent),
+		     last_seen_at = CURRENT_TIMESTAMP,
+		     failure_count = 0,
+		     updated_at = CURRENT_TIMESTAMP
+		 RETURNING `+pushSubscriptionColumns,
 		args,
-	)
-
-	return err
+	))
+	if err != nil {
+		return err
+	}
+	*sub = *saved
+	return nil
 }
 
 func GetSubscriptionsByUserID(ctx context.Context, userID string) ([]*PushSubscription, error) {
@@ -59,9 +79,10 @@ func GetSubscriptionsByUserID(ctx context.Context, userID string) ([]*PushSubscr
 
 	rows, err := conn.Query(
 		ctx,
-		`SELECT id, user_id, endpoint, keys, created_at 
-		 FROM push_subscriptions 
-		 WHERE user_id = $1`,
+		`SELECT `+pushSubscriptionColumns+`
+		 FROM push_subscriptions
+		 WHERE user_id = $1
+		 ORDER BY created_at ASC`,
 		userID,
 	)
 	if err != nil {
@@ -71,22 +92,179 @@ func GetSubscriptionsByUserID(ctx context.Context, userID string) ([]*PushSubscr
 
 	subscriptions := []*PushSubscription{}
 	for rows.Next() {
-		var sub PushSubscription
-		var keysJSON []byte
-
-		err := rows.Scan(&sub.ID, &sub.UserID, &sub.Endpoint, &keysJSON, &sub.Created)
+		sub, err := scanPushSubscription(rows)
 		if err != nil {
 			return nil, err
 		}
+		subscriptions = append(subscriptions, sub)
+	}
 
-		if err := json.Unmarshal(keysJSON, &sub.Keys); err != nil {
-			return nil, err
-		}
+	return subscriptions, rows.Err()
+}
+
+// GetUserSubscriptionByID returns one of the user's devices. It returns
+// pgx.ErrNoRows when the subscription does not exist or belongs to someone
+// else.
+func GetUserSubscriptionByID(ctx context.Context, userID string, id string) (*PushSubscription, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return scanPushSubscription(conn.QueryRow(
+		ctx,
+		`SELECT `+pushSubscriptionColumns+`
+		 FROM push_subscriptions
+		 WHERE id = $1 AND user_id = $2`,
+		id,
+		userID,
+	))
+}
+
+func UpdateSubscriptionLabel(ctx context.Context, userID string, id string, label string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE push_subscriptions SET label = $3 WHERE id = $1 AND user_id = $2`,
+		id,
+		userID,
+		nullableString(label),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func DeleteUserSubscriptionByID(ctx context.Context, userID string, id string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(ctx, `DELETE FROM push_subscriptions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
 
-		subscriptions = append(subscriptions, &sub)
+// RecordSubscriptionSuccess resets the failure streak of a device after the
+// push service accepted a notification.
+func RecordSubscriptionSuccess(ctx context.Context, id string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
 	}
+	defer conn.Release()
+
+	_, err = conn.Exec(
+		ctx,
+		`UPDATE push_subscriptions
+		 SET last_success_at = CURRENT_TIMESTAMP, failure_count = 0
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
 
-	return subscriptions, nil
+func RecordSubscriptionFailure(ctx context.Context, id string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(
+		ctx,
+		`UPDATE push_subscriptions
+		 SET last_failure_at = CURRENT_TIMESTAMP, failure_count = failure_count + 1
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+// PruneStaleSubscriptions deletes devices that failed maxFailures times in a
+// row, or that neither re-registered nor received a push within idleFor.
+func PruneStaleSubscriptions(ctx context.Context, maxFailures int, idleFor time.Duration) (int64, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`DELETE FROM push_subscriptions
+		 WHERE failure_count >= $1
+		    OR GREATEST(last_seen_at, last_success_at) < $2`,
+		maxFailures,
+		time.Now().Add(-idleFor),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+const pushSubscriptionColumns = `id, user_id, endpoint, keys, label, user_agent, last_seen_at,
+	last_success_at, last_failure_at, failure_count, created_at`
+
+func scanPushSubscription(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*PushSubscription, error) {
+	var sub PushSubscription
+	var keysJSON []byte
+	var label, userAgent sql.NullString
+	var lastSuccessAt, lastFailureAt sql.NullTime
+	err := scanner.Scan(
+		&sub.ID,
+		&sub.UserID,
+		&sub.Endpoint,
+		&keysJSON,
+		&label,
+		&userAgent,
+		&sub.LastSeenAt,
+		&lastSuccessAt,
+		&lastFailureAt,
+		&sub.FailureCount,
+		&sub.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(keysJSON, &sub.Keys); err != nil {
+		return nil, err
+	}
+	sub.Label = label.String
+	sub.UserAgent = userAgent.String
+	if lastSuccessAt.Valid {
+		sub.LastSuccessAt = &lastSuccessAt.Time
+	}
+	if lastFailureAt.Valid {
+		sub.LastFailureAt = &lastFailureAt.Time
+	}
+	return &sub, nil
 }
 
 // DeleteSubscriptionByID removes a push subscription by its primary key. Used
@@ -0,0 +1,348 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending OutboxStatus = "pending"
+	OutboxStatusSent    OutboxStatus = "sent"
+	OutboxStatusFailed  OutboxStatus = "failed"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusSent    DeliveryStatus = "sent"
+	DeliveryStatusFailed  DeliveryStatus = "failed"
+	DeliveryStatusExpired DeliveryStatus = "expired"
+)
+
+// OutboxNotification is a queued push notification. Workers claim pending
+// rows whose next_attempt_at has passed and retry them with backoff until
+// they are sent or max_attempts is reached.
+type OutboxNotification struct {
+	ID            string                 `json:"id"`
+	UserID        string                 `json:"user_id"`
+	Kind          string                 `json:"kind"`
+	DedupeKey     string                 `json:"dedupe_key,omitempty"`
+	Payload       json.RawMessage        `json:"payload"`
+	Status        OutboxStatus           `json:"status"`
+	Attempts      int                    `json:"attempts"`
+	MaxAttempts   int                    `json:"max_attempts"`
+	NextAttemptAt time.Time              `json:"next_attempt_at"`
+	LastError     string                 `json:"last_error,omitempty"`
+	SentCount     int                    `json:"sent_count"`
+	SentAt        *time.Time             `json:"sent_at,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	Deliveries    []NotificationDelivery `json:"deliveries,omitempty"`
+}
+
+// NotificationDelivery records one attempt to deliver an outbox notification
+// to a single push subscription.
+type NotificationDelivery struct {
+	ID             string         `json:"id"`
+	OutboxID       string         `json:"outbox_id"`
+	UserID         string         `json:"user_id"`
+	SubscriptionID string         `json:"subscription_id,omitempty"`
+	Endpoint       string         `json:"endpoint,omitempty"`
+	Status         DeliveryStatus `json:"status"`
+	Error          string         `json:"error,omitempty"`
+	AttemptedAt    time.Time      `json:"attempted_at"`
+}
+
+// EnqueueNotification inserts a pending notification and returns its id.
+// When dedupeKey is not empty and a row with the same key already exists, the
+// existing id is returned and nothing new is queued.
+func EnqueueNotification(ctx context.Context, userID string, kind string, dedupeKey string, payload json.RawMessage) (string, error) {
+	return EnqueueNotificationAt(ctx, userID, kind, dedupeKey, payload, time.Time{})
+}
+
+// EnqueueNotificationAt behaves like EnqueueNotification but holds the first
+// attempt until notBefore. A zero notBefore means "as soon as possible".
+func EnqueueNotificationAt(ctx context.Context, userID string, kind string, dedupeKey string, payload json.RawMessage, notBefore time.Time) (string, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var id string
+	err = conn.QueryRow(
+		ctx,
+		`INSERT INTO notification_outbox (user_id, kind, dedupe_key, payload, next_attempt_at)
+		 VALUES (@userID, @kind, @dedupeKey, @payload, COALESCE(@notBefore::timestamptz, CURRENT_TIMESTAMP))
+		 ON CONFLICT (dedupe_key) DO UPDATE SET dedupe_key = EXCLUDED.dedupe_key
+		 RETURNING id`,
+		pgx.NamedArgs{
+			"userID":    userID,
+			"kind":      kind,
+			"dedupeKey": nullableString(dedupeKey),
+			"payload":   payload,
+			"notBefore": nullableTime(notBefore),
+		},
+	).Scan(&id)
+	return id, err
+}
+
+// ClaimOutboxNotifications leases up to `limit` due notifications. Each claim
+// counts as an attempt and pushes next_attempt_at forward by `lease`, so a
+// worker that dies mid-send does not block the row forever. SKIP LOCKED lets
+// several workers drain the queue concurrently.
+func ClaimOutboxNotifications(ctx context.Context, limit int, lease time.Duration) ([]*OutboxNotification, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`UPDATE notification_outbox o
+		 SET attempts = o.attempts + 1,
+		     next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		 WHERE o.id IN (
+			SELECT id
+			FROM notification_outbox
+			WHERE status = 'pending' AND next_attempt_at <= CURRENT_TIMESTAMP
+			ORDER BY next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+outboxColumnsSQL("o"),
+		limit,
+		lease.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanOutboxNotifications(rows)
+}
+
+func MarkOutboxSent(ctx context.Context, id string, sentCount int) error {
+	return updateOutbox(ctx,
+		`UPDATE notification_outbox
+		 SET status = 'sent', sent_count = $2, sent_at = CURRENT_TIMESTAMP, last_error = NULL
+		 WHERE id = $1`,
+		id, sentCount,
+	)
+}
+
+func MarkOutboxRetry(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	return updateOutbox(ctx,
+		`UPDATE notification_outbox
+		 SET next_attempt_at = $2, last_error = $3
+		 WHERE id = $1`,
+		id, nextAttemptAt, lastError,
+	)
+}
+
+func MarkOutboxFailed(ctx context.Context, id string, lastError string) error {
+	return updateOutbox(ctx,
+		`UPDATE notification_outbox
+		 SET status = 'failed', last_error = $2
+		 WHERE id = $1`,
+		id, lastError,
+	)
+}
+
+func updateOutbox(ctx context.Context, query string, args ...interface{}) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(ctx, query, args...)
+	return err
+}
+
+func RecordNotificationDelivery(ctx context.Context, delivery *NotificationDelivery) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`INSERT INTO notification_deliveries (
+			outbox_id, user_id, subscription_id, endpoint, status, error
+		) VALUES (
+			@outboxID, @userID, @subscriptionID, @endpoint, @status, @error
+		)`,
+		pgx.NamedArgs{
+			"outboxID":       delivery.OutboxID,
+			"userID":         delivery.UserID,
+			"subscriptionID": nullableString(delivery.SubscriptionID),
+			"endpoint":       nullableString(delivery.Endpoint),
+			"status":         string(delivery.Status),
+			"error":          nullableString(delivery.Error),
+		},
+	)
+	return err
+}
+
+// ListUserNotificationLog returns the user's most recent outbox notifications
+// with every delivery attempt attached, newest first.
+func ListUserNotificationLog(ctx context.Context, userID string, limit int) ([]*OutboxNotification, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	rows, err := conn.Query(
+		ctx,
+		`SELECT `+outboxColumnsSQL("o")+`
+		 FROM notification_outbox o
+		 WHERE o.user_id = $1
+		 ORDER BY o.created_at DESC
+		 LIMIT $2`,
+		userID,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	items, err := scanOutboxNotifications(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	ids := make([]string, 0, len(items))
+	byID := make(map[string]*OutboxNotification, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+		byID[item.ID] = item
+	}
+
+	deliveryRows, err := conn.Query(
+		ctx,
+		`SELECT id, outbox_id, user_id, subscription_id::text, endpoint, status, error, attempted_at
+		 FROM notification_deliveries
+		 WHERE outbox_id = ANY($1::uuid[])
+		 ORDER BY attempted_at ASC`,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer deliveryRows.Close()
+
+	for deliveryRows.Next() {
+		var delivery NotificationDelivery
+		var subscriptionID, endpoint, deliveryErr sql.NullString
+		if err := deliveryRows.Scan(
+			&delivery.ID,
+			&delivery.OutboxID,
+			&delivery.UserID,
+			&subscriptionID,
+			&endpoint,
+			&delivery.Status,
+			&deliveryErr,
+			&delivery.AttemptedAt,
+		); err != nil {
+			return nil, err
+		}
+		delivery.SubscriptionID = subscriptionID.String
+		delivery.Endpoint = endpoint.String
+		delivery.Error = deliveryErr.String
+		if item, ok := byID[delivery.OutboxID]; ok {
+			item.Deliveries = append(item.Deliveries, delivery)
+		}
+	}
+	if err := deliveryRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func outboxColumnsSQL(alias string) string {
+	return alias + `.id, ` +
+		alias + `.user_id, ` +
+		alias + `.kind, ` +
+		alias + `.dedupe_key, ` +
+		alias + `.payload, ` +
+		alias + `.status, ` +
+		alias + `.attempts, ` +
+		alias + `.max_attempts, ` +
+		alias + `.next_attempt_at, ` +
+		alias + `.last_error, ` +
+		alias + `.sent_count, ` +
+		alias + `.sent_at, ` +
+		alias + `.created_at, ` +
+		alias + `.updated_at`
+}
+
+func scanOutboxNotifications(rows pgx.Rows) ([]*OutboxNotification, error) {
+	items := []*OutboxNotification{}
+	for rows.Next() {
+		var item OutboxNotification
+		var dedupeKey, lastError sql.NullString
+		var sentAt sql.NullTime
+		var payload []byte
+		if err := rows.Scan(
+			&item.ID,
+			&item.UserID,
+			&item.Kind,
+			&dedupeKey,
+			&payload,
+			&item.Status,
+			&item.Attempts,
+			&item.MaxAttempts,
+			&item.NextAttemptAt,
+			&lastError,
+			&item.SentCount,
+			&sentAt,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		item.Payload = json.RawMessage(payload)
+		item.DedupeKey = dedupeKey.String
+		item.LastError = lastError.String
+		if sentAt.Valid {
+			item.SentAt = &sentAt.Time
+		}
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
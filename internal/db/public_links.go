@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"strings"
+	"time"
+)
+
+type PublicShareScope string
+
+const (
+	PublicShareScopeDay      PublicShareScope = "day"
+	PublicShareScopeRange    PublicShareScope = "range"
+	PublicShareScopeCategory PublicShareScope = "category"
+)
+
+// MaxPublicShareDays bounds the dates a single link can show, like the
+// history and metrics ranges.
+const MaxPublicShareDays = 90
+
+// PublicShareLink is a read-only view of part of the owner's plan for people
+// without an account. From and To are dates (YYYY-MM-DD); a day link has
+// From == To and a category link only shows tasks of Category.
+type PublicShareLink struct {
+	ID             string           `json:"id"`
+	OwnerUserID    string           `json:"owner_user_id"`
+	OwnerUsername  string           `json:"-"`
+	OwnerFullname  string           `json:"-"`
+	Label          string           `json:"label,omitempty"`
+	Scope          PublicShareScope `json:"scope"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	Category       string           `json:"category,omitempty"`
+	ExpiresAt      *time.Time       `json:"expires_at,omitempty"`
+	RevokedAt      *time.Time       `json:"revoked_at,omitempty"`
+	AccessCount    int              `json:"access_count"`
+	LastAccessedAt *time.Time       `json:"last_accessed_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// PublicTaskItem is the part of a task a public link shows: no ids, owners
+// or assignees.
+type PublicTaskItem struct {
+	Date              string     `json:"date"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description,omitempty"`
+	Category          string     `json:"category,omitempty"`
+	PriorityLevel     string     `json:"priority_level"`
+	StatusLevel       string     `json:"status_level"`
+	IsRequired        bool       `json:"is_required"`
+	ScheduleStartTime *string    `json:"schedule_start_time,omitempty"`
+	ScheduleEndTime   *string    `json:"schedule_end_time,omitempty"`
+	DurationMinutes   *int       `json:"duration_minutes,omitempty"`
+	TargetCount       *int       `json:"target_count,omitempty"`
+	CurrentCount      int        `json:"current_count"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+// PublicShareDay is one day of a public link, with its completion rate so a
+// range can be drawn as a heatmap.
+type PublicShareDay struct {
+	Date       string           `json:"date"`
+	Total      int              `json:"total"`
+	Completed  int              `json:"completed"`
+	Percentage float64          `json:"percentage"`
+	Tasks      []PublicTaskItem `json:"tasks"`
+}
+
+func NewPublicTaskItem(task *DetailedTask) PublicTaskItem {
+	item := NewTaskFeedItem(task)
+	return PublicTaskItem{
+		Date:              task.Date.In(time.Local).Format("2006-01-02"),
+		Title:             item.Title,
+		Description:       item.Description,
+		Category:          task.Category,
+		PriorityLevel:     item.PriorityLevel,
+		StatusLevel:       item.StatusLevel,
+		IsRequired:        item.IsRequired,
+		ScheduleStartTime: item.ScheduleStartTime,
+		ScheduleEndTime:   item.ScheduleEndTime,
+		DurationMinutes:   item.DurationMinutes,
+		TargetCount:       item.TargetCount,
+		CurrentCount:      item.CurrentCount,
+		CompletedAt:       item.CompletedAt,
+	}
+}
+
+// BuildPublicShareDays groups the tasks of a link by day. Every day of the
+// link is listed, including days without tasks, and tasks outside the link
+// category are dropped.
+func BuildPublicShareDays(link *PublicShareLink, tasks []*DetailedTask) []PublicShareDay {
+	from, errFrom := time.ParseInLocation("2006-01-02", link.From, time.Local)
+	to, errTo := time.ParseInLocation("2006-01-02", link.To, time.Local)
+	if errFrom != nil || errTo != nil {
+		return []PublicShareDay{}
+	}
+
+	days := []PublicShareDay{}
+	index := map[string]int{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		index[key] = len(days)
+		days = append(days, PublicShareDay{Date: key, Tasks: []PublicTaskItem{}})
+	}
+
+	category := normalizeScopeCategory(link.Category)
+	for _, task := range tasks {
+		if category != "" && normalizeScopeCategory(task.Category) != category {
+			continue
+		}
+		item := NewPublicTaskItem(task)
+		i, ok := index[item.Date]
+		if !ok {
+			continue
+		}
+		days[i].Total++
+		if task.Status == TaskStatusCompleted {
+			days[i].Completed++
+		}
+		days[i].Tasks = append(days[i].Tasks, item)
+	}
+	for i := range days {
+		if days[i].Total > 0 {
+			days[i].Percentage = math.Round(float64(days[i].Completed)*1000/float64(days[i].Total)) / 10
+		}
+	}
+	return days
+}
+
+func CreatePublicShareLink(ctx context.Context, link *PublicShareLink, tokenHash string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return conn.QueryRow(
+		ctx,
+		`INSERT INTO public_share_links (owner_user_id, token_hash, label, scope, from_date, to_date, category, expires_at)
+		 VALUES ($1, $2, $3, $4, $5::date, $6::date, $7, $8)
+		 RETURNING id, created_at`,
+		link.OwnerUserID,
+		tokenHash,
+		nullableString(strings.TrimSpace(link.Label)),
+		string(link.Scope),
+		link.From,
+		link.To,
+		nullableString(link.Category),
+		link.ExpiresAt,
+	).Scan(&link.ID, &link.CreatedAt)
+}
+
+// ListPublicShareLinks returns every link of ownerUserID, newest first,
+// including revoked and expired ones so their access counts stay visible.
+func ListPublicShareLinks(ctx context.Context, ownerUserID string) ([]*PublicShareLink, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		publicShareLinkSelectSQL("public_share_links")+` WHERE l.owner_user_id = $1 ORDER BY l.created_at DESC`,
+		ownerUserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*PublicShareLink{}
+	for rows.Next() {
+		link, err := scanPublicShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// OpenPublicShareLink finds the live link with tokenHash and counts the
+// visit. Revoked, expired and unknown links return pgx.ErrNoRows alike.
+func OpenPublicShareLink(ctx context.Context, tokenHash string) (*PublicShareLink, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return scanPublicShareLink(conn.QueryRow(
+		ctx,
+		`WITH opened AS (
+			UPDATE public_share_links
+			SET access_count = access_count + 1, last_accessed_at = CURRENT_TIMESTAMP
+			WHERE token_hash = $1
+			  AND revoked_at IS NULL
+			  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			RETURNING *
+		) `+publicShareLinkSelectSQL("opened"),
+		tokenHash,
+	))
+}
+
+func RevokePublicShareLink(ctx context.Context, ownerUserID string, id string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE public_share_links
+		 SET revoked_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND owner_user_id = $2 AND revoked_at IS NULL`,
+		id,
+		ownerUserID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// publicShareLinkSelectSQL reads links from source, aliased l: the table, or
+// a CTE returning its rows.
+func publicShareLinkSelectSQL(source string) string {
+	return `SELECT
+		l.id,
+		l.owner_user_id,
+		owner.username,
+		owner.fullname,
+		l.label,
+		l.scope,
+		l.from_date::text,
+		l.to_date::text,
+		l.category,
+		l.expires_at,
+		l.revoked_at,
+		l.access_count,
+		l.last_accessed_at,
+		l.created_at
+	FROM ` + source + ` l
+	INNER JOIN users owner ON owner.id = l.owner_user_id`
+}
+
+func scanPublicShareLink(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*PublicShareLink, error) {
+	var link PublicShareLink
+	var label, category sql.NullString
+	var expiresAt, revokedAt, lastAccessedAt sql.NullTime
+	err := scanner.Scan(
+		&link.ID,
+		&link.OwnerUserID,
+		&link.OwnerUsername,
+		&link.OwnerFullname,
+		&label,
+		&link.Scope,
+		&link.From,
+		&link.To,
+		&category,
+		&expiresAt,
+		&revokedAt,
+		&link.AccessCount,
+		&lastAccessedAt,
+		&link.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	link.Label = label.String
+	link.Category = category.String
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		link.RevokedAt = &revokedAt.Time
+	}
+	if lastAccessedAt.Valid {
+		link.LastAccessedAt = &lastAccessedAt.Time
+	}
+	return &link, nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildPublicShareDays(t *testing.T) {
+	link := &PublicShareLink{
+		Scope:    PublicShareScopeCategory,
+		From:     "2026-03-02",
+		To:       "2026-03-04",
+		Category: "Gym",
+	}
+	day := func(d int) time.Time {
+		return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local)
+	}
+	tasks := []*DetailedTask{
+		{ID: "a", Title: "Run", Date: day(2), Category: "gym", Status: TaskStatusCompleted},
+		{ID: "b", Title: "Lift", Date: day(2), Category: " GYM", Status: TaskStatusPending},
+		{ID: "c", Title: "Read", Date: day(2), Category: "study", Status: TaskStatusCompleted},
+		{ID: "d", Title: "Swim", Date: day(4), Category: "gym", Status: TaskStatusCompleted},
+		{ID: "e", Title: "Late", Date: day(5), Category: "gym", Status: TaskStatusCompleted},
+	}
+
+	days := BuildPublicShareDays(link, tasks)
+	if len(days) != 3 {
+		t.Fatalf("len(days) = %d, want 3", len(days))
+	}
+	want := []struct {
+		date       string
+		total      int
+		completed  int
+		percentage float64
+	}{
+		{"2026-03-02", 2, 1, 50},
+		{"2026-03-03", 0, 0, 0},
+		{"2026-03-04", 1, 1, 100},
+	}
+	for i, w := range want {
+		got := days[i]
+		if got.Date != w.date || got.Total != w.total || got.Completed != w.completed || got.Percentage != w.percentage {
+			t.Fatalf("days[%d] = %+v, want %+v", i, got, w)
+		}
+		if len(got.Tasks) != w.total {
+			t.Fatalf("days[%d] has %d tasks, want %d", i, len(got.Tasks), w.total)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// task_daily_rollups holds one row per responsible user, day and schedule
+// with the counts history and metrics read. Triggers on tasks and
+// task_completions keep it current; ReconcileTaskDailyRollups rebuilds a range
+// from the source tables in case a write slipped past them.
+
+// ReconcileTaskDailyRollups recounts every rollup between from and to and
+// returns how many rows the range holds afterwards.
+func ReconcileTaskDailyRollups(ctx context.Context, from time.Time, to time.Time) (int64, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM task_daily_rollups WHERE day BETWEEN $1::date AND $2::date`, from, to); err != nil {
+		return 0, err
+	}
+	tag, err := tx.Exec(
+		ctx,
+		`INSERT INTO task_daily_rollups (
+			user_id, day, schedule_task_id, category,
+			total, completed, pending, skipped, failed, in_progress, completions, partial_progress
+		)
+		SELECT
+			r.user_id,
+			r.day,
+			r.schedule_task_id,
+			COALESCE(LOWER(TRIM(st.category)), ''),
+			SUM(r.total),
+			SUM(r.completed),
+			SUM(r.pending),
+			SUM(r.skipped),
+			SUM(r.failed),
+			SUM(r.in_progress),
+			SUM(r.completions),
+			SUM(r.partial_progress)
+		FROM (
+			SELECT
+				`+taskResponsibleUserSQL+` AS user_id,
+				DATE(date) AS day,
+				schedule_task_id,
+				1 AS total,
+				(status_level = 'completed')::int AS completed,
+				(status_level = 'pending')::int AS pending,
+				(status_level = 'skipped')::int AS skipped,
+				(status_level = 'failed')::int AS failed,
+				(status_level = 'in_progress')::int AS in_progress,
+				0 AS completions,
+				CASE
+					WHEN status_level <> 'completed' AND COALESCE(target_count, schedule_target) > 0
+						THEN LEAST(current_count::float8 / COALESCE(target_count, schedule_target), 1)
+					ELSE 0
+				END AS partial_progress
+			FROM (
+				SELECT
+					tasks.*,
+					(SELECT s.target_count FROM schedule_tasks s WHERE s.id = tasks.schedule_task_id) AS schedule_target
+				FROM tasks
+				WHERE DATE(tasks.date) BETWEEN $1::date AND $2::date
+			) tasks
+			UNION ALL
+			SELECT
+				COALESCE(tc.completed_by_user_id, tc.user_id),
+				DATE(tc.completed_at),
+				tk.schedule_task_id,
+				0, 0, 0, 0, 0, 0,
+				1,
+				0
+			FROM task_completions tc
+			INNER JOIN tasks tk ON tk.id = tc.task_id
+			WHERE DATE(tc.completed_at) BETWEEN $1::date AND $2::date
+		) r
+		INNER JOIN users u ON u.id = r.user_id
+		LEFT JOIN schedule_tasks st ON st.id = r.schedule_task_id
+		GROUP BY r.user_id, r.day, r.schedule_task_id, st.category
+		ON CONFLICT ON CONSTRAINT task_daily_rollups_key DO UPDATE SET
+			category = EXCLUDED.category,
+			total = EXCLUDED.total,
+			completed = EXCLUDED.completed,
+			pending = EXCLUDED.pending,
+			skipped = EXCLUDED.skipped,
+			failed = EXCLUDED.failed,
+			in_progress = EXCLUDED.in_progress,
+			completions = EXCLUDED.completions,
+			partial_progress = EXCLUDED.partial_progress`,
+		from,
+		to,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
@@ -63,6 +63,7 @@ type ScheduleTask struct {
 	ID          string `db:"id" json:"id,omitempty"`
 	UserID      string `db:"user_id" json:"userId,omitempty"`
 	CreatedBy   string `db:"created_by" json:"createdBy,omitempty"`
+	SpaceID     string `db:"space_id" json:"spaceId,omitempty"`
 	Title       string `db:"title" json:"title,omitempty" binding:"required"`
 	Description string `db:"description" json:"description,omitempty"`
 
@@ -161,7 +162,7 @@ func CreateScheduleTask(ctx context.Context, task *ScheduleTask) error {
 			start_date, end_date, duration, duration_minutes, target_count,
 			required, is_required, repeating, repeat_frequency, repeat_interval,
 			repeat_weekdays, repeat_end_date, frequency, frequency_config, category,
-			status, status_level, priority, priority_level
+			status, status_level, priority, priority_level, space_id
 		) VALUES (
 			@id, @title, @userID, @createdBy, @description,
 			date_trunc('minute', @startTime::timestamptz),
@@ -171,7 +172,7 @@ func CreateScheduleTask(ctx context.Context, task *ScheduleTask) error {
 			@startDate, @endDate, @duration, @durationMinutes, @targetCount,
 			@isRequired, @isRequired, @repeating, @repeatFrequency, @repeatInterval,
 			@repeatWeekdays, @repeatEndDate, @frequency, @frequencyConfig, @category,
-			@legacyStatus, @status, @legacyPriority, @priority
+			@legacyStatus, @status, @legacyPriority, @priority, @spaceID
 		)`,
 		args,
 	)
@@ -218,7 +219,8 @@ func UpdateScheduleTask(ctx context.Context, task *ScheduleTask) error {
 			status = @legacyStatus,
 			status_level = @status,
 			priority = @legacyPriority,
-			priority_level = @priority
+			priority_level = @priority,
+			space_id = @spaceID
 		WHERE id = @id AND user_id = @userID`,
 		args,
 	)
@@ -335,6 +337,7 @@ func scanScheduleTask(scanner scheduleScanner) (*ScheduleTask, error) {
 		category        sql.NullString
 		createdBy       sql.NullString
 		description     sql.NullString
+		spaceID         sql.NullString
 		duration        sql.NullInt64
 		durationMinutes sql.NullInt64
 		endDate         sql.NullTime
@@ -373,6 +376,7 @@ func scanScheduleTask(scanner scheduleScanner) (*ScheduleTask, error) {
 		&category,
 		&task.Status,
 		&task.Priority,
+		&spaceID,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 	)
@@ -389,6 +393,9 @@ func scanScheduleTask(scanner scheduleScanner) (*ScheduleTask, error) {
 	if description.Valid {
 		task.Description = description.String
 	}
+	if spaceID.Valid {
+		task.SpaceID = spaceID.String
+	}
 	if duration.Valid {
 		task.Duration = time.Duration(duration.Int64) * time.Minute
 	}
@@ -454,6 +461,7 @@ func scheduleSelectSQL() string {
 		category,
 		status_level,
 		priority_level,
+		space_id::text,
 		created_at,
 		updated_at
 	FROM schedule_tasks`
@@ -488,6 +496,7 @@ func scheduleArgs(task *ScheduleTask) pgx.NamedArgs {
 		"status":          task.Status,
 		"legacyPriority":  legacyPriority(task.Priority),
 		"priority":        task.Priority,
+		"spaceID":         nullableString(task.SpaceID),
 	}
 }
 
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+type ScheduleDayStatus string
+
+const (
+	ScheduleDayCompleted ScheduleDayStatus = "completed"
+	ScheduleDayMissed    ScheduleDayStatus = "missed"
+	ScheduleDayPending   ScheduleDayStatus = "pending"
+)
+
+// ScheduleStatsDay is one day the schedule was due. Days the schedule does
+// not repeat on are left out, so they never break a streak.
+type ScheduleStatsDay struct {
+	Date         string            `json:"date"`
+	Status       ScheduleDayStatus `json:"status"`
+	CurrentCount int               `json:"current_count"`
+}
+
+// ScheduleStats summarizes one schedule over a date range. CompletionRate only
+// counts settled days: today stays pending until it is completed.
+type ScheduleStats struct {
+	ScheduleID     string             `json:"schedule_id"`
+	From           string             `json:"from"`
+	To             string             `json:"to"`
+	ScheduledDays  int                `json:"scheduled_days"`
+	Completed      int                `json:"completed"`
+	Missed         int                `json:"missed"`
+	CompletionRate float64            `json:"completion_rate"`
+	CurrentStreak  int                `json:"current_streak"`
+	LongestStreak  int                `json:"longest_streak"`
+	TargetCount    *int               `json:"target_count,omitempty"`
+	AverageCount   float64            `json:"average_count"`
+	TargetRate     *float64           `json:"target_rate,omitempty"`
+	Days           []ScheduleStatsDay `json:"days"`
+}
+
+// ScheduleTaskDay is the generated task of a schedule on one day.
+type ScheduleTaskDay struct {
+	Status       TaskStatus
+	CurrentCount int
+}
+
+func GetScheduleStats(ctx context.Context, schedule *ScheduleTask, from time.Time, to time.Time) (*ScheduleStats, error) {
+	days, err := GetScheduleTaskDays(ctx, schedule.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return BuildScheduleStats(schedule, from, to, time.Now(), days), nil
+}
+
+// GetScheduleTaskDays returns the tasks generated from scheduleID between
+// from and to, keyed by date (YYYY-MM-DD).
+func GetScheduleTaskDays(ctx context.Context, scheduleID string, from time.Time, to time.Time) (map[string]ScheduleTaskDay, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT DATE(date), status_level, current_count
+		FROM tasks
+		WHERE schedule_task_id = $1
+			AND DATE(date) BETWEEN $2::date AND $3::date`,
+		scheduleID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[string]ScheduleTaskDay{}
+	for rows.Next() {
+		var date time.Time
+		var day ScheduleTaskDay
+		if err := rows.Scan(&date, &day.Status, &day.CurrentCount); err != nil {
+			return nil, err
+		}
+		days[date.Format("2006-01-02")] = day
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// BuildScheduleStats walks the range a day at a time. A day counts when the
+// schedule repeats on it or a task was generated for it anyway; days before
+// the schedule existed and days after today are skipped.
+func BuildScheduleStats(schedule *ScheduleTask, from time.Time, to time.Time, now time.Time, tasks map[string]ScheduleTaskDay) *ScheduleStats {
+	stats := &ScheduleStats{
+		ScheduleID:  schedule.ID,
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		TargetCount: schedule.TargetCount,
+		Days:        []ScheduleStatsDay{},
+	}
+
+	today := now.Format("2006-01-02")
+	created := ""
+	if !schedule.CreatedAt.IsZero() {
+		created = schedule.CreatedAt.In(time.Local).Format("2006-01-02")
+	}
+
+	start := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, time.Local)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 12, 0, 0, 0, time.Local)
+	countTotal, countDays, streak := 0, 0, 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		if key > today {
+			break
+		}
+		task, generated := tasks[key]
+		if !generated && (key < created || !shouldCreateTaskForToday(schedule, day)) {
+			continue
+		}
+
+		entry := ScheduleStatsDay{Date: key, CurrentCount: task.CurrentCount}
+		switch {
+		case task.Status == TaskStatusCompleted:
+			entry.Status = ScheduleDayCompleted
+			stats.Completed++
+			streak++
+			stats.LongestStreak = max(stats.LongestStreak, streak)
+		case key == today:
+			// Today can still be completed, so it neither counts nor breaks
+			// the streak yet.
+			entry.Status = ScheduleDayPending
+		default:
+			entry.Status = ScheduleDayMissed
+			stats.Missed++
+			streak = 0
+		}
+		if generated {
+			countTotal += task.CurrentCount
+			countDays++
+		}
+		stats.Days = append(stats.Days, entry)
+	}
+
+	stats.ScheduledDays = len(stats.Days)
+	stats.CurrentStreak = streak
+	if settled := stats.Completed + stats.Missed; settled > 0 {
+		stats.CompletionRate = math.Round(float64(stats.Completed)*1000/float64(settled)) / 10
+	}
+	if countDays > 0 {
+		stats.AverageCount = math.Round(float64(countTotal)*10/float64(countDays)) / 10
+		if schedule.TargetCount != nil && *schedule.TargetCount > 0 {
+			rate := math.Round(float64(countTotal)*1000/float64(countDays**schedule.TargetCount)) / 10
+			stats.TargetRate = &rate
+		}
+	}
+	return stats
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildScheduleStatsSkipsUnscheduledDays(t *testing.T) {
+	target := 4
+	// Mon/Wed/Fri habit; June 1, 2026 is a Monday.
+	schedule := &ScheduleTask{
+		ID:              "schedule-1",
+		Repeating:       true,
+		RepeatFrequency: ScheduleTaskRepeatFrequencyWeekly,
+		RepeatInterval:  1,
+		RepeatWeekdays:  []int{1, 3, 5},
+		StartDate:       date(2026, 5, 1),
+		TargetCount:     &target,
+	}
+	tasks := map[string]ScheduleTaskDay{
+		"2026-06-01": {Status: TaskStatusCompleted, CurrentCount: 4},
+		"2026-06-03": {Status: TaskStatusFailed, CurrentCount: 1},
+		"2026-06-05": {Status: TaskStatusCompleted, CurrentCount: 4},
+		"2026-06-08": {Status: TaskStatusCompleted, CurrentCount: 3},
+		"2026-06-10": {Status: TaskStatusCompleted, CurrentCount: 4},
+		"2026-06-12": {Status: TaskStatusPending},
+	}
+	from := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(2026, 6, 14, 12, 0, 0, 0, time.Local)
+	now := time.Date(2026, 6, 12, 9, 0, 0, 0, time.Local)
+
+	stats := BuildScheduleStats(schedule, from, to, now, tasks)
+	if stats.ScheduledDays != 6 || stats.Completed != 4 || stats.Missed != 1 {
+		t.Fatalf("stats = %+v, want 6 scheduled, 4 completed, 1 missed", stats)
+	}
+	if stats.CurrentStreak != 3 || stats.LongestStreak != 3 {
+		t.Fatalf("streaks = %d/%d, want 3/3", stats.CurrentStreak, stats.LongestStreak)
+	}
+	if stats.CompletionRate != 80 {
+		t.Fatalf("CompletionRate = %v, want 80", stats.CompletionRate)
+	}
+	if stats.AverageCount != 2.7 || stats.TargetRate == nil || *stats.TargetRate != 66.7 {
+		t.Fatalf("AverageCount = %v TargetRate = %v, want 2.7 and 66.7", stats.AverageCount, stats.TargetRate)
+	}
+	if last := stats.Days[len(stats.Days)-1]; last.Date != "2026-06-12" || last.Status != ScheduleDayPending {
+		t.Fatalf("last day = %+v, want pending 2026-06-12", last)
+	}
+}
+
+func TestBuildScheduleStatsCountsUngeneratedDaysAsMissed(t *testing.T) {
+	schedule := &ScheduleTask{
+		ID:              "schedule-1",
+		Repeating:       true,
+		RepeatFrequency: ScheduleTaskRepeatFrequencyDaily,
+		RepeatInterval:  1,
+		StartDate:       date(2026, 5, 1),
+		CreatedAt:       time.Date(2026, 6, 2, 8, 0, 0, 0, time.Local),
+	}
+	tasks := map[string]ScheduleTaskDay{
+		"2026-06-02": {Status: TaskStatusCompleted},
+		"2026-06-04": {Status: TaskStatusCompleted},
+	}
+	from := time.Date(2026, 6, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(2026, 6, 4, 12, 0, 0, 0, time.Local)
+	now := time.Date(2026, 6, 20, 9, 0, 0, 0, time.Local)
+
+	stats := BuildScheduleStats(schedule, from, to, now, tasks)
+	if stats.ScheduledDays != 3 || stats.Missed != 1 || stats.CurrentStreak != 1 || stats.LongestStreak != 1 {
+		t.Fatalf("stats = %+v, want 3 scheduled days, 1 missed and streaks of 1", stats)
+	}
+	if stats.TargetRate != nil {
+		t.Fatalf("TargetRate = %v, want nil without a target", *stats.TargetRate)
+	}
+}
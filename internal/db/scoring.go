@@ -0,0 +1,214 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxTaskScoreWeight bounds every weight of the scoring model.
+const MaxTaskScoreWeight = 10
+
+// TaskScoreWeights is the productivity score model of a user. A task weighs
+// its required or optional weight times its urgent or medium weight, and
+// earns its weight when completed. With PartialCredit an unfinished counter
+// task earns the share of its target it reached.
+type TaskScoreWeights struct {
+	Required      float64 `json:"required_weight"`
+	Optional      float64 `json:"optional_weight"`
+	Urgent        float64 `json:"urgent_weight"`
+	Medium        float64 `json:"medium_weight"`
+	PartialCredit bool    `json:"partial_credit"`
+}
+
+func DefaultTaskScoreWeights() TaskScoreWeights {
+	return TaskScoreWeights{Required: 2, Optional: 1, Urgent: 2, Medium: 1, PartialCredit: true}
+}
+
+// Valid reports whether every weight is above 0 and at most
+// MaxTaskScoreWeight.
+func (weights TaskScoreWeights) Valid() bool {
+	for _, weight := range []float64{weights.Required, weights.Optional, weights.Urgent, weights.Medium} {
+		if !(weight > 0 && weight <= MaxTaskScoreWeight) {
+			return false
+		}
+	}
+	return true
+}
+
+func (weights TaskScoreWeights) weight(required bool, urgent bool) float64 {
+	weight := weights.Optional
+	if required {
+		weight = weights.Required
+	}
+	if urgent {
+		return weight * weights.Urgent
+	}
+	return weight * weights.Medium
+}
+
+// taskScoreCounts are the tasks of one day sharing required and urgency.
+// PartialProgress sums the share of the target reached by the unfinished
+// ones.
+type taskScoreCounts struct {
+	required        bool
+	urgent          bool
+	total           int
+	completed       int
+	partialProgress float64
+}
+
+// taskScoreTally is what a day earned out of what it could have.
+type taskScoreTally struct {
+	earned   float64
+	possible float64
+}
+
+func (tally *taskScoreTally) add(other taskScoreTally) {
+	tally.earned += other.earned
+	tally.possible += other.possible
+}
+
+func (tally taskScoreTally) score() float64 {
+	if tally.possible <= 0 {
+		return 0
+	}
+	return math.Round(tally.earned*1000/tally.possible) / 10
+}
+
+func (weights TaskScoreWeights) tally(counts taskScoreCounts) taskScoreTally {
+	weight := weights.weight(counts.required, counts.urgent)
+	earned := float64(counts.completed)
+	if weights.PartialCredit {
+		earned += counts.partialProgress
+	}
+	return taskScoreTally{earned: weight * earned, possible: weight * float64(counts.total)}
+}
+
+func GetUserScoreWeights(ctx context.Context, userID string) (TaskScoreWeights, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return TaskScoreWeights{}, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var weights TaskScoreWeights
+	err = conn.QueryRow(
+		ctx,
+		`SELECT required_weight, optional_weight, urgent_weight, medium_weight, partial_credit
+		FROM user_score_weights
+		WHERE user_id = $1`,
+		userID,
+	).Scan(&weights.Required, &weights.Optional, &weights.Urgent, &weights.Medium, &weights.PartialCredit)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return DefaultTaskScoreWeights(), nil
+		}
+		return TaskScoreWeights{}, err
+	}
+	return weights, nil
+}
+
+func SetUserScoreWeights(ctx context.Context, userID string, weights TaskScoreWeights) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`INSERT INTO user_score_weights (user_id, required_weight, optional_weight, urgent_weight, medium_weight, partial_credit)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (user_id) DO UPDATE SET
+			required_weight = EXCLUDED.required_weight,
+			optional_weight = EXCLUDED.optional_weight,
+			urgent_weight = EXCLUDED.urgent_weight,
+			medium_weight = EXCLUDED.medium_weight,
+			partial_credit = EXCLUDED.partial_credit`,
+		userID,
+		weights.Required,
+		weights.Optional,
+		weights.Urgent,
+		weights.Medium,
+		weights.PartialCredit,
+	)
+	return err
+}
+
+// getUserTaskScores loads the weights of userID and the score tallies of
+// every day between from and to.
+func getUserTaskScores(ctx context.Context, userID string, from time.Time, to time.Time) (TaskScoreWeights, map[string]taskScoreTally, error) {
+	weights, err := GetUserScoreWeights(ctx, userID)
+	if err != nil {
+		return TaskScoreWeights{}, nil, err
+	}
+	tallies, err := getUserTaskScoreTallies(ctx, userID, weights, from, to)
+	if err != nil {
+		return TaskScoreWeights{}, nil, err
+	}
+	return weights, tallies, nil
+}
+
+// getUserTaskScoreTallies weighs the tasks of userID between from and to
+// with weights, keyed by date (YYYY-MM-DD). Days without tasks are missing.
+func getUserTaskScoreTallies(ctx context.Context, userID string, weights TaskScoreWeights, from time.Time, to time.Time) (map[string]taskScoreTally, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			r.day,
+			COALESCE(st.is_required, FALSE) AS required,
+			COALESCE(st.priority_level IN ('urgent', 'high'), FALSE) AS urgent,
+			SUM(r.total),
+			SUM(r.completed),
+			SUM(r.partial_progress)
+		FROM task_daily_rollups r
+		LEFT JOIN schedule_tasks st ON st.id = r.schedule_task_id
+		WHERE r.user_id = $1
+			AND r.day BETWEEN $2::date AND $3::date
+			AND r.total > 0
+		GROUP BY r.day, required, urgent`,
+		userID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tallies := map[string]taskScoreTally{}
+	for rows.Next() {
+		var day time.Time
+		var counts taskScoreCounts
+		if err := rows.Scan(&day, &counts.required, &counts.urgent, &counts.total, &counts.completed, &counts.partialProgress); err != nil {
+			return nil, err
+		}
+		key := day.Format("2006-01-02")
+		tally := tallies[key]
+		tally.add(weights.tally(counts))
+		tallies[key] = tally
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tallies, nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskScoreWeightsTally(t *testing.T) {
+	weights := DefaultTaskScoreWeights()
+	var day taskScoreTally
+	day.add(weights.tally(taskScoreCounts{required: true, urgent: true, total: 1, completed: 0}))
+	day.add(weights.tally(taskScoreCounts{total: 2, completed: 1, partialProgress: 0.5}))
+	// A skipped required urgent task weighs 4, the two optional medium ones 1.
+	if day.possible != 6 || day.earned != 1.5 || day.score() != 25 {
+		t.Fatalf("tally = %v/%v score %v, want 1.5/6 score 25", day.earned, day.possible, day.score())
+	}
+
+	weights.PartialCredit = false
+	if score := weights.tally(taskScoreCounts{total: 2, completed: 1, partialProgress: 0.5}).score(); score != 50 {
+		t.Fatalf("score without partial credit = %v, want 50", score)
+	}
+	if score := (taskScoreTally{}).score(); score != 0 {
+		t.Fatalf("empty score = %v, want 0", score)
+	}
+}
+
+func TestTaskScoreWeightsValid(t *testing.T) {
+	if !DefaultTaskScoreWeights().Valid() {
+		t.Fatal("default weights should be valid")
+	}
+	for _, weights := range []TaskScoreWeights{
+		{Required: 0, Optional: 1, Urgent: 1, Medium: 1},
+		{Required: 1, Optional: -1, Urgent: 1, Medium: 1},
+		{Required: 1, Optional: 1, Urgent: MaxTaskScoreWeight + 1, Medium: 1},
+	} {
+		if weights.Valid() {
+			t.Fatalf("%+v should be invalid", weights)
+		}
+	}
+}
+
+func TestGroupTaskHistoryScore(t *testing.T) {
+	history := GroupTaskHistory(&TaskHistoryRange{
+		Days: []TaskHistoryDay{
+			{Date: "2026-06-01", Total: 1, Completed: 1, scoreTally: taskScoreTally{earned: 4, possible: 4}},
+			{Date: "2026-06-02", Total: 1, scoreTally: taskScoreTally{earned: 0, possible: 1}},
+		},
+	}, TimeGranularityWeek, time.Monday)
+
+	if len(history.Days) != 1 || history.Days[0].Score != 80 || history.Days[0].Percentage != 50 {
+		t.Fatalf("grouped = %+v, want one week at 50%% scoring 80", history.Days)
+	}
+}
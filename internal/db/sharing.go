@@ -28,6 +28,39 @@ const (
 
 var ErrInvalidSharingAccessLevel = errors.New("invalid sharing access level")
 
+var (
+	ErrSharingInvitationNotFound   = errors.New("sharing invitation not found")
+	ErrSharingInvitationNotPending = errors.New("sharing invitation is not pending")
+)
+
+type SharingInvitationStatus string
+
+const (
+	SharingInvitationPending   SharingInvitationStatus = "pending"
+	SharingInvitationAccepted  SharingInvitationStatus = "accepted"
+	SharingInvitationDeclined  SharingInvitationStatus = "declined"
+	SharingInvitationCancelled SharingInvitationStatus = "cancelled"
+	SharingInvitationExpired   SharingInvitationStatus = "expired"
+)
+
+// liveGrantSQL matches grants of alias g that are neither revoked nor past
+// expires_at. Expired grants stop working at expires_at even before the expiry
+// job marks them revoked.
+const liveGrantSQL = `g.revoked_at IS NULL AND (g.expires_at IS NULL OR g.expires_at > CURRENT_TIMESTAMP)`
+
+// activeGrantSQL matches live grants the grantee accepted. Only these confer
+// permissions.
+const activeGrantSQL = liveGrantSQL + ` AND g.accepted_at IS NOT NULL`
+
+// openGrantSQL matches grants that are active or still waiting on an
+// invitation that has not expired. Open grants hold the owner/grantee pair,
+// so a second invitation cannot be sent while one is pending.
+const openGrantSQL = liveGrantSQL + ` AND (g.accepted_at IS NOT NULL OR EXISTS (
+	SELECT 1 FROM sharing_invitations inv
+	WHERE inv.grant_id = g.id AND inv.status = 'pending'
+	  AND (inv.expires_at IS NULL OR inv.expires_at > CURRENT_TIMESTAMP)
+))`
+
 type SharingUser struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
@@ -35,24 +68,44 @@ type SharingUser struct {
 	Email    string `json:"email,omitempty"`
 }
 
+// TaskAccessGrant lets the grantee act on the owner's tasks. ScopeCategories
+// and ScopeScheduleIDs restrict it to part of the owner's schedules (both
+// empty covers everything), and a grant with ExpiresAt stops working at that
+// time. A grant without AcceptedAt is an invitation the grantee has not
+// answered yet and confers nothing.
 type TaskAccessGrant struct {
-	ID              string             `json:"id"`
-	OwnerUserID     string             `json:"owner_user_id"`
-	GranteeUserID   string             `json:"grantee_user_id"`
-	AccessLevel     SharingAccessLevel `json:"access_level"`
-	CanView         bool               `json:"can_view"`
-	CanCreate       bool               `json:"can_create"`
-	CanEditTasks    bool               `json:"can_edit_tasks"`
-	CanPing         bool               `json:"can_ping"`
-	OwnerUsername   string             `json:"owner_username,omitempty"`
-	OwnerFullname   string             `json:"owner_fullname,omitempty"`
-	OwnerEmail      string             `json:"owner_email,omitempty"`
-	GranteeUsername string             `json:"grantee_username,omitempty"`
-	GranteeFullname string             `json:"grantee_fullname,omitempty"`
-	GranteeEmail    string             `json:"grantee_email,omitempty"`
-	CreatedAt       time.Time          `json:"created_at"`
-	UpdatedAt       time.Time          `json:"updated_at"`
-	RevokedAt       *time.Time         `json:"revoked_at,omitempty"`
+	ID               string             `json:"id"`
+	OwnerUserID      string             `json:"owner_user_id"`
+	GranteeUserID    string             `json:"grantee_user_id"`
+	AccessLevel      SharingAccessLevel `json:"access_level"`
+	CanView          bool               `json:"can_view"`
+	CanCreate        bool               `json:"can_create"`
+	CanEditTasks     bool               `json:"can_edit_tasks"`
+	CanPing          bool               `json:"can_ping"`
+	ScopeCategories  []string           `json:"scope_categories"`
+	ScopeScheduleIDs []string           `json:"scope_schedule_ids"`
+	ExpiresAt        *time.Time         `json:"expires_at,omitempty"`
+	AcceptedAt       *time.Time         `json:"accepted_at,omitempty"`
+	OwnerUsername    string             `json:"owner_username,omitempty"`
+	OwnerFullname    string             `json:"owner_fullname,omitempty"`
+	OwnerEmail       string             `json:"owner_email,omitempty"`
+	GranteeUsername  string             `json:"grantee_username,omitempty"`
+	GranteeFullname  string             `json:"grantee_fullname,omitempty"`
+	GranteeEmail     string             `json:"grantee_email,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	RevokedAt        *time.Time         `json:"revoked_at,omitempty"`
+
+	// RequiresApproval turns the grantee's edits and new schedules into
+	// change requests the owner has to approve.
+	RequiresApproval bool `json:"requires_approval"`
+	// ShareAnalytics lets the grantee read the owner's progress, history and
+	// metrics. See AllowsAnalytics.
+	ShareAnalytics bool `json:"share_analytics"`
+
+	// Accountability is set when the owner made the grantee an
+	// accountability partner.
+	Accountability *AccountabilitySettings `json:"accountability,omitempty"`
 }
 
 type TaskPing struct {
@@ -66,19 +119,6 @@ type TaskPing struct {
 	ReadAt           *time.Time `json:"read_at,omitempty"`
 }
 
-type NotificationInboxItem struct {
-	ID               string     `json:"id"`
-	TaskID           string     `json:"task_id"`
-	TaskTitle        string     `json:"task_title"`
-	SenderUserID     string     `json:"sender_user_id"`
-	SenderUsername   string     `json:"sender_username"`
-	SenderFullname   string     `json:"sender_fullname"`
-	Message          string     `json:"message,omitempty"`
-	NotificationSent bool       `json:"notification_sent"`
-	CreatedAt        time.Time  `json:"created_at"`
-	ReadAt           *time.Time `json:"read_at,omitempty"`
-}
-
 func NormalizeSharingAccessLevel(value string) (SharingAccessLevel, error) {
 	switch SharingAccessLevel(strings.TrimSpace(value)) {
 	case SharingAccessLevelView:
@@ -92,6 +132,79 @@ func NormalizeSharingAccessLevel(value string) (SharingAccessLevel, error) {
 	}
 }
 
+// NormalizeSharingScopeCategories trims, lowercases and deduplicates the
+// categories of a grant scope, dropping empty entries.
+func NormalizeSharingScopeCategories(categories []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, category := range categories {
+		category = normalizeScopeCategory(category)
+		if category == "" || seen[category] {
+			continue
+		}
+		seen[category] = true
+		normalized = append(normalized, category)
+	}
+	return normalized
+}
+
+func normalizeScopeCategory(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// Allows reports whether the grant's access level includes permission,
+// mirroring the checks in UserHasScheduleTaskPermission.
+func (grant *TaskAccessGrant) Allows(permission string) bool {
+	switch permission {
+	case SharingPermissionView:
+		return grant.CanView
+	case SharingPermissionCreate:
+		return grant.CanCreate
+	case SharingPermissionEdit:
+		return grant.AccessLevel == SharingAccessLevelManage
+	case SharingPermissionPing:
+		return grant.CanPing
+	}
+	return false
+}
+
+// AllowsAnalytics reports whether the grantee may read the owner's progress,
+// history and metrics. Those aggregate every task of the owner, so scoped
+// grants never allow them.
+func (grant *TaskAccessGrant) AllowsAnalytics() bool {
+	return grant.CanView && grant.ShareAnalytics && !grant.IsScoped()
+}
+
+func (grant *TaskAccessGrant) IsScoped() bool {
+	return len(grant.ScopeCategories) > 0 || len(grant.ScopeScheduleIDs) > 0
+}
+
+// CoversSchedule reports whether a schedule with the given id and category
+// falls inside the grant scope. Pass an empty id for schedules that do not
+// exist yet.
+func (grant *TaskAccessGrant) CoversSchedule(scheduleID string, category string) bool {
+	if !grant.IsScoped() {
+		return true
+	}
+	if scheduleID != "" {
+		for _, id := range grant.ScopeScheduleIDs {
+			if id == scheduleID {
+				return true
+			}
+		}
+	}
+	category = normalizeScopeCategory(category)
+	if category == "" {
+		return false
+	}
+	for _, scoped := range grant.ScopeCategories {
+		if scoped == category {
+			return true
+		}
+	}
+	return false
+}
+
 func ApplySharingAccessLevel(grant *TaskAccessGrant) {
 	switch grant.AccessLevel {
 	case SharingAccessLevelManage:
@@ -189,6 +302,16 @@ func GetUserByUsernameOrEmail(ctx context.Context, identifier string) (*User, er
 }
 
 func GetActiveTaskAccessGrantByPair(ctx context.Context, ownerUserID string, granteeUserID string) (*TaskAccessGrant, error) {
+	return getTaskAccessGrantByPair(ctx, activeGrantSQL, ownerUserID, granteeUserID)
+}
+
+// GetOpenTaskAccessGrantByPair returns the active or pending grant between
+// owner and grantee, or pgx.ErrNoRows.
+func GetOpenTaskAccessGrantByPair(ctx context.Context, ownerUserID string, granteeUserID string) (*TaskAccessGrant, error) {
+	return getTaskAccessGrantByPair(ctx, openGrantSQL, ownerUserID, granteeUserID)
+}
+
+func getTaskAccessGrantByPair(ctx context.Context, state string, ownerUserID string, granteeUserID string) (*TaskAccessGrant, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
 		return nil, err
@@ -199,14 +322,17 @@ func GetActiveTaskAccessGrantByPair(ctx context.Context, ownerUserID string, gra
 	defer cancel()
 
 	row := conn.QueryRow(ctx, taskAccessGrantSelectSQL()+`
-		WHERE g.owner_user_id = $1 AND g.grantee_user_id = $2 AND g.revoked_at IS NULL`,
+		WHERE g.owner_user_id = $1 AND g.grantee_user_id = $2 AND `+state,
 		ownerUserID,
 		granteeUserID,
 	)
 	return scanTaskAccessGrant(row)
 }
 
-func CreateTaskAccessGrant(ctx context.Context, grant *TaskAccessGrant) error {
+// CreateTaskAccessGrant inserts a pending grant together with the invitation
+// the grantee has to accept before it takes effect. A nil invitationExpiresAt
+// keeps the invitation open until answered or cancelled.
+func CreateTaskAccessGrant(ctx context.Context, grant *TaskAccessGrant, invitationExpiresAt *time.Time) error {
 	conn, err := GetConn(ctx)
 	if err != nil {
 		return err
@@ -220,32 +346,281 @@ func CreateTaskAccessGrant(ctx context.Context, grant *TaskAccessGrant) error {
 		grant.ID = uuid.Must(uuid.NewV7()).String()
 	}
 	ApplySharingAccessLevel(grant)
-	_, err = conn.Exec(
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lapsed grants and unanswered invitations past their expiry still hold
+	// the active-pair unique index until the expiry job catches up.
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE sharing_invitations
+		 SET status = 'expired', responded_at = CURRENT_TIMESTAMP
+		 WHERE owner_user_id = $1 AND grantee_user_id = $2
+		   AND status = 'pending' AND expires_at <= CURRENT_TIMESTAMP`,
+		grant.OwnerUserID,
+		grant.GranteeUserID,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE task_access_grants g
+		 SET revoked_at = CASE WHEN g.expires_at <= CURRENT_TIMESTAMP THEN g.expires_at ELSE CURRENT_TIMESTAMP END
+		 WHERE g.owner_user_id = $1 AND g.grantee_user_id = $2
+		   AND g.revoked_at IS NULL AND NOT (`+openGrantSQL+`)`,
+		grant.OwnerUserID,
+		grant.GranteeUserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
 		ctx,
 		`INSERT INTO task_access_grants (
-			id, owner_user_id, grantee_user_id, access_level, can_view, can_create, can_ping
+			id, owner_user_id, grantee_user_id, access_level, can_view, can_create, can_ping,
+			scope_categories, scope_schedule_ids, expires_at, requires_approval, share_analytics
 		) VALUES (
-			@id, @ownerUserID, @granteeUserID, @accessLevel, @canView, @canCreate, @canPing
+			@id, @ownerUserID, @granteeUserID, @accessLevel, @canView, @canCreate, @canPing,
+			@scopeCategories, @scopeScheduleIDs::uuid[], @expiresAt, @requiresApproval, @shareAnalytics
 		)`,
 		pgx.NamedArgs{
-			"id":            grant.ID,
-			"ownerUserID":   grant.OwnerUserID,
-			"granteeUserID": grant.GranteeUserID,
-			"accessLevel":   grant.AccessLevel,
-			"canView":       grant.CanView,
-			"canCreate":     grant.CanCreate,
-			"canPing":       grant.CanPing,
+			"id":               grant.ID,
+			"ownerUserID":      grant.OwnerUserID,
+			"granteeUserID":    grant.GranteeUserID,
+			"accessLevel":      grant.AccessLevel,
+			"canView":          grant.CanView,
+			"canCreate":        grant.CanCreate,
+			"canPing":          grant.CanPing,
+			"scopeCategories":  NormalizeSharingScopeCategories(grant.ScopeCategories),
+			"scopeScheduleIDs": nonNilStrings(grant.ScopeScheduleIDs),
+			"expiresAt":        grant.ExpiresAt,
+			"requiresApproval": grant.RequiresApproval,
+			"shareAnalytics":   grant.ShareAnalytics,
 		},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO sharing_invitations (grant_id, owner_user_id, grantee_user_id, expires_at)
+		 VALUES ($1, $2, $3, $4)`,
+		grant.ID,
+		grant.OwnerUserID,
+		grant.GranteeUserID,
+		invitationExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ExtendTaskAccessGrant moves the expiry of an active or pending grant owned
+// by ownerUserID. A nil expiresAt makes the grant permanent. Grants that
+// already lapsed cannot be extended.
+func ExtendTaskAccessGrant(ctx context.Context, ownerUserID string, grantID string, expiresAt *time.Time) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE task_access_grants g
+		 SET expires_at = $3
+		 WHERE g.id = $1 AND g.owner_user_id = $2 AND `+openGrantSQL,
+		grantID,
+		ownerUserID,
+		expiresAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetTaskAccessGrantApproval switches approval mode on an active or pending
+// grant owned by ownerUserID. Pending change requests are kept either way.
+func SetTaskAccessGrantApproval(ctx context.Context, ownerUserID string, grantID string, requiresApproval bool) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE task_access_grants g
+		 SET requires_approval = $3
+		 WHERE g.id = $1 AND g.owner_user_id = $2 AND `+openGrantSQL,
+		grantID,
+		ownerUserID,
+		requiresApproval,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetTaskAccessGrantAnalytics shows or hides the owner's analytics on an
+// active or pending grant owned by ownerUserID.
+func SetTaskAccessGrantAnalytics(ctx context.Context, ownerUserID string, grantID string, shareAnalytics bool) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE task_access_grants g
+		 SET share_analytics = $3
+		 WHERE g.id = $1 AND g.owner_user_id = $2 AND `+openGrantSQL,
+		grantID,
+		ownerUserID,
+		shareAnalytics,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RevokeExpiredTaskAccessGrants marks every lapsed grant as revoked at its
+// expiry time and returns them so both parties can be notified. Grants that
+// were never accepted are left to ExpireSharingInvitations.
+func RevokeExpiredTaskAccessGrants(ctx context.Context) ([]*TaskAccessGrant, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`WITH expired AS (
+			UPDATE task_access_grants
+			SET revoked_at = expires_at
+			WHERE revoked_at IS NULL AND accepted_at IS NOT NULL
+			  AND expires_at <= CURRENT_TIMESTAMP
+			RETURNING id
+		)
+		`+taskAccessGrantSelectSQL()+`
+		WHERE g.id IN (SELECT id FROM expired)`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []*TaskAccessGrant{}
+	for rows.Next() {
+		grant, err := scanTaskAccessGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		// The select reads the pre-update snapshot.
+		grant.RevokedAt = grant.ExpiresAt
+		grants = append(grants, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// UpdateTaskAccessGrantScope replaces the scope of an active or pending grant
+// owned by ownerUserID. Schedule ids must belong to the owner.
+func UpdateTaskAccessGrantScope(ctx context.Context, ownerUserID string, grantID string, categories []string, scheduleIDs []string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE task_access_grants g
+		 SET scope_categories = @categories, scope_schedule_ids = @scheduleIDs::uuid[]
+		 WHERE g.id = @id AND g.owner_user_id = @ownerUserID AND `+openGrantSQL,
+		pgx.NamedArgs{
+			"id":          grantID,
+			"ownerUserID": ownerUserID,
+			"categories":  NormalizeSharingScopeCategories(categories),
+			"scheduleIDs": nonNilStrings(scheduleIDs),
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// CountOwnedSchedules returns how many of scheduleIDs belong to ownerUserID.
+func CountOwnedSchedules(ctx context.Context, ownerUserID string, scheduleIDs []string) (int, error) {
+	if len(scheduleIDs) == 0 {
+		return 0, nil
+	}
+
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var count int
+	err = conn.QueryRow(
+		ctx,
+		`SELECT COUNT(DISTINCT id) FROM schedule_tasks WHERE user_id = $1 AND id = ANY($2::uuid[])`,
+		ownerUserID,
+		scheduleIDs,
+	).Scan(&count)
+	return count, err
 }
 
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// ListTaskAccessGrantsByOwner includes pending grants so the owner can follow
+// and cancel outstanding invitations.
 func ListTaskAccessGrantsByOwner(ctx context.Context, ownerUserID string) ([]*TaskAccessGrant, error) {
-	return listTaskAccessGrants(ctx, `g.owner_user_id = $1 AND g.revoked_at IS NULL`, ownerUserID)
+	return listTaskAccessGrants(ctx, `g.owner_user_id = $1 AND `+openGrantSQL, ownerUserID)
 }
 
 func ListTaskAccessGrantsForGrantee(ctx context.Context, granteeUserID string) ([]*TaskAccessGrant, error) {
-	return listTaskAccessGrants(ctx, `g.grantee_user_id = $1 AND g.revoked_at IS NULL`, granteeUserID)
+	return listTaskAccessGrants(ctx, `g.grantee_user_id = $1 AND `+activeGrantSQL, granteeUserID)
 }
 
 func listTaskAccessGrants(ctx context.Context, where string, userID string) ([]*TaskAccessGrant, error) {
@@ -282,6 +657,8 @@ func listTaskAccessGrants(ctx context.Context, where string, userID string) ([]*
 	return grants, nil
 }
 
+// RevokeTaskAccessGrant ends a grant owned by ownerUserID. Revoking a grant
+// that was never accepted cancels its invitation.
 func RevokeTaskAccessGrant(ctx context.Context, ownerUserID string, grantID string) (bool, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
@@ -292,7 +669,13 @@ func RevokeTaskAccessGrant(ctx context.Context, ownerUserID string, grantID stri
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	tag, err := conn.Exec(
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
 		ctx,
 		`UPDATE task_access_grants
 		 SET revoked_at = CURRENT_TIMESTAMP
@@ -303,10 +686,28 @@ func RevokeTaskAccessGrant(ctx context.Context, ownerUserID string, grantID stri
 	if err != nil {
 		return false, err
 	}
-	return tag.RowsAffected() > 0, nil
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE sharing_invitations
+		 SET status = 'cancelled', responded_at = CURRENT_TIMESTAMP
+		 WHERE grant_id = $1 AND status = 'pending'`,
+		grantID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
 }
 
-func UserHasTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (bool, error) {
+// UserHasScheduleTaskPermission reports whether the grantee holds permission
+// over the given schedule of the owner, and over its tasks, taking the grant
+// scope into account. Members of the space holding the schedule get the
+// permissions of their role.
+func UserHasScheduleTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, scheduleTaskID string, permission string) (bool, error) {
 	if ownerUserID == granteeUserID {
 		return true, nil
 	}
@@ -325,25 +726,57 @@ func UserHasTaskPermission(ctx context.Context, ownerUserID string, granteeUserI
 		ctx,
 		`SELECT EXISTS (
 			SELECT 1
-			FROM task_access_grants
-			WHERE owner_user_id = $1
-			  AND grantee_user_id = $2
-			  AND revoked_at IS NULL
-			  AND CASE $3
-				WHEN 'view' THEN can_view
-				WHEN 'create' THEN can_create
-				WHEN 'edit' THEN access_level = 'manage'
-				WHEN 'ping' THEN can_ping
+			FROM task_access_grants g
+			INNER JOIN schedule_tasks st ON st.id = $3 AND st.user_id = g.owner_user_id
+			WHERE g.owner_user_id = $1
+			  AND g.grantee_user_id = $2
+			  AND `+activeGrantSQL+`
+			  AND CASE $4
+				WHEN 'view' THEN g.can_view
+				WHEN 'create' THEN g.can_create
+				WHEN 'edit' THEN g.access_level = 'manage'
+				WHEN 'ping' THEN g.can_ping
 				ELSE FALSE
 			  END
+			  AND (
+				(cardinality(g.scope_categories) = 0 AND cardinality(g.scope_schedule_ids) = 0)
+				OR st.id = ANY(g.scope_schedule_ids)
+				OR LOWER(TRIM(COALESCE(st.category, ''))) = ANY(g.scope_categories)
+			  )
+		) OR EXISTS (
+			SELECT 1
+			FROM schedule_tasks st
+			INNER JOIN space_members m ON m.space_id = st.space_id
+			WHERE st.id = $3
+			  AND st.user_id = $1
+			  AND m.user_id = $2
+			  AND m.role = ANY($5)
 		)`,
 		ownerUserID,
 		granteeUserID,
+		scheduleTaskID,
 		permission,
+		spaceRolesAllowing(permission),
 	).Scan(&allowed)
 	return allowed, err
 }
 
+// ResolveSharingGrant returns the active grant through which the grantee holds
+// permission over the owner's tasks, or nil when there is none.
+func ResolveSharingGrant(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (*TaskAccessGrant, error) {
+	grant, err := GetActiveTaskAccessGrantByPair(ctx, ownerUserID, granteeUserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !grant.Allows(permission) {
+		return nil, nil
+	}
+	return grant, nil
+}
+
 func RecentTaskPingExists(ctx context.Context, taskID string, senderUserID string, recipientUserID string, since time.Time) (bool, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
@@ -409,81 +842,6 @@ func CreateTaskPing(ctx context.Context, ping *TaskPing) error {
 	return err
 }
 
-func ListNotificationInbox(ctx context.Context, recipientUserID string) ([]*NotificationInboxItem, error) {
-	conn, err := GetConn(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Release()
-
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	rows, err := conn.Query(
-		ctx,
-		`SELECT
-			p.id,
-			p.task_id,
-			COALESCE(NULLIF(t.title, ''), st.title, 'Tarea') AS task_title,
-			p.sender_user_id,
-			u.username,
-			u.fullname,
-			p.message,
-			p.notification_sent,
-			p.created_at,
-			p.read_at
-		 FROM task_pings p
-		 INNER JOIN users u ON u.id = p.sender_user_id
-		 INNER JOIN tasks t ON t.id = p.task_id
-		 INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
-		 WHERE p.recipient_user_id = $1
-		 ORDER BY p.read_at IS NOT NULL ASC, p.created_at DESC
-		 LIMIT 50`,
-		recipientUserID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	items := []*NotificationInboxItem{}
-	for rows.Next() {
-		item, err := scanNotificationInboxItem(rows)
-		if err != nil {
-			return nil, err
-		}
-		items = append(items, item)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-func MarkNotificationInboxItemRead(ctx context.Context, recipientUserID string, pingID string) (bool, error) {
-	conn, err := GetConn(ctx)
-	if err != nil {
-		return false, err
-	}
-	defer conn.Release()
-
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	tag, err := conn.Exec(
-		ctx,
-		`UPDATE task_pings
-		 SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
-		 WHERE id = $1 AND recipient_user_id = $2`,
-		pingID,
-		recipientUserID,
-	)
-	if err != nil {
-		return false, err
-	}
-	return tag.RowsAffected() > 0, nil
-}
-
 func CountTaskPings(ctx context.Context, taskID string, senderUserID string) (int, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
@@ -515,6 +873,12 @@ func taskAccessGrantSelectSQL() string {
 		g.can_view,
 		g.can_create,
 		g.can_ping,
+		g.scope_categories,
+		g.scope_schedule_ids::text[],
+		g.expires_at,
+		g.accepted_at,
+		g.requires_approval,
+		g.share_analytics,
 		owner.username,
 		owner.fullname,
 		owner.email,
@@ -523,10 +887,14 @@ func taskAccessGrantSelectSQL() string {
 		grantee.email,
 		g.created_at,
 		g.updated_at,
-		g.revoked_at
+		g.revoked_at,
+		ap.grace_minutes,
+		ap.weekly_summary,
+		ap.created_at
 	FROM task_access_grants g
 	INNER JOIN users owner ON owner.id = g.owner_user_id
-	INNER JOIN users grantee ON grantee.id = g.grantee_user_id`
+	INNER JOIN users grantee ON grantee.id = g.grantee_user_id
+	LEFT JOIN accountability_partners ap ON ap.grant_id = g.id`
 }
 
 func scanTaskAccessGrant(scanner interface {
@@ -534,7 +902,9 @@ func scanTaskAccessGrant(scanner interface {
 }) (*TaskAccessGrant, error) {
 	var grant TaskAccessGrant
 	var ownerEmail, granteeEmail sql.NullString
-	var revokedAt sql.NullTime
+	var revokedAt, expiresAt, acceptedAt, partnerSince sql.NullTime
+	var graceMinutes sql.NullInt32
+	var weeklySummary sql.NullBool
 	err := scanner.Scan(
 		&grant.ID,
 		&grant.OwnerUserID,
@@ -543,6 +913,12 @@ func scanTaskAccessGrant(scanner interface {
 		&grant.CanView,
 		&grant.CanCreate,
 		&grant.CanPing,
+		&grant.ScopeCategories,
+		&grant.ScopeScheduleIDs,
+		&expiresAt,
+		&acceptedAt,
+		&grant.RequiresApproval,
+		&grant.ShareAnalytics,
 		&grant.OwnerUsername,
 		&grant.OwnerFullname,
 		&ownerEmail,
@@ -552,6 +928,9 @@ func scanTaskAccessGrant(scanner interface {
 		&grant.CreatedAt,
 		&grant.UpdatedAt,
 		&revokedAt,
+		&graceMinutes,
+		&weeklySummary,
+		&partnerSince,
 	)
 	if err != nil {
 		return nil, err
@@ -565,43 +944,98 @@ func scanTaskAccessGrant(scanner interface {
 	if revokedAt.Valid {
 		grant.RevokedAt = &revokedAt.Time
 	}
+	if expiresAt.Valid {
+		grant.ExpiresAt = &expiresAt.Time
+	}
+	if acceptedAt.Valid {
+		grant.AcceptedAt = &acceptedAt.Time
+	}
+	if partnerSince.Valid {
+		grant.Accountability = &AccountabilitySettings{
+			GraceMinutes:  int(graceMinutes.Int32),
+			WeeklySummary: weeklySummary.Bool,
+			CreatedAt:     partnerSince.Time,
+		}
+	}
 	grant.CanEditTasks = grant.AccessLevel == SharingAccessLevelManage
 	return &grant, nil
 }
 
 // ── Sharing Invitations ──────────────────────────────────────────────────────
 
+// SharingInvitation asks the grantee to accept a grant. The grant only takes
+// effect once the invitation is accepted; declining, cancelling or letting it
+// expire revokes the grant.
 type SharingInvitation struct {
-	ID            string     `json:"id"`
-	GrantID       string     `json:"grant_id"`
-	OwnerUserID   string     `json:"owner_user_id"`
-	OwnerUsername string     `json:"owner_username"`
-	OwnerFullname string     `json:"owner_fullname"`
-	AccessLevel   string     `json:"access_level"`
-	ReadAt        *time.Time `json:"read_at,omitempty"`
-	CreatedAt     time.Time  `json:"created_at"`
+	ID              string                  `json:"id"`
+	GrantID         string                  `json:"grant_id"`
+	OwnerUserID     string                  `json:"owner_user_id"`
+	OwnerUsername   string                  `json:"owner_username"`
+	OwnerFullname   string                  `json:"owner_fullname"`
+	GranteeUserID   string                  `json:"grantee_user_id"`
+	GranteeUsername string                  `json:"grantee_username"`
+	GranteeFullname string                  `json:"grantee_fullname"`
+	AccessLevel     string                  `json:"access_level"`
+	Status          SharingInvitationStatus `json:"status"`
+	ReadAt          *time.Time              `json:"read_at,omitempty"`
+	RespondedAt     *time.Time              `json:"responded_at,omitempty"`
+	ExpiresAt       *time.Time              `json:"expires_at,omitempty"`
+	CreatedAt       time.Time               `json:"created_at"`
 }
 
-func CreateSharingInvitation(ctx context.Context, grantID, ownerUserID, granteeUserID string) error {
+// ListSharingInvitations returns the grantee's invitations whose grant is
+// still live, pending ones first.
+func ListSharingInvitations(ctx context.Context, granteeUserID string) ([]*SharingInvitation, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer conn.Release()
 
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	_, err = conn.Exec(ctx, `
-		INSERT INTO sharing_invitations (grant_id, owner_user_id, grantee_user_id)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (grant_id) DO NOTHING`,
-		grantID, ownerUserID, granteeUserID,
+	rows, err := conn.Query(ctx, sharingInvitationSelectSQL()+`
+		WHERE si.grantee_user_id = $1
+		  AND `+liveGrantSQL+`
+		ORDER BY si.status = 'pending' DESC, si.read_at IS NOT NULL ASC, si.created_at DESC
+		LIMIT 50`,
+		granteeUserID,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*SharingInvitation{}
+	for rows.Next() {
+		item, err := scanSharingInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func ListSharingInvitations(ctx context.Context, granteeUserID string) ([]*SharingInvitation, error) {
+// AcceptSharingInvitation activates the invitation's grant. It returns
+// ErrSharingInvitationNotFound when the invitation is not addressed to
+// granteeUserID and ErrSharingInvitationNotPending when it was already
+// answered, cancelled or has expired.
+func AcceptSharingInvitation(ctx context.Context, granteeUserID string, invitationID string) (*SharingInvitation, error) {
+	return respondToSharingInvitation(ctx, granteeUserID, invitationID, SharingInvitationAccepted)
+}
+
+// DeclineSharingInvitation refuses the invitation and revokes its grant. It
+// fails like AcceptSharingInvitation.
+func DeclineSharingInvitation(ctx context.Context, granteeUserID string, invitationID string) (*SharingInvitation, error) {
+	return respondToSharingInvitation(ctx, granteeUserID, invitationID, SharingInvitationDeclined)
+}
+
+func respondToSharingInvitation(ctx context.Context, granteeUserID string, invitationID string, status SharingInvitationStatus) (*SharingInvitation, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
 		return nil, err
@@ -611,105 +1045,214 @@ func ListSharingInvitations(ctx context.Context, granteeUserID string) ([]*Shari
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	rows, err := conn.Query(ctx, `
-		SELECT
-			si.id,
-			si.grant_id,
-			si.owner_user_id,
-			u.username,
-			u.fullname,
-			COALESCE(g.access_level::text, 'view'),
-			si.read_at,
-			si.created_at
-		FROM sharing_invitations si
-		INNER JOIN users u ON u.id = si.owner_user_id
-		INNER JOIN task_access_grants g ON g.id = si.grant_id
-		WHERE si.grantee_user_id = $1
-		  AND g.revoked_at IS NULL
-		ORDER BY si.read_at IS NOT NULL ASC, si.created_at DESC
-		LIMIT 50`,
-		granteeUserID,
-	)
+	tx, err := conn.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback(ctx)
 
-	items := []*SharingInvitation{}
-	for rows.Next() {
-		var item SharingInvitation
-		var readAt sql.NullTime
-		if err := rows.Scan(
-			&item.ID,
-			&item.GrantID,
-			&item.OwnerUserID,
-			&item.OwnerUsername,
-			&item.OwnerFullname,
-			&item.AccessLevel,
-			&readAt,
-			&item.CreatedAt,
-		); err != nil {
+	var grantID string
+	err = tx.QueryRow(
+		ctx,
+		`UPDATE sharing_invitations si
+		 SET status = $3, responded_at = CURRENT_TIMESTAMP, read_at = COALESCE(si.read_at, CURRENT_TIMESTAMP)
+		 FROM task_access_grants g
+		 WHERE si.id = $1 AND si.grantee_user_id = $2 AND si.status = 'pending'
+		   AND (si.expires_at IS NULL OR si.expires_at > CURRENT_TIMESTAMP)
+		   AND g.id = si.grant_id AND `+liveGrantSQL+`
+		 RETURNING si.grant_id`,
+		invitationID,
+		granteeUserID,
+		status,
+	).Scan(&grantID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		err = tx.QueryRow(
+			ctx,
+			`SELECT EXISTS (SELECT 1 FROM sharing_invitations WHERE id = $1 AND grantee_user_id = $2)`,
+			invitationID,
+			granteeUserID,
+		).Scan(&exists)
+		if err != nil {
 			return nil, err
 		}
-		if readAt.Valid {
-			item.ReadAt = &readAt.Time
+		if !exists {
+			return nil, ErrSharingInvitationNotFound
 		}
-		items = append(items, &item)
+		return nil, ErrSharingInvitationNotPending
 	}
-	if err := rows.Err(); err != nil {
+	if err != nil {
 		return nil, err
 	}
-	return items, nil
+
+	grantUpdate := `UPDATE task_access_grants SET accepted_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if status != SharingInvitationAccepted {
+		grantUpdate = `UPDATE task_access_grants SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1`
+	}
+	if _, err := tx.Exec(ctx, grantUpdate, grantID); err != nil {
+		return nil, err
+	}
+
+	invitation, err := scanSharingInvitation(tx.QueryRow(ctx, sharingInvitationSelectSQL()+` WHERE si.id = $1`, invitationID))
+	if err != nil {
+		return nil, err
+	}
+	return invitation, tx.Commit(ctx)
 }
 
-func MarkSharingInvitationRead(ctx context.Context, granteeUserID, invitationID string) (bool, error) {
+// ExpireSharingInvitations marks pending invitations past their expiry as
+// expired, revokes their grants and returns them so the owner can be told.
+func ExpireSharingInvitations(ctx context.Context) ([]*SharingInvitation, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	defer conn.Release()
 
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	tag, err := conn.Exec(ctx, `
-		UPDATE sharing_invitations
-		SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
-		WHERE id = $1 AND grantee_user_id = $2`,
-		invitationID, granteeUserID,
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(
+		ctx,
+		`UPDATE sharing_invitations
+		 SET status = 'expired', responded_at = expires_at
+		 WHERE status = 'pending' AND expires_at <= CURRENT_TIMESTAMP
+		 RETURNING id`,
 	)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	return tag.RowsAffected() > 0, nil
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []*SharingInvitation{}, nil
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE task_access_grants
+		 SET revoked_at = CURRENT_TIMESTAMP
+		 WHERE revoked_at IS NULL AND accepted_at IS NULL
+		   AND id IN (SELECT grant_id FROM sharing_invitations WHERE id = ANY($1::uuid[]))`,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(ctx, sharingInvitationSelectSQL()+` WHERE si.id = ANY($1::uuid[])`, ids)
+	if err != nil {
+		return nil, err
+	}
+	invitations := []*SharingInvitation{}
+	for rows.Next() {
+		invitation, err := scanSharingInvitation(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, tx.Commit(ctx)
 }
 
-func scanNotificationInboxItem(scanner interface {
+func sharingInvitationSelectSQL() string {
+	return `SELECT
+			si.id,
+			si.grant_id,
+			si.owner_user_id,
+			owner.username,
+			owner.fullname,
+			si.grantee_user_id,
+			grantee.username,
+			grantee.fullname,
+			COALESCE(g.access_level::text, 'view'),
+			si.status,
+			si.read_at,
+			si.responded_at,
+			si.expires_at,
+			si.created_at
+		FROM sharing_invitations si
+		INNER JOIN users owner ON owner.id = si.owner_user_id
+		INNER JOIN users grantee ON grantee.id = si.grantee_user_id
+		INNER JOIN task_access_grants g ON g.id = si.grant_id`
+}
+
+func scanSharingInvitation(scanner interface {
 	Scan(dest ...interface{}) error
-}) (*NotificationInboxItem, error) {
-	var item NotificationInboxItem
-	var message sql.NullString
-	var readAt sql.NullTime
+}) (*SharingInvitation, error) {
+	var item SharingInvitation
+	var readAt, respondedAt, expiresAt sql.NullTime
 	err := scanner.Scan(
 		&item.ID,
-		&item.TaskID,
-		&item.TaskTitle,
-		&item.SenderUserID,
-		&item.SenderUsername,
-		&item.SenderFullname,
-		&message,
-		&item.NotificationSent,
-		&item.CreatedAt,
+		&item.GrantID,
+		&item.OwnerUserID,
+		&item.OwnerUsername,
+		&item.OwnerFullname,
+		&item.GranteeUserID,
+		&item.GranteeUsername,
+		&item.GranteeFullname,
+		&item.AccessLevel,
+		&item.Status,
 		&readAt,
+		&respondedAt,
+		&expiresAt,
+		&item.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
-	if message.Valid {
-		item.Message = message.String
-	}
 	if readAt.Valid {
 		item.ReadAt = &readAt.Time
 	}
+	if respondedAt.Valid {
+		item.RespondedAt = &respondedAt.Time
+	}
+	if expiresAt.Valid {
+		item.ExpiresAt = &expiresAt.Time
+	}
 	return &item, nil
 }
+
+func MarkSharingInvitationRead(ctx context.Context, granteeUserID, invitationID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(ctx, `
+		UPDATE sharing_invitations
+		SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND grantee_user_id = $2`,
+		invitationID, granteeUserID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSharingScopeCategories(t *testing.T) {
+	got := NormalizeSharingScopeCategories([]string{" Gym ", "", "gym", "Study"})
+	if want := []string{"gym", "study"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("NormalizeSharingScopeCategories = %v, want %v", got, want)
+	}
+}
+
+func TestTaskAccessGrantCoversSchedule(t *testing.T) {
+	unscoped := &TaskAccessGrant{}
+	if !unscoped.CoversSchedule("any", "") {
+		t.Fatalf("unscoped grant should cover every schedule")
+	}
+
+	scoped := &TaskAccessGrant{
+		ScopeCategories:  []string{"gym"},
+		ScopeScheduleIDs: []string{"schedule-1"},
+	}
+	cases := []struct {
+		scheduleID string
+		category   string
+		want       bool
+	}{
+		{"schedule-1", "study", true},
+		{"schedule-2", " Gym", true},
+		{"schedule-2", "study", false},
+		{"schedule-2", "", false},
+		{"", "gym", true},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		if got := scoped.CoversSchedule(tc.scheduleID, tc.category); got != tc.want {
+			t.Fatalf("CoversSchedule(%q, %q) = %v, want %v", tc.scheduleID, tc.category, got, tc.want)
+		}
+	}
+}
+
+func TestTaskAccessGrantAllowsAnalytics(t *testing.T) {
+	cases := []struct {
+		name  string
+		grant TaskAccessGrant
+		want  bool
+	}{
+		{"shared", TaskAccessGrant{CanView: true, ShareAnalytics: true}, true},
+		{"hidden", TaskAccessGrant{CanView: true}, false},
+		{"ping only", TaskAccessGrant{ShareAnalytics: true}, false},
+		{"scoped", TaskAccessGrant{CanView: true, ShareAnalytics: true, ScopeCategories: []string{"gym"}}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.grant.AllowsAnalytics(); got != tc.want {
+			t.Fatalf("%s: AllowsAnalytics = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
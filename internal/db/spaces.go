@@ -0,0 +1,705 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type SpaceRole string
+
+const (
+	SpaceRoleOwner   SpaceRole = "owner"
+	SpaceRoleManager SpaceRole = "manager"
+	SpaceRoleMember  SpaceRole = "member"
+	SpaceRoleViewer  SpaceRole = "viewer"
+)
+
+const MaxSpaceNameLength = 80
+
+var (
+	ErrInvalidSpaceRole  = errors.New("invalid space role")
+	ErrSpaceNotFound     = errors.New("space not found")
+	ErrSpaceNameRequired = errors.New("space name is required")
+	ErrSpaceNameTooLong  = errors.New("space name is too long")
+	ErrSpaceTaskNotFound = errors.New("space task not found")
+)
+
+// spaceRolePermissions lists what each role may do on the schedules of a
+// space, using the same permission names as sharing grants. Members may add
+// their own schedules (create) but only managers edit other members' tasks.
+var spaceRolePermissions = map[SpaceRole][]string{
+	SpaceRoleOwner:   {SharingPermissionView, SharingPermissionCreate, SharingPermissionEdit, SharingPermissionPing},
+	SpaceRoleManager: {SharingPermissionView, SharingPermissionCreate, SharingPermissionEdit, SharingPermissionPing},
+	SpaceRoleMember:  {SharingPermissionView, SharingPermissionCreate, SharingPermissionPing},
+	SpaceRoleViewer:  {SharingPermissionView},
+}
+
+// Space groups schedules of several users. Role is the role of the user the
+// space was loaded for.
+type Space struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"created_by"`
+	Role        SpaceRole `json:"role"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type SpaceMember struct {
+	SpaceID   string    `json:"space_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Fullname  string    `json:"fullname"`
+	Role      SpaceRole `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SpaceMemberProgress is the share of a space day attributed to one member:
+// tasks they accepted, or owned by them when not delegated.
+type SpaceMemberProgress struct {
+	UserID     string  `json:"user_id"`
+	Username   string  `json:"username"`
+	Fullname   string  `json:"fullname"`
+	Total      int     `json:"total"`
+	Completed  int     `json:"completed"`
+	Percentage float64 `json:"percentage"`
+}
+
+type SpaceDayProgress struct {
+	SpaceID    string                 `json:"space_id"`
+	Date       string                 `json:"date"`
+	Total      int                    `json:"total"`
+	Completed  int                    `json:"completed"`
+	Percentage float64                `json:"percentage"`
+	Members    []*SpaceMemberProgress `json:"members"`
+}
+
+func NormalizeSpaceRole(value string) (SpaceRole, error) {
+	role := SpaceRole(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := spaceRolePermissions[role]; !ok {
+		return "", ErrInvalidSpaceRole
+	}
+	return role, nil
+}
+
+// NormalizeSpaceName trims the name and enforces the length limit.
+func NormalizeSpaceName(name string) (string, error) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return "", ErrSpaceNameRequired
+	}
+	if len([]rune(trimmed)) > MaxSpaceNameLength {
+		return "", ErrSpaceNameTooLong
+	}
+	return trimmed, nil
+}
+
+func (role SpaceRole) Allows(permission string) bool {
+	for _, allowed := range spaceRolePermissions[role] {
+		if allowed == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageMembers reports whether the role may invite, remove and change the
+// role of other members.
+func (role SpaceRole) CanManageMembers() bool {
+	return role == SpaceRoleOwner || role == SpaceRoleManager
+}
+
+// spaceRolesAllowing returns the roles that grant permission, for use in SQL
+// membership checks.
+func spaceRolesAllowing(permission string) []string {
+	roles := []string{}
+	for role, permissions := range spaceRolePermissions {
+		for _, allowed := range permissions {
+			if allowed == permission {
+				roles = append(roles, string(role))
+				break
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// CreateSpace inserts the space and makes ownerUserID its owner.
+func CreateSpace(ctx context.Context, space *Space, ownerUserID string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(
+		ctx,
+		`INSERT INTO spaces (name, description, created_by)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, created_at, updated_at`,
+		space.Name,
+		nullableString(space.Description),
+		ownerUserID,
+	).Scan(&space.ID, &space.CreatedAt, &space.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		ctx,
+		`INSERT INTO space_members (space_id, user_id, role) VALUES ($1, $2, 'owner')`,
+		space.ID,
+		ownerUserID,
+	)
+	if err != nil {
+		return err
+	}
+
+	space.CreatedBy = ownerUserID
+	space.Role = SpaceRoleOwner
+	space.MemberCount = 1
+	return tx.Commit(ctx)
+}
+
+func ListUserSpaces(ctx context.Context, userID string) ([]*Space, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(ctx, spaceSelectSQL()+` ORDER BY s.name ASC, s.created_at ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spaces := []*Space{}
+	for rows.Next() {
+		space, err := scanSpace(rows)
+		if err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, space)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return spaces, nil
+}
+
+// GetSpaceForMember returns the space with the member's role, or
+// ErrSpaceNotFound when the space does not exist or userID is not a member.
+func GetSpaceForMember(ctx context.Context, spaceID string, userID string) (*Space, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	space, err := scanSpace(conn.QueryRow(ctx, spaceSelectSQL()+` AND s.id = $2`, userID, spaceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSpaceNotFound
+	}
+	return space, err
+}
+
+func UpdateSpace(ctx context.Context, spaceID string, name string, description string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE spaces SET name = $2, description = $3 WHERE id = $1`,
+		spaceID,
+		name,
+		nullableString(description),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSpaceNotFound
+	}
+	return nil
+}
+
+// DeleteSpace removes the space. Its schedules stay with their owners and
+// leave the space, and their task assignments are cleared.
+func DeleteSpace(ctx context.Context, spaceID string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE tasks t SET `+clearTaskAssignmentSQL+`
+		 FROM schedule_tasks st
+		 WHERE st.id = t.schedule_task_id AND st.space_id = $1 AND t.assignee_user_id IS NOT NULL`,
+		spaceID,
+	)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM spaces WHERE id = $1`, spaceID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSpaceNotFound
+	}
+	return tx.Commit(ctx)
+}
+
+func ListSpaceMembers(ctx context.Context, spaceID string) ([]*SpaceMember, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT m.space_id, m.user_id, u.username, u.fullname, m.role, m.created_at
+		 FROM space_members m
+		 INNER JOIN users u ON u.id = m.user_id
+		 WHERE m.space_id = $1
+		 ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'manager' THEN 1 WHEN 'member' THEN 2 ELSE 3 END, u.username`,
+		spaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*SpaceMember{}
+	for rows.Next() {
+		var member SpaceMember
+		if err := rows.Scan(&member.SpaceID, &member.UserID, &member.Username, &member.Fullname, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetSpaceMemberRole returns the role of userID in the space, or "" when they
+// are not a member.
+func GetSpaceMemberRole(ctx context.Context, spaceID string, userID string) (SpaceRole, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var role SpaceRole
+	err = conn.QueryRow(
+		ctx,
+		`SELECT role FROM space_members WHERE space_id = $1 AND user_id = $2`,
+		spaceID,
+		userID,
+	).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// AddSpaceMember adds userID to the space. It returns false when they are
+// already a member.
+func AddSpaceMember(ctx context.Context, spaceID string, userID string, role SpaceRole) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`INSERT INTO space_members (space_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (space_id, user_id) DO NOTHING`,
+		spaceID,
+		userID,
+		role,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// UpdateSpaceMemberRole changes the role of a member other than the owner.
+func UpdateSpaceMemberRole(ctx context.Context, spaceID string, userID string, role SpaceRole) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE space_members SET role = $3
+		 WHERE space_id = $1 AND user_id = $2 AND role <> 'owner'`,
+		spaceID,
+		userID,
+		role,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RemoveSpaceMember removes a member other than the owner, detaches their
+// schedules from the space and unassigns the space tasks they were given.
+func RemoveSpaceMember(ctx context.Context, spaceID string, userID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`DELETE FROM space_members WHERE space_id = $1 AND user_id = $2 AND role <> 'owner'`,
+		spaceID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE tasks t SET `+clearTaskAssignmentSQL+`
+		 FROM schedule_tasks st
+		 WHERE st.id = t.schedule_task_id AND st.space_id = $1 AND t.assignee_user_id = $2`,
+		spaceID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE schedule_tasks SET space_id = NULL WHERE space_id = $1 AND user_id = $2`,
+		spaceID,
+		userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// SetScheduleSpace moves a schedule of ownerUserID into spaceID, or out of
+// any space when spaceID is empty. Leaving a space clears the assignments
+// made on the schedule's tasks.
+func SetScheduleSpace(ctx context.Context, scheduleID string, ownerUserID string, spaceID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var previousSpaceID *string
+	err = tx.QueryRow(
+		ctx,
+		`SELECT space_id::text FROM schedule_tasks WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+		scheduleID,
+		ownerUserID,
+	).Scan(&previousSpaceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE schedule_tasks SET space_id = $2 WHERE id = $1`, scheduleID, nullableString(spaceID))
+	if err != nil {
+		return false, err
+	}
+	if previousSpaceID == nil || *previousSpaceID == spaceID {
+		return true, tx.Commit(ctx)
+	}
+	// Assignments made inside the space the schedule left no longer apply.
+	_, err = tx.Exec(
+		ctx,
+		`UPDATE tasks SET `+clearTaskAssignmentSQL+` WHERE schedule_task_id = $1 AND assignee_user_id IS NOT NULL`,
+		scheduleID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ListSpaceScheduleOwnerIDs returns the users that own schedules in the space.
+func ListSpaceScheduleOwnerIDs(ctx context.Context, spaceID string) ([]string, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT DISTINCT user_id FROM schedule_tasks WHERE space_id = $1 AND status_level = 'active'`,
+		spaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// GetSpaceDateDetailedTasks returns the tasks of every schedule in the space
+// for the given date. Instances must already exist; callers generate them per
+// schedule owner first.
+func GetSpaceDateDetailedTasks(ctx context.Context, spaceID string, date time.Time) ([]*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		detailedTaskSelectSQL()+`
+		WHERE space_id = $1
+		  AND id IS NOT NULL
+		  AND DATE(date) = DATE($2::timestamptz)
+		ORDER BY start_time ASC NULLS LAST, created_at ASC`,
+		spaceID,
+		date,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanDetailedTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*DetailedTask{}
+	}
+	return tasks, nil
+}
+
+// GetSpaceDayProgress aggregates completion of the space tasks on day, overall
+// and per member, counting like GetUserDayProgress.
+func GetSpaceDayProgress(ctx context.Context, spaceID string, day time.Time) (*SpaceDayProgress, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			u.id,
+			u.username,
+			u.fullname,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE t.status_level = 'completed')
+		 FROM tasks t
+		 INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
+		 INNER JOIN users u ON u.id = COALESCE(CASE WHEN t.assignment_status = 'accepted' THEN t.assignee_user_id END, st.user_id)
+		 WHERE st.space_id = $1
+		   AND DATE(t.date) = DATE($2::timestamptz)
+		 GROUP BY u.id, u.username, u.fullname
+		 ORDER BY u.username`,
+		spaceID,
+		day,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	progress := &SpaceDayProgress{
+		SpaceID: spaceID,
+		Date:    day.Format("2006-01-02"),
+		Members: []*SpaceMemberProgress{},
+	}
+	for rows.Next() {
+		var member SpaceMemberProgress
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Fullname, &member.Total, &member.Completed); err != nil {
+			return nil, err
+		}
+		member.Percentage = completionPercentage(member.Completed, member.Total)
+		progress.Total += member.Total
+		progress.Completed += member.Completed
+		progress.Members = append(progress.Members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	progress.Percentage = completionPercentage(progress.Completed, progress.Total)
+	return progress, nil
+}
+
+// GetSpaceTask returns a task of the space, or ErrSpaceTaskNotFound when the
+// task does not belong to one of its schedules.
+func GetSpaceTask(ctx context.Context, spaceID string, taskID string) (*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	task, err := scanDetailedTask(conn.QueryRow(ctx, detailedTaskSelectSQL()+` WHERE id = $1 AND space_id = $2`, taskID, spaceID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSpaceTaskNotFound
+	}
+	return task, err
+}
+
+func completionPercentage(completed int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(completed)*1000/float64(total)) / 10
+}
+
+func spaceSelectSQL() string {
+	return `SELECT
+			s.id,
+			s.name,
+			s.description,
+			s.created_by,
+			m.role,
+			(SELECT COUNT(*) FROM space_members c WHERE c.space_id = s.id),
+			s.created_at,
+			s.updated_at
+		FROM spaces s
+		INNER JOIN space_members m ON m.space_id = s.id AND m.user_id = $1
+		WHERE TRUE`
+}
+
+func scanSpace(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*Space, error) {
+	var space Space
+	var description sql.NullString
+	err := scanner.Scan(
+		&space.ID,
+		&space.Name,
+		&description,
+		&space.CreatedBy,
+		&space.Role,
+		&space.MemberCount,
+		&space.CreatedAt,
+		&space.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if description.Valid {
+		space.Description = description.String
+	}
+	return &space, nil
+}
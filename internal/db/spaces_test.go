@@ -0,0 +1,41 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSpaceRole(t *testing.T) {
+	role, err := NormalizeSpaceRole(" Manager ")
+	if err != nil || role != SpaceRoleManager {
+		t.Fatalf("NormalizeSpaceRole = %q, %v, want manager", role, err)
+	}
+	if _, err := NormalizeSpaceRole("admin"); err != ErrInvalidSpaceRole {
+		t.Fatalf("NormalizeSpaceRole(admin) err = %v, want ErrInvalidSpaceRole", err)
+	}
+}
+
+func TestSpaceRolePermissions(t *testing.T) {
+	cases := []struct {
+		role       SpaceRole
+		permission string
+		want       bool
+	}{
+		{SpaceRoleOwner, SharingPermissionEdit, true},
+		{SpaceRoleManager, SharingPermissionEdit, true},
+		{SpaceRoleMember, SharingPermissionCreate, true},
+		{SpaceRoleMember, SharingPermissionEdit, false},
+		{SpaceRoleViewer, SharingPermissionView, true},
+		{SpaceRoleViewer, SharingPermissionPing, false},
+		{SpaceRole(""), SharingPermissionView, false},
+	}
+	for _, tc := range cases {
+		if got := tc.role.Allows(tc.permission); got != tc.want {
+			t.Fatalf("%q.Allows(%q) = %v, want %v", tc.role, tc.permission, got, tc.want)
+		}
+	}
+
+	if got, want := spaceRolesAllowing(SharingPermissionEdit), []string{"manager", "owner"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("spaceRolesAllowing(edit) = %v, want %v", got, want)
+	}
+}
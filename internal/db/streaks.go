@@ -0,0 +1,404 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// A freeze is earned every StreakFreezeEarnEvery perfect days of a streak,
+// up to MaxStreakFreezes unused at a time.
+const (
+	StreakFreezeEarnEvery = 7
+	MaxStreakFreezes      = 2
+)
+
+// maxStreakEvaluationDays bounds how far back a streak is first evaluated,
+// like the history ranges.
+const maxStreakEvaluationDays = 90
+
+type StreakFreezeKind string
+
+const (
+	StreakFreezeEarned StreakFreezeKind = "earned"
+	StreakFreezeUsed   StreakFreezeKind = "used"
+	// StreakRestDay marks a rest day the streak skipped when it was counted.
+	// It is kept apart from the freeze history.
+	StreakRestDay StreakFreezeKind = "rest"
+)
+
+// UserStreak is the running streak of a user, counted through
+// EvaluatedThrough (YYYY-MM-DD). Rest weekdays (0 = Sunday) neither break nor
+// extend it, and a missed day is covered by a freeze while one is available.
+type UserStreak struct {
+	UserID           string  `json:"user_id"`
+	RestWeekdays     []int   `json:"rest_weekdays"`
+	CurrentStreak    int     `json:"current_streak"`
+	LongestStreak    int     `json:"longest_streak"`
+	FreezesAvailable int     `json:"freezes_available"`
+	EvaluatedThrough *string `json:"evaluated_through,omitempty"`
+}
+
+// StreakFreezeEvent records a freeze earned on Date, or a missed Date a
+// freeze covered. Streak is the streak length at that point.
+type StreakFreezeEvent struct {
+	ID        string           `json:"id"`
+	Date      string           `json:"date"`
+	Kind      StreakFreezeKind `json:"kind"`
+	Streak    int              `json:"streak"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// streakRules are the days a streak skips over: the days covered by a
+// freeze and the rest days. Days up to evaluatedThrough rested as they were
+// counted; later ones follow the user's current rest weekdays.
+type streakRules struct {
+	restWeekdays     []int
+	evaluatedThrough string
+	rested           map[string]bool
+	frozen           map[string]bool
+}
+
+func (rules streakRules) skips(day TaskHistoryDay) bool {
+	if rules.frozen[day.Date] || rules.rested[day.Date] {
+		return true
+	}
+	return day.Date > rules.evaluatedThrough && isRestDay(rules.restWeekdays, day.Date)
+}
+
+func isRestDay(restWeekdays []int, date string) bool {
+	if len(restWeekdays) == 0 {
+		return false
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(restWeekdays, int(day.Weekday()))
+}
+
+func isPerfectDay(day TaskHistoryDay) bool {
+	return day.Total > 0 && day.Percentage >= 100
+}
+
+// NormalizeRestWeekdays sorts and deduplicates weekdays. It reports false
+// when one of them is not between 0 (Sunday) and 6.
+func NormalizeRestWeekdays(weekdays []int) ([]int, bool) {
+	normalized := []int{}
+	for _, weekday := range weekdays {
+		if weekday < 0 || weekday > 6 {
+			return nil, false
+		}
+		if !slices.Contains(normalized, weekday) {
+			normalized = append(normalized, weekday)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, true
+}
+
+// calculateStreaks returns the trailing and the longest run of perfect days,
+// skipping rest and frozen days.
+func calculateStreaks(days []TaskHistoryDay, rules streakRules) (current int, longest int) {
+	for _, day := range days {
+		if rules.skips(day) {
+			continue
+		}
+		if !isPerfectDay(day) {
+			current = 0
+			continue
+		}
+		current++
+		longest = max(longest, current)
+	}
+	return current, longest
+}
+
+// advanceStreak counts days, oldest first, towards streak. It returns the
+// rest days skipped and the freezes earned and used on the way.
+func advanceStreak(streak *UserStreak, days []TaskHistoryDay) []StreakFreezeEvent {
+	events := []StreakFreezeEvent{}
+	for _, day := range days {
+		if isRestDay(streak.RestWeekdays, day.Date) {
+			events = append(events, StreakFreezeEvent{Date: day.Date, Kind: StreakRestDay, Streak: streak.CurrentStreak})
+			continue
+		}
+		if isPerfectDay(day) {
+			streak.CurrentStreak++
+			streak.LongestStreak = max(streak.LongestStreak, streak.CurrentStreak)
+			if streak.CurrentStreak%StreakFreezeEarnEvery == 0 && streak.FreezesAvailable < MaxStreakFreezes {
+				streak.FreezesAvailable++
+				events = append(events, StreakFreezeEvent{Date: day.Date, Kind: StreakFreezeEarned, Streak: streak.CurrentStreak})
+			}
+			continue
+		}
+		if streak.CurrentStreak > 0 && streak.FreezesAvailable > 0 {
+			streak.FreezesAvailable--
+			events = append(events, StreakFreezeEvent{Date: day.Date, Kind: StreakFreezeUsed, Streak: streak.CurrentStreak})
+			continue
+		}
+		streak.CurrentStreak = 0
+	}
+	return events
+}
+
+func GetUserStreak(ctx context.Context, userID string) (*UserStreak, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	streak, err := scanUserStreak(conn.QueryRow(ctx, userStreakSelectSQL+` WHERE user_id = $1`, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &UserStreak{UserID: userID, RestWeekdays: []int{}}, nil
+		}
+		return nil, err
+	}
+	return streak, nil
+}
+
+// SetUserRestWeekdays replaces the rest weekdays of userID. Days already
+// counted keep their rest days and the freezes they earned or used.
+func SetUserRestWeekdays(ctx context.Context, userID string, weekdays []int) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = conn.Exec(
+		ctx,
+		`INSERT INTO user_streaks (user_id, rest_weekdays)
+		 VALUES ($1, $2)
+		 ON CONFLICT (user_id) DO UPDATE SET rest_weekdays = EXCLUDED.rest_weekdays`,
+		userID,
+		weekdays,
+	)
+	return err
+}
+
+// RefreshUserStreak counts every finished day since the last refresh, up to
+// the day before today, and records the rest days and the freezes earned and
+// used. It is safe to call concurrently: the streak row is locked while it
+// advances. The history is loaded before the lock is taken, so the
+// transaction never waits on a second connection.
+func RefreshUserStreak(ctx context.Context, userID string, today time.Time) (*UserStreak, error) {
+	current, err := GetUserStreak(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	through := time.Date(today.Year(), today.Month(), today.Day(), 12, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	from, err := streakEvaluationStart(current, through)
+	if err != nil {
+		return nil, err
+	}
+	var history *TaskHistoryRange
+	if !from.After(through) {
+		history, err = GetUserTaskHistory(ctx, userID, from, through)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO user_streaks (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		return nil, err
+	}
+	streak, err := scanUserStreak(tx.QueryRow(ctx, userStreakSelectSQL+` WHERE user_id = $1 FOR UPDATE`, userID))
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		return streak, tx.Commit(ctx)
+	}
+
+	// A concurrent refresh may have counted some of the days meanwhile; only
+	// the ones after it are left.
+	days := history.Days
+	if streak.EvaluatedThrough != nil {
+		days = slices.DeleteFunc(slices.Clone(days), func(day TaskHistoryDay) bool {
+			return day.Date <= *streak.EvaluatedThrough
+		})
+	}
+	if len(days) == 0 {
+		return streak, tx.Commit(ctx)
+	}
+
+	events := advanceStreak(streak, days)
+	for _, event := range events {
+		if _, err := tx.Exec(
+			ctx,
+			`INSERT INTO streak_freeze_events (user_id, event_date, kind, streak)
+			 VALUES ($1, $2::date, $3, $4)
+			 ON CONFLICT (user_id, event_date, kind) DO NOTHING`,
+			userID,
+			event.Date,
+			string(event.Kind),
+			event.Streak,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	evaluatedThrough := history.To
+	streak.EvaluatedThrough = &evaluatedThrough
+	if _, err := tx.Exec(
+		ctx,
+		`UPDATE user_streaks
+		 SET current_streak = $2, longest_streak = $3, freezes_available = $4, evaluated_through = $5::date
+		 WHERE user_id = $1`,
+		userID,
+		streak.CurrentStreak,
+		streak.LongestStreak,
+		streak.FreezesAvailable,
+		evaluatedThrough,
+	); err != nil {
+		return nil, err
+	}
+
+	return streak, tx.Commit(ctx)
+}
+
+// streakEvaluationStart returns the first day streak has not counted yet, or
+// the start of the evaluation window ending at through for a new streak.
+func streakEvaluationStart(streak *UserStreak, through time.Time) (time.Time, error) {
+	if streak.EvaluatedThrough == nil {
+		return through.AddDate(0, 0, -(maxStreakEvaluationDays - 1)), nil
+	}
+	evaluated, err := time.ParseInLocation("2006-01-02", *streak.EvaluatedThrough, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(evaluated.Year(), evaluated.Month(), evaluated.Day()+1, 12, 0, 0, 0, time.Local), nil
+}
+
+// ListStreakFreezeEvents returns the freeze history of userID between from
+// and to, newest first.
+func ListStreakFreezeEvents(ctx context.Context, userID string, from time.Time, to time.Time) ([]*StreakFreezeEvent, error) {
+	return listStreakEvents(ctx, userID, from, to, []StreakFreezeKind{StreakFreezeEarned, StreakFreezeUsed})
+}
+
+func listStreakEvents(ctx context.Context, userID string, from time.Time, to time.Time, kinds []StreakFreezeKind) ([]*StreakFreezeEvent, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	kindNames := make([]string, len(kinds))
+	for i, kind := range kinds {
+		kindNames[i] = string(kind)
+	}
+	rows, err := conn.Query(
+		ctx,
+		`SELECT id, event_date::text, kind, streak, created_at
+		 FROM streak_freeze_events
+		 WHERE user_id = $1 AND event_date BETWEEN $2::date AND $3::date AND kind = ANY($4::text[])
+		 ORDER BY event_date DESC, kind ASC`,
+		userID,
+		from,
+		to,
+		kindNames,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*StreakFreezeEvent{}
+	for rows.Next() {
+		var event StreakFreezeEvent
+		if err := rows.Scan(&event.ID, &event.Date, &event.Kind, &event.Streak, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// getUserStreakRules refreshes the streak of userID and returns the days a
+// streak between from and to skips.
+func getUserStreakRules(ctx context.Context, userID string, from time.Time, to time.Time) (streakRules, error) {
+	streak, err := RefreshUserStreak(ctx, userID, time.Now())
+	if err != nil {
+		return streakRules{}, err
+	}
+	events, err := listStreakEvents(ctx, userID, from, to, []StreakFreezeKind{StreakFreezeUsed, StreakRestDay})
+	if err != nil {
+		return streakRules{}, err
+	}
+
+	rules := streakRules{restWeekdays: streak.RestWeekdays, rested: map[string]bool{}, frozen: map[string]bool{}}
+	if streak.EvaluatedThrough != nil {
+		rules.evaluatedThrough = *streak.EvaluatedThrough
+	}
+	for _, event := range events {
+		switch event.Kind {
+		case StreakFreezeUsed:
+			rules.frozen[event.Date] = true
+		case StreakRestDay:
+			rules.rested[event.Date] = true
+		}
+	}
+	return rules, nil
+}
+
+const userStreakSelectSQL = `SELECT
+	user_id,
+	rest_weekdays,
+	current_streak,
+	longest_streak,
+	freezes_available,
+	evaluated_through::text
+FROM user_streaks`
+
+func scanUserStreak(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*UserStreak, error) {
+	var streak UserStreak
+	if err := scanner.Scan(
+		&streak.UserID,
+		&streak.RestWeekdays,
+		&streak.CurrentStreak,
+		&streak.LongestStreak,
+		&streak.FreezesAvailable,
+		&streak.EvaluatedThrough,
+	); err != nil {
+		return nil, err
+	}
+	if streak.RestWeekdays == nil {
+		streak.RestWeekdays = []int{}
+	}
+	return &streak, nil
+}
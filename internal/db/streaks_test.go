@@ -0,0 +1,110 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func streakDays(percentages ...float64) []TaskHistoryDay {
+	// Starts on Monday, June 1, 2026.
+	days := make([]TaskHistoryDay, len(percentages))
+	for i, percentage := range percentages {
+		days[i] = TaskHistoryDay{
+			Date:       date(2026, 6, 1+i).Format("2006-01-02"),
+			Total:      1,
+			Percentage: percentage,
+		}
+		if percentage < 0 {
+			days[i].Total = 0
+			days[i].Percentage = 0
+		}
+	}
+	return days
+}
+
+func TestCalculateStreaksSkipsRestAndFrozenDays(t *testing.T) {
+	// Mon..Sun: 100, 100, 0, 100, 100, empty, 100
+	days := streakDays(100, 100, 0, 100, 100, -1, 100)
+
+	current, longest := calculateStreaks(days, streakRules{})
+	if current != 1 || longest != 2 {
+		t.Fatalf("plain streaks = %d/%d, want 1/2", current, longest)
+	}
+
+	rules := streakRules{restWeekdays: []int{6}, frozen: map[string]bool{"2026-06-03": true}}
+	current, longest = calculateStreaks(days, rules)
+	if current != 5 || longest != 5 {
+		t.Fatalf("streaks with rest and frozen days = %d/%d, want 5/5", current, longest)
+	}
+}
+
+func TestAdvanceStreakEarnsAndUsesFreezes(t *testing.T) {
+	percentages := make([]float64, 0, 10)
+	for range StreakFreezeEarnEvery {
+		percentages = append(percentages, 100)
+	}
+	percentages = append(percentages, 50, 0, 100)
+	streak := &UserStreak{}
+
+	events := advanceStreak(streak, streakDays(percentages...))
+	want := []StreakFreezeEvent{
+		{Date: "2026-06-07", Kind: StreakFreezeEarned, Streak: 7},
+		{Date: "2026-06-08", Kind: StreakFreezeUsed, Streak: 7},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %+v, want %+v", events, want)
+	}
+	if streak.CurrentStreak != 1 || streak.LongestStreak != 7 || streak.FreezesAvailable != 0 {
+		t.Fatalf("streak = %+v, want current 1, longest 7 and no freezes", streak)
+	}
+}
+
+func TestAdvanceStreakRestDaysDoNotCount(t *testing.T) {
+	// Saturday and Sunday are rest days, even when they were missed.
+	streak := &UserStreak{RestWeekdays: []int{0, 6}}
+	advanceStreak(streak, streakDays(100, 100, 100, 100, 100, 0, -1, 100))
+	if streak.CurrentStreak != 6 || streak.FreezesAvailable != 0 {
+		t.Fatalf("streak = %+v, want current 6 and no freezes", streak)
+	}
+}
+
+func TestCalculateStreaksKeepsEvaluatedRestDays(t *testing.T) {
+	// Saturday was a rest day when the week was counted.
+	days := streakDays(100, 100, 100, 100, 100, 0, 100)
+	streak := &UserStreak{RestWeekdays: []int{6}}
+	events := advanceStreak(streak, days[:6])
+	if len(events) != 1 || events[0].Kind != StreakRestDay || events[0].Date != "2026-06-06" {
+		t.Fatalf("events = %+v, want Saturday as a rest day", events)
+	}
+
+	// Dropping the rest day afterwards leaves the counted Saturday alone but
+	// applies to the days not counted yet.
+	rules := streakRules{
+		restWeekdays:     []int{},
+		evaluatedThrough: days[5].Date,
+		rested:           map[string]bool{events[0].Date: true},
+	}
+	current, _ := calculateStreaks(days[:6], rules)
+	if current != streak.CurrentStreak || current != 5 {
+		t.Fatalf("metrics streak = %d, stored streak = %d, want 5", current, streak.CurrentStreak)
+	}
+	if current, _ := calculateStreaks(days, rules); current != 6 {
+		t.Fatalf("streak through Sunday = %d, want 6", current)
+	}
+
+	rules.evaluatedThrough = days[4].Date
+	rules.rested = map[string]bool{}
+	if current, _ := calculateStreaks(days, rules); current != 1 {
+		t.Fatalf("streak with an uncounted missed Saturday = %d, want 1", current)
+	}
+}
+
+func TestNormalizeRestWeekdays(t *testing.T) {
+	got, ok := NormalizeRestWeekdays([]int{6, 0, 6})
+	if !ok || !reflect.DeepEqual(got, []int{0, 6}) {
+		t.Fatalf("NormalizeRestWeekdays = %v, %v, want [0 6], true", got, ok)
+	}
+	if _, ok := NormalizeRestWeekdays([]int{7}); ok {
+		t.Fatal("weekday 7 should be rejected")
+	}
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+type TaskAssignmentStatus string
+
+const (
+	TaskAssignmentPending  TaskAssignmentStatus = "pending"
+	TaskAssignmentAccepted TaskAssignmentStatus = "accepted"
+	TaskAssignmentDeclined TaskAssignmentStatus = "declined"
+)
+
+// taskResponsibleUserSQL is the user a task counts for in progress and
+// metrics: the assignee once they accepted it, otherwise the owner. It reads
+// unqualified tasks columns.
+const taskResponsibleUserSQL = `COALESCE(CASE WHEN assignment_status = 'accepted' THEN assignee_user_id END, user_id)`
+
+// clearTaskAssignmentSQL is the SET list that removes any assignment.
+const clearTaskAssignmentSQL = `assignee_user_id = NULL,
+		assignment_status = NULL,
+		assigned_by_user_id = NULL,
+		assigned_at = NULL,
+		assignment_responded_at = NULL`
+
+// IsActive reports whether the assignee currently holds the task: a pending
+// assignee may see it and an accepted one may also work on it.
+func (status TaskAssignmentStatus) IsActive() bool {
+	return status == TaskAssignmentPending || status == TaskAssignmentAccepted
+}
+
+// SetTaskAssignee assigns the task to assigneeUserID on behalf of
+// assignedByUserID with the given status, or clears the assignment when
+// assigneeUserID is empty.
+func SetTaskAssignee(ctx context.Context, taskID string, assigneeUserID string, assignedByUserID string, status TaskAssignmentStatus) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if assigneeUserID == "" {
+		_, err = conn.Exec(ctx, `UPDATE tasks SET `+clearTaskAssignmentSQL+` WHERE id = $1`, taskID)
+		return err
+	}
+
+	_, err = conn.Exec(
+		ctx,
+		`UPDATE tasks SET
+			assignee_user_id = $2,
+			assigned_by_user_id = $3,
+			assignment_status = $4,
+			assigned_at = CURRENT_TIMESTAMP,
+			assignment_responded_at = CASE WHEN $4 = 'pending' THEN NULL ELSE CURRENT_TIMESTAMP END
+		 WHERE id = $1`,
+		taskID,
+		assigneeUserID,
+		nullableString(assignedByUserID),
+		string(status),
+	)
+	return err
+}
+
+// RespondToTaskAssignment records the assignee's answer to a pending
+// assignment. It returns false when the task has no pending assignment for
+// assigneeUserID.
+func RespondToTaskAssignment(ctx context.Context, taskID string, assigneeUserID string, status TaskAssignmentStatus) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE tasks
+		 SET assignment_status = $3, assignment_responded_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND assignee_user_id = $2 AND assignment_status = 'pending'`,
+		taskID,
+		assigneeUserID,
+		string(status),
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetUserAssignedDetailedTasks returns the tasks of other users that userID
+// accepted and that fall on date, for their day view.
+func GetUserAssignedDetailedTasks(ctx context.Context, userID string, date time.Time) ([]*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		detailedTaskSelectSQL()+`
+		WHERE assignee_user_id = $1
+		  AND assignment_status = 'accepted'
+		  AND user_id <> $1
+		  AND DATE(date) = DATE($2::timestamptz)
+		ORDER BY
+			(CASE WHEN completed_at IS NULL THEN 1 ELSE 2 END) ASC,
+			start_time ASC NULLS LAST`,
+		userID,
+		date,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDetailedTasks(rows)
+}
+
+// ListUserTaskAssignments returns the tasks assigned to userID that still need
+// something from them: pending assignments, and accepted ones that are not
+// finished or fall on today or later.
+func ListUserTaskAssignments(ctx context.Context, userID string) ([]*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		detailedTaskSelectSQL()+`
+		WHERE assignee_user_id = $1
+		  AND (
+			assignment_status = 'pending'
+			OR (assignment_status = 'accepted' AND (status NOT IN ('completed', 'skipped') OR DATE(date) >= CURRENT_DATE))
+		  )
+		ORDER BY (CASE WHEN assignment_status = 'pending' THEN 1 ELSE 2 END) ASC, date ASC, start_time ASC NULLS LAST`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanDetailedTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tasks == nil {
+		tasks = []*DetailedTask{}
+	}
+	return tasks, nil
+}
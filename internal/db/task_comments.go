@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const MaxTaskCommentLength = 2000
+
+var (
+	ErrTaskCommentNotFound = errors.New("task comment not found")
+	ErrTaskCommentEmpty    = errors.New("task comment cannot be empty")
+	ErrTaskCommentTooLong  = errors.New("task comment is too long")
+)
+
+// mentionPattern matches @username tokens that are not part of an email
+// address. Usernames follow the signup rule: 3-32 of [a-z0-9_-].
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@-])@([A-Za-z0-9_-]{3,32})\b`)
+
+// TaskComment is a message on a task thread. Threads are visible to the task
+// owner and to anyone the owner granted view access.
+type TaskComment struct {
+	ID             string        `json:"id"`
+	TaskID         string        `json:"task_id"`
+	AuthorUserID   string        `json:"author_user_id"`
+	AuthorUsername string        `json:"author_username"`
+	AuthorFullname string        `json:"author_fullname"`
+	Body           string        `json:"body"`
+	Mentions       []SharingUser `json:"mentions"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+	EditedAt       *time.Time    `json:"edited_at,omitempty"`
+}
+
+// NormalizeTaskCommentBody trims the body and enforces the length limit.
+func NormalizeTaskCommentBody(body string) (string, error) {
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" {
+		return "", ErrTaskCommentEmpty
+	}
+	if len([]rune(trimmed)) > MaxTaskCommentLength {
+		return "", ErrTaskCommentTooLong
+	}
+	return trimmed, nil
+}
+
+// ExtractMentionUsernames returns the distinct, lowercased usernames
+// mentioned in body, in order of first appearance.
+func ExtractMentionUsernames(body string) []string {
+	usernames := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		username := strings.ToLower(match[1])
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+func GetUsersByUsernames(ctx context.Context, usernames []string) ([]*SharingUser, error) {
+	users := []*SharingUser{}
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT id, username, fullname
+		 FROM users
+		 WHERE username = ANY($1)`,
+		usernames,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user SharingUser
+		if err := rows.Scan(&user.ID, &user.Username, &user.Fullname); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+func ListTaskComments(ctx context.Context, taskID string) ([]*TaskComment, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		taskCommentSelectSQL()+`
+		 WHERE c.task_id = $1 AND c.deleted_at IS NULL
+		 ORDER BY c.created_at ASC, c.id ASC`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*TaskComment{}
+	for rows.Next() {
+		comment, err := scanTaskComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// GetTaskComment returns a live comment of the task or ErrTaskCommentNotFound.
+func GetTaskComment(ctx context.Context, taskID string, commentID string) (*TaskComment, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	comment, err := scanTaskComment(conn.QueryRow(
+		ctx,
+		taskCommentSelectSQL()+`
+		 WHERE c.id = $1 AND c.task_id = $2 AND c.deleted_at IS NULL`,
+		commentID,
+		taskID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTaskCommentNotFound
+	}
+	return comment, err
+}
+
+// CreateTaskComment inserts the comment and its mentions in one transaction
+// and fills in the generated id and timestamps.
+func CreateTaskComment(ctx context.Context, comment *TaskComment, mentionUserIDs []string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(
+		ctx,
+		`INSERT INTO task_comments (task_id, author_user_id, body)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, created_at, updated_at`,
+		comment.TaskID,
+		comment.AuthorUserID,
+		comment.Body,
+	).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err := replaceTaskCommentMentions(ctx, tx, comment.ID, mentionUserIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UpdateTaskComment replaces the body and mentions of a comment written by
+// authorUserID. It returns ErrTaskCommentNotFound when the comment does not
+// exist, was deleted or belongs to someone else.
+func UpdateTaskComment(ctx context.Context, taskID string, commentID string, authorUserID string, body string, mentionUserIDs []string) error {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(
+		ctx,
+		`UPDATE task_comments
+		 SET body = $4, edited_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND task_id = $2 AND author_user_id = $3 AND deleted_at IS NULL`,
+		commentID,
+		taskID,
+		authorUserID,
+		body,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTaskCommentNotFound
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM task_comment_mentions WHERE comment_id = $1`, commentID); err != nil {
+		return err
+	}
+	if err := replaceTaskCommentMentions(ctx, tx, commentID, mentionUserIDs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteTaskComment soft-deletes a comment written by authorUserID.
+func DeleteTaskComment(ctx context.Context, taskID string, commentID string, authorUserID string) (bool, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	tag, err := conn.Exec(
+		ctx,
+		`UPDATE task_comments
+		 SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND task_id = $2 AND author_user_id = $3 AND deleted_at IS NULL`,
+		commentID,
+		taskID,
+		authorUserID,
+	)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListTaskCommentParticipants returns everyone involved in the task thread:
+// the task owner, authors of live comments and users mentioned in them.
+func ListTaskCommentParticipants(ctx context.Context, taskID string) ([]string, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT user_id FROM tasks WHERE id = $1
+		 UNION
+		 SELECT author_user_id FROM task_comments WHERE task_id = $1 AND deleted_at IS NULL
+		 UNION
+		 SELECT m.user_id
+		 FROM task_comment_mentions m
+		 INNER JOIN task_comments c ON c.id = m.comment_id
+		 WHERE c.task_id = $1 AND c.deleted_at IS NULL`,
+		taskID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func replaceTaskCommentMentions(ctx context.Context, tx pgx.Tx, commentID string, userIDs []string) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(
+		ctx,
+		`INSERT INTO task_comment_mentions (comment_id, user_id)
+		 SELECT $1, unnest($2::uuid[])
+		 ON CONFLICT DO NOTHING`,
+		commentID,
+		userIDs,
+	)
+	return err
+}
+
+func taskCommentSelectSQL() string {
+	return `SELECT
+			c.id,
+			c.task_id,
+			c.author_user_id,
+			u.username,
+			u.fullname,
+			c.body,
+			COALESCE((
+				SELECT json_agg(json_build_object('id', mu.id, 'username', mu.username, 'fullname', mu.fullname) ORDER BY mu.username)
+				FROM task_comment_mentions m
+				INNER JOIN users mu ON mu.id = m.user_id
+				WHERE m.comment_id = c.id
+			), '[]'::json),
+			c.created_at,
+			c.updated_at,
+			c.edited_at
+		 FROM task_comments c
+		 INNER JOIN users u ON u.id = c.author_user_id`
+}
+
+func scanTaskComment(scanner interface {
+	Scan(dest ...interface{}) error
+}) (*TaskComment, error) {
+	var comment TaskComment
+	var mentions []byte
+	var editedAt sql.NullTime
+	err := scanner.Scan(
+		&comment.ID,
+		&comment.TaskID,
+		&comment.AuthorUserID,
+		&comment.AuthorUsername,
+		&comment.AuthorFullname,
+		&comment.Body,
+		&mentions,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&editedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comment.Mentions = []SharingUser{}
+	if err := json.Unmarshal(mentions, &comment.Mentions); err != nil {
+		return nil, err
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	return &comment, nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractMentionUsernames(t *testing.T) {
+	cases := map[string][]string{
+		"@ana revisa esto":                  {"ana"},
+		"hola @Ana, @bob_1 y @ana otra vez": {"ana", "bob_1"},
+		"escribe a ana@example.com":         {},
+		"(@carla-m) listo":                  {"carla-m"},
+		"@ab es muy corto":                  {},
+	}
+	for body, want := range cases {
+		if got := ExtractMentionUsernames(body); !reflect.DeepEqual(got, want) {
+			t.Fatalf("ExtractMentionUsernames(%q) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestNormalizeTaskCommentBody(t *testing.T) {
+	if body, err := NormalizeTaskCommentBody("  hola  "); err != nil || body != "hola" {
+		t.Fatalf("NormalizeTaskCommentBody trimmed = (%q, %v)", body, err)
+	}
+	if _, err := NormalizeTaskCommentBody("   "); !errors.Is(err, ErrTaskCommentEmpty) {
+		t.Fatalf("expected ErrTaskCommentEmpty, got %v", err)
+	}
+	if _, err := NormalizeTaskCommentBody(strings.Repeat("á", MaxTaskCommentLength+1)); !errors.Is(err, ErrTaskCommentTooLong) {
+		t.Fatalf("expected ErrTaskCommentTooLong, got %v", err)
+	}
+}
@@ -44,8 +44,12 @@ type Task struct {
 	CurrentCount   int        `db:"current_count" json:"currentCount"`
 	TargetCount    *int       `db:"target_count" json:"targetCount,omitempty"`
 	Notes          string     `db:"notes" json:"notes,omitempty"`
-	CreatedAt      time.Time  `db:"created_at" json:"createdAt,omitzero"`
-	UpdatedAt      time.Time  `db:"updated_at" json:"updatedAt,omitzero"`
+	AssigneeUserID string     `db:"assignee_user_id" json:"assigneeUserId,omitempty"`
+	// AssignmentStatus is empty when the task is not assigned.
+	AssignmentStatus TaskAssignmentStatus `db:"assignment_status" json:"assignmentStatus,omitempty"`
+	AssignedByUserID string               `db:"assigned_by_user_id" json:"assignedByUserId,omitempty"`
+	CreatedAt        time.Time            `db:"created_at" json:"createdAt,omitzero"`
+	UpdatedAt        time.Time            `db:"updated_at" json:"updatedAt,omitzero"`
 }
 
 type DetailedTask struct {
@@ -72,6 +76,10 @@ type DetailedTask struct {
 	Notes              string                `db:"notes" json:"notes,omitempty"`
 	Frequency          ScheduleTaskFrequency `db:"frequency" json:"frequency,omitempty"`
 	Category           string                `db:"category" json:"category,omitempty"`
+	SpaceID            string                `db:"space_id" json:"spaceId,omitempty"`
+	AssigneeUserID     string                `db:"assignee_user_id" json:"assigneeUserId,omitempty"`
+	AssignmentStatus   TaskAssignmentStatus  `db:"assignment_status" json:"assignmentStatus,omitempty"`
+	AssignedByUserID   string                `db:"assigned_by_user_id" json:"assignedByUserId,omitempty"`
 	CreatedAt          time.Time             `db:"created_at" json:"createdAt"`
 	UpdatedAt          time.Time             `db:"updated_at" json:"updatedAt"`
 	CanEdit            bool                  `json:"canEdit"`
@@ -87,6 +95,9 @@ type TaskCompletion struct {
 	ActualEnd   time.Time `db:"actual_end" json:"actualEnd,omitzero"`
 	Count       int       `db:"count" json:"count"`
 	Notes       string    `db:"notes" json:"notes,omitempty"`
+	// CompletedByUserID is who marked the task completed, which differs from
+	// UserID when the task was delegated.
+	CompletedByUserID string `db:"completed_by_user_id" json:"completedByUserId,omitempty"`
 }
 
 type TaskFeedItem struct {
@@ -104,6 +115,9 @@ type TaskFeedItem struct {
 	CurrentCount      int        `json:"current_count"`
 	CreatedAt         time.Time  `json:"created_at"`
 	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	OwnerUserID       string     `json:"owner_user_id,omitempty"`
+	AssigneeUserID    string     `json:"assignee_user_id,omitempty"`
+	AssignmentStatus  string     `json:"assignment_status,omitempty"`
 }
 
 func NewDetailedTask(task *Task, scheduleTask *ScheduleTask) *DetailedTask {
@@ -117,31 +131,35 @@ func NewDetailedTask(task *Task, scheduleTask *ScheduleTask) *DetailedTask {
 	}
 
 	return &DetailedTask{
-		ID:             task.ID,
-		UserID:         task.UserID,
-		ScheduleTaskID: scheduleTask.ID,
-		Title:          title,
-		Description:    description,
-		Date:           task.Date,
-		Status:         task.Status,
-		Priority:       scheduleTask.Priority,
-		Required:       scheduleTask.IsRequired,
-		IsRequired:     scheduleTask.IsRequired,
-		CompletedAt:    task.CompletedAt,
-		ActualStart:    task.ActualStart,
-		ActualEnd:      task.ActualEnd,
-		StartTime:      scheduleTask.StartTime,
-		EndTime:        scheduleTask.EndTime,
-		StartDate:      scheduleTask.StartDate,
-		EndDate:        scheduleTask.EndDate,
-		Duration:       scheduleTask.DurationMinutes,
-		CurrentCount:   task.CurrentCount,
-		TargetCount:    task.TargetCount,
-		Notes:          task.Notes,
-		Frequency:      scheduleTask.Frequency,
-		Category:       scheduleTask.Category,
-		CreatedAt:      task.CreatedAt,
-		UpdatedAt:      task.UpdatedAt,
+		ID:               task.ID,
+		UserID:           task.UserID,
+		ScheduleTaskID:   scheduleTask.ID,
+		Title:            title,
+		Description:      description,
+		Date:             task.Date,
+		Status:           task.Status,
+		Priority:         scheduleTask.Priority,
+		Required:         scheduleTask.IsRequired,
+		IsRequired:       scheduleTask.IsRequired,
+		CompletedAt:      task.CompletedAt,
+		ActualStart:      task.ActualStart,
+		ActualEnd:        task.ActualEnd,
+		StartTime:        scheduleTask.StartTime,
+		EndTime:          scheduleTask.EndTime,
+		StartDate:        scheduleTask.StartDate,
+		EndDate:          scheduleTask.EndDate,
+		Duration:         scheduleTask.DurationMinutes,
+		CurrentCount:     task.CurrentCount,
+		TargetCount:      task.TargetCount,
+		Notes:            task.Notes,
+		Frequency:        scheduleTask.Frequency,
+		Category:         scheduleTask.Category,
+		SpaceID:          scheduleTask.SpaceID,
+		AssigneeUserID:   task.AssigneeUserID,
+		AssignmentStatus: task.AssignmentStatus,
+		AssignedByUserID: task.AssignedByUserID,
+		CreatedAt:        task.CreatedAt,
+		UpdatedAt:        task.UpdatedAt,
 	}
 }
 
@@ -280,6 +298,33 @@ func GetUserDateDetailedTasks(ctx context.Context, userID string, date time.Time
 	return scanDetailedTasks(rows)
 }
 
+// GetUserRangeDetailedTasks returns the tasks of userID dated between from
+// and to, by day. Tasks that were never generated are not created.
+func GetUserRangeDetailedTasks(ctx context.Context, userID string, from time.Time, to time.Time) ([]*DetailedTask, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		detailedTaskSelectSQL()+`
+		WHERE user_id = $1 AND DATE(date) BETWEEN $2::date AND $3::date
+		ORDER BY date ASC, start_time ASC NULLS LAST`,
+		userID, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDetailedTasks(rows)
+}
+
 func GetActiveScheduleOwnerIDs(ctx context.Context) ([]string, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
@@ -785,24 +830,7 @@ func CreateTaskCompletion(ctx context.Context, completion *TaskCompletion) error
 		completion.CompletedAt = time.Now()
 	}
 
-	_, err = conn.Exec(
-		ctx,
-		`INSERT INTO task_completions (
-			id, task_id, user_id, completed_at, actual_start, actual_end, count, notes
-		) VALUES (
-			@id, @taskID, @userID, @completedAt, @actualStart, @actualEnd, @count, @notes
-		)`,
-		pgx.NamedArgs{
-			"id":          completion.ID,
-			"taskID":      completion.TaskID,
-			"userID":      completion.UserID,
-			"completedAt": completion.CompletedAt,
-			"actualStart": nullableTime(completion.ActualStart),
-			"actualEnd":   nullableTime(completion.ActualEnd),
-			"count":       completion.Count,
-			"notes":       nullableString(completion.Notes),
-		},
-	)
+	_, err = conn.Exec(ctx, taskCompletionInsertSQL, taskCompletionArgs(completion))
 	return err
 }
 
@@ -885,7 +913,7 @@ type taskScanner interface {
 func scanTask(scanner taskScanner) (*Task, error) {
 	var task Task
 	var completedAt, actualStart, actualEnd sql.NullTime
-	var description, notes, title sql.NullString
+	var description, notes, title, assigneeUserID, assignmentStatus, assignedByUserID sql.NullString
 	var targetCount sql.NullInt64
 
 	err := scanner.Scan(
@@ -902,6 +930,9 @@ func scanTask(scanner taskScanner) (*Task, error) {
 		&task.CurrentCount,
 		&targetCount,
 		&notes,
+		&assigneeUserID,
+		&assignmentStatus,
+		&assignedByUserID,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 	)
@@ -922,6 +953,15 @@ func scanTask(scanner taskScanner) (*Task, error) {
 		nextTarget := int(targetCount.Int64)
 		task.TargetCount = &nextTarget
 	}
+	if assigneeUserID.Valid {
+		task.AssigneeUserID = assigneeUserID.String
+	}
+	if assignmentStatus.Valid {
+		task.AssignmentStatus = TaskAssignmentStatus(assignmentStatus.String)
+	}
+	if assignedByUserID.Valid {
+		task.AssignedByUserID = assignedByUserID.String
+	}
 	if title.Valid {
 		task.Title = title.String
 	}
@@ -938,7 +978,7 @@ func scanTask(scanner taskScanner) (*Task, error) {
 func scanDetailedTask(scanner taskScanner) (*DetailedTask, error) {
 	var task DetailedTask
 	var completedAt, actualStart, actualEnd, startTime, endTime, startDate, endDate sql.NullTime
-	var description, notes, category sql.NullString
+	var description, notes, category, spaceID, assigneeUserID, assignmentStatus, assignedByUserID sql.NullString
 	var duration, currentCount, targetCount sql.NullInt64
 
 	err := scanner.Scan(
@@ -965,6 +1005,10 @@ func scanDetailedTask(scanner taskScanner) (*DetailedTask, error) {
 		&notes,
 		&task.Frequency,
 		&category,
+		&spaceID,
+		&assigneeUserID,
+		&assignmentStatus,
+		&assignedByUserID,
 		&task.CreatedAt,
 		&task.UpdatedAt,
 	)
@@ -1012,6 +1056,18 @@ func scanDetailedTask(scanner taskScanner) (*DetailedTask, error) {
 	if category.Valid {
 		task.Category = category.String
 	}
+	if spaceID.Valid {
+		task.SpaceID = spaceID.String
+	}
+	if assigneeUserID.Valid {
+		task.AssigneeUserID = assigneeUserID.String
+	}
+	if assignmentStatus.Valid {
+		task.AssignmentStatus = TaskAssignmentStatus(assignmentStatus.String)
+	}
+	if assignedByUserID.Valid {
+		task.AssignedByUserID = assignedByUserID.String
+	}
 
 	return &task, nil
 }
@@ -1046,6 +1102,9 @@ func taskSelectSQL() string {
 		current_count,
 		target_count,
 		notes,
+		assignee_user_id::text,
+		assignment_status,
+		assigned_by_user_id::text,
 		created_at,
 		updated_at
 	FROM tasks`
@@ -1076,6 +1135,10 @@ func detailedTaskSelectSQL() string {
 		notes,
 		frequency,
 		category,
+		space_id::text,
+		assignee_user_id::text,
+		assignment_status,
+		assigned_by_user_id::text,
 		created_at,
 		updated_at
 	FROM detailed_tasks`
@@ -1137,6 +1200,9 @@ func NewTaskFeedItem(task *DetailedTask) TaskFeedItem {
 		CurrentCount:      task.CurrentCount,
 		CreatedAt:         task.CreatedAt,
 		CompletedAt:       completedAt,
+		OwnerUserID:       task.UserID,
+		AssigneeUserID:    task.AssigneeUserID,
+		AssignmentStatus:  string(task.AssignmentStatus),
 	}
 }
 
@@ -1183,9 +1249,9 @@ const scheduleUpdateSQL = `UPDATE schedule_tasks SET
 	WHERE id = @id AND user_id = @userID`
 
 const taskCompletionInsertSQL = `INSERT INTO task_completions (
-		id, task_id, user_id, completed_at, actual_start, actual_end, count, notes
+		id, task_id, user_id, completed_at, actual_start, actual_end, count, notes, completed_by_user_id
 	) VALUES (
-		@id, @taskID, @userID, @completedAt, @actualStart, @actualEnd, @count, @notes
+		@id, @taskID, @userID, @completedAt, @actualStart, @actualEnd, @count, @notes, @completedBy
 	)`
 
 func taskCompletionArgs(c *TaskCompletion) pgx.NamedArgs {
@@ -1198,6 +1264,7 @@ func taskCompletionArgs(c *TaskCompletion) pgx.NamedArgs {
 		"actualEnd":   nullableTime(c.ActualEnd),
 		"count":       c.Count,
 		"notes":       nullableString(c.Notes),
+		"completedBy": nullableString(c.CompletedByUserID),
 	}
 }
 
@@ -1305,10 +1372,15 @@ type DayProgress struct {
 	Failed     int     `json:"failed"`
 	InProgress int     `json:"in_progress"`
 	Percentage float64 `json:"percentage"`
+	// Score is the weighted percentage; see TaskScoreWeights.
+	Score float64 `json:"score"`
 }
 
+// TaskHistoryDay counts the tasks of one day, or of the bucket from Date to
+// EndDate when the history is grouped by week or month.
 type TaskHistoryDay struct {
 	Date       string  `json:"date"`
+	EndDate    string  `json:"end_date,omitempty"`
 	Total      int     `json:"total"`
 	Completed  int     `json:"completed"`
 	Pending    int     `json:"pending"`
@@ -1316,12 +1388,23 @@ type TaskHistoryDay struct {
 	Failed     int     `json:"failed"`
 	InProgress int     `json:"in_progress"`
 	Percentage float64 `json:"percentage"`
+	Score      float64 `json:"score"`
+	// Breakdown splits the day by the dimension the history was asked for;
+	// days without tasks have none.
+	Breakdown []TaskBreakdownCount `json:"breakdown,omitempty"`
+
+	scoreTally taskScoreTally
 }
 
 type TaskHistoryRange struct {
-	From string           `json:"from"`
-	To   string           `json:"to"`
-	Days []TaskHistoryDay `json:"days"`
+	From        string                 `json:"from"`
+	To          string                 `json:"to"`
+	Granularity TimeGranularity        `json:"granularity,omitempty"`
+	WeekStart   *int                   `json:"week_start,omitempty"`
+	Breakdown   TaskBreakdownDimension `json:"breakdown,omitempty"`
+	Days        []TaskHistoryDay       `json:"days"`
+
+	scoreWeights TaskScoreWeights
 }
 
 type TaskMetricsBestDay struct {
@@ -1339,16 +1422,23 @@ type TaskMetricsRange struct {
 	Failed           int                 `json:"failed"`
 	InProgress       int                 `json:"in_progress"`
 	Percentage       float64             `json:"percentage"`
+	Score            float64             `json:"score"`
 	DaysCount        int                 `json:"days_count"`
 	CompletionsCount int                 `json:"completions_count"`
 	ActiveDays       int                 `json:"active_days"`
 	BestDay          *TaskMetricsBestDay `json:"best_day,omitempty"`
 	CurrentStreak    int                 `json:"current_streak"`
+	LongestStreak    int                 `json:"longest_streak"`
+	// FrozenDays counts the missed days in the range a streak freeze covered.
+	FrozenDays   int               `json:"frozen_days"`
+	Breakdowns   *TaskBreakdowns   `json:"breakdowns"`
+	ScoreWeights *TaskScoreWeights `json:"score_weights"`
 }
 
-// GetUserDayProgress counts the tasks of a given user for the calendar day
-// represented by `day` and returns a DayProgress aggregate. Percentage is 0
-// when there are no tasks (no division by zero).
+// GetUserDayProgress counts the tasks a given user is responsible for on the
+// calendar day represented by `day` and returns a DayProgress aggregate.
+// Delegated tasks count for the assignee once accepted. Percentage is 0 when
+// there are no tasks (no division by zero).
 func GetUserDayProgress(ctx context.Context, userID string, day time.Time) (*DayProgress, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
@@ -1370,7 +1460,7 @@ func GetUserDayProgress(ctx context.Context, userID string, day time.Time) (*Day
 			COUNT(*) FILTER (WHERE status_level = 'failed') AS failed,
 			COUNT(*) FILTER (WHERE status_level = 'in_progress') AS in_progress
 		FROM tasks
-		WHERE user_id = $1 AND DATE(date) = DATE($2::timestamptz)`,
+		WHERE `+taskResponsibleUserSQL+` = $1 AND DATE(date) = DATE($2::timestamptz)`,
 		userID,
 		day,
 	)
@@ -1389,9 +1479,17 @@ func GetUserDayProgress(ctx context.Context, userID string, day time.Time) (*Day
 		progress.Percentage = math.Round(float64(progress.Completed)*1000/float64(progress.Total)) / 10
 	}
 
+	_, tallies, err := getUserTaskScores(ctx, userID, day, day)
+	if err != nil {
+		return nil, err
+	}
+	progress.Score = tallies[progress.Date].score()
+
 	return progress, nil
 }
 
+// GetUserTaskHistory returns one row per day between from and to, read from
+// the daily rollups, scored with the user's weights.
 func GetUserTaskHistory(ctx context.Context, userID string, from time.Time, to time.Time) (*TaskHistoryRange, error) {
 	conn, err := GetConn(ctx)
 	if err != nil {
@@ -1409,17 +1507,17 @@ func GetUserTaskHistory(ctx context.Context, userID string, from time.Time, to t
 		),
 		task_counts AS (
 			SELECT
-				DATE(date) AS day,
-				COUNT(*) AS total,
-				COUNT(*) FILTER (WHERE status_level = 'completed') AS completed,
-				COUNT(*) FILTER (WHERE status_level = 'pending') AS pending,
-				COUNT(*) FILTER (WHERE status_level = 'skipped') AS skipped,
-				COUNT(*) FILTER (WHERE status_level = 'failed') AS failed,
-				COUNT(*) FILTER (WHERE status_level = 'in_progress') AS in_progress
-			FROM tasks
+				day,
+				SUM(total) AS total,
+				SUM(completed) AS completed,
+				SUM(pending) AS pending,
+				SUM(skipped) AS skipped,
+				SUM(failed) AS failed,
+				SUM(in_progress) AS in_progress
+			FROM task_daily_rollups
 			WHERE user_id = $1
-				AND DATE(date) BETWEEN $2::date AND $3::date
-			GROUP BY DATE(date)
+				AND day BETWEEN $2::date AND $3::date
+			GROUP BY day
 		)
 		SELECT
 			d.day,
@@ -1441,10 +1539,16 @@ func GetUserTaskHistory(ctx context.Context, userID string, from time.Time, to t
 	}
 	defer rows.Close()
 
+	weights, tallies, err := getUserTaskScores(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
 	history := &TaskHistoryRange{
-		From: from.Format("2006-01-02"),
-		To:   to.Format("2006-01-02"),
-		Days: []TaskHistoryDay{},
+		From:         from.Format("2006-01-02"),
+		To:           to.Format("2006-01-02"),
+		Days:         []TaskHistoryDay{},
+		scoreWeights: weights,
 	}
 
 	for rows.Next() {
@@ -1465,6 +1569,8 @@ func GetUserTaskHistory(ctx context.Context, userID string, from time.Time, to t
 		if day.Total > 0 {
 			day.Percentage = math.Round(float64(day.Completed)*1000/float64(day.Total)) / 10
 		}
+		day.scoreTally = tallies[day.Date]
+		day.Score = day.scoreTally.score()
 		history.Days = append(history.Days, day)
 	}
 	if err := rows.Err(); err != nil {
@@ -1481,11 +1587,14 @@ func GetUserTaskMetrics(ctx context.Context, userID string, from time.Time, to t
 	}
 
 	metrics := &TaskMetricsRange{
-		From:      history.From,
-		To:        history.To,
-		DaysCount: len(history.Days),
+		From:         history.From,
+		To:           history.To,
+		DaysCount:    len(history.Days),
+		ScoreWeights: &history.scoreWeights,
 	}
+	var scoreTally taskScoreTally
 	for _, day := range history.Days {
+		scoreTally.add(day.scoreTally)
 		metrics.Total += day.Total
 		metrics.Completed += day.Completed
 		metrics.Pending += day.Pending
@@ -1505,7 +1614,13 @@ func GetUserTaskMetrics(ctx context.Context, userID string, from time.Time, to t
 	if metrics.Total > 0 {
 		metrics.Percentage = math.Round(float64(metrics.Completed)*1000/float64(metrics.Total)) / 10
 	}
-	metrics.CurrentStreak = calculateCurrentStreak(history.Days)
+	metrics.Score = scoreTally.score()
+	rules, err := getUserStreakRules(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	metrics.CurrentStreak, metrics.LongestStreak = calculateStreaks(history.Days, rules)
+	metrics.FrozenDays = len(rules.frozen)
 
 	completionsCount, err := countUserTaskCompletions(ctx, userID, from, to)
 	if err != nil {
@@ -1513,6 +1628,11 @@ func GetUserTaskMetrics(ctx context.Context, userID string, from time.Time, to t
 	}
 	metrics.CompletionsCount = completionsCount
 
+	metrics.Breakdowns, err = GetUserTaskBreakdowns(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
 	return metrics, nil
 }
 
@@ -1529,10 +1649,10 @@ func countUserTaskCompletions(ctx context.Context, userID string, from time.Time
 	var count int
 	err = conn.QueryRow(
 		ctx,
-		`SELECT COUNT(*)
-		FROM task_completions
+		`SELECT COALESCE(SUM(completions), 0)
+		FROM task_daily_rollups
 		WHERE user_id = $1
-			AND DATE(completed_at) BETWEEN $2::date AND $3::date`,
+			AND day BETWEEN $2::date AND $3::date`,
 		userID,
 		from,
 		to,
@@ -1543,14 +1663,25 @@ func countUserTaskCompletions(ctx context.Context, userID string, from time.Time
 	return count, nil
 }
 
-func calculateCurrentStreak(days []TaskHistoryDay) int {
-	streak := 0
-	for index := len(days) - 1; index >= 0; index-- {
-		day := days[index]
-		if day.Total == 0 || day.Percentage < 100 {
-			break
-		}
-		streak++
+// GetUserCurrentStreak returns the number of consecutive 100% days ending at
+// `day`, skipping rest and frozen days. When `day` itself is not complete yet
+// it does not break the streak: the count is taken up to the previous day
+// instead.
+func GetUserCurrentStreak(ctx context.Context, userID string, day time.Time) (int, error) {
+	from := day.AddDate(0, 0, -89)
+	history, err := GetUserTaskHistory(ctx, userID, from, day)
+	if err != nil {
+		return 0, err
+	}
+	rules, err := getUserStreakRules(ctx, userID, from, day)
+	if err != nil {
+		return 0, err
+	}
+
+	days := history.Days
+	if len(days) > 0 && !isPerfectDay(days[len(days)-1]) {
+		days = days[:len(days)-1]
 	}
-	return streak
+	streak, _ := calculateStreaks(days, rules)
+	return streak, nil
 }
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+)
+
+// OnTimeStartToleranceMinutes is how far from the scheduled start a task can
+// be started and still count as on time.
+const OnTimeStartToleranceMinutes = 5
+
+// TimeEntry is the planned and tracked time of one task. PlannedStart is nil
+// for schedules without a start time, ActualStart for tasks nobody timed.
+type TimeEntry struct {
+	Date           string
+	Category       string
+	PlannedStart   *time.Time
+	PlannedMinutes int
+	ActualStart    *time.Time
+	ActualMinutes  int
+	Tracked        bool
+}
+
+// TimeCategoryMinutes is the time of one category in a bucket; uncategorized
+// tasks have an empty Category.
+type TimeCategoryMinutes struct {
+	Category       string `json:"category"`
+	PlannedMinutes int    `json:"planned_minutes"`
+	FocusedMinutes int    `json:"focused_minutes"`
+}
+
+// TimeBucket compares planned with tracked time between Start and End.
+// Overrun and underrun only count tracked tasks with a planned duration, and
+// start delays only tasks with both a scheduled and an actual start.
+type TimeBucket struct {
+	Start                    string                `json:"start"`
+	End                      string                `json:"end"`
+	Tasks                    int                   `json:"tasks"`
+	TrackedTasks             int                   `json:"tracked_tasks"`
+	PlannedMinutes           int                   `json:"planned_minutes"`
+	FocusedMinutes           int                   `json:"focused_minutes"`
+	OverrunMinutes           int                   `json:"overrun_minutes"`
+	UnderrunMinutes          int                   `json:"underrun_minutes"`
+	TimedStarts              int                   `json:"timed_starts"`
+	OnTimeStarts             int                   `json:"on_time_starts"`
+	LateStarts               int                   `json:"late_starts"`
+	EarlyStarts              int                   `json:"early_starts"`
+	AverageStartDelayMinutes float64               `json:"average_start_delay_minutes"`
+	Categories               []TimeCategoryMinutes `json:"categories"`
+
+	startDelayTotal int
+}
+
+type TimeAccountingRange struct {
+	From        string          `json:"from"`
+	To          string          `json:"to"`
+	Granularity TimeGranularity `json:"granularity"`
+	Totals      TimeBucket      `json:"totals"`
+	Buckets     []TimeBucket    `json:"buckets"`
+}
+
+func GetUserTimeAccounting(ctx context.Context, userID string, from time.Time, to time.Time, granularity TimeGranularity, weekStart time.Weekday) (*TimeAccountingRange, error) {
+	entries, err := GetUserTimeEntries(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTimeAccounting(entries, from, to, granularity, weekStart), nil
+}
+
+// GetUserTimeEntries loads the planned and tracked time of the tasks userID
+// is responsible for between from and to. Tracked time is the sum of the
+// timed completions of a task, or the task's own actual start and end when it
+// has none.
+func GetUserTimeEntries(ctx context.Context, userID string, from time.Time, to time.Time) ([]TimeEntry, error) {
+	conn, err := GetConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := conn.Query(
+		ctx,
+		`SELECT
+			DATE(t.date),
+			COALESCE(LOWER(TRIM(st.category)), ''),
+			to_char(st.schedule_start_time, 'HH24:MI'),
+			to_char(st.schedule_end_time, 'HH24:MI'),
+			COALESCE(st.duration_minutes, 0),
+			COALESCE(c.first_start, t.actual_start),
+			COALESCE(c.tracked_seconds, EXTRACT(EPOCH FROM (t.actual_end - t.actual_start)))::float8
+		FROM (
+			SELECT id, date, schedule_task_id, actual_start, actual_end
+			FROM tasks
+			WHERE `+taskResponsibleUserSQL+` = $1
+				AND DATE(date) BETWEEN $2::date AND $3::date
+		) t
+		INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
+		LEFT JOIN LATERAL (
+			SELECT
+				MIN(actual_start) AS first_start,
+				SUM(EXTRACT(EPOCH FROM (actual_end - actual_start))) AS tracked_seconds
+			FROM task_completions
+			WHERE task_id = t.id
+				AND actual_start IS NOT NULL
+				AND actual_end > actual_start
+		) c ON TRUE`,
+		userID,
+		from,
+		to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []TimeEntry{}
+	for rows.Next() {
+		var day time.Time
+		var startClock, endClock sql.NullString
+		var durationMinutes int
+		var actualStart sql.NullTime
+		var trackedSeconds sql.NullFloat64
+		var entry TimeEntry
+		if err := rows.Scan(&day, &entry.Category, &startClock, &endClock, &durationMinutes, &actualStart, &trackedSeconds); err != nil {
+			return nil, err
+		}
+		entry.Date = day.Format("2006-01-02")
+		entry.PlannedMinutes = durationMinutes
+		if startClock.Valid {
+			start := clockOnDay(day, startClock.String)
+			entry.PlannedStart = &start
+			if entry.PlannedMinutes == 0 && endClock.Valid {
+				if minutes := int(clockOnDay(day, endClock.String).Sub(start).Minutes()); minutes > 0 {
+					entry.PlannedMinutes = minutes
+				}
+			}
+		}
+		if actualStart.Valid {
+			start := actualStart.Time
+			entry.ActualStart = &start
+			entry.Tracked = true
+		}
+		if trackedSeconds.Valid && trackedSeconds.Float64 > 0 {
+			entry.ActualMinutes = int(math.Round(trackedSeconds.Float64 / 60))
+			entry.Tracked = true
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// clockOnDay places an "HH:MM" clock on the calendar day of day, in local
+// time.
+func clockOnDay(day time.Time, clock string) time.Time {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, time.Local)
+}
+
+// BuildTimeAccounting groups entries in buckets of granularity. Buckets are
+// clipped to the range, so the first and last ones may be partial.
+func BuildTimeAccounting(entries []TimeEntry, from time.Time, to time.Time, granularity TimeGranularity, weekStart time.Weekday) *TimeAccountingRange {
+	accounting := &TimeAccountingRange{
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Granularity: granularity,
+		Totals:      TimeBucket{Start: from.Format("2006-01-02"), End: to.Format("2006-01-02")},
+		Buckets:     []TimeBucket{},
+	}
+
+	index := map[string]int{}
+	first := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, time.Local)
+	last := time.Date(to.Year(), to.Month(), to.Day(), 12, 0, 0, 0, time.Local)
+	for start := bucketStart(first, granularity, weekStart); !start.After(last); start = bucketEnd(start, granularity).AddDate(0, 0, 1) {
+		clippedStart := start
+		if clippedStart.Before(first) {
+			clippedStart = first
+		}
+		clippedEnd := bucketEnd(start, granularity)
+		if clippedEnd.After(last) {
+			clippedEnd = last
+		}
+		index[start.Format("2006-01-02")] = len(accounting.Buckets)
+		accounting.Buckets = append(accounting.Buckets, TimeBucket{
+			Start: clippedStart.Format("2006-01-02"),
+			End:   clippedEnd.Format("2006-01-02"),
+		})
+	}
+
+	for _, entry := range entries {
+		if entry.Date < accounting.From || entry.Date > accounting.To {
+			continue
+		}
+		day, err := time.ParseInLocation("2006-01-02", entry.Date, time.Local)
+		if err != nil {
+			continue
+		}
+		i, ok := index[bucketStart(day, granularity, weekStart).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		accounting.Buckets[i].add(entry)
+		accounting.Totals.add(entry)
+	}
+
+	for i := range accounting.Buckets {
+		accounting.Buckets[i].finish()
+	}
+	accounting.Totals.finish()
+	return accounting
+}
+
+func (bucket *TimeBucket) add(entry TimeEntry) {
+	bucket.Tasks++
+	bucket.PlannedMinutes += entry.PlannedMinutes
+	category := bucket.category(entry.Category)
+	category.PlannedMinutes += entry.PlannedMinutes
+	if !entry.Tracked {
+		return
+	}
+
+	bucket.TrackedTasks++
+	bucket.FocusedMinutes += entry.ActualMinutes
+	category.FocusedMinutes += entry.ActualMinutes
+	if entry.PlannedMinutes > 0 && entry.ActualMinutes > 0 {
+		if difference := entry.ActualMinutes - entry.PlannedMinutes; difference > 0 {
+			bucket.OverrunMinutes += difference
+		} else {
+			bucket.UnderrunMinutes -= difference
+		}
+	}
+	if entry.PlannedStart != nil && entry.ActualStart != nil {
+		delay := int(math.Round(entry.ActualStart.Sub(*entry.PlannedStart).Minutes()))
+		bucket.TimedStarts++
+		bucket.startDelayTotal += delay
+		switch {
+		case delay > OnTimeStartToleranceMinutes:
+			bucket.LateStarts++
+		case delay < -OnTimeStartToleranceMinutes:
+			bucket.EarlyStarts++
+		default:
+			bucket.OnTimeStarts++
+		}
+	}
+}
+
+func (bucket *TimeBucket) category(name string) *TimeCategoryMinutes {
+	for i := range bucket.Categories {
+		if bucket.Categories[i].Category == name {
+			return &bucket.Categories[i]
+		}
+	}
+	bucket.Categories = append(bucket.Categories, TimeCategoryMinutes{Category: name})
+	return &bucket.Categories[len(bucket.Categories)-1]
+}
+
+func (bucket *TimeBucket) finish() {
+	if bucket.TimedStarts > 0 {
+		bucket.AverageStartDelayMinutes = math.Round(float64(bucket.startDelayTotal)*10/float64(bucket.TimedStarts)) / 10
+	}
+	if bucket.Categories == nil {
+		bucket.Categories = []TimeCategoryMinutes{}
+	}
+	sort.SliceStable(bucket.Categories, func(i, j int) bool {
+		if bucket.Categories[i].FocusedMinutes != bucket.Categories[j].FocusedMinutes {
+			return bucket.Categories[i].FocusedMinutes > bucket.Categories[j].FocusedMinutes
+		}
+		return bucket.Categories[i].Category < bucket.Categories[j].Category
+	})
+}
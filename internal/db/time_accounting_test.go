@@ -0,0 +1,48 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildTimeAccounting(t *testing.T) {
+	at := func(day int, hour int, minute int) *time.Time {
+		value := time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+		return &value
+	}
+	entries := []TimeEntry{
+		// Started 12 minutes late and ran 30 minutes over.
+		{Date: "2026-10-02", Category: "study", PlannedStart: at(2, 8, 0), PlannedMinutes: 60, ActualStart: at(2, 8, 12), ActualMinutes: 90, Tracked: true},
+		// Started on time and finished 15 minutes early.
+		{Date: "2026-10-05", Category: "study", PlannedStart: at(5, 8, 0), PlannedMinutes: 60, ActualStart: at(5, 8, 2), ActualMinutes: 45, Tracked: true},
+		// Never timed.
+		{Date: "2026-10-05", Category: "gym", PlannedMinutes: 30},
+		// Outside the range.
+		{Date: "2026-09-28", Category: "gym", PlannedMinutes: 30},
+	}
+	from := time.Date(2026, 10, 1, 12, 0, 0, 0, time.Local)
+	to := time.Date(2026, 10, 10, 12, 0, 0, 0, time.Local)
+	accounting := BuildTimeAccounting(entries, from, to, TimeGranularityWeek, time.Monday)
+
+	if len(accounting.Buckets) != 2 {
+		t.Fatalf("buckets = %+v, want 2 weeks", accounting.Buckets)
+	}
+	first, second := accounting.Buckets[0], accounting.Buckets[1]
+	if first.Start != "2026-10-01" || first.End != "2026-10-04" || second.Start != "2026-10-05" || second.End != "2026-10-10" {
+		t.Fatalf("bucket bounds = %s..%s, %s..%s", first.Start, first.End, second.Start, second.End)
+	}
+	if first.LateStarts != 1 || first.OverrunMinutes != 30 || first.AverageStartDelayMinutes != 12 {
+		t.Fatalf("first = %+v, want a late start and 30 minutes over", first)
+	}
+	if second.Tasks != 2 || second.TrackedTasks != 1 || second.OnTimeStarts != 1 || second.UnderrunMinutes != 15 {
+		t.Fatalf("second = %+v, want an on-time start and 15 minutes under", second)
+	}
+
+	totals := accounting.Totals
+	if totals.Tasks != 3 || totals.PlannedMinutes != 150 || totals.FocusedMinutes != 135 || totals.TimedStarts != 2 || totals.AverageStartDelayMinutes != 7 {
+		t.Fatalf("totals = %+v", totals)
+	}
+	if len(totals.Categories) != 2 || totals.Categories[0].Category != "study" || totals.Categories[0].FocusedMinutes != 135 || totals.Categories[1].PlannedMinutes != 30 {
+		t.Fatalf("categories = %+v", totals.Categories)
+	}
+}
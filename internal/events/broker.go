@@ -0,0 +1,90 @@
+// Package events fans out per-user change events, received through Postgres
+// LISTEN/NOTIFY, to the Server-Sent Events streams open on this instance.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+const (
+	// subscriberBuffer is how many events a slow stream may fall behind
+	// before new events are dropped for it.
+	subscriberBuffer = 32
+	reconnectDelay   = 5 * time.Second
+)
+
+// Default is the broker shared by the HTTP handlers and started from main.
+var Default = NewBroker()
+
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan db.UserEvent]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: map[string]map[chan db.UserEvent]struct{}{}}
+}
+
+// Subscribe registers a stream for userID. The returned function removes the
+// subscription and closes the channel; it must be called exactly once.
+func (b *Broker) Subscribe(userID string) (<-chan db.UserEvent, func()) {
+	ch := make(chan db.UserEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = map[chan db.UserEvent]struct{}{}
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers[userID], ch)
+		if len(b.subscribers[userID]) == 0 {
+			delete(b.subscribers, userID)
+		}
+		b.mu.Unlock()
+		close(ch)
+	}
+}
+
+// Publish delivers the event to every stream of its user without blocking.
+func (b *Broker) Publish(event db.UserEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("events: dropping %s event for slow subscriber of user %s", event.Type, event.UserID)
+		}
+	}
+}
+
+// Run listens for database events until ctx is cancelled, reconnecting after
+// connection failures. Every replica runs its own listener, so an event is
+// seen by streams on all of them.
+func (b *Broker) Run(ctx context.Context) {
+	log.Println("user event listener started")
+	for {
+		err := db.ListenUserEvents(ctx, b.Publish)
+		if ctx.Err() != nil {
+			log.Println("user event listener stopped")
+			return
+		}
+		log.Printf("user event listener: %v; reconnecting in %s", err, reconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			log.Println("user event listener stopped")
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
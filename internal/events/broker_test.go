@@ -0,0 +1,59 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestBrokerDeliversOnlyToOwnUser(t *testing.T) {
+	broker := NewBroker()
+	own, unsubscribeOwn := broker.Subscribe("user-1")
+	defer unsubscribeOwn()
+	other, unsubscribeOther := broker.Subscribe("user-2")
+	defer unsubscribeOther()
+
+	broker.Publish(db.UserEvent{UserID: "user-1", Type: "task", Op: "update", ID: "task-1"})
+
+	select {
+	case event := <-own:
+		if event.ID != "task-1" || event.Type != "task" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	default:
+		t.Fatal("expected event for subscribed user")
+	}
+	select {
+	case event := <-other:
+		t.Fatalf("other user received %+v", event)
+	default:
+	}
+}
+
+func TestBrokerUnsubscribeClosesChannel(t *testing.T) {
+	broker := NewBroker()
+	stream, unsubscribe := broker.Subscribe("user-1")
+	unsubscribe()
+
+	if _, ok := <-stream; ok {
+		t.Fatal("expected closed channel after unsubscribe")
+	}
+	if len(broker.subscribers) != 0 {
+		t.Fatalf("expected no subscribers left, got %d", len(broker.subscribers))
+	}
+	// Publishing after the last stream left must not panic.
+	broker.Publish(db.UserEvent{UserID: "user-1", Type: "task"})
+}
+
+func TestBrokerDropsWhenSubscriberIsFull(t *testing.T) {
+	broker := NewBroker()
+	stream, unsubscribe := broker.Subscribe("user-1")
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer+5; i++ {
+		broker.Publish(db.UserEvent{UserID: "user-1", Type: "inbox"})
+	}
+	if len(stream) != subscriberBuffer {
+		t.Fatalf("expected buffer to cap at %d, got %d", subscriberBuffer, len(stream))
+	}
+}
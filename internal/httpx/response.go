@@ -28,6 +28,10 @@ func Created(c *gin.Context, data interface{}, message string) {
 	JSON(c, http.StatusCreated, data, message)
 }
 
+func Accepted(c *gin.Context, data interface{}, message string) {
+	JSON(c, http.StatusAccepted, data, message)
+}
+
 func Error(c *gin.Context, status int, message string) {
 	c.JSON(status, Response{
 		Data:    nil,
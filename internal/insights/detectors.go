@@ -0,0 +1,329 @@
+package insights
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// Thresholds of the detectors. A pattern needs enough tasks behind it before
+// it is worth telling the user about.
+const (
+	// A weekday is a skip pattern when at least weekdaySkipMinRate of its
+	// tasks were missed and weekdaySkipMinGap more than on the other days.
+	weekdaySkipMinSamples = 3
+	weekdaySkipMinRate    = 60.0
+	weekdaySkipMinGap     = 30.0
+
+	// A decline compares the last declineWindowDays with the ones before and
+	// reports a relative drop of at least declineMinDrop percent.
+	declineWindowDays = 28
+	declineMinSamples = 4
+	declineMinDrop    = 30.0
+
+	// Starts and finishes are late when their median delay reaches
+	// lateMinMinutes over at least lateMinSamples tasks.
+	lateMinSamples = 5
+	lateMinMinutes = 15
+)
+
+var weekdayNames = [...]string{"domingos", "lunes", "martes", "miércoles", "jueves", "viernes", "sábados"}
+
+// WeekdaySkipEvidence backs an InsightKindWeekdaySkip finding. Rates are
+// percentages of missed tasks.
+type WeekdaySkipEvidence struct {
+	ScheduleTitle string   `json:"schedule_title"`
+	From          string   `json:"from"`
+	To            string   `json:"to"`
+	Weekday       int      `json:"weekday"`
+	WeekdayTotal  int      `json:"weekday_total"`
+	WeekdayMissed int      `json:"weekday_missed"`
+	WeekdayRate   float64  `json:"weekday_rate"`
+	OtherTotal    int      `json:"other_total"`
+	OtherMissed   int      `json:"other_missed"`
+	OtherRate     float64  `json:"other_rate"`
+	MissedDates   []string `json:"missed_dates"`
+}
+
+// CompletionWeek is the completion of a schedule over seven days from Start.
+type CompletionWeek struct {
+	Start      string  `json:"start"`
+	Total      int     `json:"total"`
+	Completed  int     `json:"completed"`
+	Percentage float64 `json:"percentage"`
+}
+
+// CompletionDeclineEvidence backs an InsightKindCompletionDecline finding.
+// Change is the relative change of the percentage, negative for a drop.
+type CompletionDeclineEvidence struct {
+	ScheduleTitle      string           `json:"schedule_title"`
+	PreviousFrom       string           `json:"previous_from"`
+	PreviousTo         string           `json:"previous_to"`
+	PreviousTotal      int              `json:"previous_total"`
+	PreviousCompleted  int              `json:"previous_completed"`
+	PreviousPercentage float64          `json:"previous_percentage"`
+	RecentFrom         string           `json:"recent_from"`
+	RecentTo           string           `json:"recent_to"`
+	RecentTotal        int              `json:"recent_total"`
+	RecentCompleted    int              `json:"recent_completed"`
+	RecentPercentage   float64          `json:"recent_percentage"`
+	Change             float64          `json:"change"`
+	Weeks              []CompletionWeek `json:"weeks"`
+}
+
+// LatenessSample is how many minutes after the plan a task started or
+// finished; early ones are negative.
+type LatenessSample struct {
+	Date    string `json:"date"`
+	Minutes int    `json:"minutes"`
+}
+
+// LatenessEvidence backs InsightKindLateStart and InsightKindLateFinish
+// findings.
+type LatenessEvidence struct {
+	ScheduleTitle  string           `json:"schedule_title"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	Samples        int              `json:"samples"`
+	LateSamples    int              `json:"late_samples"`
+	MedianMinutes  int              `json:"median_minutes"`
+	AverageMinutes float64          `json:"average_minutes"`
+	Delays         []LatenessSample `json:"delays"`
+}
+
+// Detect runs every detector over the observations of one user between from
+// and to, schedule by schedule. The insights it returns carry no user yet.
+func Detect(observations []db.ScheduleObservation, from time.Time, to time.Time) []*db.Insight {
+	bySchedule := map[string][]db.ScheduleObservation{}
+	scheduleIDs := []string{}
+	for _, observation := range observations {
+		if _, ok := bySchedule[observation.ScheduleTaskID]; !ok {
+			scheduleIDs = append(scheduleIDs, observation.ScheduleTaskID)
+		}
+		bySchedule[observation.ScheduleTaskID] = append(bySchedule[observation.ScheduleTaskID], observation)
+	}
+
+	insights := []*db.Insight{}
+	for _, scheduleID := range scheduleIDs {
+		schedule := bySchedule[scheduleID]
+		insights = append(insights, detectWeekdaySkips(schedule, from, to)...)
+		if insight := detectCompletionDecline(schedule, to); insight != nil {
+			insights = append(insights, insight)
+		}
+		if insight := detectLateness(schedule, from, to, db.InsightKindLateStart); insight != nil {
+			insights = append(insights, insight)
+		}
+		if insight := detectLateness(schedule, from, to, db.InsightKindLateFinish); insight != nil {
+			insights = append(insights, insight)
+		}
+	}
+	return insights
+}
+
+func detectWeekdaySkips(schedule []db.ScheduleObservation, from time.Time, to time.Time) []*db.Insight {
+	var totals, missed [7]int
+	var missedDates [7][]string
+	for _, observation := range schedule {
+		weekday := observation.Date.Weekday()
+		totals[weekday]++
+		if observation.Status != db.TaskStatusCompleted {
+			missed[weekday]++
+			missedDates[weekday] = append(missedDates[weekday], observation.Date.Format("2006-01-02"))
+		}
+	}
+	allTotal, allMissed := 0, 0
+	for weekday := range totals {
+		allTotal += totals[weekday]
+		allMissed += missed[weekday]
+	}
+
+	insights := []*db.Insight{}
+	for weekday := range totals {
+		otherTotal := allTotal - totals[weekday]
+		otherMissed := allMissed - missed[weekday]
+		if totals[weekday] < weekdaySkipMinSamples || otherTotal < weekdaySkipMinSamples {
+			continue
+		}
+		rate := percentage(missed[weekday], totals[weekday])
+		otherRate := percentage(otherMissed, otherTotal)
+		if rate < weekdaySkipMinRate || rate-otherRate < weekdaySkipMinGap {
+			continue
+		}
+
+		title := schedule[0].Title
+		insights = append(insights, newInsight(schedule[0].ScheduleTaskID, db.InsightKindWeekdaySkip, fmt.Sprintf("%d", weekday),
+			fmt.Sprintf("Sueles saltarte «%s» los %s", title, weekdayNames[weekday]),
+			fmt.Sprintf("No completaste «%s» %d de %d %s desde el %s, frente al %.0f%% sin completar el resto de los días.",
+				title, missed[weekday], totals[weekday], weekdayNames[weekday], from.Format("02/01"), otherRate),
+			WeekdaySkipEvidence{
+				ScheduleTitle: title,
+				From:          from.Format("2006-01-02"),
+				To:            to.Format("2006-01-02"),
+				Weekday:       weekday,
+				WeekdayTotal:  totals[weekday],
+				WeekdayMissed: missed[weekday],
+				WeekdayRate:   rate,
+				OtherTotal:    otherTotal,
+				OtherMissed:   otherMissed,
+				OtherRate:     otherRate,
+				MissedDates:   missedDates[weekday],
+			},
+		))
+	}
+	return insights
+}
+
+func detectCompletionDecline(schedule []db.ScheduleObservation, to time.Time) *db.Insight {
+	recentFrom := to.AddDate(0, 0, -(declineWindowDays - 1))
+	previousTo := recentFrom.AddDate(0, 0, -1)
+	previousFrom := previousTo.AddDate(0, 0, -(declineWindowDays - 1))
+	first := sameDay(previousFrom)
+
+	evidence := CompletionDeclineEvidence{
+		ScheduleTitle: schedule[0].Title,
+		PreviousFrom:  previousFrom.Format("2006-01-02"),
+		PreviousTo:    previousTo.Format("2006-01-02"),
+		RecentFrom:    recentFrom.Format("2006-01-02"),
+		RecentTo:      to.Format("2006-01-02"),
+		Weeks:         make([]CompletionWeek, 2*declineWindowDays/7),
+	}
+	for i := range evidence.Weeks {
+		evidence.Weeks[i].Start = previousFrom.AddDate(0, 0, 7*i).Format("2006-01-02")
+	}
+	for _, observation := range schedule {
+		day := sameDay(observation.Date)
+		offset := int(math.Round(day.Sub(first).Hours() / 24))
+		if offset < 0 || offset >= 2*declineWindowDays {
+			continue
+		}
+		completed := 0
+		if observation.Status == db.TaskStatusCompleted {
+			completed = 1
+		}
+		week := &evidence.Weeks[offset/7]
+		week.Total++
+		week.Completed += completed
+		if offset < declineWindowDays {
+			evidence.PreviousTotal++
+			evidence.PreviousCompleted += completed
+		} else {
+			evidence.RecentTotal++
+			evidence.RecentCompleted += completed
+		}
+	}
+	for i := range evidence.Weeks {
+		evidence.Weeks[i].Percentage = percentage(evidence.Weeks[i].Completed, evidence.Weeks[i].Total)
+	}
+	if evidence.PreviousTotal < declineMinSamples || evidence.RecentTotal < declineMinSamples || evidence.PreviousCompleted == 0 {
+		return nil
+	}
+	evidence.PreviousPercentage = percentage(evidence.PreviousCompleted, evidence.PreviousTotal)
+	evidence.RecentPercentage = percentage(evidence.RecentCompleted, evidence.RecentTotal)
+	evidence.Change = math.Round((evidence.RecentPercentage-evidence.PreviousPercentage)*1000/evidence.PreviousPercentage) / 10
+	if -evidence.Change < declineMinDrop {
+		return nil
+	}
+
+	title := schedule[0].Title
+	return newInsight(schedule[0].ScheduleTaskID, db.InsightKindCompletionDecline, "",
+		fmt.Sprintf("«%s» va a la baja", title),
+		fmt.Sprintf("Completaste «%s» un %.0f%% menos en las últimas %d semanas: del %.0f%% al %.0f%%.",
+			title, -evidence.Change, declineWindowDays/7, evidence.PreviousPercentage, evidence.RecentPercentage),
+		evidence,
+	)
+}
+
+// detectLateness compares actual with planned starts for
+// InsightKindLateStart, and finishes with planned ends for
+// InsightKindLateFinish.
+func detectLateness(schedule []db.ScheduleObservation, from time.Time, to time.Time, kind db.InsightKind) *db.Insight {
+	evidence := LatenessEvidence{
+		ScheduleTitle: schedule[0].Title,
+		From:          from.Format("2006-01-02"),
+		To:            to.Format("2006-01-02"),
+		Delays:        []LatenessSample{},
+	}
+	total := 0
+	for _, observation := range schedule {
+		planned, actual := observation.PlannedStart, observation.ActualStart
+		if kind == db.InsightKindLateFinish {
+			planned, actual = observation.PlannedEnd, observation.FinishedAt
+		}
+		if planned == nil || actual == nil {
+			continue
+		}
+		minutes := int(math.Round(actual.Sub(*planned).Minutes()))
+		evidence.Delays = append(evidence.Delays, LatenessSample{
+			Date:    observation.Date.Format("2006-01-02"),
+			Minutes: minutes,
+		})
+		total += minutes
+		if minutes >= lateMinMinutes {
+			evidence.LateSamples++
+		}
+	}
+	evidence.Samples = len(evidence.Delays)
+	if evidence.Samples < lateMinSamples {
+		return nil
+	}
+	evidence.MedianMinutes = medianMinutes(evidence.Delays)
+	evidence.AverageMinutes = math.Round(float64(total)*10/float64(evidence.Samples)) / 10
+	if evidence.MedianMinutes < lateMinMinutes {
+		return nil
+	}
+
+	title := schedule[0].Title
+	verb, noun := "empezar", "Empiezas"
+	if kind == db.InsightKindLateFinish {
+		verb, noun = "terminar", "Terminas"
+	}
+	return newInsight(schedule[0].ScheduleTaskID, kind, "",
+		fmt.Sprintf("%s «%s» tarde", noun, title),
+		fmt.Sprintf("Sueles %s «%s» %d minutos tarde: pasó %d de %d veces desde el %s.",
+			verb, title, evidence.MedianMinutes, evidence.LateSamples, evidence.Samples, from.Format("02/01")),
+		evidence,
+	)
+}
+
+func newInsight(scheduleID string, kind db.InsightKind, subject string, title string, body string, evidence any) *db.Insight {
+	raw, err := json.Marshal(evidence)
+	if err != nil {
+		raw = json.RawMessage(`{}`)
+	}
+	return &db.Insight{
+		ScheduleTaskID: scheduleID,
+		Kind:           kind,
+		Subject:        subject,
+		Title:          title,
+		Body:           body,
+		Evidence:       raw,
+	}
+}
+
+func medianMinutes(samples []LatenessSample) int {
+	minutes := make([]int, len(samples))
+	for i, sample := range samples {
+		minutes[i] = sample.Minutes
+	}
+	sort.Ints(minutes)
+	middle := len(minutes) / 2
+	if len(minutes)%2 == 1 {
+		return minutes[middle]
+	}
+	return int(math.Round(float64(minutes[middle-1]+minutes[middle]) / 2))
+}
+
+func percentage(part int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
+
+func sameDay(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 12, 0, 0, 0, time.Local)
+}
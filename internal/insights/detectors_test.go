@@ -0,0 +1,127 @@
+package insights
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestDetectWeekdaySkipAndLateFinish(t *testing.T) {
+	to := time.Date(2026, time.June, 28, 12, 0, 0, 0, time.Local)
+	from := to.AddDate(0, 0, -(insightWindowDays - 1))
+	observations := []db.ScheduleObservation{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		end := time.Date(day.Year(), day.Month(), day.Day(), 8, 0, 0, 0, time.Local)
+		observation := db.ScheduleObservation{
+			ScheduleTaskID: "run",
+			Title:          "Correr",
+			Date:           day,
+			Status:         db.TaskStatusCompleted,
+			PlannedEnd:     &end,
+		}
+		if day.Weekday() == time.Friday {
+			observation.Status = db.TaskStatusSkipped
+		} else {
+			finished := end.Add(40 * time.Minute)
+			observation.FinishedAt = &finished
+		}
+		observations = append(observations, observation)
+	}
+
+	insights := Detect(observations, from, to)
+	kinds := map[db.InsightKind]*db.Insight{}
+	for _, insight := range insights {
+		kinds[insight.Kind] = insight
+	}
+	if len(insights) != 2 || kinds[db.InsightKindWeekdaySkip] == nil || kinds[db.InsightKindLateFinish] == nil {
+		t.Fatalf("insights = %+v, want a Friday skip and a late finish", insights)
+	}
+
+	skip := kinds[db.InsightKindWeekdaySkip]
+	var skipEvidence WeekdaySkipEvidence
+	if err := json.Unmarshal(skip.Evidence, &skipEvidence); err != nil {
+		t.Fatalf("decode skip evidence: %v", err)
+	}
+	if skip.Subject != "5" || skipEvidence.WeekdayTotal != 8 || skipEvidence.WeekdayMissed != 8 || skipEvidence.OtherRate != 0 || len(skipEvidence.MissedDates) != 8 {
+		t.Fatalf("unexpected skip %q: %s", skip.Subject, skip.Evidence)
+	}
+
+	late := kinds[db.InsightKindLateFinish]
+	var lateEvidence LatenessEvidence
+	if err := json.Unmarshal(late.Evidence, &lateEvidence); err != nil {
+		t.Fatalf("decode late evidence: %v", err)
+	}
+	if lateEvidence.MedianMinutes != 40 || lateEvidence.Samples != 48 || lateEvidence.LateSamples != 48 {
+		t.Fatalf("unexpected late finish: %s", late.Evidence)
+	}
+}
+
+func TestDetectCompletionDecline(t *testing.T) {
+	to := time.Date(2026, time.June, 28, 12, 0, 0, 0, time.Local)
+	from := to.AddDate(0, 0, -(insightWindowDays - 1))
+	observations := func(recentDone int) []db.ScheduleObservation {
+		observations := []db.ScheduleObservation{}
+		for i := 0; i < insightWindowDays; i++ {
+			status := db.TaskStatusCompleted
+			// The recent half completes one day in every 7 - recentDone.
+			if i >= declineWindowDays && i%7 >= recentDone {
+				status = db.TaskStatusFailed
+			}
+			observations = append(observations, db.ScheduleObservation{
+				ScheduleTaskID: "read",
+				Title:          "Leer",
+				Date:           from.AddDate(0, 0, i),
+				Status:         status,
+			})
+		}
+		return observations
+	}
+
+	insights := Detect(observations(4), from, to)
+	if len(insights) != 1 || insights[0].Kind != db.InsightKindCompletionDecline {
+		t.Fatalf("insights = %+v, want a decline", insights)
+	}
+	var evidence CompletionDeclineEvidence
+	if err := json.Unmarshal(insights[0].Evidence, &evidence); err != nil {
+		t.Fatalf("decode decline evidence: %v", err)
+	}
+	if evidence.PreviousPercentage != 100 || evidence.RecentPercentage != 57.1 || evidence.Change != -42.9 || len(evidence.Weeks) != 8 {
+		t.Fatalf("unexpected decline: %s", insights[0].Evidence)
+	}
+	if evidence.Weeks[0].Percentage != 100 || evidence.Weeks[7].Completed != 4 {
+		t.Fatalf("unexpected weeks: %+v", evidence.Weeks)
+	}
+
+	// A 14% drop is noise.
+	if insights := Detect(observations(6), from, to); len(insights) != 0 {
+		t.Fatalf("insights = %+v, want none for a small drop", insights)
+	}
+}
+
+func TestDetectNeedsEnoughSamples(t *testing.T) {
+	to := time.Date(2026, time.June, 28, 12, 0, 0, 0, time.Local)
+	from := to.AddDate(0, 0, -(insightWindowDays - 1))
+	start := time.Date(2026, time.June, 26, 7, 0, 0, 0, time.Local)
+	late := start.Add(2 * time.Hour)
+	observations := []db.ScheduleObservation{
+		{ScheduleTaskID: "gym", Title: "Gym", Date: to.AddDate(0, 0, -2), Status: db.TaskStatusSkipped, PlannedStart: &start, ActualStart: &late},
+		{ScheduleTaskID: "gym", Title: "Gym", Date: to.AddDate(0, 0, -9), Status: db.TaskStatusSkipped},
+		{ScheduleTaskID: "gym", Title: "Gym", Date: to.AddDate(0, 0, -1), Status: db.TaskStatusCompleted},
+	}
+	if insights := Detect(observations, from, to); len(insights) != 0 {
+		t.Fatalf("insights = %+v, want none with so few tasks", insights)
+	}
+}
+
+func TestNextInsightRun(t *testing.T) {
+	before := time.Date(2026, time.June, 1, 2, 0, 0, 0, time.Local)
+	if next := nextInsightRun(before); !next.Equal(time.Date(2026, time.June, 1, insightRunHour, 0, 0, 0, time.Local)) {
+		t.Fatalf("next run before the hour = %s", next)
+	}
+	after := time.Date(2026, time.June, 1, 9, 0, 0, 0, time.Local)
+	if next := nextInsightRun(after); !next.Equal(time.Date(2026, time.June, 2, insightRunHour, 0, 0, 0, time.Local)) {
+		t.Fatalf("next run after the hour = %s", next)
+	}
+}
@@ -0,0 +1,123 @@
+package insights
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// The engine runs once a night, at insightRunHour local time, after the
+// rollups were reconciled, over the last insightWindowDays days up to
+// yesterday: today is not over yet.
+const (
+	insightRunHour    = 4
+	insightWindowDays = 2 * declineWindowDays
+)
+
+const insightNoticeURL = "/insights"
+
+// Engine looks for habit patterns in the recent history of every active user,
+// stores them as insights and announces the new ones in the inbox.
+type Engine struct {
+	ctx context.Context
+}
+
+func NewEngine(ctx context.Context) *Engine {
+	return &Engine{ctx: ctx}
+}
+
+func (e *Engine) Start() {
+	log.Printf("insights engine started hour=%d days=%d", insightRunHour, insightWindowDays)
+
+	for {
+		timer := time.NewTimer(time.Until(nextInsightRun(time.Now())))
+		select {
+		case <-e.ctx.Done():
+			timer.Stop()
+			log.Println("insights engine stopped")
+			return
+		case <-timer.C:
+			e.runOnce()
+		}
+	}
+}
+
+func (e *Engine) runOnce() {
+	now := time.Now()
+	from, _ := insightWindow(now)
+	userIDs, err := db.ListInsightUserIDs(e.ctx, from)
+	if err != nil {
+		log.Printf("insights: list users: %v", err)
+		return
+	}
+
+	found := 0
+	for _, userID := range userIDs {
+		insights, err := AnalyzeUser(e.ctx, userID, now)
+		if err != nil {
+			log.Printf("insights: analyze user %s: %v", userID, err)
+			continue
+		}
+		found += len(insights)
+	}
+	log.Printf("insights run completed users=%d insights=%d", len(userIDs), found)
+}
+
+// AnalyzeUser detects the insights of userID as of now, stores them,
+// resolves the ones no longer detected and sends an inbox notice for each
+// one that surfaced.
+func AnalyzeUser(ctx context.Context, userID string, now time.Time) ([]*db.Insight, error) {
+	from, to := insightWindow(now)
+	observations, err := db.ListUserScheduleObservations(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	insights := Detect(observations, from, to)
+	keepIDs := make([]string, 0, len(insights))
+	for _, insight := range insights {
+		insight.UserID = userID
+		surfaced, err := db.SaveInsight(ctx, insight)
+		if err != nil {
+			return nil, err
+		}
+		keepIDs = append(keepIDs, insight.ID)
+		if surfaced {
+			sendInsightNotice(ctx, insight)
+		}
+	}
+	if _, err := db.ResolveUserInsights(ctx, userID, keepIDs); err != nil {
+		return nil, err
+	}
+	return insights, nil
+}
+
+func sendInsightNotice(ctx context.Context, insight *db.Insight) {
+	data := map[string]string{
+		"kind":             "insight",
+		"insight_id":       insight.ID,
+		"insight_kind":     string(insight.Kind),
+		"schedule_task_id": insight.ScheduleTaskID,
+	}
+	if _, err := db.CreateInboxNotice(ctx, insight.UserID, db.InboxItemTypeSystem, insight.Title, insight.Body, insightNoticeURL, data); err != nil {
+		log.Printf("insights: store notice for %s: %v", insight.UserID, err)
+	}
+}
+
+// insightWindow returns the first and last day analyzed at now, at local
+// noon.
+func insightWindow(now time.Time) (time.Time, time.Time) {
+	to := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	return to.AddDate(0, 0, -(insightWindowDays - 1)), to
+}
+
+// nextInsightRun returns the first insightRunHour after now.
+func nextInsightRun(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), insightRunHour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,320 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+const defaultAccountabilityInterval = 5 * time.Minute
+
+// accountabilityLookbackDays bounds how far back missed tasks are reported,
+// so a worker that was down for a while does not flood the partner.
+const accountabilityLookbackDays = 7
+
+// AccountabilityWorker alerts accountability partners when the owner misses a
+// required task or breaks a streak, and sends them a weekly summary of the
+// owner's adherence. A day is only judged once the partnership's grace period
+// has passed after midnight, so late check-ins still count.
+type AccountabilityWorker struct {
+	ctx      context.Context
+	interval time.Duration
+}
+
+func NewAccountabilityWorker(ctx context.Context, interval time.Duration) *AccountabilityWorker {
+	if interval <= 0 {
+		interval = defaultAccountabilityInterval
+	}
+	return &AccountabilityWorker{ctx: ctx, interval: interval}
+}
+
+func (w *AccountabilityWorker) Start() {
+	log.Printf("accountability worker started interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			log.Println("accountability worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce()
+		}
+	}
+}
+
+func (w *AccountabilityWorker) runOnce() {
+	grants, err := db.ListAccountabilityPartnerships(w.ctx)
+	if err != nil {
+		log.Printf("accountability: list partnerships: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, grant := range grants {
+		CheckAccountabilityPartner(w.ctx, grant, now)
+	}
+}
+
+// CheckAccountabilityPartner sends the partner on grant every alert that is
+// due at now. Alerts are recorded before they are sent, so each one goes out
+// at most once, and released when sending fails, so the next check retries
+// them.
+func CheckAccountabilityPartner(ctx context.Context, grant *db.TaskAccessGrant, now time.Time) {
+	settings := grant.Accountability
+	if settings == nil {
+		return
+	}
+
+	day := AccountabilityJudgedDay(now, settings.Grace())
+	since := localDay(settings.CreatedAt)
+	if day.Before(since) {
+		return
+	}
+	from := day.AddDate(0, 0, -(accountabilityLookbackDays - 1))
+	if from.Before(since) {
+		from = since
+	}
+
+	ownerName := displayName(grant.OwnerFullname, grant.OwnerUsername)
+	checkMissedTasks(ctx, grant, ownerName, from, day)
+	// Streaks span every task of the owner, so partners on scoped grants do
+	// not hear about them.
+	if !grant.IsScoped() {
+		checkBrokenStreak(ctx, grant, ownerName, day)
+	}
+	if settings.WeeklySummary {
+		checkWeeklySummary(ctx, grant, ownerName, day, since)
+	}
+}
+
+func checkMissedTasks(ctx context.Context, grant *db.TaskAccessGrant, ownerName string, from time.Time, to time.Time) {
+	tasks, err := db.ListUnreportedMissedTasks(ctx, grant.ID, grant.OwnerUserID, from, to)
+	if err != nil {
+		log.Printf("accountability: list missed tasks for grant %s: %v", grant.ID, err)
+		return
+	}
+
+	for _, task := range tasks {
+		if !grant.CoversSchedule(task.ScheduleTaskID, task.Category) {
+			continue
+		}
+		claimed, err := db.RecordAccountabilityAlert(ctx, grant.ID, db.AccountabilityAlertMissedTask, task.ID)
+		if err != nil {
+			log.Printf("accountability: record missed task %s for grant %s: %v", task.ID, grant.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		verb := "no completó"
+		if task.Status == db.TaskStatusSkipped {
+			verb = "omitió"
+		}
+		body := fmt.Sprintf("%s %s %s el %s.", ownerName, verb, taskAssignmentTitle(task), task.Date.In(time.Local).Format("02/01"))
+		if err := sendAccountabilityNotice(ctx, grant, db.AccountabilityAlertMissedTask, task.ID, "Tarea requerida sin cumplir", body, map[string]string{
+			"task_id": task.ID,
+			"status":  string(task.Status),
+		}); err != nil {
+			log.Printf("accountability: send missed task %s for grant %s: %v", task.ID, grant.ID, err)
+			releaseAccountabilityAlert(ctx, grant, db.AccountabilityAlertMissedTask, task.ID)
+		}
+	}
+}
+
+func checkBrokenStreak(ctx context.Context, grant *db.TaskAccessGrant, ownerName string, day time.Time) {
+	streak, err := db.GetUserBrokenStreak(ctx, grant.OwnerUserID, day)
+	if err != nil {
+		log.Printf("accountability: check streak for grant %s: %v", grant.ID, err)
+		return
+	}
+	if streak == 0 {
+		return
+	}
+
+	subject := day.Format("2006-01-02")
+	claimed, err := db.RecordAccountabilityAlert(ctx, grant.ID, db.AccountabilityAlertStreakBroken, subject)
+	if err != nil {
+		log.Printf("accountability: record broken streak for grant %s: %v", grant.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	body := fmt.Sprintf("%s perdió una racha de %d días el %s.", ownerName, streak, day.Format("02/01"))
+	if err := sendAccountabilityNotice(ctx, grant, db.AccountabilityAlertStreakBroken, subject, "Racha interrumpida", body, map[string]string{
+		"date":   subject,
+		"streak": fmt.Sprintf("%d", streak),
+	}); err != nil {
+		log.Printf("accountability: send broken streak for grant %s: %v", grant.ID, err)
+		releaseAccountabilityAlert(ctx, grant, db.AccountabilityAlertStreakBroken, subject)
+	}
+}
+
+func checkWeeklySummary(ctx context.Context, grant *db.TaskAccessGrant, ownerName string, day time.Time, since time.Time) {
+	weekStart, weekEnd := AccountabilityWeek(day)
+	if weekEnd.Before(since) {
+		return
+	}
+
+	// The week is loaded before the summary is claimed, so a failed load is
+	// retried on the next check.
+	tasks, err := db.GetUserAccountableTasks(ctx, grant.OwnerUserID, weekStart, weekEnd)
+	if err != nil {
+		log.Printf("accountability: load week for grant %s: %v", grant.ID, err)
+		return
+	}
+
+	subject := weekStart.Format("2006-01-02")
+	claimed, err := db.RecordAccountabilityAlert(ctx, grant.ID, db.AccountabilityAlertWeeklySummary, subject)
+	if err != nil {
+		log.Printf("accountability: record weekly summary for grant %s: %v", grant.ID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	covered := make([]*db.DetailedTask, 0, len(tasks))
+	for _, task := range tasks {
+		if grant.CoversSchedule(task.ScheduleTaskID, task.Category) {
+			covered = append(covered, task)
+		}
+	}
+
+	summary := SummarizeAccountabilityWeek(covered)
+	body := BuildAccountabilityWeeklyBody(ownerName, summary, weekStart, weekEnd)
+	if err := sendAccountabilityNotice(ctx, grant, db.AccountabilityAlertWeeklySummary, subject, "Resumen semanal de "+ownerName, body, map[string]string{
+		"from":       subject,
+		"to":         weekEnd.Format("2006-01-02"),
+		"percentage": fmt.Sprintf("%.1f", summary.Percentage),
+	}); err != nil {
+		log.Printf("accountability: send weekly summary for grant %s: %v", grant.ID, err)
+		releaseAccountabilityAlert(ctx, grant, db.AccountabilityAlertWeeklySummary, subject)
+	}
+}
+
+func releaseAccountabilityAlert(ctx context.Context, grant *db.TaskAccessGrant, kind db.AccountabilityAlertKind, subject string) {
+	if err := db.ReleaseAccountabilityAlert(ctx, grant.ID, kind, subject); err != nil {
+		log.Printf("accountability: release %s alert for grant %s: %v", kind, grant.ID, err)
+	}
+}
+
+// sendAccountabilityNotice queues the push for the partner and stores the
+// matching inbox notice. The push goes first: its dedupe key makes a retry
+// after a failed notice queue it only once.
+func sendAccountabilityNotice(ctx context.Context, grant *db.TaskAccessGrant, kind db.AccountabilityAlertKind, subject string, title string, body string, data map[string]string) error {
+	const url = "/shared"
+	data["grant_id"] = grant.ID
+	data["owner_user_id"] = grant.OwnerUserID
+	data["kind"] = string(kind)
+
+	payload := &NotificationPayload{
+		Title: title,
+		Body:  body,
+		Icon:  "/icon-192x192.png",
+		Badge: "/badge-72x72.png",
+		Tag:   "accountability-" + string(kind) + "-" + grant.ID,
+		URL:   url,
+		Data:  data,
+	}
+	dedupeKey := "accountability:" + grant.ID + ":" + string(kind) + ":" + subject
+	if _, err := Enqueue(ctx, grant.GranteeUserID, "accountability_"+string(kind), dedupeKey, payload); err != nil {
+		return fmt.Errorf("queue push: %w", err)
+	}
+
+	if _, err := db.CreateInboxNotice(ctx, grant.GranteeUserID, db.InboxItemTypeSystem, title, body, url, data); err != nil {
+		return fmt.Errorf("store notice: %w", err)
+	}
+	return nil
+}
+
+// AccountabilityWeekSummary is the owner's adherence over one week.
+type AccountabilityWeekSummary struct {
+	Total             int
+	Completed         int
+	RequiredTotal     int
+	RequiredCompleted int
+	PerfectDays       int
+	Percentage        float64
+}
+
+// SummarizeAccountabilityWeek counts completions, required completions and
+// the days where every task was completed.
+func SummarizeAccountabilityWeek(tasks []*db.DetailedTask) AccountabilityWeekSummary {
+	var summary AccountabilityWeekSummary
+	type dayCount struct{ total, completed int }
+	days := map[string]*dayCount{}
+
+	for _, task := range tasks {
+		key := task.Date.In(time.Local).Format("2006-01-02")
+		if days[key] == nil {
+			days[key] = &dayCount{}
+		}
+		completed := task.Status == db.TaskStatusCompleted
+
+		summary.Total++
+		days[key].total++
+		if completed {
+			summary.Completed++
+			days[key].completed++
+		}
+		if task.IsRequired {
+			summary.RequiredTotal++
+			if completed {
+				summary.RequiredCompleted++
+			}
+		}
+	}
+	for _, day := range days {
+		if day.completed == day.total {
+			summary.PerfectDays++
+		}
+	}
+	if summary.Total > 0 {
+		summary.Percentage = math.Round(float64(summary.Completed)*1000/float64(summary.Total)) / 10
+	}
+	return summary
+}
+
+func BuildAccountabilityWeeklyBody(ownerName string, summary AccountabilityWeekSummary, from time.Time, to time.Time) string {
+	period := from.Format("02/01") + " al " + to.Format("02/01")
+	if summary.Total == 0 {
+		return fmt.Sprintf("%s no tuvo tareas del %s.", ownerName, period)
+	}
+
+	body := fmt.Sprintf("Del %s, %s completó %d de %d tareas (%.0f%%).",
+		period, ownerName, summary.Completed, summary.Total, summary.Percentage)
+	if summary.RequiredTotal > 0 {
+		body += fmt.Sprintf(" Requeridas: %d de %d.", summary.RequiredCompleted, summary.RequiredTotal)
+	}
+	body += fmt.Sprintf(" Días perfectos: %d.", summary.PerfectDays)
+	return body
+}
+
+// AccountabilityJudgedDay returns the most recent day whose grace period had
+// passed at now, at local midnight.
+func AccountabilityJudgedDay(now time.Time, grace time.Duration) time.Time {
+	return localDay(now.Add(-grace)).AddDate(0, 0, -1)
+}
+
+// AccountabilityWeek returns the Monday and Sunday of the latest week that
+// ended on or before day.
+func AccountabilityWeek(day time.Time) (time.Time, time.Time) {
+	end := localDay(day)
+	end = end.AddDate(0, 0, -int(end.Weekday()))
+	return end.AddDate(0, 0, -6), end
+}
+
+func localDay(value time.Time) time.Time {
+	value = value.In(time.Local)
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestAccountabilityJudgedDayWaitsForGrace(t *testing.T) {
+	grace := 2 * time.Hour
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2026, 10, 19, 1, 59, 0, 0, time.Local), "2026-10-17"},
+		{time.Date(2026, 10, 19, 2, 0, 0, 0, time.Local), "2026-10-18"},
+		{time.Date(2026, 10, 19, 23, 0, 0, 0, time.Local), "2026-10-18"},
+	}
+	for _, tt := range tests {
+		if got := AccountabilityJudgedDay(tt.now, grace).Format("2006-01-02"); got != tt.want {
+			t.Fatalf("AccountabilityJudgedDay(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestAccountabilityWeekEndsOnSunday(t *testing.T) {
+	tests := []struct {
+		day        time.Time
+		start, end string
+	}{
+		{time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local), "2026-10-12", "2026-10-18"},
+		{time.Date(2026, 10, 21, 0, 0, 0, 0, time.Local), "2026-10-12", "2026-10-18"},
+		{time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local), "2026-10-05", "2026-10-11"},
+	}
+	for _, tt := range tests {
+		start, end := AccountabilityWeek(tt.day)
+		if start.Format("2006-01-02") != tt.start || end.Format("2006-01-02") != tt.end {
+			t.Fatalf("AccountabilityWeek(%s) = %s..%s, want %s..%s", tt.day.Format("2006-01-02"), start.Format("2006-01-02"), end.Format("2006-01-02"), tt.start, tt.end)
+		}
+	}
+}
+
+func TestSummarizeAccountabilityWeek(t *testing.T) {
+	monday := time.Date(2026, 10, 12, 12, 0, 0, 0, time.Local)
+	tuesday := monday.AddDate(0, 0, 1)
+	tasks := []*db.DetailedTask{
+		{Date: monday, Status: db.TaskStatusCompleted, IsRequired: true},
+		{Date: monday, Status: db.TaskStatusCompleted},
+		{Date: tuesday, Status: db.TaskStatusCompleted},
+		{Date: tuesday, Status: db.TaskStatusSkipped, IsRequired: true},
+	}
+
+	summary := SummarizeAccountabilityWeek(tasks)
+	want := AccountabilityWeekSummary{Total: 4, Completed: 3, RequiredTotal: 2, RequiredCompleted: 1, PerfectDays: 1, Percentage: 75}
+	if summary != want {
+		t.Fatalf("SummarizeAccountabilityWeek = %+v, want %+v", summary, want)
+	}
+
+	body := BuildAccountabilityWeeklyBody("Ana", summary, monday, monday.AddDate(0, 0, 6))
+	for _, part := range []string{"12/10 al 18/10", "3 de 4 tareas (75%)", "Requeridas: 1 de 2", "Días perfectos: 1"} {
+		if !strings.Contains(body, part) {
+			t.Fatalf("expected %q in weekly body, got %q", part, body)
+		}
+	}
+	if body := BuildAccountabilityWeeklyBody("Ana", AccountabilityWeekSummary{}, monday, monday); !strings.Contains(body, "no tuvo tareas") {
+		t.Fatalf("expected empty week body, got %q", body)
+	}
+}
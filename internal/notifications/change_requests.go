@@ -0,0 +1,82 @@
+package notifications
+
+import (
+	"context"
+	"log"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// NotifyChangeRequestCreated asks the owner to review a change a grantee in
+// approval mode proposed.
+func NotifyChangeRequestCreated(ctx context.Context, request *db.ChangeRequest) {
+	requesterName := displayName(request.RequesterFullname, request.RequesterUsername)
+	body := requesterName + " propone cambios en " + changeRequestSubject(request) + "."
+	sendChangeRequestNotice(ctx, request.OwnerUserID, "/profile", "Cambio pendiente de aprobación", body, request)
+}
+
+// NotifyChangeRequestResolved tells the requester whether the owner approved
+// or rejected their change.
+func NotifyChangeRequestResolved(ctx context.Context, request *db.ChangeRequest) {
+	ownerName := displayName(request.OwnerFullname, request.OwnerUsername)
+	var title, body string
+	switch request.Status {
+	case db.ChangeRequestApproved:
+		title = "Cambio aprobado"
+		body = ownerName + " aprobó tus cambios en " + changeRequestSubject(request) + "."
+	case db.ChangeRequestRejected:
+		title = "Cambio rechazado"
+		body = ownerName + " rechazó tus cambios en " + changeRequestSubject(request) + "."
+		if request.ReviewNote != "" {
+			body += " Nota: " + request.ReviewNote
+		}
+	default:
+		return
+	}
+
+	sendChangeRequestNotice(ctx, request.RequesterUserID, "/shared", title, body, request)
+}
+
+// sendChangeRequestNotice stores an inbox notice and queues a push. Each
+// request changes status once, so there is no dedupe key.
+func sendChangeRequestNotice(ctx context.Context, userID string, url string, title string, body string, request *db.ChangeRequest) {
+	data := map[string]string{
+		"change_request_id": request.ID,
+		"kind":              string(request.Kind),
+		"status":            string(request.Status),
+	}
+	if request.TaskID != "" {
+		data["task_id"] = request.TaskID
+	}
+	if _, err := db.CreateInboxNotice(ctx, userID, db.InboxItemTypeSystem, title, body, url, data); err != nil {
+		log.Printf("change request: store notice for %s: %v", userID, err)
+	}
+
+	payload := &NotificationPayload{
+		Title: title,
+		Body:  body,
+		Icon:  "/icon-192x192.png",
+		Badge: "/badge-72x72.png",
+		Tag:   "change-request-" + request.ID,
+		URL:   url,
+		Data:  data,
+	}
+	if _, err := Enqueue(ctx, userID, "change_request_"+string(request.Status), "", payload); err != nil {
+		log.Printf("change request: queue push for %s: %v", userID, err)
+	}
+}
+
+func changeRequestSubject(request *db.ChangeRequest) string {
+	if request.Kind == db.ChangeRequestScheduleCreate {
+		for _, field := range request.Diff {
+			if title, ok := field.To.(string); ok && field.Field == "title" && title != "" {
+				return "una nueva rutina \"" + title + "\""
+			}
+		}
+		return "una nueva rutina"
+	}
+	if request.TaskTitle == "" {
+		return "una tarea"
+	}
+	return "\"" + request.TaskTitle + "\""
+}
@@ -0,0 +1,207 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// maxDigestItems caps the number of task titles listed in a digest body so
+// the push payload stays readable on small screens.
+const maxDigestItems = 5
+
+// checkAndSendDigests delivers the morning agenda and the evening summary to
+// every user with a push subscription once their configured time has passed.
+// digest_notifications guarantees one queued digest per user, kind and day.
+func (s *TaskScheduler) checkAndSendDigests() {
+	config := LoadConfigFromEnv()
+	if !config.CanSend() {
+		return // warnedNoConfig already logged by checkAndNotifyTasks
+	}
+
+	recipients, err := db.ListDigestRecipients(s.ctx)
+	if err != nil {
+		log.Printf("digest scheduler: list recipients: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, settings := range recipients {
+		if settings.MorningEnabled && digestDue(now, settings.MorningTime) {
+			s.sendDigest(settings.UserID, db.DigestKindMorningAgenda, now)
+		}
+		if settings.EveningEnabled && digestDue(now, settings.EveningTime) {
+			s.sendDigest(settings.UserID, db.DigestKindEveningSummary, now)
+		}
+	}
+}
+
+func (s *TaskScheduler) sendDigest(userID string, kind db.DigestKind, now time.Time) {
+	sent, err := db.DigestAlreadySent(s.ctx, userID, kind, now)
+	if err != nil {
+		log.Printf("digest scheduler: check %s for user %s: %v", kind, userID, err)
+		return
+	}
+	if sent {
+		return
+	}
+
+	var payload *NotificationPayload
+	switch kind {
+	case db.DigestKindMorningAgenda:
+		payload, err = buildMorningAgenda(s.ctx, userID, now)
+	case db.DigestKindEveningSummary:
+		payload, err = buildEveningSummary(s.ctx, userID, now)
+	}
+	if err != nil {
+		log.Printf("digest scheduler: build %s for user %s: %v", kind, userID, err)
+		return
+	}
+
+	outboxID := ""
+	if payload != nil {
+		dedupeKey := fmt.Sprintf("digest:%s:%s:%s", kind, userID, now.Format("2006-01-02"))
+		outboxID, err = Enqueue(s.ctx, userID, string(kind), dedupeKey, payload)
+		if err != nil {
+			log.Printf("digest scheduler: queue %s for user %s: %v", kind, userID, err)
+			return
+		}
+		// Keep a copy in the inbox so the digest is readable after the push
+		// is dismissed.
+		data := map[string]string{"digest_kind": string(kind)}
+		if _, err := db.CreateInboxNotice(s.ctx, userID, db.InboxItemTypeDigest, payload.Title, payload.Body, payload.URL, data); err != nil {
+			log.Printf("digest scheduler: store %s notice for user %s: %v", kind, userID, err)
+		}
+	}
+
+	if err := db.MarkDigestSent(s.ctx, userID, kind, now, outboxID); err != nil {
+		log.Printf("digest scheduler: mark %s for user %s: %v", kind, userID, err)
+		return
+	}
+	log.Printf("digest scheduler: processed %s for user %s outbox=%s", kind, userID, outboxID)
+}
+
+func buildMorningAgenda(ctx context.Context, userID string, now time.Time) (*NotificationPayload, error) {
+	// The task generator may be disabled, so make sure today's instances exist
+	// before reading them back.
+	if _, err := db.CreateUsersTodayTasks(ctx, userID); err != nil {
+		return nil, err
+	}
+	tasks, err := db.GetUserTodayDetailedTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildMorningAgendaPayload(tasks, now), nil
+}
+
+func buildEveningSummary(ctx context.Context, userID string, now time.Time) (*NotificationPayload, error) {
+	progress, err := db.GetUserDayProgress(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := db.GetUserTodayDetailedTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	streak, err := db.GetUserCurrentStreak(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return BuildEveningSummaryPayload(progress, tasks, streak, now), nil
+}
+
+// BuildMorningAgendaPayload lists today's required and timed tasks. It returns
+// nil when there is nothing worth announcing.
+func BuildMorningAgendaPayload(tasks []*db.DetailedTask, day time.Time) *NotificationPayload {
+	var lines []string
+	for _, task := range tasks {
+		if !task.IsRequired && task.StartTime.IsZero() {
+			continue
+		}
+		line := task.Title
+		if !task.StartTime.IsZero() {
+			line = task.StartTime.Format("15:04") + " " + line
+		}
+		if task.IsRequired {
+			line += " *"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return &NotificationPayload{
+		Title:              fmt.Sprintf("Tu agenda de hoy (%d)", len(lines)),
+		Body:               joinDigestLines(lines),
+		Icon:               "/icon-192x192.png",
+		Badge:              "/badge-72x72.png",
+		Tag:                "digest-morning-" + day.Format("2006-01-02"),
+		RequireInteraction: false,
+		URL:                "/tasks",
+		Data: map[string]string{
+			"kind": string(db.DigestKindMorningAgenda),
+			"date": day.Format("2006-01-02"),
+		},
+	}
+}
+
+// BuildEveningSummaryPayload reports the completion percentage, the required
+// tasks that were not completed and the current streak. It returns nil when
+// the user had no tasks today.
+func BuildEveningSummaryPayload(progress *db.DayProgress, tasks []*db.DetailedTask, streak int, day time.Time) *NotificationPayload {
+	if progress == nil || progress.Total == 0 {
+		return nil
+	}
+
+	var missed []string
+	for _, task := range tasks {
+		if task.IsRequired && task.Status != db.TaskStatusCompleted {
+			missed = append(missed, task.Title)
+		}
+	}
+
+	body := fmt.Sprintf("Completaste %d de %d tareas (%.0f%%). Racha actual: %d días.",
+		progress.Completed, progress.Total, progress.Percentage, streak)
+	if len(missed) > 0 {
+		body += "\nRequeridas pendientes:\n" + joinDigestLines(missed)
+	}
+
+	return &NotificationPayload{
+		Title:              "Resumen del día",
+		Body:               body,
+		Icon:               "/icon-192x192.png",
+		Badge:              "/badge-72x72.png",
+		Tag:                "digest-evening-" + day.Format("2006-01-02"),
+		RequireInteraction: false,
+		URL:                "/tasks",
+		Data: map[string]string{
+			"kind":       string(db.DigestKindEveningSummary),
+			"date":       day.Format("2006-01-02"),
+			"percentage": fmt.Sprintf("%.1f", progress.Percentage),
+			"streak":     fmt.Sprintf("%d", streak),
+			"missed":     fmt.Sprintf("%d", len(missed)),
+		},
+	}
+}
+
+func joinDigestLines(lines []string) string {
+	if len(lines) <= maxDigestItems {
+		return strings.Join(lines, "\n")
+	}
+	rest := len(lines) - maxDigestItems
+	return strings.Join(lines[:maxDigestItems], "\n") + fmt.Sprintf("\n+%d más", rest)
+}
+
+// digestDue reports whether the "HH:MM" clock has been reached on now's day.
+func digestDue(now time.Time, clock string) bool {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return false
+	}
+	return now.Hour()*60+now.Minute() >= parsed.Hour()*60+parsed.Minute()
+}
@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestDigestDue(t *testing.T) {
+	now := time.Date(2026, 5, 19, 7, 30, 0, 0, time.UTC)
+	tests := []struct {
+		clock string
+		want  bool
+	}{
+		{"07:00", true},
+		{"07:30", true},
+		{"07:31", false},
+		{"21:00", false},
+		{"invalid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clock, func(t *testing.T) {
+			if got := digestDue(now, tt.clock); got != tt.want {
+				t.Fatalf("digestDue(%s) = %v, want %v", tt.clock, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMorningAgendaPayloadListsRequiredAndTimedTasks(t *testing.T) {
+	day := time.Date(2026, 5, 19, 7, 0, 0, 0, time.UTC)
+	tasks := []*db.DetailedTask{
+		{Title: "Gym", StartTime: time.Date(2026, 5, 19, 6, 30, 0, 0, time.UTC)},
+		{Title: "Vitaminas", IsRequired: true},
+		{Title: "Leer"},
+	}
+
+	payload := BuildMorningAgendaPayload(tasks, day)
+	if payload == nil {
+		t.Fatal("expected agenda payload")
+	}
+	if !strings.Contains(payload.Body, "06:30 Gym") {
+		t.Fatalf("expected timed task in body, got %q", payload.Body)
+	}
+	if !strings.Contains(payload.Body, "Vitaminas *") {
+		t.Fatalf("expected required task in body, got %q", payload.Body)
+	}
+	if strings.Contains(payload.Body, "Leer") {
+		t.Fatalf("untimed optional task should be omitted, got %q", payload.Body)
+	}
+	if payload.Tag != "digest-morning-2026-05-19" {
+		t.Fatalf("unexpected tag %q", payload.Tag)
+	}
+}
+
+func TestBuildMorningAgendaPayloadEmpty(t *testing.T) {
+	if payload := BuildMorningAgendaPayload([]*db.DetailedTask{{Title: "Leer"}}, time.Now()); payload != nil {
+		t.Fatalf("expected nil payload, got %#v", payload)
+	}
+}
+
+func TestBuildEveningSummaryPayload(t *testing.T) {
+	day := time.Date(2026, 5, 19, 21, 0, 0, 0, time.UTC)
+	progress := &db.DayProgress{Total: 4, Completed: 3, Percentage: 75}
+	tasks := []*db.DetailedTask{
+		{Title: "Gym", IsRequired: true, Status: db.TaskStatusCompleted},
+		{Title: "Estudiar", IsRequired: true, Status: db.TaskStatusSkipped},
+		{Title: "Leer", Status: db.TaskStatusPending},
+	}
+
+	payload := BuildEveningSummaryPayload(progress, tasks, 4, day)
+	if payload == nil {
+		t.Fatal("expected summary payload")
+	}
+	if !strings.Contains(payload.Body, "3 de 4") || !strings.Contains(payload.Body, "75%") {
+		t.Fatalf("expected completion figures in body, got %q", payload.Body)
+	}
+	if !strings.Contains(payload.Body, "Estudiar") || strings.Contains(payload.Body, "Leer") {
+		t.Fatalf("expected only missed required tasks, got %q", payload.Body)
+	}
+	if payload.Data["streak"] != "4" || payload.Data["missed"] != "1" {
+		t.Fatalf("unexpected data %#v", payload.Data)
+	}
+
+	if BuildEveningSummaryPayload(&db.DayProgress{}, nil, 0, day) != nil {
+		t.Fatal("expected nil payload for a day without tasks")
+	}
+}
+
+func TestJoinDigestLinesTruncates(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g"}
+	got := joinDigestLines(lines)
+	if !strings.HasSuffix(got, "+2 más") {
+		t.Fatalf("expected truncation suffix, got %q", got)
+	}
+}
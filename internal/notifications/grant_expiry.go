@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+const defaultGrantExpiryInterval = 1 * time.Minute
+
+// GrantExpiryWorker revokes sharing grants whose expires_at has passed and
+// tells the owner and the grantee. It also expires unanswered invitations and
+// tells the owner. Permission checks already ignore lapsed grants and pending
+// invitations, so the interval only affects how soon people are notified.
+type GrantExpiryWorker struct {
+	ctx      context.Context
+	interval time.Duration
+}
+
+func NewGrantExpiryWorker(ctx context.Context, interval time.Duration) *GrantExpiryWorker {
+	if interval <= 0 {
+		interval = defaultGrantExpiryInterval
+	}
+	return &GrantExpiryWorker{ctx: ctx, interval: interval}
+}
+
+func (w *GrantExpiryWorker) Start() {
+	log.Printf("sharing grant expiry worker started interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			log.Println("sharing grant expiry worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce()
+		}
+	}
+}
+
+func (w *GrantExpiryWorker) runOnce() {
+	grants, err := db.RevokeExpiredTaskAccessGrants(w.ctx)
+	if err != nil {
+		log.Printf("grant expiry: revoke expired grants: %v", err)
+		return
+	}
+	for _, grant := range grants {
+		w.notify(grant)
+	}
+
+	invitations, err := db.ExpireSharingInvitations(w.ctx)
+	if err != nil {
+		log.Printf("grant expiry: expire invitations: %v", err)
+		return
+	}
+	for _, invitation := range invitations {
+		NotifySharingInvitationOutcome(w.ctx, invitation)
+	}
+}
+
+func (w *GrantExpiryWorker) notify(grant *db.TaskAccessGrant) {
+	ownerName := displayName(grant.OwnerFullname, grant.OwnerUsername)
+	granteeName := displayName(grant.GranteeFullname, grant.GranteeUsername)
+
+	messages := []struct {
+		userID string
+		body   string
+		url    string
+	}{
+		{grant.OwnerUserID, granteeName + " ya no tiene acceso a tus tareas.", "/profile"},
+		{grant.GranteeUserID, "Tu acceso a las tareas de " + ownerName + " terminó.", "/shared"},
+	}
+
+	const title = "Acceso compartido vencido"
+	data := map[string]string{"grant_id": grant.ID}
+	for _, message := range messages {
+		if _, err := db.CreateInboxNotice(w.ctx, message.userID, db.InboxItemTypeSystem, title, message.body, message.url, data); err != nil {
+			log.Printf("grant expiry: store notice for %s: %v", message.userID, err)
+		}
+
+		payload := &NotificationPayload{
+			Title: title,
+			Body:  message.body,
+			Icon:  "/icon-192x192.png",
+			Badge: "/badge-72x72.png",
+			Tag:   "sharing-expired-" + grant.ID,
+			URL:   message.url,
+			Data:  data,
+		}
+		dedupeKey := "grant-expired:" + grant.ID + ":" + message.userID
+		if _, err := Enqueue(w.ctx, message.userID, "grant_expired", dedupeKey, payload); err != nil {
+			log.Printf("grant expiry: queue push for %s: %v", message.userID, err)
+		}
+	}
+}
+
+func displayName(fullname string, username string) string {
+	if fullname != "" {
+		return fullname
+	}
+	return "@" + username
+}
@@ -150,18 +150,8 @@ func SendNotificationToUserWithConfig(ctx context.Context, userID string, payloa
 	sentCount := 0
 	for _, sub := range subscriptions {
 		// Send to each subscription (each device)
-		err := SendNotificationWithConfig(sub, payload, config)
-		if err != nil {
-			if errors.Is(err, ErrSubscriptionExpired) {
-				// The push service permanently rejected this subscription.
-				// Remove it so we stop retrying on every future send.
-				if delErr := db.DeleteSubscriptionByID(ctx, sub.ID); delErr != nil {
-					log.Printf("failed to remove expired subscription %s: %v", sub.ID, delErr)
-				} else {
-					log.Printf("removed expired push subscription %s (endpoint %s) for user %s",
-						sub.ID, sub.Endpoint, userID)
-				}
-			} else {
+		if err := SendToSubscription(ctx, sub, payload, config); err != nil {
+			if !errors.Is(err, ErrSubscriptionExpired) {
 				// Transient error — log but keep the subscription for next time.
 				log.Printf("Error sending notification to %s\n", err)
 			}
@@ -172,3 +162,31 @@ func SendNotificationToUserWithConfig(ctx context.Context, userID string, payloa
 
 	return sentCount, nil
 }
+
+// SendToSubscription pushes the payload to a single device and keeps its
+// health columns current. Subscriptions the push service rejected
+// permanently are deleted; the returned error still wraps
+// ErrSubscriptionExpired so callers can report it.
+func SendToSubscription(ctx context.Context, sub *db.PushSubscription, payload *NotificationPayload, config Config) error {
+	err := SendNotificationWithConfig(sub, payload, config)
+	switch {
+	case err == nil:
+		if recErr := db.RecordSubscriptionSuccess(ctx, sub.ID); recErr != nil {
+			log.Printf("failed to record push success for subscription %s: %v", sub.ID, recErr)
+		}
+	case errors.Is(err, ErrSubscriptionExpired):
+		// The push service permanently rejected this subscription.
+		// Remove it so we stop retrying on every future send.
+		if delErr := db.DeleteSubscriptionByID(ctx, sub.ID); delErr != nil {
+			log.Printf("failed to remove expired subscription %s: %v", sub.ID, delErr)
+		} else {
+			log.Printf("removed expired push subscription %s (endpoint %s) for user %s",
+				sub.ID, sub.Endpoint, sub.UserID)
+		}
+	default:
+		if recErr := db.RecordSubscriptionFailure(ctx, sub.ID); recErr != nil {
+			log.Printf("failed to record push failure for subscription %s: %v", sub.ID, recErr)
+		}
+	}
+	return err
+}
@@ -3,7 +3,9 @@ package notifications
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestErrSubscriptionExpiredWrapping(t *testing.T) {
@@ -48,3 +50,50 @@ func TestConfigCanSend(t *testing.T) {
 		})
 	}
 }
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 16 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("attempt_%d", tt.attempts), func(t *testing.T) {
+			if got := outboxBackoff(tt.attempts); got != tt.want {
+				t.Fatalf("outboxBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTaskReminderPayloadActions(t *testing.T) {
+	t.Setenv("JWT_SECRET", strings.Repeat("a", 32))
+
+	payload, err := BuildTaskReminderPayload("user-1", "task-1", "Leer", "")
+	if err != nil {
+		t.Fatalf("build reminder payload: %v", err)
+	}
+	want := []NotificationAction{
+		{Action: ActionComplete, Title: "Completar"},
+		{Action: ActionSnooze, Title: "Posponer"},
+		{Action: ActionSkip, Title: "Omitir"},
+	}
+	if len(payload.Actions) != len(want) {
+		t.Fatalf("actions = %+v, want %+v", payload.Actions, want)
+	}
+	for i := range want {
+		if payload.Actions[i].Action != want[i].Action || payload.Actions[i].Title != want[i].Title {
+			t.Fatalf("actions = %+v, want %+v", payload.Actions, want)
+		}
+	}
+	if payload.Data["action_token"] == "" {
+		t.Fatalf("expected an action token, got %#v", payload.Data)
+	}
+}
@@ -0,0 +1,217 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+const (
+	defaultOutboxInterval = 15 * time.Second
+	outboxBatchSize       = 50
+	// outboxLease is how long a claimed notification stays invisible to other
+	// workers. It must comfortably exceed the time needed to push to every
+	// device of a user.
+	outboxLease       = 2 * time.Minute
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = 1 * time.Hour
+
+	// Devices that failed this many sends in a row, or that neither
+	// re-registered nor received a push in subscriptionIdleTTL, are pruned.
+	subscriptionMaxFailures = 5
+	subscriptionIdleTTL     = 90 * 24 * time.Hour
+	subscriptionPruneEvery  = 1 * time.Hour
+)
+
+var errNoSubscriptions = errors.New("user has no push subscriptions")
+
+// Enqueue stores the payload in the notification outbox so the OutboxWorker
+// delivers it, retrying transient failures. Enqueuing twice with the same
+// non-empty dedupeKey returns the first notification's id.
+func Enqueue(ctx context.Context, userID string, kind string, dedupeKey string, payload *NotificationPayload) (string, error) {
+	return EnqueueAt(ctx, userID, kind, dedupeKey, payload, time.Time{})
+}
+
+// EnqueueAt is Enqueue with the first delivery attempt held until notBefore.
+func EnqueueAt(ctx context.Context, userID string, kind string, dedupeKey string, payload *NotificationPayload, notBefore time.Time) (string, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return db.EnqueueNotificationAt(ctx, userID, kind, dedupeKey, payloadJSON, notBefore)
+}
+
+// OutboxWorker drains notification_outbox. Claims use SKIP LOCKED, so it is
+// safe to run one worker per backend instance.
+type OutboxWorker struct {
+	ctx        context.Context
+	interval   time.Duration
+	lastPruned time.Time
+}
+
+func NewOutboxWorker(ctx context.Context, interval time.Duration) *OutboxWorker {
+	if interval <= 0 {
+		interval = defaultOutboxInterval
+	}
+	return &OutboxWorker{ctx: ctx, interval: interval}
+}
+
+func (w *OutboxWorker) Start() {
+	log.Printf("notification outbox worker started interval=%s", w.interval)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			log.Println("notification outbox worker stopped")
+			return
+		case <-ticker.C:
+			w.runOnce()
+		}
+	}
+}
+
+func (w *OutboxWorker) runOnce() {
+	w.pruneSubscriptions()
+
+	config := LoadConfigFromEnv()
+	if !config.CanSend() {
+		return // pending rows wait until VAPID keys are configured
+	}
+
+	for {
+		items, err := db.ClaimOutboxNotifications(w.ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			log.Printf("notification outbox: claim: %v", err)
+			return
+		}
+		for _, item := range items {
+			w.process(item, config)
+		}
+		if len(items) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (w *OutboxWorker) pruneSubscriptions() {
+	if time.Since(w.lastPruned) < subscriptionPruneEvery {
+		return
+	}
+	w.lastPruned = time.Now()
+
+	pruned, err := db.PruneStaleSubscriptions(w.ctx, subscriptionMaxFailures, subscriptionIdleTTL)
+	if err != nil {
+		log.Printf("notification outbox: prune subscriptions: %v", err)
+		return
+	}
+	if pruned > 0 {
+		log.Printf("notification outbox: pruned %d stale push subscriptions", pruned)
+	}
+}
+
+func (w *OutboxWorker) process(item *db.OutboxNotification, config Config) {
+	var payload NotificationPayload
+	if err := json.Unmarshal(item.Payload, &payload); err != nil {
+		w.fail(item, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+
+	sentCount, err := w.deliver(item, &payload, config)
+	if err == nil {
+		if err := db.MarkOutboxSent(w.ctx, item.ID, sentCount); err != nil {
+			log.Printf("notification outbox: mark %s sent: %v", item.ID, err)
+		}
+		return
+	}
+
+	if errors.Is(err, errNoSubscriptions) || item.Attempts >= item.MaxAttempts {
+		w.fail(item, err.Error())
+		return
+	}
+
+	nextAttemptAt := time.Now().Add(outboxBackoff(item.Attempts))
+	if err := db.MarkOutboxRetry(w.ctx, item.ID, nextAttemptAt, err.Error()); err != nil {
+		log.Printf("notification outbox: schedule retry for %s: %v", item.ID, err)
+	}
+}
+
+// deliver pushes the payload to every device of the user and logs one
+// delivery row per subscription. It succeeds when at least one device got the
+// notification. Expired subscriptions are removed by SendToSubscription and
+// do not count as a transient failure.
+func (w *OutboxWorker) deliver(item *db.OutboxNotification, payload *NotificationPayload, config Config) (int, error) {
+	subscriptions, err := db.GetSubscriptionsByUserID(w.ctx, item.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	sentCount := 0
+	var lastErr error
+	for _, sub := range subscriptions {
+		delivery := &db.NotificationDelivery{
+			OutboxID:       item.ID,
+			UserID:         item.UserID,
+			SubscriptionID: sub.ID,
+			Endpoint:       sub.Endpoint,
+			Status:         db.DeliveryStatusSent,
+		}
+
+		err := SendToSubscription(w.ctx, sub, payload, config)
+		switch {
+		case err == nil:
+			sentCount++
+		case errors.Is(err, ErrSubscriptionExpired):
+			delivery.Status = db.DeliveryStatusExpired
+			delivery.Error = err.Error()
+		default:
+			delivery.Status = db.DeliveryStatusFailed
+			delivery.Error = err.Error()
+			lastErr = err
+		}
+
+		if err := db.RecordNotificationDelivery(w.ctx, delivery); err != nil {
+			log.Printf("notification outbox: record delivery for %s: %v", item.ID, err)
+		}
+	}
+
+	if sentCount > 0 {
+		return sentCount, nil
+	}
+	if lastErr != nil {
+		return 0, lastErr
+	}
+	return 0, errNoSubscriptions
+}
+
+func (w *OutboxWorker) fail(item *db.OutboxNotification, reason string) {
+	if err := db.MarkOutboxFailed(w.ctx, item.ID, reason); err != nil {
+		log.Printf("notification outbox: mark %s failed: %v", item.ID, err)
+		return
+	}
+	log.Printf("notification outbox: %s for user %s failed after %d attempts: %s",
+		item.Kind, item.UserID, item.Attempts, reason)
+}
+
+// outboxBackoff returns the delay before the next attempt: 30s doubled per
+// attempt already made, capped at one hour.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return delay
+}
@@ -39,6 +39,7 @@ func (s *TaskScheduler) Start() {
 		case <-ticker.C:
 			s.checkAndNotifyTasks()
 			s.checkAndNotifyHydration()
+			s.checkAndSendDigests()
 		}
 	}
 }
@@ -101,27 +102,14 @@ func (s *TaskScheduler) checkAndNotifyTasks() {
 	rows.Close()
 
 	for _, t := range pending {
-		payload := &NotificationPayload{
-			Title:              "Tarea pendiente: " + t.title,
-			Body:               t.description,
-			Icon:               "/icon-192x192.png",
-			Badge:              "/badge-72x72.png",
-			Tag:                "task-" + t.id,
-			RequireInteraction: true,
-			URL:                "/tasks",
-			TaskID:             t.id,
-			Actions: []NotificationAction{
-				{Action: "view", Title: "View Task"},
-				{Action: "complete", Title: "Mark Complete"},
-			},
-		}
-
-		sentCount, err := SendNotificationToUserWithConfig(s.ctx, t.userID, payload, config)
+		payload, err := BuildTaskReminderPayload(t.userID, t.id, t.title, t.description)
 		if err != nil {
-			log.Printf("Error sending notification for task %s: %v", t.id, err)
+			log.Printf("Error building notification for task %s: %v", t.id, err)
 			continue
 		}
-		if sentCount == 0 {
+
+		if _, err := Enqueue(s.ctx, t.userID, "task_reminder", "task-reminder:"+t.id, payload); err != nil {
+			log.Printf("Error queueing notification for task %s: %v", t.id, err)
 			continue
 		}
 		if _, err := conn.Exec(
@@ -134,7 +122,7 @@ func (s *TaskScheduler) checkAndNotifyTasks() {
 			log.Printf("Error marking task notification as sent for %s: %v", t.id, err)
 			continue
 		}
-		log.Printf("Sent notification for task: %s to user: %s", t.title, t.userID)
+		log.Printf("Queued notification for task: %s to user: %s", t.title, t.userID)
 	}
 }
 
@@ -225,12 +213,8 @@ func (s *TaskScheduler) checkAndNotifyHydration() {
 			TaskID:             task.ID,
 		}
 
-		sentCount, err := SendNotificationToUserWithConfig(s.ctx, ws.userID, payload, config)
-		if err != nil {
-			log.Printf("hydration scheduler: send notification for schedule %s: %v", ws.id, err)
-			continue
-		}
-		if sentCount == 0 {
+		if _, err := Enqueue(s.ctx, ws.userID, "hydration", "hydration:"+task.ID, payload); err != nil {
+			log.Printf("hydration scheduler: queue notification for schedule %s: %v", ws.id, err)
 			continue
 		}
 
@@ -244,6 +228,6 @@ func (s *TaskScheduler) checkAndNotifyHydration() {
 			log.Printf("hydration scheduler: mark task_notifications(%s): %v", task.ID, err)
 			continue
 		}
-		log.Printf("hydration scheduler: sent reminder for schedule %s to user %s", ws.id, ws.userID)
+		log.Printf("hydration scheduler: queued reminder for schedule %s to user %s", ws.id, ws.userID)
 	}
 }
@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"context"
+	"log"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// NotifySharingInvitationOutcome tells the owner that the grantee accepted or
+// declined an invitation, or that it expired unanswered.
+func NotifySharingInvitationOutcome(ctx context.Context, invitation *db.SharingInvitation) {
+	granteeName := displayName(invitation.GranteeFullname, invitation.GranteeUsername)
+
+	var title, body string
+	switch invitation.Status {
+	case db.SharingInvitationAccepted:
+		title = "Invitación aceptada"
+		body = granteeName + " aceptó tu invitación y ya puede acceder a tus tareas."
+	case db.SharingInvitationDeclined:
+		title = "Invitación rechazada"
+		body = granteeName + " rechazó tu invitación."
+	case db.SharingInvitationExpired:
+		title = "Invitación vencida"
+		body = granteeName + " no respondió tu invitación a tiempo."
+	default:
+		return
+	}
+
+	const url = "/profile"
+	data := map[string]string{
+		"invitation_id": invitation.ID,
+		"grant_id":      invitation.GrantID,
+		"status":        string(invitation.Status),
+	}
+	if _, err := db.CreateInboxNotice(ctx, invitation.OwnerUserID, db.InboxItemTypeSystem, title, body, url, data); err != nil {
+		log.Printf("sharing invitation: store notice for %s: %v", invitation.OwnerUserID, err)
+	}
+
+	payload := &NotificationPayload{
+		Title: title,
+		Body:  body,
+		Icon:  "/icon-192x192.png",
+		Badge: "/badge-72x72.png",
+		Tag:   "sharing-invitation-" + invitation.ID,
+		URL:   url,
+		Data:  data,
+	}
+	dedupeKey := "invitation-" + string(invitation.Status) + ":" + invitation.ID
+	if _, err := Enqueue(ctx, invitation.OwnerUserID, "sharing_invitation_"+string(invitation.Status), dedupeKey, payload); err != nil {
+		log.Printf("sharing invitation: queue push for %s: %v", invitation.OwnerUserID, err)
+	}
+}
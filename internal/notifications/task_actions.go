@@ -0,0 +1,67 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/auth"
+)
+
+// Actions a user can take straight from a task reminder.
+const (
+	ActionComplete = "complete"
+	ActionSnooze   = "snooze"
+	ActionSkip     = "skip"
+)
+
+const (
+	DefaultSnoozeDuration = 10 * time.Minute
+	MaxSnoozeDuration     = 2 * time.Hour
+)
+
+// BuildTaskReminderPayload builds the reminder sent ahead of a task's start
+// time. The payload carries a signed, single-use action token so the service
+// worker can complete, snooze or skip the task without a session cookie.
+func BuildTaskReminderPayload(userID string, taskID string, title string, description string) (*NotificationPayload, error) {
+	token, err := auth.CreateNotificationActionToken(userID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationPayload{
+		Title:              "Tarea pendiente: " + title,
+		Body:               description,
+		Icon:               "/icon-192x192.png",
+		Badge:              "/badge-72x72.png",
+		Tag:                "task-" + taskID,
+		RequireInteraction: true,
+		URL:                "/tasks",
+		TaskID:             taskID,
+		Data: map[string]string{
+			"kind":         "task_reminder",
+			"action_token": token,
+		},
+		Actions: []NotificationAction{
+			{Action: ActionComplete, Title: "Completar"},
+			{Action: ActionSnooze, Title: "Posponer"},
+			{Action: ActionSkip, Title: "Omitir"},
+		},
+	}, nil
+}
+
+// SnoozeTaskReminder queues a fresh reminder, with a new action token, to be
+// delivered after delay.
+func SnoozeTaskReminder(ctx context.Context, userID string, taskID string, title string, description string, delay time.Duration) (time.Time, error) {
+	payload, err := BuildTaskReminderPayload(userID, taskID, title, description)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	remindAt := time.Now().Add(delay)
+	dedupeKey := fmt.Sprintf("task-snooze:%s:%d", taskID, remindAt.Unix())
+	if _, err := EnqueueAt(ctx, userID, "task_reminder", dedupeKey, payload, remindAt); err != nil {
+		return time.Time{}, err
+	}
+	return remindAt, nil
+}
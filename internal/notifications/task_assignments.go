@@ -0,0 +1,91 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// NotifyTaskDelegated asks the assignee to accept or decline a task that
+// delegator handed to them.
+func NotifyTaskDelegated(ctx context.Context, task *db.DetailedTask, delegator *db.User) {
+	body := displayName(delegator.Fullname, delegator.Username) + " te asignó " + taskAssignmentTitle(task) + "."
+	sendTaskAssignmentNotice(ctx, task.AssigneeUserID, "task_assigned", "Nueva tarea asignada", body, task)
+}
+
+// NotifyTaskAssignmentOutcome tells whoever delegated the task that the
+// assignee accepted or declined it.
+func NotifyTaskAssignmentOutcome(ctx context.Context, task *db.DetailedTask, assignee *db.User) {
+	if task.AssignedByUserID == "" {
+		return
+	}
+
+	assigneeName := displayName(assignee.Fullname, assignee.Username)
+	var title, body string
+	switch task.AssignmentStatus {
+	case db.TaskAssignmentAccepted:
+		title = "Tarea aceptada"
+		body = assigneeName + " aceptó " + taskAssignmentTitle(task) + "."
+	case db.TaskAssignmentDeclined:
+		title = "Tarea rechazada"
+		body = assigneeName + " rechazó " + taskAssignmentTitle(task) + "."
+	default:
+		return
+	}
+
+	sendTaskAssignmentNotice(ctx, task.AssignedByUserID, "task_assignment_"+string(task.AssignmentStatus), title, body, task)
+}
+
+// NotifyDelegatedTaskCompleted tells the user who delegated the task, and its
+// owner when someone else delegated it, that the assignee completed it.
+func NotifyDelegatedTaskCompleted(ctx context.Context, task *db.DetailedTask, assignee *db.User) {
+	body := displayName(assignee.Fullname, assignee.Username) + " completó " + taskAssignmentTitle(task) + "."
+
+	recipients := []string{task.AssignedByUserID}
+	if task.UserID != task.AssignedByUserID {
+		recipients = append(recipients, task.UserID)
+	}
+	for _, userID := range recipients {
+		if userID == "" || userID == assignee.ID {
+			continue
+		}
+		sendTaskAssignmentNotice(ctx, userID, "task_assignment_completed", "Tarea completada", body, task)
+	}
+}
+
+// sendTaskAssignmentNotice stores an inbox notice and queues a push. There is
+// no dedupe key: a task can be delegated, answered and completed again.
+func sendTaskAssignmentNotice(ctx context.Context, userID string, kind string, title string, body string, task *db.DetailedTask) {
+	url := "/tasks/" + task.ID
+	data := map[string]string{
+		"task_id":           task.ID,
+		"assignment_status": string(task.AssignmentStatus),
+	}
+	if _, err := db.CreateInboxNotice(ctx, userID, db.InboxItemTypeSystem, title, body, url, data); err != nil {
+		log.Printf("task assignment: store notice for %s: %v", userID, err)
+	}
+
+	payload := &NotificationPayload{
+		Title:  title,
+		Body:   body,
+		Icon:   "/icon-192x192.png",
+		Badge:  "/badge-72x72.png",
+		Tag:    "task-assignment-" + task.ID,
+		URL:    url,
+		TaskID: task.ID,
+		Data:   data,
+	}
+	if _, err := Enqueue(ctx, userID, kind, "", payload); err != nil {
+		log.Printf("task assignment: queue push for %s: %v", userID, err)
+	}
+}
+
+func taskAssignmentTitle(task *db.DetailedTask) string {
+	title := strings.TrimSpace(task.Title)
+	if title == "" {
+		return "una tarea"
+	}
+	return "\"" + title + "\""
+}
@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/notifications"
+)
+
+func TestAccountabilityPartnerMissedTaskAlert(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	ownerUsername := fmt.Sprintf("acctowner_%d", suffix)
+	partnerUsername := fmt.Sprintf("acctpartner_%d", suffix)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, partnerUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, partnerUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	partnerCookie := registerPhase5User(t, router, partnerUsername, password)
+
+	schedule := createRouteSchedule(t, router, ownerCookie, "Accountable workout", "07:00", "08:00")
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	_, err = conn.Exec(context.Background(), `UPDATE schedule_tasks SET is_required = TRUE WHERE id = $1`, schedule.Data.Schedule.ID)
+	conn.Release()
+	if err != nil {
+		t.Fatalf("failed to mark schedule required: %v", err)
+	}
+
+	grantID := createPhase9Grant(t, router, ownerCookie, partnerUsername, "view")
+	accountabilityPath := "/api/v1/sharing/grants/" + grantID + "/accountability"
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPut, accountabilityPath, map[string]int{"grace_minutes": 60}, []*http.Cookie{partnerCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected partner setup 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, accountabilityPath, map[string]int{"grace_minutes": db.MaxAccountabilityGraceMinutes + 1}, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected long grace 400, got %d body = %s", status, body)
+	}
+	// Without the weekly summary the only alert left is the missed task, even
+	// when the test runs on a Sunday.
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, accountabilityPath, map[string]interface{}{
+		"grace_minutes":  60,
+		"weekly_summary": false,
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"grace_minutes":60`) {
+		t.Fatalf("set accountability status = %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("owner today status = %d body = %s", status, body)
+	}
+	var today routeTodayResponse
+	if err := json.Unmarshal([]byte(body), &today); err != nil {
+		t.Fatalf("decode today: %v body=%s", err, body)
+	}
+	task := findTaskBySchedule(t, today.Data.Tasks, schedule.Data.Schedule.ID)
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/tasks/"+task.ID, map[string]string{"status": "skipped"}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("skip task status = %d body = %s", status, body)
+	}
+
+	partnerships, err := db.ListAccountabilityPartnerships(context.Background())
+	if err != nil {
+		t.Fatalf("list partnerships: %v", err)
+	}
+	var grant *db.TaskAccessGrant
+	for _, candidate := range partnerships {
+		if candidate.ID == grantID {
+			grant = candidate
+		}
+	}
+	if grant == nil {
+		t.Fatalf("expected partnership for grant %s", grantID)
+	}
+
+	// Within the grace period the day is not judged yet.
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	midnight := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, time.Local)
+	notifications.CheckAccountabilityPartner(context.Background(), grant, midnight.Add(30*time.Minute))
+	if count := countAccountabilityAlerts(t, grantID); count != 0 {
+		t.Fatalf("expected no alerts during grace, got %d", count)
+	}
+	notifications.CheckAccountabilityPartner(context.Background(), grant, midnight.Add(2*time.Hour))
+	notifications.CheckAccountabilityPartner(context.Background(), grant, midnight.Add(3*time.Hour))
+	if count := countAccountabilityAlerts(t, grantID); count != 1 {
+		t.Fatalf("expected a single missed task alert, got %d", count)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/notifications/inbox?type=system", nil, []*http.Cookie{partnerCookie})
+	if status != http.StatusOK || !strings.Contains(body, "omitió") {
+		t.Fatalf("expected missed task notice for partner, got %d body = %s", status, body)
+	}
+
+	// The partner may step down; the grant survives.
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, accountabilityPath, nil, []*http.Cookie{partnerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("partner remove status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, accountabilityPath, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected second remove 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/sharing/shared-with-me", nil, []*http.Cookie{partnerCookie})
+	if status != http.StatusOK || !strings.Contains(body, grantID) || strings.Contains(body, `"accountability"`) {
+		t.Fatalf("expected grant without accountability, got %d body = %s", status, body)
+	}
+}
+
+func countAccountabilityAlerts(t *testing.T, grantID string) int {
+	t.Helper()
+
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	defer conn.Release()
+
+	var count int
+	if err := conn.QueryRow(context.Background(), `SELECT COUNT(*) FROM accountability_alerts WHERE grant_id = $1`, grantID).Scan(&count); err != nil {
+		t.Fatalf("count accountability alerts: %v", err)
+	}
+	return count
+}
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskMetricsAndHistoryBreakdowns(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("breakdown_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/schedules", map[string]interface{}{
+		"category":            "Study",
+		"frequency":           "daily",
+		"is_required":         true,
+		"priority_level":      "urgent",
+		"schedule_start_time": "08:00",
+		"schedule_end_time":   "09:00",
+		"title":               "Breakdown study",
+	}, []*http.Cookie{authCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create schedule status = %d body = %s", status, body)
+	}
+	var created routeScheduleResponse
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("decode schedule: %v", err)
+	}
+	createRouteSchedule(t, router, authCookie, "Breakdown other", "10:00", "11:00")
+
+	today := getRouteTodayTasks(t, router, authCookie)
+	study := findTaskBySchedule(t, today.Data.Tasks, created.Data.Schedule.ID)
+	updatePhase7Task(t, router, authCookie, study.ID, map[string]interface{}{"status": "completed"})
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/metrics", nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("metrics status = %d body = %s", status, body)
+	}
+	var metrics struct {
+		Data struct {
+			Metrics struct {
+				Breakdowns struct {
+					Category []struct {
+						Key        string   `json:"key"`
+						Total      int      `json:"total"`
+						Percentage float64  `json:"percentage"`
+						Trend      *float64 `json:"trend"`
+					} `json:"category"`
+					Required []struct {
+						Key        string  `json:"key"`
+						Percentage float64 `json:"percentage"`
+					} `json:"required"`
+				} `json:"breakdowns"`
+			} `json:"metrics"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &metrics); err != nil {
+		t.Fatalf("decode metrics: %v", err)
+	}
+	breakdowns := metrics.Data.Metrics.Breakdowns
+	found := false
+	for _, slice := range breakdowns.Category {
+		if slice.Key == "study" {
+			found = true
+			if slice.Total != 1 || slice.Percentage != 100 || slice.Trend != nil {
+				t.Fatalf("unexpected study slice: %s", body)
+			}
+		}
+	}
+	if !found || len(breakdowns.Category) != 2 || len(breakdowns.Required) != 2 {
+		t.Fatalf("unexpected breakdowns: %s", body)
+	}
+
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/history?breakdown=status", nil, []*http.Cookie{authCookie}); status != http.StatusBadRequest {
+		t.Fatalf("expected invalid breakdown 400, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/history?breakdown=priority", nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"breakdown":"priority"`) || !strings.Contains(body, `"key":"urgent","total":2,"completed":1,"percentage":50`) {
+		t.Fatalf("history breakdown status = %d body = %s", status, body)
+	}
+}
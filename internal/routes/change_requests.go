@@ -0,0 +1,346 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+	"github.com/vladwithcode/tasktracker/internal/notifications"
+	schedulesvc "github.com/vladwithcode/tasktracker/internal/schedules"
+	tasksvc "github.com/vladwithcode/tasktracker/internal/tasks"
+)
+
+type setSharingApprovalRequest struct {
+	RequiresApproval bool `json:"requires_approval"`
+}
+
+type reviewChangeRequestRequest struct {
+	Note string `json:"note"`
+}
+
+func registerChangeRequestRoutes(router *gin.RouterGroup) {
+	router.PUT("/sharing/grants/:id/approval", SetSharingGrantApproval)
+	router.GET("/sharing/change-requests", ListChangeRequests)
+	router.POST("/sharing/change-requests/:id/approve", ApproveChangeRequest)
+	router.POST("/sharing/change-requests/:id/reject", RejectChangeRequest)
+	router.DELETE("/sharing/change-requests/:id", CancelChangeRequest)
+}
+
+// SetSharingGrantApproval turns approval mode on or off for a grant. Requests
+// that are already pending stay pending either way.
+func SetSharingGrantApproval(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	grantID := c.Param("id")
+	if _, err := uuid.Parse(grantID); err != nil {
+		httpx.BadRequest(c, "ID de permiso inválido")
+		return
+	}
+
+	var request setSharingApprovalRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+
+	updated, err := db.SetTaskAccessGrantApproval(c.Request.Context(), authData.ID, grantID, request.RequiresApproval)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo actualizar el permiso")
+		log.Printf("failed to set sharing grant approval: %v", err)
+		return
+	}
+	if !updated {
+		httpx.NotFound(c, "Permiso no encontrado")
+		return
+	}
+
+	respondOwnedSharingGrant(c, authData.ID, grantID, "Modo de aprobación actualizado")
+}
+
+// ListChangeRequests lists the requests sent to the user (role=incoming, the
+// default) or the ones they sent (role=sent), optionally filtered by status.
+func ListChangeRequests(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	status, ok := db.NormalizeChangeRequestStatus(strings.TrimSpace(c.Query("status")))
+	if !ok {
+		httpx.BadRequest(c, "Estado de solicitud inválido")
+		return
+	}
+
+	var requests []*db.ChangeRequest
+	switch c.DefaultQuery("role", "incoming") {
+	case "incoming":
+		requests, err = db.ListChangeRequestsForOwner(c.Request.Context(), authData.ID, status)
+	case "sent":
+		requests, err = db.ListChangeRequestsByRequester(c.Request.Context(), authData.ID, status)
+	default:
+		httpx.BadRequest(c, "Rol de solicitud inválido")
+		return
+	}
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron recuperar las solicitudes")
+		log.Printf("failed to list change requests: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"change_requests": requests}, "Solicitudes recuperadas")
+}
+
+// ApproveChangeRequest applies a pending request through the same service
+// path the owner would use to make the change. The request is claimed first
+// so it is applied once; if applying fails it goes back to pending.
+func ApproveChangeRequest(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	request, ok := loadPendingChangeRequest(c, authData.ID)
+	if !ok {
+		return
+	}
+
+	claimed, err := db.ResolveChangeRequest(c.Request.Context(), request.ID, authData.ID, db.ChangeRequestApproved, "")
+	if err != nil {
+		httpx.ServerError(c, "No se pudo aprobar la solicitud")
+		log.Printf("failed to approve change request: %v", err)
+		return
+	}
+	if !claimed {
+		changeRequestNotPending(c)
+		return
+	}
+
+	data := gin.H{}
+	switch request.Kind {
+	case db.ChangeRequestTaskUpdate:
+		service := tasksvc.NewService(tasksvc.NewRepository())
+		task, applyErr := service.ApplyChangeRequest(c.Request.Context(), authData, request)
+		err = applyErr
+		data["task"] = task
+	case db.ChangeRequestScheduleCreate:
+		service := schedulesvc.NewService(schedulesvc.NewRepository())
+		schedule, applyErr := service.ApplyChangeRequest(c.Request.Context(), request)
+		err = applyErr
+		data["schedule"] = schedule
+	}
+	if err != nil {
+		if reopenErr := db.ReopenChangeRequest(c.Request.Context(), request.ID); reopenErr != nil {
+			log.Printf("failed to reopen change request %s: %v", request.ID, reopenErr)
+		}
+		if errors.Is(err, tasksvc.ErrNotFound) {
+			httpx.NotFound(c, "Tarea no encontrada")
+			return
+		}
+		httpx.ServerError(c, "No se pudo aplicar la solicitud")
+		log.Printf("failed to apply change request %s: %v", request.ID, err)
+		return
+	}
+
+	respondResolvedChangeRequest(c, request.ID, data, "Solicitud aprobada")
+}
+
+// RejectChangeRequest discards a pending request, with an optional note for
+// the requester.
+func RejectChangeRequest(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	var review reviewChangeRequestRequest
+	if err := c.ShouldBindJSON(&review); err != nil && !errors.Is(err, io.EOF) {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	note := strings.TrimSpace(review.Note)
+	if utf8.RuneCountInString(note) > db.MaxChangeRequestNoteLength {
+		httpx.BadRequest(c, "La nota es demasiado larga")
+		return
+	}
+
+	request, ok := loadPendingChangeRequest(c, authData.ID)
+	if !ok {
+		return
+	}
+
+	rejected, err := db.ResolveChangeRequest(c.Request.Context(), request.ID, authData.ID, db.ChangeRequestRejected, note)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo rechazar la solicitud")
+		log.Printf("failed to reject change request: %v", err)
+		return
+	}
+	if !rejected {
+		changeRequestNotPending(c)
+		return
+	}
+
+	respondResolvedChangeRequest(c, request.ID, gin.H{}, "Solicitud rechazada")
+}
+
+// CancelChangeRequest lets the requester withdraw a request the owner has not
+// reviewed yet.
+func CancelChangeRequest(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	requestID := c.Param("id")
+	if _, err := uuid.Parse(requestID); err != nil {
+		httpx.BadRequest(c, "ID de solicitud inválido")
+		return
+	}
+	request, err := db.GetChangeRequest(c.Request.Context(), requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.NotFound(c, "Solicitud no encontrada")
+			return
+		}
+		httpx.ServerError(c, "No se pudo recuperar la solicitud")
+		log.Printf("failed to get change request: %v", err)
+		return
+	}
+	if request.RequesterUserID != authData.ID {
+		httpx.NotFound(c, "Solicitud no encontrada")
+		return
+	}
+
+	cancelled, err := db.CancelChangeRequest(c.Request.Context(), request.ID, authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo cancelar la solicitud")
+		log.Printf("failed to cancel change request: %v", err)
+		return
+	}
+	if !cancelled {
+		changeRequestNotPending(c)
+		return
+	}
+
+	httpx.OK(c, gin.H{"change_request_id": request.ID}, "Solicitud cancelada")
+}
+
+// loadPendingChangeRequest reads the :id request and checks that ownerUserID
+// may review it. Requests of other owners look missing.
+func loadPendingChangeRequest(c *gin.Context, ownerUserID string) (*db.ChangeRequest, bool) {
+	requestID := c.Param("id")
+	if _, err := uuid.Parse(requestID); err != nil {
+		httpx.BadRequest(c, "ID de solicitud inválido")
+		return nil, false
+	}
+
+	request, err := db.GetChangeRequest(c.Request.Context(), requestID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.NotFound(c, "Solicitud no encontrada")
+			return nil, false
+		}
+		httpx.ServerError(c, "No se pudo recuperar la solicitud")
+		log.Printf("failed to get change request: %v", err)
+		return nil, false
+	}
+	if request.OwnerUserID != ownerUserID {
+		httpx.NotFound(c, "Solicitud no encontrada")
+		return nil, false
+	}
+	if request.Status != db.ChangeRequestPending {
+		changeRequestNotPending(c)
+		return nil, false
+	}
+
+	return request, true
+}
+
+// respondResolvedChangeRequest reloads a reviewed request, tells the
+// requester about it and responds with it next to data.
+func respondResolvedChangeRequest(c *gin.Context, requestID string, data gin.H, okMsg string) {
+	request, err := db.GetChangeRequest(c.Request.Context(), requestID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar la solicitud")
+		log.Printf("failed to reload change request: %v", err)
+		return
+	}
+
+	go notifications.NotifyChangeRequestResolved(context.WithoutCancel(c.Request.Context()), request)
+
+	data["change_request"] = request
+	httpx.OK(c, data, okMsg)
+}
+
+func changeRequestNotPending(c *gin.Context) {
+	httpx.Conflict(c, "change_request_not_pending", "La solicitud ya fue resuelta")
+}
+
+// proposeTaskUpdate stores a grantee's task edit for the owner to review and
+// responds 202 with the pending request.
+func proposeTaskUpdate(c *gin.Context, authData *auth.Auth, taskID string, input tasksvc.UpdateTaskInput) {
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	request, err := service.ProposeUpdate(c.Request.Context(), authData, taskID, input)
+	if err != nil {
+		if errors.Is(err, tasksvc.ErrNotFound) {
+			httpx.NotFound(c, "Tarea no encontrada")
+			return
+		}
+		if errors.Is(err, tasksvc.ErrForbidden) {
+			httpx.Forbidden(c, "No tienes permisos para editar esta tarea")
+			return
+		}
+		if errors.Is(err, tasksvc.ErrNoChanges) {
+			httpx.BadRequest(c, "La solicitud no contiene cambios")
+			return
+		}
+		httpx.ServerError(c, "No se pudo enviar la solicitud")
+		log.Printf("failed to propose task update: %v", err)
+		return
+	}
+
+	respondProposedChangeRequest(c, request.ID)
+}
+
+// proposeSchedule stores a grantee's new schedule for the owner to review
+// and responds 202 with the pending request.
+func proposeSchedule(c *gin.Context, grant *db.TaskAccessGrant, requesterUserID string, schedule *db.ScheduleTask) {
+	service := schedulesvc.NewService(schedulesvc.NewRepository())
+	request, err := service.ProposeCreateForOwner(c.Request.Context(), grant, requesterUserID, schedule)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo enviar la solicitud")
+		log.Printf("failed to propose schedule: %v", err)
+		return
+	}
+
+	respondProposedChangeRequest(c, request.ID)
+}
+
+func respondProposedChangeRequest(c *gin.Context, requestID string) {
+	request, err := db.GetChangeRequest(c.Request.Context(), requestID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar la solicitud")
+		log.Printf("failed to reload change request: %v", err)
+		return
+	}
+
+	go notifications.NotifyChangeRequestCreated(context.WithoutCancel(c.Request.Context()), request)
+
+	httpx.Accepted(c, gin.H{"change_request": request}, "Cambio enviado para aprobación")
+}
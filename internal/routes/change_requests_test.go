@@ -0,0 +1,166 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+type routeChangeRequestResponse struct {
+	Data struct {
+		ChangeRequest db.ChangeRequest `json:"change_request"`
+	} `json:"data"`
+}
+
+func TestSharingApprovalModeChangeRequests(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	ownerUsername := fmt.Sprintf("approvalowner_%d", suffix)
+	granteeUsername := fmt.Sprintf("approvalgrantee_%d", suffix)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, granteeUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, granteeUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	granteeCookie := registerPhase5User(t, router, granteeUsername, password)
+	ownerID := getPhase9UserID(t, ownerUsername)
+
+	schedule := createRouteSchedule(t, router, ownerCookie, "Approval chores", "10:00", "11:00")
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("owner today status = %d body = %s", status, body)
+	}
+	var today routeTodayResponse
+	if err := json.Unmarshal([]byte(body), &today); err != nil {
+		t.Fatalf("decode today: %v body=%s", err, body)
+	}
+	task := findTaskBySchedule(t, today.Data.Tasks, schedule.Data.Schedule.ID)
+	taskPath := "/api/v1/tasks/" + task.ID
+
+	grantID := createPhase9Grant(t, router, ownerCookie, granteeUsername, "manage")
+	approvalPath := "/api/v1/sharing/grants/" + grantID + "/approval"
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, approvalPath, map[string]bool{"requires_approval": true}, []*http.Cookie{granteeCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected grantee approval toggle 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, approvalPath, map[string]bool{"requires_approval": true}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"requires_approval":true`) {
+		t.Fatalf("enable approval status = %d body = %s", status, body)
+	}
+
+	// Delegating is an edit too: the grantee cannot hand the task to
+	// themselves and then edit it as its assignee.
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath+"/assignee", map[string]string{"user": granteeUsername}, []*http.Cookie{granteeCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "sharing_approval_required") {
+		t.Fatalf("expected grantee delegate 403, got %d body = %s", status, body)
+	}
+
+	// The grantee's edit is held for review; the task does not change.
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath, map[string]string{"status": "completed"}, []*http.Cookie{granteeCookie})
+	if status != http.StatusAccepted {
+		t.Fatalf("expected grantee edit 202, got %d body = %s", status, body)
+	}
+	var proposed routeChangeRequestResponse
+	if err := json.Unmarshal([]byte(body), &proposed); err != nil {
+		t.Fatalf("decode change request: %v body=%s", err, body)
+	}
+	updateRequest := proposed.Data.ChangeRequest
+	if updateRequest.Status != db.ChangeRequestPending || len(updateRequest.Diff) != 1 || updateRequest.Diff[0].Field != "status" {
+		t.Fatalf("unexpected change request: %s", body)
+	}
+	current, err := db.GetTaskByID(context.Background(), task.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if current.Status == db.TaskStatusCompleted {
+		t.Fatalf("expected task to stay unchanged before approval")
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/sharing/change-requests?status=pending", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, updateRequest.ID) {
+		t.Fatalf("owner incoming status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/change-requests/"+updateRequest.ID+"/approve", nil, []*http.Cookie{granteeCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected grantee approve 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/change-requests/"+updateRequest.ID+"/approve", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"status":"approved"`) {
+		t.Fatalf("approve status = %d body = %s", status, body)
+	}
+	current, err = db.GetTaskByID(context.Background(), task.ID)
+	if err != nil {
+		t.Fatalf("get task: %v", err)
+	}
+	if current.Status != db.TaskStatusCompleted {
+		t.Fatalf("expected approved change to complete the task, got %s", current.Status)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/change-requests/"+updateRequest.ID+"/approve", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusConflict {
+		t.Fatalf("expected second approve 409, got %d body = %s", status, body)
+	}
+
+	// A proposed schedule is only created once approved; rejections carry a note.
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/schedules", map[string]interface{}{
+		"frequency":           "daily",
+		"owner_user_id":       ownerID,
+		"schedule_end_time":   "19:00",
+		"schedule_start_time": "18:00",
+		"title":               "Proposed reading",
+	}, []*http.Cookie{granteeCookie})
+	if status != http.StatusAccepted || !strings.Contains(body, "Proposed reading") {
+		t.Fatalf("expected proposed schedule 202, got %d body = %s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &proposed); err != nil {
+		t.Fatalf("decode change request: %v body=%s", err, body)
+	}
+	scheduleRequest := proposed.Data.ChangeRequest
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/change-requests/"+scheduleRequest.ID+"/approve", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"schedule"`) {
+		t.Fatalf("approve schedule status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/schedules", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, "Proposed reading") {
+		t.Fatalf("expected approved schedule for owner, got %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath, map[string]string{"notes": "done twice"}, []*http.Cookie{granteeCookie})
+	if status != http.StatusAccepted {
+		t.Fatalf("expected grantee notes edit 202, got %d body = %s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &proposed); err != nil {
+		t.Fatalf("decode change request: %v body=%s", err, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/change-requests/"+proposed.Data.ChangeRequest.ID+"/reject", map[string]string{"note": "Not needed"}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"status":"rejected"`) || !strings.Contains(body, "Not needed") {
+		t.Fatalf("reject status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/sharing/change-requests?role=sent", nil, []*http.Cookie{granteeCookie})
+	if status != http.StatusOK || strings.Count(body, `"kind"`) != 3 {
+		t.Fatalf("grantee sent status = %d body = %s", status, body)
+	}
+
+	// Without approval mode the grantee edits directly again.
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, approvalPath, map[string]bool{"requires_approval": false}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"requires_approval":false`) {
+		t.Fatalf("disable approval status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath, map[string]string{"notes": "direct"}, []*http.Cookie{granteeCookie})
+	if status != http.StatusOK {
+		t.Fatalf("expected direct grantee edit 200, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath+"/assignee", map[string]string{"user": granteeUsername}, []*http.Cookie{granteeCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected grantee self delegate 400, got %d body = %s", status, body)
+	}
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/events"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+)
+
+// eventsHeartbeat keeps idle streams alive through proxies that close quiet
+// connections.
+const eventsHeartbeat = 25 * time.Second
+
+func registerEventRoutes(router *gin.RouterGroup) {
+	router.GET("/events", StreamEvents)
+}
+
+// StreamEvents is a Server-Sent Events stream of the current user's task,
+// schedule, inbox and sharing invitation changes. Each event is named after
+// its type and carries the changed row id; clients refetch what they show.
+func StreamEvents(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	stream, unsubscribe := events.Default.Subscribe(authData.ID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("ready", gin.H{"user_id": authData.ID})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-stream:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"at": time.Now()})
+			return true
+		}
+	})
+}
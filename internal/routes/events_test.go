@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/events"
+)
+
+func TestEventStreamDeliversOwnEvents(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("eventsowner_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+
+	authCookie := registerPhase5User(t, router, username, password)
+	userID := getPhase8UserID(t, username)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/events", nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	request.AddCookie(authCookie)
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("open event stream: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("event stream status = %d", response.StatusCode)
+	}
+
+	reader := bufio.NewReader(response.Body)
+	readUntil := func(prefix string) string {
+		t.Helper()
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event stream waiting for %q: %v", prefix, err)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line
+			}
+		}
+	}
+
+	readUntil("event:ready")
+	events.Default.Publish(db.UserEvent{UserID: "someone-else", Type: "task", Op: "update", ID: "other-task"})
+	events.Default.Publish(db.UserEvent{UserID: userID, Type: "inbox", Op: "insert", ID: "ping-1"})
+
+	readUntil("event:inbox")
+	if data := readUntil("data:"); !strings.Contains(data, "ping-1") {
+		t.Fatalf("expected own event payload, got %q", data)
+	}
+}
+
+func TestEventStreamRequiresAuth(t *testing.T) {
+	router := setupAuthRouteTest(t)
+
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/events", nil, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without session, got %d body = %s", status, body)
+	}
+}
@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTaskHeatmap(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("heatmap_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/schedules", map[string]interface{}{
+		"category":            "Reading",
+		"frequency":           "daily",
+		"schedule_start_time": "08:00",
+		"schedule_end_time":   "09:00",
+		"title":               "Heatmap reading",
+	}, []*http.Cookie{authCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create schedule status = %d body = %s", status, body)
+	}
+	var reading routeScheduleResponse
+	if err := json.Unmarshal([]byte(body), &reading); err != nil {
+		t.Fatalf("decode schedule: %v", err)
+	}
+	other := createRouteSchedule(t, router, authCookie, "Heatmap other", "10:00", "11:00")
+	today := getRouteTodayTasks(t, router, authCookie)
+	task := findTaskBySchedule(t, today.Data.Tasks, reading.Data.Schedule.ID)
+	updatePhase7Task(t, router, authCookie, task.ID, map[string]interface{}{"status": "completed"})
+
+	for _, path := range []string{
+		"/api/v1/tasks/heatmap?year=1999",
+		"/api/v1/tasks/heatmap?year=soon",
+		"/api/v1/tasks/heatmap?schedule_id=not-a-uuid",
+	} {
+		if status, body, _, _ := performJSONPayload(router, http.MethodGet, path, nil, []*http.Cookie{authCookie}); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body = %s", path, status, body)
+		}
+	}
+
+	type heatmapResponse struct {
+		Data struct {
+			Heatmap struct {
+				Year int `json:"year"`
+				Days []struct {
+					Date      string `json:"date"`
+					Total     int    `json:"total"`
+					Completed int    `json:"completed"`
+					Level     int    `json:"level"`
+				} `json:"days"`
+			} `json:"heatmap"`
+		} `json:"data"`
+	}
+	todayKey := time.Now().Format("2006-01-02")
+	getToday := func(query string) (int, int, int) {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/heatmap"+query, nil, []*http.Cookie{authCookie})
+		if status != http.StatusOK {
+			t.Fatalf("heatmap%s status = %d body = %s", query, status, body)
+		}
+		var response heatmapResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("decode heatmap: %v", err)
+		}
+		if response.Data.Heatmap.Year != time.Now().Year() || len(response.Data.Heatmap.Days) < 365 {
+			t.Fatalf("unexpected heatmap year: %s", body)
+		}
+		for _, day := range response.Data.Heatmap.Days {
+			if day.Date == todayKey {
+				return day.Total, day.Completed, day.Level
+			}
+		}
+		t.Fatalf("heatmap%s has no cell for today", query)
+		return 0, 0, 0
+	}
+
+	if total, completed, level := getToday(""); total != 2 || completed != 1 || level != 3 {
+		t.Fatalf("today = %d/%d level %d, want 1/2 at level 3", completed, total, level)
+	}
+	if total, completed, level := getToday("?category=reading"); total != 1 || completed != 1 || level != 4 {
+		t.Fatalf("reading today = %d/%d level %d, want 1/1 at level 4", completed, total, level)
+	}
+	if total, completed, level := getToday("?schedule_id=" + other.Data.Schedule.ID); total != 1 || completed != 0 || level != 0 {
+		t.Fatalf("other today = %d/%d level %d, want 0/1 at level 0", completed, total, level)
+	}
+}
@@ -0,0 +1,82 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTaskHistoryGranularity(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("granularity_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+
+	first := createRouteSchedule(t, router, authCookie, "Granularity first", "08:00", "09:00")
+	createRouteSchedule(t, router, authCookie, "Granularity second", "10:00", "11:00")
+	today := getRouteTodayTasks(t, router, authCookie)
+	task := findTaskBySchedule(t, today.Data.Tasks, first.Data.Schedule.ID)
+	updatePhase7Task(t, router, authCookie, task.ID, map[string]interface{}{"status": "completed"})
+
+	for _, path := range []string{
+		"/api/v1/tasks/history?granularity=year",
+		"/api/v1/tasks/history?granularity=week&week_start=7",
+		"/api/v1/tasks/history?granularity=day&from=2025-01-01&to=2025-12-31",
+	} {
+		if status, body, _, _ := performJSONPayload(router, http.MethodGet, path, nil, []*http.Cookie{authCookie}); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body = %s", path, status, body)
+		}
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 12, 0, 0, 0, time.Local).AddDate(0, -11, 0)
+	url := fmt.Sprintf("/api/v1/tasks/history?granularity=month&from=%s&to=%s", from.Format("2006-01-02"), now.Format("2006-01-02"))
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, url, nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("monthly history status = %d body = %s", status, body)
+	}
+	var monthly phase7HistoryEnvelope
+	if err := json.Unmarshal([]byte(body), &monthly); err != nil {
+		t.Fatalf("decode monthly history: %v", err)
+	}
+	days := monthly.Data.History.Days
+	if len(days) != 12 {
+		t.Fatalf("expected 12 months, got %d body = %s", len(days), body)
+	}
+	current := days[len(days)-1]
+	if current.Date != time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006-01-02") || current.Total != 2 || current.Completed != 1 || current.Percentage != 50 {
+		t.Fatalf("unexpected current month: %+v", current)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/history?granularity=week&week_start=0", nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("weekly history status = %d body = %s", status, body)
+	}
+	var weekly struct {
+		Data struct {
+			History struct {
+				Granularity string `json:"granularity"`
+				WeekStart   *int   `json:"week_start"`
+				Days        []struct {
+					Date    string `json:"date"`
+					EndDate string `json:"end_date"`
+				} `json:"days"`
+			} `json:"history"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &weekly); err != nil {
+		t.Fatalf("decode weekly history: %v", err)
+	}
+	history := weekly.Data.History
+	if history.Granularity != "week" || history.WeekStart == nil || *history.WeekStart != 0 || len(history.Days) == 0 || len(history.Days) > 2 {
+		t.Fatalf("unexpected weekly history: %s", body)
+	}
+	for _, week := range history.Days[1:] {
+		if start, _ := time.Parse("2006-01-02", week.Date); start.Weekday() != time.Sunday {
+			t.Fatalf("week %s does not start on Sunday: %s", week.Date, body)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package routes
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+)
+
+func registerInsightRoutes(router *gin.RouterGroup) {
+	router.GET("/insights", GetInsights)
+	router.POST("/insights/:id/dismiss", DismissInsight)
+}
+
+// GetInsights lists the open insights of the authenticated user, newest
+// first. Query: ?include_dismissed=true adds the dismissed ones.
+func GetInsights(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	includeDismissed := c.Query("include_dismissed") == "true"
+	insights, err := db.ListUserInsights(c.Request.Context(), authData.ID, includeDismissed)
+	if err != nil {
+		httpx.ServerError(c, "Error al obtener los hallazgos")
+		log.Printf("failed to list insights: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"insights": insights}, "Hallazgos obtenidos")
+}
+
+// DismissInsight hides an insight until it is detected again after
+// db.InsightDismissCooldownDays.
+func DismissInsight(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	insightID := strings.TrimSpace(c.Param("id"))
+	if _, err := uuid.Parse(insightID); err != nil {
+		httpx.BadRequest(c, "ID de hallazgo inválido")
+		return
+	}
+
+	dismissed, err := db.DismissInsight(c.Request.Context(), authData.ID, insightID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo descartar el hallazgo")
+		log.Printf("failed to dismiss insight: %v\n", err)
+		return
+	}
+	if !dismissed {
+		httpx.NotFound(c, "Hallazgo no encontrado")
+		return
+	}
+
+	httpx.OK(c, gin.H{"insight_id": insightID}, "Hallazgo descartado")
+}
@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/insights"
+)
+
+func TestInsights(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("insights_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+	userID := getPhase9UserID(t, username)
+	schedule := createRouteSchedule(t, router, authCookie, "Insight run", "07:00", "08:00")
+
+	// Eight weeks of runs: skipped every Friday, finished 40 minutes late on
+	// the other days.
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	now := time.Now()
+	for back := 1; back <= 56; back++ {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local).AddDate(0, 0, -back)
+		status := "completed"
+		var completedAt *time.Time
+		if day.Weekday() == time.Friday {
+			status = "skipped"
+		} else {
+			finished := time.Date(day.Year(), day.Month(), day.Day(), 8, 40, 0, 0, time.Local)
+			completedAt = &finished
+		}
+		_, err = conn.Exec(
+			context.Background(),
+			`INSERT INTO tasks (user_id, schedule_task_id, date, status, status_level, completed_at, actual_end)
+			 VALUES ($1, $2, $3, $4, $4::task_status, $5, $5)`,
+			userID,
+			schedule.Data.Schedule.ID,
+			day,
+			status,
+			completedAt,
+		)
+		if err != nil {
+			conn.Release()
+			t.Fatalf("failed to insert past task: %v", err)
+		}
+	}
+	conn.Release()
+
+	found, err := insights.AnalyzeUser(context.Background(), userID, now)
+	if err != nil {
+		t.Fatalf("analyze user: %v", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 insights, got %+v", found)
+	}
+	// A second run refreshes the same insights without announcing them again.
+	if _, err := insights.AnalyzeUser(context.Background(), userID, now); err != nil {
+		t.Fatalf("analyze user again: %v", err)
+	}
+
+	type insightsResponse struct {
+		Data struct {
+			Insights []struct {
+				ID       string          `json:"id"`
+				Kind     string          `json:"kind"`
+				Subject  string          `json:"subject"`
+				Body     string          `json:"body"`
+				Evidence json.RawMessage `json:"evidence"`
+			} `json:"insights"`
+		} `json:"data"`
+	}
+	getInsights := func(query string) insightsResponse {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/insights"+query, nil, []*http.Cookie{authCookie})
+		if status != http.StatusOK {
+			t.Fatalf("insights%s status = %d body = %s", query, status, body)
+		}
+		var response insightsResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("decode insights: %v", err)
+		}
+		return response
+	}
+
+	listed := getInsights("").Data.Insights
+	if len(listed) != 2 {
+		t.Fatalf("expected 2 listed insights, got %+v", listed)
+	}
+	var skipID string
+	for _, insight := range listed {
+		switch insight.Kind {
+		case string(db.InsightKindWeekdaySkip):
+			skipID = insight.ID
+			var evidence insights.WeekdaySkipEvidence
+			if err := json.Unmarshal(insight.Evidence, &evidence); err != nil {
+				t.Fatalf("decode skip evidence: %v", err)
+			}
+			if insight.Subject != "5" || evidence.WeekdayTotal != 8 || evidence.WeekdayMissed != 8 {
+				t.Fatalf("unexpected skip insight: %+v %s", insight, insight.Evidence)
+			}
+		case string(db.InsightKindLateFinish):
+			if !strings.Contains(insight.Body, "40 minutos tarde") {
+				t.Fatalf("unexpected late finish insight: %+v", insight)
+			}
+		default:
+			t.Fatalf("unexpected insight kind %q", insight.Kind)
+		}
+	}
+
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/notifications/inbox?type=system", nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("inbox status = %d body = %s", status, body)
+	}
+	if count := strings.Count(body, `"insight_id"`); count != 2 {
+		t.Fatalf("expected 2 insight notices, got %d body = %s", count, body)
+	}
+
+	if status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/insights/not-a-uuid/dismiss", nil, []*http.Cookie{authCookie}); status != http.StatusBadRequest {
+		t.Fatalf("expected invalid id 400, got %d body = %s", status, body)
+	}
+	if status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/insights/"+uuid.NewString()+"/dismiss", nil, []*http.Cookie{authCookie}); status != http.StatusNotFound {
+		t.Fatalf("expected unknown id 404, got %d body = %s", status, body)
+	}
+	if status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/insights/"+skipID+"/dismiss", nil, []*http.Cookie{authCookie}); status != http.StatusOK {
+		t.Fatalf("dismiss status = %d body = %s", status, body)
+	}
+
+	// The dismissed insight stays hidden while it is still detected.
+	if _, err := insights.AnalyzeUser(context.Background(), userID, now); err != nil {
+		t.Fatalf("analyze user after dismiss: %v", err)
+	}
+	if listed := getInsights("").Data.Insights; len(listed) != 1 || listed[0].Kind != string(db.InsightKindLateFinish) {
+		t.Fatalf("expected only the late finish insight, got %+v", listed)
+	}
+	if listed := getInsights("?include_dismissed=true").Data.Insights; len(listed) != 2 {
+		t.Fatalf("expected 2 insights with dismissed, got %+v", listed)
+	}
+}
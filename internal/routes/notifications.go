@@ -1,20 +1,27 @@
 package routes
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/vladwithcode/tasktracker/internal/auth"
 	"github.com/vladwithcode/tasktracker/internal/db"
 	"github.com/vladwithcode/tasktracker/internal/httpx"
 	"github.com/vladwithcode/tasktracker/internal/notifications"
+	tasksvc "github.com/vladwithcode/tasktracker/internal/tasks"
 )
 
 type pushSubscriptionRequest struct {
 	Endpoint string `json:"endpoint"`
+	Label    string `json:"label"`
 	Keys     struct {
 		P256dh string `json:"p256dh"`
 		Auth   string `json:"auth"`
@@ -25,18 +32,56 @@ type unsubscribeRequest struct {
 	Endpoint string `json:"endpoint"`
 }
 
+type pushDeviceRequest struct {
+	Label *string `json:"label"`
+}
+
+// maxDeviceLabelLength and maxUserAgentLength keep device metadata bounded;
+// both are display-only.
+const (
+	maxDeviceLabelLength = 80
+	maxUserAgentLength   = 512
+)
+
+type digestSettingsRequest struct {
+	MorningEnabled *bool   `json:"morning_enabled"`
+	MorningTime    *string `json:"morning_time"`
+	EveningEnabled *bool   `json:"evening_enabled"`
+	EveningTime    *string `json:"evening_time"`
+}
+
+type notificationActionRequest struct {
+	Token         string `json:"token"`
+	Action        string `json:"action"`
+	SnoozeMinutes *int   `json:"snooze_minutes"`
+}
+
 func registerPublicNotificationRoutes(router *gin.RouterGroup) {
 	router.GET("/notifications/vapid-public-key", GetVAPIDPublicKey)
 	router.GET("/notifications/vapid-key", GetVAPIDPublicKey)
 	router.GET("/vapid-key", GetVAPIDPublicKey)
+	// Authenticated by the signed action token in the body, not the session
+	// cookie, because service workers act on behalf of a closed app.
+	router.POST("/notifications/actions", HandleNotificationAction)
 }
 
 func registerNotificationRoutes(router *gin.RouterGroup) {
 	router.GET("/notifications/inbox", GetNotificationInbox)
+	router.GET("/notifications/inbox/unread-count", GetNotificationInboxUnreadCount)
+	router.POST("/notifications/inbox/read-all", MarkAllNotificationInboxItemsRead)
 	router.POST("/notifications/inbox/:id/read", MarkNotificationInboxItemRead)
+	router.POST("/notifications/inbox/:id/archive", ArchiveNotificationInboxItem)
+	router.DELETE("/notifications/inbox/:id", DeleteNotificationInboxItem)
 	router.POST("/notifications/subscriptions", SubscribeToPush)
 	router.DELETE("/notifications/subscriptions", UnsubscribeFromPush)
 	router.POST("/notifications/test", SendTestNotification)
+	router.GET("/notifications/digests", GetDigestSettings)
+	router.PUT("/notifications/digests", UpdateDigestSettings)
+	router.GET("/notifications/deliveries", GetNotificationDeliveryLog)
+	router.GET("/notifications/devices", ListPushDevices)
+	router.PUT("/notifications/devices/:id", UpdatePushDevice)
+	router.DELETE("/notifications/devices/:id", DeletePushDevice)
+	router.POST("/notifications/devices/:id/test", SendPushDeviceTest)
 
 	// Legacy aliases kept for the existing frontend code while the canonical
 	// `/notifications/subscriptions` path rolls out.
@@ -77,6 +122,7 @@ func SubscribeToPush(c *gin.Context) {
 		httpx.BadRequest(c, err.Error())
 		return
 	}
+	subscription.UserAgent = truncateRunes(strings.TrimSpace(c.Request.UserAgent()), maxUserAgentLength)
 
 	if err := db.SaveSubscription(c.Request.Context(), authData.ID, subscription); err != nil {
 		httpx.ServerError(c, "No se pudo guardar la suscripción")
@@ -127,23 +173,10 @@ func SendTestNotification(c *gin.Context) {
 		return
 	}
 
-	payload := &notifications.NotificationPayload{
-		Title:              "Routine Ritual",
-		Body:               "Notificación de prueba activada correctamente.",
-		Icon:               "/icon-192x192.png",
-		Badge:              "/badge-72x72.png",
-		Tag:                "test-notification",
-		RequireInteraction: false,
-		URL:                "/tasks",
-		Actions: []notifications.NotificationAction{
-			{Action: "view", Title: "Ver tareas"},
-		},
-	}
-
 	sentCount, err := notifications.SendNotificationToUserWithConfig(
 		c.Request.Context(),
 		authData.ID,
-		payload,
+		testNotificationPayload(),
 		config,
 	)
 	if err != nil {
@@ -159,6 +192,185 @@ func SendTestNotification(c *gin.Context) {
 	httpx.OK(c, gin.H{"sent_count": sentCount}, "Notificación de prueba enviada")
 }
 
+func ListPushDevices(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	devices, err := db.GetSubscriptionsByUserID(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar los dispositivos")
+		log.Printf("failed to list push devices: %v", err)
+		return
+	}
+
+	payload := make([]gin.H, 0, len(devices))
+	for _, device := range devices {
+		payload = append(payload, pushDevicePayload(device))
+	}
+
+	httpx.OK(c, gin.H{"devices": payload}, "Dispositivos recuperados")
+}
+
+func UpdatePushDevice(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	deviceID := strings.TrimSpace(c.Param("id"))
+	if _, err := uuid.Parse(deviceID); err != nil {
+		httpx.BadRequest(c, "ID de dispositivo inválido")
+		return
+	}
+
+	var request pushDeviceRequest
+	if err := c.ShouldBindJSON(&request); err != nil || request.Label == nil {
+		httpx.BadRequest(c, "Nombre de dispositivo requerido")
+		return
+	}
+	label := strings.TrimSpace(*request.Label)
+	if len([]rune(label)) > maxDeviceLabelLength {
+		httpx.BadRequest(c, "Nombre de dispositivo demasiado largo")
+		return
+	}
+
+	ok, err := db.UpdateSubscriptionLabel(c.Request.Context(), authData.ID, deviceID, label)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo actualizar el dispositivo")
+		log.Printf("failed to update push device: %v", err)
+		return
+	}
+	if !ok {
+		httpx.NotFound(c, "Dispositivo no encontrado")
+		return
+	}
+
+	device, err := db.GetUserSubscriptionByID(c.Request.Context(), authData.ID, deviceID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo actualizar el dispositivo")
+		log.Printf("failed to reload push device: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"device": pushDevicePayload(device)}, "Dispositivo actualizado")
+}
+
+// pushDevicePayload describes a device for its owner. The subscription keys
+// stay server-side: they are the secrets push messages are encrypted with.
+func pushDevicePayload(device *db.PushSubscription) gin.H {
+	payload := gin.H{
+		"id":            device.ID,
+		"endpoint":      device.Endpoint,
+		"label":         device.Label,
+		"user_agent":    device.UserAgent,
+		"last_seen_at":  device.LastSeenAt,
+		"failure_count": device.FailureCount,
+		"created_at":    device.Created,
+	}
+
+	if device.LastSuccessAt != nil {
+		payload["last_success_at"] = device.LastSuccessAt
+	}
+	if device.LastFailureAt != nil {
+		payload["last_failure_at"] = device.LastFailureAt
+	}
+
+	return payload
+}
+
+func DeletePushDevice(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	deviceID := strings.TrimSpace(c.Param("id"))
+	if _, err := uuid.Parse(deviceID); err != nil {
+		httpx.BadRequest(c, "ID de dispositivo inválido")
+		return
+	}
+
+	ok, err := db.DeleteUserSubscriptionByID(c.Request.Context(), authData.ID, deviceID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo eliminar el dispositivo")
+		log.Printf("failed to delete push device: %v", err)
+		return
+	}
+	if !ok {
+		httpx.NotFound(c, "Dispositivo no encontrado")
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Dispositivo eliminado")
+}
+
+// SendPushDeviceTest sends the test notification to a single device so users
+// can tell which of their subscriptions still works.
+func SendPushDeviceTest(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	deviceID := strings.TrimSpace(c.Param("id"))
+	if _, err := uuid.Parse(deviceID); err != nil {
+		httpx.BadRequest(c, "ID de dispositivo inválido")
+		return
+	}
+
+	config := notifications.LoadConfigFromEnv()
+	if !config.CanSend() {
+		httpx.ErrorCode(c, http.StatusServiceUnavailable, "web_push_not_configured", "Web Push no está configurado")
+		return
+	}
+
+	device, err := db.GetUserSubscriptionByID(c.Request.Context(), authData.ID, deviceID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.NotFound(c, "Dispositivo no encontrado")
+			return
+		}
+		httpx.ServerError(c, "No se pudo enviar la notificación de prueba")
+		log.Printf("failed to load push device: %v", err)
+		return
+	}
+
+	if err := notifications.SendToSubscription(c.Request.Context(), device, testNotificationPayload(), config); err != nil {
+		if errors.Is(err, notifications.ErrSubscriptionExpired) {
+			httpx.ErrorCode(c, http.StatusGone, "subscription_expired", "El dispositivo ya no acepta notificaciones y fue eliminado")
+			return
+		}
+		httpx.ErrorCode(c, http.StatusBadGateway, "push_failed", "El servicio de notificaciones rechazó el envío")
+		log.Printf("failed to send device test notification: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"device_id": device.ID}, "Notificación de prueba enviada")
+}
+
+func testNotificationPayload() *notifications.NotificationPayload {
+	return &notifications.NotificationPayload{
+		Title:              "Routine Ritual",
+		Body:               "Notificación de prueba activada correctamente.",
+		Icon:               "/icon-192x192.png",
+		Badge:              "/badge-72x72.png",
+		Tag:                "test-notification",
+		RequireInteraction: false,
+		URL:                "/tasks",
+		Actions: []notifications.NotificationAction{
+			{Action: "view", Title: "Ver tareas"},
+		},
+	}
+}
+
+// GetNotificationInbox lists one page of the unified inbox. Query params:
+// type (comma separated), state (all|unread|read), archived, cursor, limit.
 func GetNotificationInbox(c *gin.Context) {
 	authData, err := auth.GetAuth(c)
 	if err != nil {
@@ -166,30 +378,167 @@ func GetNotificationInbox(c *gin.Context) {
 		return
 	}
 
-	items, err := db.ListNotificationInbox(c.Request.Context(), authData.ID)
+	types, ok := parseInboxTypes(c.Query("type"))
+	if !ok {
+		httpx.BadRequest(c, "Tipo de notificación inválido")
+		return
+	}
+
+	filter := db.InboxFilter{
+		Types:     types,
+		ReadState: db.InboxReadStateAll,
+		Cursor:    strings.TrimSpace(c.Query("cursor")),
+	}
+	switch state := db.InboxReadState(strings.TrimSpace(c.Query("state"))); state {
+	case "", db.InboxReadStateAll:
+	case db.InboxReadStateUnread, db.InboxReadStateRead:
+		filter.ReadState = state
+	default:
+		httpx.BadRequest(c, "Estado inválido")
+		return
+	}
+	if raw := strings.TrimSpace(c.Query("archived")); raw != "" {
+		filter.Archived, err = strconv.ParseBool(raw)
+		if err != nil {
+			httpx.BadRequest(c, "Valor de archivado inválido")
+			return
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		filter.Limit, err = strconv.Atoi(raw)
+		if err != nil || filter.Limit <= 0 {
+			httpx.BadRequest(c, "Límite inválido")
+			return
+		}
+	}
+
+	page, err := db.ListInboxItems(c.Request.Context(), authData.ID, filter)
 	if err != nil {
+		if errors.Is(err, db.ErrInvalidInboxCursor) {
+			httpx.BadRequest(c, "Cursor inválido")
+			return
+		}
 		httpx.ServerError(c, "No se pudo cargar la bandeja")
 		log.Printf("failed to list notification inbox: %v", err)
 		return
 	}
-	if items == nil {
-		items = []*db.NotificationInboxItem{}
+
+	httpx.OK(c, gin.H{"items": page.Items, "next_cursor": page.NextCursor}, "Bandeja recuperada")
+}
+
+func GetNotificationInboxUnreadCount(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	count, err := db.CountUnreadInboxItems(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo contar las notificaciones")
+		log.Printf("failed to count unread inbox items: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"unread": count.Total, "by_type": count.ByType}, "Notificaciones sin leer contadas")
+}
+
+type markAllInboxReadRequest struct {
+	Types []string `json:"types"`
+}
+
+func MarkAllNotificationInboxItemsRead(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	var request markAllInboxReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			httpx.BadRequest(c, "Solicitud inválida")
+			return
+		}
+	}
+	types, ok := parseInboxTypes(strings.Join(request.Types, ","))
+	if !ok {
+		httpx.BadRequest(c, "Tipo de notificación inválido")
+		return
+	}
+
+	updated, err := db.MarkAllInboxItemsRead(c.Request.Context(), authData.ID, types)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron marcar como leídas")
+		log.Printf("failed to mark all inbox items read: %v", err)
+		return
 	}
 
-	httpx.OK(c, gin.H{"items": items}, "Bandeja recuperada")
+	httpx.OK(c, gin.H{"updated": updated}, "Notificaciones marcadas como leídas")
+}
+
+// GetNotificationDeliveryLog lists the user's queued push notifications with
+// their status, retry state and per-device delivery attempts.
+func GetNotificationDeliveryLog(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	limit := 0
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			httpx.BadRequest(c, "Límite inválido")
+			return
+		}
+	}
+
+	items, err := db.ListUserNotificationLog(c.Request.Context(), authData.ID, limit)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo cargar el historial de envíos")
+		log.Printf("failed to list notification delivery log: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"notifications": items}, "Historial de envíos recuperado")
 }
 
 func MarkNotificationInboxItemRead(c *gin.Context) {
+	updateNotificationInboxItem(c, db.MarkInboxItemRead, "No se pudo marcar como leído", "Notificación marcada como leída")
+}
+
+func ArchiveNotificationInboxItem(c *gin.Context) {
+	updateNotificationInboxItem(c, db.ArchiveInboxItem, "No se pudo archivar", "Notificación archivada")
+}
+
+func DeleteNotificationInboxItem(c *gin.Context) {
+	updateNotificationInboxItem(c, db.DeleteInboxItem, "No se pudo eliminar", "Notificación eliminada")
+}
+
+func updateNotificationInboxItem(
+	c *gin.Context,
+	update func(ctx context.Context, userID string, itemID string) (bool, error),
+	failureMessage string,
+	successMessage string,
+) {
 	authData, err := auth.GetAuth(c)
 	if err != nil {
 		httpx.Unauthorized(c, "No autorizado")
 		return
 	}
 
-	ok, err := db.MarkNotificationInboxItemRead(c.Request.Context(), authData.ID, c.Param("id"))
+	itemID := c.Param("id")
+	if _, err := uuid.Parse(itemID); err != nil {
+		httpx.BadRequest(c, "ID de notificación inválido")
+		return
+	}
+
+	ok, err := update(c.Request.Context(), authData.ID, itemID)
 	if err != nil {
-		httpx.ServerError(c, "No se pudo marcar como leído")
-		log.Printf("failed to mark inbox item read: %v", err)
+		httpx.ServerError(c, failureMessage)
+		log.Printf("failed to update inbox item %s: %v", itemID, err)
 		return
 	}
 	if !ok {
@@ -197,7 +546,98 @@ func MarkNotificationInboxItemRead(c *gin.Context) {
 		return
 	}
 
-	httpx.OK(c, gin.H{}, "Notificación marcada como leída")
+	httpx.OK(c, gin.H{}, successMessage)
+}
+
+// parseInboxTypes parses a comma separated list of inbox item types. An empty
+// list means every type.
+func parseInboxTypes(raw string) ([]db.InboxItemType, bool) {
+	types := []db.InboxItemType{}
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		itemType, ok := db.NormalizeInboxItemType(part)
+		if !ok {
+			return nil, false
+		}
+		types = append(types, itemType)
+	}
+	return types, true
+}
+
+func GetDigestSettings(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	settings, err := db.GetDigestSettings(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo cargar la configuración de resúmenes")
+		log.Printf("failed to get digest settings: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"settings": settings}, "Configuración recuperada")
+}
+
+func UpdateDigestSettings(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	var request digestSettingsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+
+	settings, err := db.GetDigestSettings(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo cargar la configuración de resúmenes")
+		log.Printf("failed to get digest settings: %v", err)
+		return
+	}
+	if err := request.applyTo(settings); err != nil {
+		httpx.BadRequest(c, "Hora inválida, usa formato HH:MM")
+		return
+	}
+
+	if err := db.UpsertDigestSettings(c.Request.Context(), settings); err != nil {
+		httpx.ServerError(c, "No se pudo guardar la configuración de resúmenes")
+		log.Printf("failed to save digest settings: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"settings": settings}, "Configuración guardada")
+}
+
+func (r digestSettingsRequest) applyTo(settings *db.DigestSettings) error {
+	if r.MorningEnabled != nil {
+		settings.MorningEnabled = *r.MorningEnabled
+	}
+	if r.EveningEnabled != nil {
+		settings.EveningEnabled = *r.EveningEnabled
+	}
+	if r.MorningTime != nil {
+		clock, err := parseClockTime(*r.MorningTime)
+		if err != nil {
+			return err
+		}
+		settings.MorningTime = clock.Format("15:04")
+	}
+	if r.EveningTime != nil {
+		clock, err := parseClockTime(*r.EveningTime)
+		if err != nil {
+			return err
+		}
+		settings.EveningTime = clock.Format("15:04")
+	}
+	return nil
 }
 
 func (r pushSubscriptionRequest) toDBSubscription() (*db.PushSubscription, error) {
@@ -215,11 +655,135 @@ func (r pushSubscriptionRequest) toDBSubscription() (*db.PushSubscription, error
 		return nil, errors.New("Key auth requerida")
 	}
 
+	label := strings.TrimSpace(r.Label)
+	if len([]rune(label)) > maxDeviceLabelLength {
+		return nil, errors.New("Nombre de dispositivo demasiado largo")
+	}
+
 	return &db.PushSubscription{
 		Endpoint: endpoint,
+		Label:    label,
 		Keys: db.Keys{
 			Auth:   authKey,
 			P256dh: p256dh,
 		},
 	}, nil
 }
+
+// HandleNotificationAction applies the action chosen on a task reminder. The
+// token identifies the user and task and is redeemed on first use, so a
+// replayed request is rejected with 409. A token whose action fails is
+// released so the user can retry it.
+func HandleNotificationAction(c *gin.Context) {
+	var request notificationActionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+
+	action := strings.TrimSpace(request.Action)
+	if action != notifications.ActionComplete && action != notifications.ActionSnooze && action != notifications.ActionSkip {
+		httpx.BadRequest(c, "Acción inválida")
+		return
+	}
+
+	snooze := notifications.DefaultSnoozeDuration
+	if request.SnoozeMinutes != nil {
+		snooze = time.Duration(*request.SnoozeMinutes) * time.Minute
+		if snooze <= 0 || snooze > notifications.MaxSnoozeDuration {
+			httpx.BadRequest(c, "Duración de posposición inválida")
+			return
+		}
+	}
+
+	claims, err := auth.ParseNotificationActionToken(strings.TrimSpace(request.Token))
+	if err != nil {
+		httpx.Unauthorized(c, "Token de acción inválido o expirado")
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := db.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		httpx.Unauthorized(c, "Token de acción inválido o expirado")
+		log.Printf("failed to load user for notification action: %v", err)
+		return
+	}
+	authData := &auth.Auth{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Fullname: user.Fullname,
+		Role:     user.Role,
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	task, err := service.GetDetails(ctx, authData, claims.TaskID)
+	if err != nil {
+		respondNotificationActionError(c, err)
+		return
+	}
+
+	redeemed, err := db.RedeemNotificationActionToken(ctx, claims.ID, claims.UserID, claims.TaskID, action, claims.ExpiresAt.Time)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo aplicar la acción")
+		log.Printf("failed to redeem notification action token: %v", err)
+		return
+	}
+	if !redeemed {
+		httpx.Conflict(c, "action_token_used", "Esta acción ya fue utilizada")
+		return
+	}
+
+	switch action {
+	case notifications.ActionSnooze:
+		remindAt, err := notifications.SnoozeTaskReminder(ctx, authData.ID, task.ID, task.Title, task.Description, snooze)
+		if err != nil {
+			releaseNotificationActionToken(ctx, claims.ID)
+			httpx.ServerError(c, "No se pudo posponer el recordatorio")
+			log.Printf("failed to snooze task reminder: %v", err)
+			return
+		}
+		httpx.OK(c, gin.H{"task": task, "action": action, "remind_at": remindAt}, "Recordatorio pospuesto")
+	default:
+		status := db.TaskStatusCompleted
+		if action == notifications.ActionSkip {
+			status = db.TaskStatusSkipped
+		}
+		updated, err := service.Update(ctx, authData, task.ID, tasksvc.UpdateTaskInput{Status: status})
+		if err != nil {
+			releaseNotificationActionToken(ctx, claims.ID)
+			respondNotificationActionError(c, err)
+			return
+		}
+		notifyDelegatedTaskCompletion(ctx, task.Status, updated, authData.ID)
+		httpx.OK(c, gin.H{"task": updated, "action": action}, "Tarea actualizada")
+	}
+}
+
+func releaseNotificationActionToken(ctx context.Context, tokenID string) {
+	if err := db.ReleaseNotificationActionToken(ctx, tokenID); err != nil {
+		log.Printf("failed to release notification action token: %v", err)
+	}
+}
+
+func respondNotificationActionError(c *gin.Context, err error) {
+	if errors.Is(err, tasksvc.ErrNotFound) {
+		httpx.NotFound(c, "Tarea no encontrada")
+		return
+	}
+	if errors.Is(err, tasksvc.ErrForbidden) || errors.Is(err, tasksvc.ErrApprovalRequired) {
+		httpx.Forbidden(c, "No tienes permisos para editar esta tarea")
+		return
+	}
+	httpx.ServerError(c, "No se pudo aplicar la acción")
+	log.Printf("failed to apply notification action: %v", err)
+}
+
+func truncateRunes(value string, limit int) string {
+	runes := []rune(value)
+	if len(runes) <= limit {
+		return value
+	}
+	return string(runes[:limit])
+}
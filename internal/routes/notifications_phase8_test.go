@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/vladwithcode/tasktracker/internal/auth"
 	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/notifications"
 )
 
 func TestNotificationPublicKeyEndpoint(t *testing.T) {
@@ -170,3 +173,318 @@ func countPhase8Subscriptions(t *testing.T, userID string, endpoint string) int
 	}
 	return count
 }
+
+func TestNotificationDeliveryLogListsOwnOutbox(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	ownerUsername := fmt.Sprintf("outboxowner_%d", time.Now().UnixNano()%1_000_000_000)
+	otherUsername := fmt.Sprintf("outboxother_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, otherUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, otherUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	otherCookie := registerPhase5User(t, router, otherUsername, password)
+	ownerID := getPhase8UserID(t, ownerUsername)
+
+	dedupeKey := "test-outbox:" + ownerID
+	payload := []byte(`{"title":"Recordatorio","body":"Prueba"}`)
+	firstID, err := db.EnqueueNotification(context.Background(), ownerID, "task_reminder", dedupeKey, payload)
+	if err != nil {
+		t.Fatalf("enqueue notification: %v", err)
+	}
+	secondID, err := db.EnqueueNotification(context.Background(), ownerID, "task_reminder", dedupeKey, payload)
+	if err != nil {
+		t.Fatalf("enqueue duplicate notification: %v", err)
+	}
+	if firstID != secondID {
+		t.Fatalf("expected dedupe key to return the same outbox id, got %s and %s", firstID, secondID)
+	}
+
+	var envelope struct {
+		Data struct {
+			Notifications []db.OutboxNotification `json:"notifications"`
+		} `json:"data"`
+	}
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/notifications/deliveries", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("delivery log status = %d body = %s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		t.Fatalf("decode delivery log: %v body=%s", err, body)
+	}
+	if len(envelope.Data.Notifications) != 1 || envelope.Data.Notifications[0].Status != db.OutboxStatusPending {
+		t.Fatalf("expected one pending notification, got %+v", envelope.Data.Notifications)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/notifications/deliveries", nil, []*http.Cookie{otherCookie})
+	if status != http.StatusOK {
+		t.Fatalf("other delivery log status = %d body = %s", status, body)
+	}
+	envelope.Data.Notifications = nil
+	if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+		t.Fatalf("decode other delivery log: %v body=%s", err, body)
+	}
+	if len(envelope.Data.Notifications) != 0 {
+		t.Fatalf("expected other user to see no notifications, got %+v", envelope.Data.Notifications)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/notifications/deliveries?limit=abc", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid limit, got %d body=%s", status, body)
+	}
+}
+
+func TestNotificationActionCompletesTaskOnce(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("actionowner_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+
+	authCookie := registerPhase5User(t, router, username, password)
+	ownerID := getPhase8UserID(t, username)
+	schedule := createRouteSchedule(t, router, authCookie, "Action task", "09:00", "10:00")
+	task := findTaskBySchedule(t, getRouteTodayTasks(t, router, authCookie).Data.Tasks, schedule.Data.Schedule.ID)
+
+	payload, err := notifications.BuildTaskReminderPayload(ownerID, task.ID, "Action task", "")
+	if err != nil {
+		t.Fatalf("build reminder payload: %v", err)
+	}
+	token := payload.Data["action_token"]
+	if token == "" {
+		t.Fatalf("expected action token in reminder payload: %#v", payload.Data)
+	}
+	actions := []string{}
+	for _, action := range payload.Actions {
+		actions = append(actions, action.Action)
+	}
+	if strings.Join(actions, ",") != "complete,snooze,skip" {
+		t.Fatalf("expected complete, snooze and skip actions, got %v", actions)
+	}
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/notifications/actions", map[string]string{
+		"token":  token,
+		"action": "complete",
+	}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("complete action status = %d body = %s", status, body)
+	}
+	if countTaskCompletions(t, task.ID) != 1 {
+		t.Fatalf("expected completion history row after action")
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/notifications/actions", map[string]string{
+		"token":  token,
+		"action": "skip",
+	}, nil)
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 for reused token, got %d body = %s", status, body)
+	}
+
+	// A released token, as after a failed action, can be used again.
+	claims, err := auth.ParseNotificationActionToken(token)
+	if err != nil {
+		t.Fatalf("parse action token: %v", err)
+	}
+	if err := db.ReleaseNotificationActionToken(context.Background(), claims.ID); err != nil {
+		t.Fatalf("release action token: %v", err)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/notifications/actions", map[string]string{
+		"token":  token,
+		"action": "snooze",
+	}, nil)
+	if status != http.StatusOK || !strings.Contains(body, `"action":"snooze"`) {
+		t.Fatalf("expected released token to apply, got %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/notifications/actions", map[string]string{
+		"token":  "not-a-token",
+		"action": "complete",
+	}, nil)
+	if status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for invalid token, got %d body = %s", status, body)
+	}
+}
+
+func TestPushDeviceManagement(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	ownerUsername := fmt.Sprintf("deviceowner_%d", time.Now().UnixNano()%1_000_000_000)
+	otherUsername := fmt.Sprintf("deviceother_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, otherUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, otherUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	otherCookie := registerPhase5User(t, router, otherUsername, password)
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/notifications/subscriptions", map[string]interface{}{
+		"endpoint": "https://example.com/push/" + ownerUsername,
+		"label":    "Laptop",
+		"keys": map[string]string{
+			"auth":   "auth-key",
+			"p256dh": "p256dh-key",
+		},
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("subscribe status = %d body = %s", status, body)
+	}
+
+	var listEnvelope struct {
+		Data struct {
+			Devices []db.PushSubscription `json:"devices"`
+		} `json:"data"`
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/notifications/devices", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("list devices status = %d body = %s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &listEnvelope); err != nil {
+		t.Fatalf("decode devices: %v body=%s", err, body)
+	}
+	if len(listEnvelope.Data.Devices) != 1 || listEnvelope.Data.Devices[0].Label != "Laptop" {
+		t.Fatalf("expected one labelled device, got %+v", listEnvelope.Data.Devices)
+	}
+	if strings.Contains(body, "p256dh-key") || strings.Contains(body, "auth-key") {
+		t.Fatalf("expected device list without subscription keys, got %s", body)
+	}
+	deviceID := listEnvelope.Data.Devices[0].ID
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/notifications/devices/"+deviceID, map[string]string{
+		"label": "Teléfono",
+	}, []*http.Cookie{otherCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404 renaming another user's device, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/notifications/devices/"+deviceID, map[string]string{
+		"label": "Teléfono",
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("rename device status = %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, "/api/v1/notifications/devices/not-a-uuid", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid device id, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, "/api/v1/notifications/devices/"+deviceID, nil, []*http.Cookie{otherCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting another user's device, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, "/api/v1/notifications/devices/"+deviceID, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("delete device status = %d body = %s", status, body)
+	}
+}
+
+func TestUnifiedInboxPaginationAndBulkActions(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("inboxpage_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+
+	cookie := registerPhase5User(t, router, username, password)
+	userID := getPhase8UserID(t, username)
+	for i := 0; i < 3; i++ {
+		if _, err := db.CreateInboxNotice(context.Background(), userID, db.InboxItemTypeSystem, fmt.Sprintf("Aviso %d", i), "", "", nil); err != nil {
+			t.Fatalf("create notice: %v", err)
+		}
+	}
+	if _, err := db.CreateInboxNotice(context.Background(), userID, db.InboxItemTypeDigest, "Resumen", "", "/tasks", map[string]string{"digest_kind": "evening_summary"}); err != nil {
+		t.Fatalf("create digest notice: %v", err)
+	}
+
+	type inboxEnvelope struct {
+		Data struct {
+			Items      []db.InboxItem `json:"items"`
+			NextCursor string         `json:"next_cursor"`
+		} `json:"data"`
+	}
+	listInbox := func(query string) inboxEnvelope {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/notifications/inbox"+query, nil, []*http.Cookie{cookie})
+		if status != http.StatusOK {
+			t.Fatalf("inbox %q status = %d body = %s", query, status, body)
+		}
+		var envelope inboxEnvelope
+		if err := json.Unmarshal([]byte(body), &envelope); err != nil {
+			t.Fatalf("decode inbox: %v body=%s", err, body)
+		}
+		return envelope
+	}
+
+	first := listInbox("?type=system&limit=2")
+	if len(first.Data.Items) != 2 || first.Data.NextCursor == "" {
+		t.Fatalf("expected first page of 2 with cursor, got %+v", first.Data)
+	}
+	second := listInbox("?type=system&limit=2&cursor=" + first.Data.NextCursor)
+	if len(second.Data.Items) != 1 || second.Data.NextCursor != "" {
+		t.Fatalf("expected last page of 1 without cursor, got %+v", second.Data)
+	}
+	if second.Data.Items[0].ID == first.Data.Items[0].ID || second.Data.Items[0].ID == first.Data.Items[1].ID {
+		t.Fatalf("pages overlap: %+v %+v", first.Data.Items, second.Data.Items)
+	}
+
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/notifications/inbox?cursor=garbage", nil, []*http.Cookie{cookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid cursor, got %d body = %s", status, body)
+	}
+
+	var countEnvelope struct {
+		Data struct {
+			Unread int            `json:"unread"`
+			ByType map[string]int `json:"by_type"`
+		} `json:"data"`
+	}
+	readCount := func() {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/notifications/inbox/unread-count", nil, []*http.Cookie{cookie})
+		if status != http.StatusOK {
+			t.Fatalf("unread count status = %d body = %s", status, body)
+		}
+		countEnvelope.Data.ByType = nil
+		if err := json.Unmarshal([]byte(body), &countEnvelope); err != nil {
+			t.Fatalf("decode unread count: %v body=%s", err, body)
+		}
+	}
+	readCount()
+	if countEnvelope.Data.Unread != 4 || countEnvelope.Data.ByType["system"] != 3 || countEnvelope.Data.ByType["digest"] != 1 {
+		t.Fatalf("unexpected unread count %+v", countEnvelope.Data)
+	}
+
+	archivedID := first.Data.Items[0].ID
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/notifications/inbox/"+archivedID+"/archive", nil, []*http.Cookie{cookie})
+	if status != http.StatusOK {
+		t.Fatalf("archive status = %d body = %s", status, body)
+	}
+	if archived := listInbox("?archived=true"); len(archived.Data.Items) != 1 || archived.Data.Items[0].ID != archivedID {
+		t.Fatalf("expected archived item in archive view, got %+v", archived.Data.Items)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/notifications/inbox/read-all", map[string][]string{
+		"types": {"system"},
+	}, []*http.Cookie{cookie})
+	if status != http.StatusOK {
+		t.Fatalf("read-all status = %d body = %s", status, body)
+	}
+	readCount()
+	if countEnvelope.Data.Unread != 1 || countEnvelope.Data.ByType["digest"] != 1 {
+		t.Fatalf("expected only the digest unread, got %+v", countEnvelope.Data)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, "/api/v1/notifications/inbox/"+second.Data.Items[0].ID, nil, []*http.Cookie{cookie})
+	if status != http.StatusOK {
+		t.Fatalf("delete status = %d body = %s", status, body)
+	}
+	if remaining := listInbox("?type=system"); len(remaining.Data.Items) != 1 {
+		t.Fatalf("expected one visible system notice after archive and delete, got %+v", remaining.Data.Items)
+	}
+}
@@ -0,0 +1,243 @@
+package routes
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+	tasksvc "github.com/vladwithcode/tasktracker/internal/tasks"
+)
+
+// defaultPublicShareLinkTTL is how long a link lasts when it is created
+// without expires_at.
+const defaultPublicShareLinkTTL = 30 * 24 * time.Hour
+
+const maxPublicShareLabelLength = 80
+
+type createPublicShareLinkRequest struct {
+	Label    string `json:"label"`
+	Scope    string `json:"scope"`
+	Date     string `json:"date"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Category string `json:"category"`
+	// ExpiresAt defaults to defaultPublicShareLinkTTL from now.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func registerPublicShareRoutes(router *gin.RouterGroup) {
+	// Authenticated by the token in the path; the link only exposes
+	// PublicTaskItem data.
+	router.GET("/public/share/:token", GetPublicShare)
+}
+
+func registerPublicShareLinkRoutes(router *gin.RouterGroup) {
+	router.GET("/sharing/public-links", ListPublicShareLinks)
+	router.POST("/sharing/public-links", CreatePublicShareLink)
+	router.DELETE("/sharing/public-links/:id", RevokePublicShareLink)
+}
+
+func ListPublicShareLinks(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	links, err := db.ListPublicShareLinks(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron recuperar los enlaces")
+		log.Printf("failed to list public share links: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"links": links}, "Enlaces recuperados")
+}
+
+// CreatePublicShareLink creates a link and returns its token. The token is
+// not stored, so this is the only response that includes it.
+func CreatePublicShareLink(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	var request createPublicShareLinkRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	link, err := request.toLink()
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+	link.OwnerUserID = authData.ID
+
+	token, tokenHash, err := auth.NewPublicShareToken()
+	if err != nil {
+		httpx.ServerError(c, "No se pudo crear el enlace")
+		log.Printf("failed to generate public share token: %v", err)
+		return
+	}
+	if err := db.CreatePublicShareLink(c.Request.Context(), link, tokenHash); err != nil {
+		httpx.ServerError(c, "No se pudo crear el enlace")
+		log.Printf("failed to create public share link: %v", err)
+		return
+	}
+
+	httpx.Created(c, gin.H{
+		"link":  link,
+		"token": token,
+		"url":   "/share/" + token,
+	}, "Enlace creado")
+}
+
+func RevokePublicShareLink(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	linkID := c.Param("id")
+	if _, err := uuid.Parse(linkID); err != nil {
+		httpx.BadRequest(c, "ID de enlace inválido")
+		return
+	}
+
+	revoked, err := db.RevokePublicShareLink(c.Request.Context(), authData.ID, linkID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo revocar el enlace")
+		log.Printf("failed to revoke public share link: %v", err)
+		return
+	}
+	if !revoked {
+		httpx.NotFound(c, "Enlace no encontrado")
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Enlace revocado")
+}
+
+// GetPublicShare serves a link to anyone holding its token. Unknown, revoked
+// and expired links all look missing.
+func GetPublicShare(c *gin.Context) {
+	token := strings.TrimSpace(c.Param("token"))
+	if token == "" {
+		httpx.NotFound(c, "Enlace no disponible")
+		return
+	}
+
+	link, err := db.OpenPublicShareLink(c.Request.Context(), auth.HashPublicShareToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.NotFound(c, "Enlace no disponible")
+			return
+		}
+		httpx.ServerError(c, "No se pudo abrir el enlace")
+		log.Printf("failed to open public share link: %v", err)
+		return
+	}
+
+	var tasks []*db.DetailedTask
+	from, _ := parseDateOnly(link.From)
+	if link.Scope == db.PublicShareScopeDay {
+		// A day link shows the plan the owner sees, so the day's tasks are
+		// generated like in the owner's own day view.
+		service := tasksvc.NewService(tasksvc.NewRepository())
+		tasks, err = service.ListByDate(c.Request.Context(), link.OwnerUserID, from)
+	} else {
+		to, _ := parseDateOnly(link.To)
+		tasks, err = db.GetUserRangeDetailedTasks(c.Request.Context(), link.OwnerUserID, from, to)
+	}
+	if err != nil {
+		httpx.ServerError(c, "No se pudo abrir el enlace")
+		log.Printf("failed to load public share tasks: %v", err)
+		return
+	}
+
+	ownerName := link.OwnerFullname
+	if ownerName == "" {
+		ownerName = "@" + link.OwnerUsername
+	}
+	httpx.OK(c, gin.H{
+		"link": gin.H{
+			"label":      link.Label,
+			"owner_name": ownerName,
+			"scope":      link.Scope,
+			"from":       link.From,
+			"to":         link.To,
+			"category":   link.Category,
+			"expires_at": link.ExpiresAt,
+		},
+		"days": db.BuildPublicShareDays(link, tasks),
+	}, "Enlace recuperado")
+}
+
+func (r createPublicShareLinkRequest) toLink() (*db.PublicShareLink, error) {
+	link := &db.PublicShareLink{
+		Label: strings.TrimSpace(r.Label),
+		Scope: db.PublicShareScope(strings.TrimSpace(r.Scope)),
+	}
+	if utf8.RuneCountInString(link.Label) > maxPublicShareLabelLength {
+		return nil, errors.New("La etiqueta es demasiado larga")
+	}
+
+	switch link.Scope {
+	case db.PublicShareScopeDay:
+		day, err := parseDateOnly(r.Date)
+		if err != nil {
+			return nil, errors.New("Fecha inválida")
+		}
+		link.From = day.Format("2006-01-02")
+		link.To = link.From
+	case db.PublicShareScopeRange, db.PublicShareScopeCategory:
+		from, err := parseDateOnly(r.From)
+		if err != nil {
+			return nil, errors.New("Fecha inicial inválida")
+		}
+		to, err := parseDateOnly(r.To)
+		if err != nil {
+			return nil, errors.New("Fecha final inválida")
+		}
+		if from.After(to) {
+			return nil, errors.New("La fecha inicial no puede ser posterior a la fecha final")
+		}
+		if to.Sub(from).Hours()/24 > db.MaxPublicShareDays-1 {
+			return nil, errors.New("El rango máximo permitido es de 90 días")
+		}
+		link.From = from.Format("2006-01-02")
+		link.To = to.Format("2006-01-02")
+	default:
+		return nil, errors.New("Alcance inválido")
+	}
+
+	if link.Scope == db.PublicShareScopeCategory {
+		categories := db.NormalizeSharingScopeCategories([]string{r.Category})
+		if len(categories) == 0 {
+			return nil, errors.New("La categoría es requerida")
+		}
+		link.Category = categories[0]
+	}
+
+	expiresAt := time.Now().Add(defaultPublicShareLinkTTL)
+	if r.ExpiresAt != nil {
+		if !r.ExpiresAt.After(time.Now()) {
+			return nil, errors.New("La fecha de vencimiento debe estar en el futuro")
+		}
+		expiresAt = *r.ExpiresAt
+	}
+	link.ExpiresAt = &expiresAt
+
+	return link, nil
+}
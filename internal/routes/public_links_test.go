@@ -0,0 +1,86 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPublicShareLinkLifecycle(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	ownerUsername := fmt.Sprintf("publicowner_%d", suffix)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	ownerID := getPhase9UserID(t, ownerUsername)
+	createRouteSchedule(t, router, ownerCookie, "Public run", "06:00", "07:00")
+	today := time.Now().Format("2006-01-02")
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/sharing/public-links", map[string]string{
+		"scope": "range",
+		"from":  today,
+		"to":    time.Now().AddDate(0, 0, -1).Format("2006-01-02"),
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected inverted range 400, got %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/public-links", map[string]string{
+		"scope": "day",
+		"date":  today,
+		"label": "Mi día",
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create link status = %d body = %s", status, body)
+	}
+	var created struct {
+		Data struct {
+			Link struct {
+				ID string `json:"id"`
+			} `json:"link"`
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("decode created link: %v", err)
+	}
+	if created.Data.Link.ID == "" || created.Data.Token == "" {
+		t.Fatalf("expected link id and token, body = %s", body)
+	}
+
+	publicPath := "/api/v1/public/share/" + created.Data.Token
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, publicPath, nil, nil)
+	if status != http.StatusOK || !strings.Contains(body, "Public run") || !strings.Contains(body, `"date":"`+today+`"`) {
+		t.Fatalf("public share status = %d body = %s", status, body)
+	}
+	if strings.Contains(body, ownerID) || strings.Contains(body, "access_count") {
+		t.Fatalf("public share leaked private data: %s", body)
+	}
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/public/share/unknown-token", nil, nil); status != http.StatusNotFound {
+		t.Fatalf("expected unknown token 404, got %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/sharing/public-links", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"access_count":1`) || strings.Contains(body, created.Data.Token) {
+		t.Fatalf("list links status = %d body = %s", status, body)
+	}
+
+	revokePath := "/api/v1/sharing/public-links/" + created.Data.Link.ID
+	if status, body, _, _ = performJSONPayload(router, http.MethodDelete, revokePath, nil, []*http.Cookie{ownerCookie}); status != http.StatusOK {
+		t.Fatalf("revoke link status = %d body = %s", status, body)
+	}
+	if status, body, _, _ = performJSONPayload(router, http.MethodDelete, revokePath, nil, []*http.Cookie{ownerCookie}); status != http.StatusNotFound {
+		t.Fatalf("expected second revoke 404, got %d body = %s", status, body)
+	}
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, publicPath, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("expected revoked link 404, got %d body = %s", status, body)
+	}
+}
@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestTaskDailyRollupsFollowWritesAndReconcile(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("rollup_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+	userID := getPhase9UserID(t, username)
+
+	schedule := createRouteSchedule(t, router, authCookie, "Rollup first", "08:00", "09:00")
+	createRouteSchedule(t, router, authCookie, "Rollup second", "10:00", "11:00")
+	today := getRouteTodayTasks(t, router, authCookie)
+	task := findTaskBySchedule(t, today.Data.Tasks, schedule.Data.Schedule.ID)
+	updatePhase7Task(t, router, authCookie, task.ID, map[string]interface{}{"status": "completed"})
+
+	now := time.Now()
+	assertRollupMetrics := func(stage string) {
+		t.Helper()
+		metrics := getPhase7Metrics(t, router, authCookie, now, now)
+		if metrics.Total != 2 || metrics.Completed != 1 || metrics.Percentage != 50 || metrics.CompletionsCount != 1 {
+			t.Fatalf("%s: unexpected metrics %+v", stage, metrics)
+		}
+	}
+	assertRollupMetrics("after writes")
+
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	if _, err := conn.Exec(context.Background(), `DELETE FROM task_daily_rollups WHERE user_id = $1`, userID); err != nil {
+		conn.Release()
+		t.Fatalf("failed to drop rollups: %v", err)
+	}
+	conn.Release()
+	if metrics := getPhase7Metrics(t, router, authCookie, now, now); metrics.Total != 0 {
+		t.Fatalf("expected metrics to read the dropped rollups, got %+v", metrics)
+	}
+
+	if _, err := db.ReconcileTaskDailyRollups(context.Background(), now, now); err != nil {
+		t.Fatalf("reconcile rollups: %v", err)
+	}
+	assertRollupMetrics("after reconcile")
+
+	// Completions removed past the append-only guard leave the rollups too.
+	conn, err = db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(context.Background(), `ALTER TABLE task_completions DISABLE TRIGGER prevent_task_completion_delete`); err != nil {
+		t.Fatalf("failed to disable task completion delete guard: %v", err)
+	}
+	_, err = conn.Exec(context.Background(), `DELETE FROM task_completions WHERE task_id = $1`, task.ID)
+	if _, enableErr := conn.Exec(context.Background(), `ALTER TABLE task_completions ENABLE TRIGGER prevent_task_completion_delete`); enableErr != nil {
+		t.Fatalf("failed to re-enable task completion delete guard: %v", enableErr)
+	}
+	if err != nil {
+		t.Fatalf("failed to delete completion: %v", err)
+	}
+	if metrics := getPhase7Metrics(t, router, authCookie, now, now); metrics.Completed != 1 || metrics.CompletionsCount != 0 {
+		t.Fatalf("expected the deleted completion to leave the rollups, got %+v", metrics)
+	}
+}
@@ -32,14 +32,22 @@ func NewRouter() *gin.Engine {
 	// Public routes (no auth required)
 	apiRoutes := router.Group("/api/v1")
 	registerPublicNotificationRoutes(apiRoutes)
+	registerPublicShareRoutes(apiRoutes)
 	apiRoutes.Use(auth.AuthRequired())
 	apiRoutes.GET("/check-auth", CheckAuth)
 	registerSharingRoutes(apiRoutes)
+	registerChangeRequestRoutes(apiRoutes)
+	registerPublicShareLinkRoutes(apiRoutes)
+	registerSpaceRoutes(apiRoutes)
 	registerScheduleRoutes(apiRoutes)
 	registerTaskRoutes(apiRoutes)
+	registerTaskCommentRoutes(apiRoutes)
+	registerTaskAssignmentRoutes(apiRoutes)
 	registerNotificationRoutes(apiRoutes)
 	registerNotesRoutes(apiRoutes)
 	registerUserRoutes(apiRoutes)
+	registerEventRoutes(apiRoutes)
+	registerInsightRoutes(apiRoutes)
 
 	return router
 }
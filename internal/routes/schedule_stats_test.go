@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleStatsRoute(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	ownerUsername := fmt.Sprintf("statsowner_%d", suffix)
+	viewerUsername := fmt.Sprintf("statsviewer_%d", suffix)
+	strangerUsername := fmt.Sprintf("statsstranger_%d", suffix)
+	password := "Test1234"
+	usernames := []string{ownerUsername, viewerUsername, strangerUsername}
+	for _, username := range usernames {
+		cleanupTaskRouteUser(t, username)
+	}
+	t.Cleanup(func() {
+		for _, username := range usernames {
+			cleanupTaskRouteUser(t, username)
+		}
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	viewerCookie := registerPhase5User(t, router, viewerUsername, password)
+	strangerCookie := registerPhase5User(t, router, strangerUsername, password)
+
+	scheduleID := createRouteSchedule(t, router, ownerCookie, "Stats run", "06:00", "07:00").Data.Schedule.ID
+	if status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today", nil, []*http.Cookie{ownerCookie}); status != http.StatusOK {
+		t.Fatalf("owner today status = %d body = %s", status, body)
+	}
+
+	statsPath := "/api/v1/schedules/" + scheduleID + "/stats"
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, statsPath, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("owner stats status = %d body = %s", status, body)
+	}
+	var response struct {
+		Data struct {
+			Stats struct {
+				ScheduleID    string `json:"schedule_id"`
+				ScheduledDays int    `json:"scheduled_days"`
+				Missed        int    `json:"missed"`
+				Days          []struct {
+					Status string `json:"status"`
+				} `json:"days"`
+			} `json:"stats"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	stats := response.Data.Stats
+	// The schedule was created today, so earlier days of the range do not
+	// count and today is still pending.
+	if stats.ScheduleID != scheduleID || stats.ScheduledDays != 1 || stats.Missed != 0 || stats.Days[0].Status != "pending" {
+		t.Fatalf("unexpected owner stats: %s", body)
+	}
+
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, statsPath+"?from=2026-01-10&to=2026-01-01", nil, []*http.Cookie{ownerCookie}); status != http.StatusBadRequest {
+		t.Fatalf("expected inverted range 400, got %d body = %s", status, body)
+	}
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, statsPath, nil, []*http.Cookie{strangerCookie}); status != http.StatusForbidden {
+		t.Fatalf("expected stranger stats 403, got %d body = %s", status, body)
+	}
+
+	grantID := createPhase9Grant(t, router, ownerCookie, viewerUsername, "view")
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, statsPath, nil, []*http.Cookie{viewerCookie}); status != http.StatusOK {
+		t.Fatalf("viewer stats status = %d body = %s", status, body)
+	}
+	analyticsPath := "/api/v1/sharing/grants/" + grantID + "/analytics"
+	if status, body, _, _ = performJSONPayload(router, http.MethodPut, analyticsPath, map[string]bool{"share_analytics": false}, []*http.Cookie{ownerCookie}); status != http.StatusOK {
+		t.Fatalf("hide analytics status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, statsPath, nil, []*http.Cookie{viewerCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "sharing_analytics_hidden") {
+		t.Fatalf("expected hidden stats 403, got %d body = %s", status, body)
+	}
+}
@@ -46,6 +46,7 @@ func registerScheduleRoutes(router *gin.RouterGroup) {
 	router.GET("/schedules", GetSchedules)
 	router.POST("/schedules", CreateSchedule)
 	router.GET("/schedules/:id", GetSchedule)
+	router.GET("/schedules/:id/stats", GetScheduleStats)
 	router.POST("/schedules/:id/pause", PauseSchedule)
 	router.POST("/schedules/:id/resume", ResumeSchedule)
 	router.PUT("/schedules/:id", UpdateSchedule)
@@ -64,14 +65,15 @@ func GetSchedules(c *gin.Context) {
 	if ownerUserID == "" {
 		ownerUserID = sessionAuth.ID
 	}
+	var grant *db.TaskAccessGrant
 	if ownerUserID != sessionAuth.ID && !sessionAuth.HasAccess(auth.AccessLevelAdmin) {
-		allowed, err := db.UserHasTaskPermission(c.Request.Context(), ownerUserID, sessionAuth.ID, db.SharingPermissionView)
+		grant, err = db.ResolveSharingGrant(c.Request.Context(), ownerUserID, sessionAuth.ID, db.SharingPermissionView)
 		if err != nil {
 			httpx.ServerError(c, "Error al validar permisos compartidos")
 			log.Printf("failed to validate shared schedules access: %v\n", err)
 			return
 		}
-		if !allowed {
+		if grant == nil {
 			sharingPermissionDenied(c)
 			return
 		}
@@ -84,6 +86,7 @@ func GetSchedules(c *gin.Context) {
 		log.Printf("failed to get schedules: %v\n", err)
 		return
 	}
+	schedules = schedulesvc.FilterByGrant(grant, schedules)
 
 	httpx.OK(c, gin.H{"schedules": schedules, "owner_user_id": ownerUserID}, "Rutinas recuperadas")
 }
@@ -114,16 +117,21 @@ func CreateSchedule(c *gin.Context) {
 		ownerUserID = sessionAuth.ID
 	}
 	if ownerUserID != sessionAuth.ID && !sessionAuth.HasAccess(auth.AccessLevelAdmin) {
-		allowed, err := db.UserHasTaskPermission(c.Request.Context(), ownerUserID, sessionAuth.ID, db.SharingPermissionCreate)
+		grant, err := db.ResolveSharingGrant(c.Request.Context(), ownerUserID, sessionAuth.ID, db.SharingPermissionCreate)
 		if err != nil {
 			httpx.ServerError(c, "Error al validar permisos compartidos")
 			log.Printf("failed to validate shared schedule creation: %v\n", err)
 			return
 		}
-		if !allowed {
+		// A scoped grantee may only add schedules in one of its categories.
+		if grant == nil || !grant.CoversSchedule("", schedule.Category) {
 			sharingPermissionDenied(c)
 			return
 		}
+		if grant.RequiresApproval {
+			proposeSchedule(c, grant, sessionAuth.ID, schedule)
+			return
+		}
 	}
 
 	service := schedulesvc.NewService(schedulesvc.NewRepository())
@@ -164,6 +172,44 @@ func GetSchedule(c *gin.Context) {
 	httpx.OK(c, gin.H{"schedule": schedule}, "Rutina recuperada")
 }
 
+// GetScheduleStats returns completion rate, streaks and counts of one
+// schedule, counting only the days it repeats on.
+func GetScheduleStats(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	from, to, err := parseTaskRange(c)
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+
+	service := schedulesvc.NewService(schedulesvc.NewRepository())
+	stats, err := service.Stats(c.Request.Context(), sessionAuth, c.Param("id"), from, to)
+	if err != nil {
+		if errors.Is(err, schedulesvc.ErrNotFound) {
+			httpx.NotFound(c, "Rutina no encontrada")
+			return
+		}
+		if errors.Is(err, schedulesvc.ErrForbidden) {
+			httpx.Forbidden(c, "No tienes permisos para ver esta rutina")
+			return
+		}
+		if errors.Is(err, schedulesvc.ErrAnalyticsHidden) {
+			sharingAnalyticsHidden(c)
+			return
+		}
+		httpx.ServerError(c, "No se pudieron calcular las estadísticas")
+		log.Printf("failed to get schedule stats: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"stats": stats}, "Estadísticas recuperadas")
+}
+
 func UpdateSchedule(c *gin.Context) {
 	sessionAuth, err := auth.GetAuth(c)
 	if err != nil {
@@ -0,0 +1,174 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestTaskScore(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("score_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+
+	createSchedule := func(payload map[string]interface{}) string {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/schedules", payload, []*http.Cookie{authCookie})
+		if status != http.StatusCreated {
+			t.Fatalf("create schedule status = %d body = %s", status, body)
+		}
+		var created routeScheduleResponse
+		if err := json.Unmarshal([]byte(body), &created); err != nil {
+			t.Fatalf("decode schedule: %v", err)
+		}
+		return created.Data.Schedule.ID
+	}
+	urgentID := createSchedule(map[string]interface{}{
+		"frequency":           "daily",
+		"is_required":         true,
+		"priority_level":      "urgent",
+		"schedule_start_time": "08:00",
+		"schedule_end_time":   "09:00",
+		"title":               "Score urgent",
+	})
+	counterID := createSchedule(map[string]interface{}{
+		"frequency":           "daily",
+		"priority_level":      "medium",
+		"schedule_start_time": "10:00",
+		"schedule_end_time":   "11:00",
+		"target_count":        4,
+		"title":               "Score counter",
+	})
+
+	today := getRouteTodayTasks(t, router, authCookie)
+	updatePhase7Task(t, router, authCookie, findTaskBySchedule(t, today.Data.Tasks, urgentID).ID, map[string]interface{}{"status": "completed"})
+	updatePhase7Task(t, router, authCookie, findTaskBySchedule(t, today.Data.Tasks, counterID).ID, map[string]interface{}{"currentCount": 2})
+
+	getScores := func() (float64, float64, float64, float64) {
+		t.Helper()
+		var progress struct {
+			Data struct {
+				Progress struct {
+					Percentage float64 `json:"percentage"`
+					Score      float64 `json:"score"`
+				} `json:"progress"`
+			} `json:"data"`
+		}
+		status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/progress", nil, []*http.Cookie{authCookie})
+		if status != http.StatusOK {
+			t.Fatalf("progress status = %d body = %s", status, body)
+		}
+		if err := json.Unmarshal([]byte(body), &progress); err != nil {
+			t.Fatalf("decode progress: %v", err)
+		}
+
+		var history struct {
+			Data struct {
+				History struct {
+					Days []struct {
+						Score float64 `json:"score"`
+					} `json:"days"`
+				} `json:"history"`
+			} `json:"data"`
+		}
+		status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/history", nil, []*http.Cookie{authCookie})
+		if status != http.StatusOK {
+			t.Fatalf("history status = %d body = %s", status, body)
+		}
+		if err := json.Unmarshal([]byte(body), &history); err != nil {
+			t.Fatalf("decode history: %v", err)
+		}
+		days := history.Data.History.Days
+		if len(days) == 0 {
+			t.Fatalf("history has no days: %s", body)
+		}
+
+		var metrics struct {
+			Data struct {
+				Metrics struct {
+					Score float64 `json:"score"`
+				} `json:"metrics"`
+			} `json:"data"`
+		}
+		status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/metrics", nil, []*http.Cookie{authCookie})
+		if status != http.StatusOK {
+			t.Fatalf("metrics status = %d body = %s", status, body)
+		}
+		if err := json.Unmarshal([]byte(body), &metrics); err != nil {
+			t.Fatalf("decode metrics: %v", err)
+		}
+		return progress.Data.Progress.Percentage, progress.Data.Progress.Score, days[len(days)-1].Score, metrics.Data.Metrics.Score
+	}
+
+	// 4 points for the required urgent task, 1 for the counter at half.
+	if percentage, progress, history, metrics := getScores(); percentage != 50 || progress != 90 || history != 90 || metrics != 90 {
+		t.Fatalf("default scores = %v%% progress %v history %v metrics %v, want 50%% and 90", percentage, progress, history, metrics)
+	}
+
+	// Tasks without a target of their own follow the schedule's.
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	if _, err := conn.Exec(context.Background(), `UPDATE tasks SET target_count = NULL WHERE schedule_task_id = $1`, counterID); err != nil {
+		conn.Release()
+		t.Fatalf("failed to clear task target: %v", err)
+	}
+	if _, err := conn.Exec(context.Background(), `UPDATE schedule_tasks SET target_count = 8 WHERE id = $1`, counterID); err != nil {
+		conn.Release()
+		t.Fatalf("failed to raise schedule target: %v", err)
+	}
+	conn.Release()
+	if _, progress, history, metrics := getScores(); progress != 85 || history != 85 || metrics != 85 {
+		t.Fatalf("scores after the schedule target changed = progress %v history %v metrics %v, want 85", progress, history, metrics)
+	}
+
+	for _, payload := range []map[string]interface{}{
+		{"urgent_weight": 0},
+		{"medium_weight": 11},
+		{"required_weight": "heavy"},
+	} {
+		if status, body, _, _ := performJSONPayload(router, http.MethodPut, "/api/v1/tasks/score-weights", payload, []*http.Cookie{authCookie}); status != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d body = %s", payload, status, body)
+		}
+	}
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPut, "/api/v1/tasks/score-weights", map[string]interface{}{
+		"partial_credit":  false,
+		"required_weight": 1,
+		"urgent_weight":   1,
+	}, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("update weights status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/score-weights", nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("get weights status = %d body = %s", status, body)
+	}
+	var weights struct {
+		Data struct {
+			ScoreWeights struct {
+				Required      float64 `json:"required_weight"`
+				Optional      float64 `json:"optional_weight"`
+				PartialCredit bool    `json:"partial_credit"`
+			} `json:"score_weights"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &weights); err != nil {
+		t.Fatalf("decode weights: %v", err)
+	}
+	if got := weights.Data.ScoreWeights; got.Required != 1 || got.Optional != 1 || got.PartialCredit {
+		t.Fatalf("unexpected weights: %s", body)
+	}
+
+	if _, progress, history, metrics := getScores(); progress != 50 || history != 50 || metrics != 50 {
+		t.Fatalf("unweighted scores = progress %v history %v metrics %v, want 50", progress, history, metrics)
+	}
+}
@@ -0,0 +1,108 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSharedAnalyticsAndCoachDashboard(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	firstUsername := fmt.Sprintf("coachedone_%d", suffix)
+	secondUsername := fmt.Sprintf("coachedtwo_%d", suffix)
+	coachUsername := fmt.Sprintf("coach_%d", suffix)
+	password := "Test1234"
+	usernames := []string{firstUsername, secondUsername, coachUsername}
+	for _, username := range usernames {
+		cleanupTaskRouteUser(t, username)
+	}
+	t.Cleanup(func() {
+		for _, username := range usernames {
+			cleanupTaskRouteUser(t, username)
+		}
+	})
+
+	firstCookie := registerPhase5User(t, router, firstUsername, password)
+	secondCookie := registerPhase5User(t, router, secondUsername, password)
+	coachCookie := registerPhase5User(t, router, coachUsername, password)
+	firstID := getPhase9UserID(t, firstUsername)
+	secondID := getPhase9UserID(t, secondUsername)
+
+	createRouteSchedule(t, router, firstCookie, "Coached run", "06:00", "07:00")
+	if status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today", nil, []*http.Cookie{firstCookie}); status != http.StatusOK {
+		t.Fatalf("first owner today status = %d body = %s", status, body)
+	}
+
+	firstGrantID := createPhase9Grant(t, router, firstCookie, coachUsername, "view")
+	createPhase9Grant(t, router, secondCookie, coachUsername, "view")
+
+	for _, path := range []string{"/api/v1/tasks/progress", "/api/v1/tasks/history", "/api/v1/tasks/metrics"} {
+		status, body, _, _ := performJSONPayload(router, http.MethodGet, path+"?owner_user_id="+firstID, nil, []*http.Cookie{coachCookie})
+		if status != http.StatusOK || !strings.Contains(body, `"owner_user_id":"`+firstID+`"`) {
+			t.Fatalf("coach %s status = %d body = %s", path, status, body)
+		}
+	}
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/metrics?owner_user_id="+firstID, nil, []*http.Cookie{secondCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "sharing_permission_denied") {
+		t.Fatalf("expected stranger metrics 403, got %d body = %s", status, body)
+	}
+
+	// Hiding analytics keeps the tasks visible.
+	analyticsPath := "/api/v1/sharing/grants/" + firstGrantID + "/analytics"
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, analyticsPath, map[string]bool{"share_analytics": false}, []*http.Cookie{coachCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected grantee analytics toggle 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, analyticsPath, map[string]bool{"share_analytics": false}, []*http.Cookie{firstCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"share_analytics":false`) {
+		t.Fatalf("hide analytics status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/history?owner_user_id="+firstID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "sharing_analytics_hidden") {
+		t.Fatalf("expected hidden history 403, got %d body = %s", status, body)
+	}
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today?owner_user_id="+firstID, nil, []*http.Cookie{coachCookie}); status != http.StatusOK {
+		t.Fatalf("expected shared tasks to stay visible, got %d body = %s", status, body)
+	}
+
+	var dashboard struct {
+		Data struct {
+			Owners []struct {
+				OwnerUserID string `json:"owner_user_id"`
+			} `json:"owners"`
+		} `json:"data"`
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/coach", nil, []*http.Cookie{coachCookie})
+	if status != http.StatusOK {
+		t.Fatalf("coach dashboard status = %d body = %s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		t.Fatalf("decode dashboard: %v body=%s", err, body)
+	}
+	if len(dashboard.Data.Owners) != 1 || dashboard.Data.Owners[0].OwnerUserID != secondID {
+		t.Fatalf("expected only the second owner on the dashboard, got %s", body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/coach?owner_user_ids="+firstID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "sharing_analytics_hidden") {
+		t.Fatalf("expected hidden owner on dashboard 403, got %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, analyticsPath, map[string]bool{"share_analytics": true}, []*http.Cookie{firstCookie})
+	if status != http.StatusOK {
+		t.Fatalf("share analytics status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/coach?owner_user_ids="+firstID+","+secondID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusOK {
+		t.Fatalf("coach dashboard status = %d body = %s", status, body)
+	}
+	if err := json.Unmarshal([]byte(body), &dashboard); err != nil {
+		t.Fatalf("decode dashboard: %v body=%s", err, body)
+	}
+	if len(dashboard.Data.Owners) != 2 || !strings.Contains(body, `"metrics"`) || !strings.Contains(body, `"history"`) {
+		t.Fatalf("expected both owners with analytics, got %s", body)
+	}
+}
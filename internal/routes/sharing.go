@@ -4,11 +4,15 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"context"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/vladwithcode/tasktracker/internal/auth"
 	"github.com/vladwithcode/tasktracker/internal/db"
@@ -17,19 +21,58 @@ import (
 )
 
 type createSharingGrantRequest struct {
-	AccessLevel   string `json:"access_level"`
-	Grantee       string `json:"grantee"`
-	GranteeUserID string `json:"grantee_user_id"`
+	AccessLevel      string   `json:"access_level"`
+	Grantee          string   `json:"grantee"`
+	GranteeUserID    string   `json:"grantee_user_id"`
+	ScopeCategories  []string `json:"scope_categories"`
+	ScopeScheduleIDs []string `json:"scope_schedule_ids"`
+	// ExpiresAt is optional; without it the grant lasts until revoked.
+	ExpiresAt *time.Time `json:"expires_at"`
+	// RequiresApproval turns the grantee's edits into change requests.
+	RequiresApproval bool `json:"requires_approval"`
+	// ShareAnalytics defaults to true.
+	ShareAnalytics *bool `json:"share_analytics"`
+}
+
+type setSharingAnalyticsRequest struct {
+	ShareAnalytics bool `json:"share_analytics"`
+}
+
+type extendSharingGrantRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// defaultSharingInvitationTTL is how long a grantee has to answer an
+// invitation when SHARING_INVITATION_TTL_HOURS is not set.
+const defaultSharingInvitationTTL = 7 * 24 * time.Hour
+
+type updateSharingGrantScopeRequest struct {
+	ScopeCategories  []string `json:"scope_categories"`
+	ScopeScheduleIDs []string `json:"scope_schedule_ids"`
+}
+
+type setSharingAccountabilityRequest struct {
+	// GraceMinutes defaults to db.DefaultAccountabilityGraceMinutes.
+	GraceMinutes *int `json:"grace_minutes"`
+	// WeeklySummary defaults to true.
+	WeeklySummary *bool `json:"weekly_summary"`
 }
 
 func registerSharingRoutes(router *gin.RouterGroup) {
 	router.GET("/sharing/grants", ListSharingGrants)
 	router.POST("/sharing/grants", CreateSharingGrant)
+	router.PUT("/sharing/grants/:id", UpdateSharingGrantScope)
+	router.PUT("/sharing/grants/:id/expiry", ExtendSharingGrant)
+	router.PUT("/sharing/grants/:id/analytics", SetSharingGrantAnalytics)
+	router.PUT("/sharing/grants/:id/accountability", SetSharingGrantAccountability)
+	router.DELETE("/sharing/grants/:id/accountability", RemoveSharingGrantAccountability)
 	router.DELETE("/sharing/grants/:id", RevokeSharingGrant)
 	router.GET("/sharing/shared-with-me", ListSharedWithMe)
 	router.GET("/sharing/users/search", SearchSharingUsers)
 	router.GET("/sharing/invitations", ListSharingInvitations)
 	router.POST("/sharing/invitations/:id/read", MarkSharingInvitationRead)
+	router.POST("/sharing/invitations/:id/accept", AcceptSharingInvitation)
+	router.POST("/sharing/invitations/:id/decline", DeclineSharingInvitation)
 }
 
 func ListSharingGrants(c *gin.Context) {
@@ -108,6 +151,15 @@ func CreateSharingGrant(c *gin.Context) {
 		return
 	}
 
+	scheduleIDs, ok := validateSharingScopeSchedules(c, authData.ID, request.ScopeScheduleIDs)
+	if !ok {
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		httpx.BadRequest(c, "La fecha de vencimiento debe estar en el futuro")
+		return
+	}
+
 	grantee, err := resolveSharingGrantee(c, request)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -123,8 +175,12 @@ func CreateSharingGrant(c *gin.Context) {
 		return
 	}
 
-	_, err = db.GetActiveTaskAccessGrantByPair(c.Request.Context(), authData.ID, grantee.ID)
+	existing, err := db.GetOpenTaskAccessGrantByPair(c.Request.Context(), authData.ID, grantee.ID)
 	if err == nil {
+		if existing.AcceptedAt == nil {
+			httpx.Conflict(c, "invitation_pending", "Ya hay una invitación pendiente para este usuario")
+			return
+		}
 		httpx.Conflict(c, "active_grant_exists", "Ya existe un permiso activo para este usuario")
 		return
 	}
@@ -135,30 +191,47 @@ func CreateSharingGrant(c *gin.Context) {
 	}
 
 	grant := &db.TaskAccessGrant{
-		OwnerUserID:   authData.ID,
-		GranteeUserID: grantee.ID,
-		AccessLevel:   accessLevel,
-	}
-	if err := db.CreateTaskAccessGrant(c.Request.Context(), grant); err != nil {
+		OwnerUserID:      authData.ID,
+		GranteeUserID:    grantee.ID,
+		AccessLevel:      accessLevel,
+		ScopeCategories:  request.ScopeCategories,
+		ScopeScheduleIDs: scheduleIDs,
+		ExpiresAt:        request.ExpiresAt,
+		RequiresApproval: request.RequiresApproval,
+		ShareAnalytics:   request.ShareAnalytics == nil || *request.ShareAnalytics,
+	}
+	// The invitation never outlives the grant it offers.
+	invitationExpiresAt := time.Now().Add(sharingInvitationTTL())
+	if grant.ExpiresAt != nil && grant.ExpiresAt.Before(invitationExpiresAt) {
+		invitationExpiresAt = *grant.ExpiresAt
+	}
+	if err := db.CreateTaskAccessGrant(c.Request.Context(), grant, &invitationExpiresAt); err != nil {
 		httpx.ServerError(c, "No se pudo crear el permiso")
 		log.Printf("failed to create sharing grant: %v", err)
 		return
 	}
 
-	createdGrant, err := db.GetActiveTaskAccessGrantByPair(c.Request.Context(), authData.ID, grantee.ID)
+	createdGrant, err := db.GetOpenTaskAccessGrantByPair(c.Request.Context(), authData.ID, grantee.ID)
 	if err != nil {
-		httpx.Created(c, gin.H{"grant": grant}, "Permiso creado")
+		httpx.Created(c, gin.H{"grant": grant}, "Invitación enviada")
 		return
 	}
 
-	// Create invitation so grantee is notified.
-	if invErr := db.CreateSharingInvitation(c.Request.Context(), createdGrant.ID, authData.ID, grantee.ID); invErr != nil {
-		log.Printf("failed to create sharing invitation for grant %s: %v", createdGrant.ID, invErr)
-	} else {
-		go sendSharingInvitationPush(context.WithoutCancel(c.Request.Context()), createdGrant)
-	}
+	go sendSharingInvitationPush(context.WithoutCancel(c.Request.Context()), createdGrant)
+
+	httpx.Created(c, gin.H{"grant": createdGrant}, "Invitación enviada")
+}
 
-	httpx.Created(c, gin.H{"grant": createdGrant}, "Permiso creado")
+// sharingInvitationTTL reads SHARING_INVITATION_TTL_HOURS, falling back to
+// defaultSharingInvitationTTL when it is unset or not a positive integer.
+func sharingInvitationTTL() time.Duration {
+	if value := strings.TrimSpace(os.Getenv("SHARING_INVITATION_TTL_HOURS")); value != "" {
+		hours, err := strconv.Atoi(value)
+		if err == nil && hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return defaultSharingInvitationTTL
 }
 
 func sendSharingInvitationPush(ctx context.Context, grant *db.TaskAccessGrant) {
@@ -171,12 +244,12 @@ func sendSharingInvitationPush(ctx context.Context, grant *db.TaskAccessGrant) {
 		ownerName = "@" + grant.OwnerUsername
 	}
 	payload := &notifications.NotificationPayload{
-		Title: "Nuevo acceso compartido",
-		Body:  ownerName + " te compartió acceso a sus tareas.",
+		Title: "Nueva invitación",
+		Body:  ownerName + " te invitó a acceder a sus tareas.",
 		Icon:  "/icon-192x192.png",
 		Badge: "/badge-72x72.png",
 		Tag:   "sharing-" + grant.ID,
-		URL:   "/shared/" + grant.OwnerUserID,
+		URL:   "/profile",
 	}
 	if _, err := notifications.SendNotificationToUserWithConfig(ctx, grant.GranteeUserID, payload, config); err != nil {
 		log.Printf("sharing push failed for grantee %s: %v", grant.GranteeUserID, err)
@@ -224,6 +297,294 @@ func MarkSharingInvitationRead(c *gin.Context) {
 	httpx.OK(c, gin.H{}, "Invitación marcada como leída")
 }
 
+// AcceptSharingInvitation lets the grantee accept a pending invitation, which
+// activates its grant.
+func AcceptSharingInvitation(c *gin.Context) {
+	respondToSharingInvitation(c, db.AcceptSharingInvitation, "Invitación aceptada")
+}
+
+// DeclineSharingInvitation lets the grantee refuse a pending invitation. The
+// grant is revoked without ever taking effect.
+func DeclineSharingInvitation(c *gin.Context) {
+	respondToSharingInvitation(c, db.DeclineSharingInvitation, "Invitación rechazada")
+}
+
+func respondToSharingInvitation(
+	c *gin.Context,
+	respond func(ctx context.Context, granteeUserID string, invitationID string) (*db.SharingInvitation, error),
+	okMsg string,
+) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	invitationID := c.Param("id")
+	if _, err := uuid.Parse(invitationID); err != nil {
+		httpx.BadRequest(c, "ID de invitación inválido")
+		return
+	}
+
+	invitation, err := respond(c.Request.Context(), authData.ID, invitationID)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrSharingInvitationNotFound):
+			httpx.NotFound(c, "Invitación no encontrada")
+		case errors.Is(err, db.ErrSharingInvitationNotPending):
+			httpx.Conflict(c, "invitation_not_pending", "La invitación ya fue respondida, cancelada o venció")
+		default:
+			httpx.ServerError(c, "No se pudo responder la invitación")
+			log.Printf("failed to respond to sharing invitation: %v", err)
+		}
+		return
+	}
+
+	go notifications.NotifySharingInvitationOutcome(context.WithoutCancel(c.Request.Context()), invitation)
+
+	httpx.OK(c, gin.H{"invitation": invitation}, okMsg)
+}
+
+// UpdateSharingGrantScope replaces the categories and schedules a grant
+// covers. Sending both lists empty widens the grant to every schedule.
+func UpdateSharingGrantScope(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	grantID := c.Param("id")
+	if _, err := uuid.Parse(grantID); err != nil {
+		httpx.BadRequest(c, "ID de permiso inválido")
+		return
+	}
+
+	var request updateSharingGrantScopeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	scheduleIDs, ok := validateSharingScopeSchedules(c, authData.ID, request.ScopeScheduleIDs)
+	if !ok {
+		return
+	}
+
+	updated, err := db.UpdateTaskAccessGrantScope(c.Request.Context(), authData.ID, grantID, request.ScopeCategories, scheduleIDs)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo actualizar el permiso")
+		log.Printf("failed to update sharing grant scope: %v", err)
+		return
+	}
+	if !updated {
+		httpx.NotFound(c, "Permiso no encontrado")
+		return
+	}
+
+	respondOwnedSharingGrant(c, authData.ID, grantID, "Permiso actualizado")
+}
+
+// SetSharingGrantAccountability makes the grantee an accountability partner,
+// or changes the grace period and weekly summary of an existing partnership.
+// The grant must let the grantee view tasks.
+func SetSharingGrantAccountability(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	grantID := c.Param("id")
+	if _, err := uuid.Parse(grantID); err != nil {
+		httpx.BadRequest(c, "ID de permiso inválido")
+		return
+	}
+
+	var request setSharingAccountabilityRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	graceMinutes := db.DefaultAccountabilityGraceMinutes
+	if request.GraceMinutes != nil {
+		graceMinutes = *request.GraceMinutes
+	}
+	if graceMinutes < 0 || graceMinutes > db.MaxAccountabilityGraceMinutes {
+		httpx.BadRequest(c, "El periodo de gracia debe estar entre 0 y 48 horas")
+		return
+	}
+	weeklySummary := true
+	if request.WeeklySummary != nil {
+		weeklySummary = *request.WeeklySummary
+	}
+
+	set, err := db.SetAccountabilityPartner(c.Request.Context(), authData.ID, grantID, graceMinutes, weeklySummary)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo configurar el compañero de responsabilidad")
+		log.Printf("failed to set accountability partner: %v", err)
+		return
+	}
+	if !set {
+		httpx.NotFound(c, "Permiso no encontrado")
+		return
+	}
+
+	respondOwnedSharingGrant(c, authData.ID, grantID, "Compañero de responsabilidad configurado")
+}
+
+// SetSharingGrantAnalytics shows or hides the owner's progress, history and
+// metrics from the grantee.
+func SetSharingGrantAnalytics(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	grantID := c.Param("id")
+	if _, err := uuid.Parse(grantID); err != nil {
+		httpx.BadRequest(c, "ID de permiso inválido")
+		return
+	}
+
+	var request setSharingAnalyticsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+
+	updated, err := db.SetTaskAccessGrantAnalytics(c.Request.Context(), authData.ID, grantID, request.ShareAnalytics)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo actualizar el permiso")
+		log.Printf("failed to set sharing grant analytics: %v", err)
+		return
+	}
+	if !updated {
+		httpx.NotFound(c, "Permiso no encontrado")
+		return
+	}
+
+	respondOwnedSharingGrant(c, authData.ID, grantID, "Estadísticas compartidas actualizadas")
+}
+
+// RemoveSharingGrantAccountability ends an accountability partnership. The
+// owner and the partner can both end it; the grant itself is kept.
+func RemoveSharingGrantAccountability(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	grantID := c.Param("id")
+	if _, err := uuid.Parse(grantID); err != nil {
+		httpx.BadRequest(c, "ID de permiso inválido")
+		return
+	}
+
+	removed, err := db.RemoveAccountabilityPartner(c.Request.Context(), authData.ID, grantID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo terminar la responsabilidad compartida")
+		log.Printf("failed to remove accountability partner: %v", err)
+		return
+	}
+	if !removed {
+		httpx.NotFound(c, "Compañero de responsabilidad no encontrado")
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Responsabilidad compartida terminada")
+}
+
+func respondOwnedSharingGrant(c *gin.Context, ownerUserID string, grantID string, okMsg string) {
+	grants, err := db.ListTaskAccessGrantsByOwner(c.Request.Context(), ownerUserID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar el permiso")
+		log.Printf("failed to reload sharing grant: %v", err)
+		return
+	}
+	for _, grant := range grants {
+		if grant.ID == grantID {
+			httpx.OK(c, gin.H{"grant": grant}, okMsg)
+			return
+		}
+	}
+	httpx.NotFound(c, "Permiso no encontrado")
+}
+
+// ExtendSharingGrant moves the expiry of an active or pending grant. A null
+// expires_at removes the expiry. Grants that already lapsed must be created
+// again.
+func ExtendSharingGrant(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	grantID := c.Param("id")
+	if _, err := uuid.Parse(grantID); err != nil {
+		httpx.BadRequest(c, "ID de permiso inválido")
+		return
+	}
+
+	var request extendSharingGrantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		httpx.BadRequest(c, "La fecha de vencimiento debe estar en el futuro")
+		return
+	}
+
+	extended, err := db.ExtendTaskAccessGrant(c.Request.Context(), authData.ID, grantID, request.ExpiresAt)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo extender el permiso")
+		log.Printf("failed to extend sharing grant: %v", err)
+		return
+	}
+	if !extended {
+		httpx.NotFound(c, "Permiso no encontrado o vencido")
+		return
+	}
+
+	httpx.OK(c, gin.H{"expires_at": request.ExpiresAt}, "Permiso extendido")
+}
+
+// validateSharingScopeSchedules deduplicates the schedule ids of a grant scope
+// and checks that every one is a schedule of the owner.
+func validateSharingScopeSchedules(c *gin.Context, ownerUserID string, scheduleIDs []string) ([]string, bool) {
+	unique := []string{}
+	seen := map[string]bool{}
+	for _, id := range scheduleIDs {
+		parsed, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			httpx.BadRequest(c, "ID de rutina inválido en el alcance")
+			return nil, false
+		}
+		if seen[parsed.String()] {
+			continue
+		}
+		seen[parsed.String()] = true
+		unique = append(unique, parsed.String())
+	}
+
+	owned, err := db.CountOwnedSchedules(c.Request.Context(), ownerUserID, unique)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo validar el alcance del permiso")
+		log.Printf("failed to validate sharing scope schedules: %v", err)
+		return nil, false
+	}
+	if owned != len(unique) {
+		httpx.BadRequest(c, "El alcance incluye rutinas que no te pertenecen")
+		return nil, false
+	}
+	return unique, true
+}
+
+// RevokeSharingGrant ends a grant. Revoking one that is still waiting on the
+// grantee cancels its invitation.
 func RevokeSharingGrant(c *gin.Context) {
 	authData, err := auth.GetAuth(c)
 	if err != nil {
@@ -261,3 +622,7 @@ func resolveSharingGrantee(c *gin.Context, request createSharingGrantRequest) (*
 func sharingPermissionDenied(c *gin.Context) {
 	httpx.ErrorCode(c, http.StatusForbidden, "sharing_permission_denied", "No tienes permiso para acceder a este recurso compartido")
 }
+
+func sharingAnalyticsHidden(c *gin.Context) {
+	httpx.ErrorCode(c, http.StatusForbidden, "sharing_analytics_hidden", "Este usuario no comparte sus estadísticas contigo")
+}
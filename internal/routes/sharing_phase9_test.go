@@ -202,7 +202,31 @@ func createPhase9Grant(t *testing.T, router *gin.Engine, ownerCookie *http.Cooki
 	if status != http.StatusCreated {
 		t.Fatalf("create phase9 grant status = %d body = %s", status, body)
 	}
-	return decodePhase9GrantID(t, body)
+	grantID := decodePhase9GrantID(t, body)
+	acceptPhase9Grant(t, grantee, grantID)
+	return grantID
+}
+
+// acceptPhase9Grant accepts the invitation of grantID on behalf of the
+// grantee so the grant takes effect.
+func acceptPhase9Grant(t *testing.T, grantee string, grantID string) {
+	t.Helper()
+
+	granteeID := getPhase9UserID(t, grantee)
+	invitations, err := db.ListSharingInvitations(context.Background(), granteeID)
+	if err != nil {
+		t.Fatalf("list phase9 invitations: %v", err)
+	}
+	for _, invitation := range invitations {
+		if invitation.GrantID != grantID {
+			continue
+		}
+		if _, err := db.AcceptSharingInvitation(context.Background(), granteeID, invitation.ID); err != nil {
+			t.Fatalf("accept phase9 invitation: %v", err)
+		}
+		return
+	}
+	t.Fatalf("no invitation found for grant %s", grantID)
 }
 
 func decodePhase9GrantID(t *testing.T, body string) string {
@@ -435,3 +459,424 @@ func countPhase9Pings(t *testing.T, taskID string, senderUserID string) int {
 	}
 	return count
 }
+
+func TestTaskCommentThreadPermissionsAndMentions(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	ownerUsername := fmt.Sprintf("commentowner_%d", time.Now().UnixNano()%1_000_000_000)
+	viewerUsername := fmt.Sprintf("commentviewer_%d", time.Now().UnixNano()%1_000_000_000)
+	outsiderUsername := fmt.Sprintf("commentoutsider_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	for _, username := range []string{ownerUsername, viewerUsername, outsiderUsername} {
+		cleanupTaskRouteUser(t, username)
+	}
+	t.Cleanup(func() {
+		for _, username := range []string{ownerUsername, viewerUsername, outsiderUsername} {
+			cleanupTaskRouteUser(t, username)
+		}
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	viewerCookie := registerPhase5User(t, router, viewerUsername, password)
+	outsiderCookie := registerPhase5User(t, router, outsiderUsername, password)
+	createPhase9Grant(t, router, ownerCookie, viewerUsername, "view")
+	schedule := createRouteSchedule(t, router, ownerCookie, "Comment thread task", "09:00", "10:00")
+	task := findTaskBySchedule(t, getRouteTodayTasks(t, router, ownerCookie).Data.Tasks, schedule.Data.Schedule.ID)
+	commentsPath := "/api/v1/tasks/" + task.ID + "/comments"
+
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, commentsPath, nil, []*http.Cookie{outsiderCookie})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected outsider list 403, got %d body=%s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, commentsPath, map[string]string{
+		"body": "Hola @" + ownerUsername + " y @" + outsiderUsername,
+	}, []*http.Cookie{viewerCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("expected viewer comment 201, got %d body=%s", status, body)
+	}
+	var created struct {
+		Data struct {
+			Comment db.TaskComment `json:"comment"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("decode comment: %v body=%s", err, body)
+	}
+	comment := created.Data.Comment
+	if len(comment.Mentions) != 1 || comment.Mentions[0].Username != ownerUsername {
+		t.Fatalf("expected only the owner to be mentioned, got %+v", comment.Mentions)
+	}
+	commentPath := commentsPath + "/" + comment.ID
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, commentPath, map[string]string{
+		"body": "editado por otro",
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected owner editing viewer comment 404, got %d body=%s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, commentPath, map[string]string{
+		"body": "editado",
+	}, []*http.Cookie{viewerCookie})
+	if status != http.StatusOK || !strings.Contains(body, "edited_at") {
+		t.Fatalf("expected author edit OK, got %d body=%s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, commentsPath, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, "editado") {
+		t.Fatalf("expected owner to read edited thread, got %d body=%s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, commentPath, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected owner deleting viewer comment 404, got %d body=%s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, commentPath, nil, []*http.Cookie{viewerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("expected author delete OK, got %d body=%s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, commentsPath, nil, []*http.Cookie{viewerCookie})
+	if status != http.StatusOK || strings.Contains(body, comment.ID) {
+		t.Fatalf("expected deleted comment to be hidden, got %d body=%s", status, body)
+	}
+}
+
+func TestScopedSharingGrantFiltersSchedulesAndTasks(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	ownerUsername := fmt.Sprintf("scopeowner_%d", time.Now().UnixNano()%1_000_000_000)
+	coachUsername := fmt.Sprintf("scopecoach_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, coachUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, coachUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	coachCookie := registerPhase5User(t, router, coachUsername, password)
+	ownerID := getPhase9UserID(t, ownerUsername)
+
+	createCategorized := func(title string, category string) string {
+		t.Helper()
+		payload := phase9SchedulePayload(title, ownerID)
+		payload["category"] = category
+		status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/schedules", payload, []*http.Cookie{ownerCookie})
+		if status != http.StatusCreated {
+			t.Fatalf("create %s schedule status = %d body = %s", title, status, body)
+		}
+		var response routeScheduleResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("decode schedule: %v body=%s", err, body)
+		}
+		return response.Data.Schedule.ID
+	}
+	gymID := createCategorized("Scoped gym", "Gym")
+	studyID := createCategorized("Scoped study", "study")
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/sharing/grants", map[string]interface{}{
+		"access_level":       "view",
+		"grantee":            coachUsername,
+		"scope_schedule_ids": []string{"not-a-uuid"},
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected invalid scope 400, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/grants", map[string]interface{}{
+		"access_level":     "view",
+		"grantee":          coachUsername,
+		"scope_categories": []string{" GYM "},
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create scoped grant status = %d body = %s", status, body)
+	}
+	grantID := decodePhase9GrantID(t, body)
+	acceptPhase9Grant(t, coachUsername, grantID)
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today?owner_user_id="+ownerID, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("owner today status = %d body = %s", status, body)
+	}
+	var ownerToday routeTodayResponse
+	if err := json.Unmarshal([]byte(body), &ownerToday); err != nil {
+		t.Fatalf("decode owner today: %v", err)
+	}
+	studyTask := findTaskBySchedule(t, ownerToday.Data.Tasks, studyID)
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today?owner_user_id="+ownerID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusOK {
+		t.Fatalf("coach today status = %d body = %s", status, body)
+	}
+	if !strings.Contains(body, gymID) || strings.Contains(body, studyID) {
+		t.Fatalf("expected coach to see only the gym task: %s", body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/schedules?owner_user_id="+ownerID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusOK || !strings.Contains(body, gymID) || strings.Contains(body, studyID) {
+		t.Fatalf("expected coach to list only the gym schedule, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/"+studyTask.ID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected out-of-scope task detail 403, got %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/sharing/grants/"+grantID, map[string]interface{}{
+		"scope_categories":   []string{"gym"},
+		"scope_schedule_ids": []string{studyID},
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("update grant scope status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/"+studyTask.ID, nil, []*http.Cookie{coachCookie})
+	if status != http.StatusOK {
+		t.Fatalf("expected task detail after widening scope OK, got %d body = %s", status, body)
+	}
+}
+
+func TestSharingGrantExpiryAndExtension(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	ownerUsername := fmt.Sprintf("expiryowner_%d", time.Now().UnixNano()%1_000_000_000)
+	granteeUsername := fmt.Sprintf("expirygrantee_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, granteeUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, granteeUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	granteeCookie := registerPhase5User(t, router, granteeUsername, password)
+	ownerID := getPhase9UserID(t, ownerUsername)
+	granteeID := getPhase9UserID(t, granteeUsername)
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/sharing/grants", map[string]interface{}{
+		"access_level": "view",
+		"grantee":      granteeUsername,
+		"expires_at":   time.Now().Add(-time.Hour).Format(time.RFC3339),
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected past expiry 400, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, "/api/v1/sharing/grants", map[string]interface{}{
+		"access_level": "view",
+		"grantee":      granteeUsername,
+		"expires_at":   time.Now().Add(time.Hour).Format(time.RFC3339),
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create expiring grant status = %d body = %s", status, body)
+	}
+	grantID := decodePhase9GrantID(t, body)
+	acceptPhase9Grant(t, granteeUsername, grantID)
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/sharing/grants/"+grantID+"/expiry", map[string]interface{}{
+		"expires_at": time.Now().Add(48 * time.Hour).Format(time.RFC3339),
+	}, []*http.Cookie{granteeCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected grantee extend 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/sharing/grants/"+grantID+"/expiry", map[string]interface{}{
+		"expires_at": time.Now().Add(48 * time.Hour).Format(time.RFC3339),
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("owner extend status = %d body = %s", status, body)
+	}
+
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	_, err = conn.Exec(context.Background(), `UPDATE task_access_grants SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE id = $1`, grantID)
+	conn.Release()
+	if err != nil {
+		t.Fatalf("failed to lapse grant: %v", err)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today?owner_user_id="+ownerID, nil, []*http.Cookie{granteeCookie})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected lapsed grant 403, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/sharing/grants/"+grantID+"/expiry", map[string]interface{}{
+		"expires_at": nil,
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected extending a lapsed grant 404, got %d body = %s", status, body)
+	}
+
+	expired, err := db.RevokeExpiredTaskAccessGrants(context.Background())
+	if err != nil {
+		t.Fatalf("revoke expired grants: %v", err)
+	}
+	found := false
+	for _, grant := range expired {
+		if grant.ID == grantID {
+			found = grant.RevokedAt != nil
+		}
+	}
+	if !found {
+		t.Fatalf("expected lapsed grant %s to be revoked, got %+v", grantID, expired)
+	}
+	if grant, err := db.ResolveSharingGrant(context.Background(), ownerID, granteeID, db.SharingPermissionView); err != nil || grant != nil {
+		t.Fatalf("expected no active grant after expiry, got %+v err=%v", grant, err)
+	}
+	createPhase9Grant(t, router, ownerCookie, granteeUsername, "view")
+}
+
+func TestSharingInvitationAcceptDeclineAndExpiry(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	ownerUsername := fmt.Sprintf("respondowner_%d", time.Now().UnixNano()%1_000_000_000)
+	granteeUsername := fmt.Sprintf("respondgrantee_%d", time.Now().UnixNano()%1_000_000_000)
+	password := "Test1234"
+	cleanupTaskRouteUser(t, ownerUsername)
+	cleanupTaskRouteUser(t, granteeUsername)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, ownerUsername)
+		cleanupTaskRouteUser(t, granteeUsername)
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	granteeCookie := registerPhase5User(t, router, granteeUsername, password)
+	ownerID := getPhase9UserID(t, ownerUsername)
+
+	invite := func() (string, string) {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/sharing/grants", map[string]string{
+			"access_level": "view",
+			"grantee":      granteeUsername,
+		}, []*http.Cookie{ownerCookie})
+		if status != http.StatusCreated {
+			t.Fatalf("create invitation status = %d body = %s", status, body)
+		}
+		grantID := decodePhase9GrantID(t, body)
+
+		status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/sharing/invitations", nil, []*http.Cookie{granteeCookie})
+		if status != http.StatusOK {
+			t.Fatalf("list invitations status = %d body = %s", status, body)
+		}
+		var response struct {
+			Data struct {
+				Invitations []db.SharingInvitation `json:"invitations"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("decode invitations: %v body=%s", err, body)
+		}
+		for _, invitation := range response.Data.Invitations {
+			if invitation.GrantID == grantID {
+				if invitation.Status != db.SharingInvitationPending || invitation.ExpiresAt == nil {
+					t.Fatalf("expected pending invitation with expiry, got %+v", invitation)
+				}
+				return grantID, invitation.ID
+			}
+		}
+		t.Fatalf("invitation for grant %s not listed: %s", grantID, body)
+		return "", ""
+	}
+	respond := func(invitationID string, action string, cookie *http.Cookie) (int, string) {
+		t.Helper()
+		status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/sharing/invitations/"+invitationID+"/"+action, nil, []*http.Cookie{cookie})
+		return status, body
+	}
+	todayStatus := func() int {
+		t.Helper()
+		status, _, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today?owner_user_id="+ownerID, nil, []*http.Cookie{granteeCookie})
+		return status
+	}
+
+	// Pending invitations confer nothing and block a second invitation.
+	grantID, invitationID := invite()
+	if status := todayStatus(); status != http.StatusForbidden {
+		t.Fatalf("expected pending invitation 403, got %d", status)
+	}
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/sharing/grants", map[string]string{
+		"access_level": "view",
+		"grantee":      granteeUsername,
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusConflict || !strings.Contains(body, "invitation_pending") {
+		t.Fatalf("expected invitation_pending 409, got %d body = %s", status, body)
+	}
+	if status, body := respond(invitationID, "accept", ownerCookie); status != http.StatusNotFound {
+		t.Fatalf("expected owner accept 404, got %d body = %s", status, body)
+	}
+
+	// Accepting activates the grant.
+	if status, body := respond(invitationID, "accept", granteeCookie); status != http.StatusOK || !strings.Contains(body, `"status":"accepted"`) {
+		t.Fatalf("accept status = %d body = %s", status, body)
+	}
+	if status := todayStatus(); status != http.StatusOK {
+		t.Fatalf("expected accepted grant OK, got %d", status)
+	}
+	if status, body := respond(invitationID, "decline", granteeCookie); status != http.StatusConflict {
+		t.Fatalf("expected declining an accepted invitation 409, got %d body = %s", status, body)
+	}
+
+	// Declining revokes the grant.
+	if status, body, _, _ := performJSONPayload(router, http.MethodDelete, "/api/v1/sharing/grants/"+grantID, nil, []*http.Cookie{ownerCookie}); status != http.StatusOK {
+		t.Fatalf("revoke status = %d body = %s", status, body)
+	}
+	grantID, invitationID = invite()
+	if status, body := respond(invitationID, "decline", granteeCookie); status != http.StatusOK {
+		t.Fatalf("decline status = %d body = %s", status, body)
+	}
+	if status := todayStatus(); status != http.StatusForbidden {
+		t.Fatalf("expected declined invitation 403, got %d", status)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/sharing/grants", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || strings.Contains(body, grantID) {
+		t.Fatalf("expected declined grant to leave the owner's list, got %d body = %s", status, body)
+	}
+
+	// Revoking a pending grant cancels its invitation.
+	grantID, invitationID = invite()
+	if status, body, _, _ := performJSONPayload(router, http.MethodDelete, "/api/v1/sharing/grants/"+grantID, nil, []*http.Cookie{ownerCookie}); status != http.StatusOK {
+		t.Fatalf("cancel status = %d body = %s", status, body)
+	}
+	if status, body := respond(invitationID, "accept", granteeCookie); status != http.StatusConflict {
+		t.Fatalf("expected accepting a cancelled invitation 409, got %d body = %s", status, body)
+	}
+
+	// Unanswered invitations expire and revoke their grant.
+	grantID, invitationID = invite()
+	conn, err := db.GetConn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire db conn: %v", err)
+	}
+	_, err = conn.Exec(context.Background(), `UPDATE sharing_invitations SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE id = $1`, invitationID)
+	conn.Release()
+	if err != nil {
+		t.Fatalf("failed to lapse invitation: %v", err)
+	}
+	if status, body := respond(invitationID, "accept", granteeCookie); status != http.StatusConflict {
+		t.Fatalf("expected accepting an expired invitation 409, got %d body = %s", status, body)
+	}
+	expired, err := db.ExpireSharingInvitations(context.Background())
+	if err != nil {
+		t.Fatalf("expire invitations: %v", err)
+	}
+	found := false
+	for _, invitation := range expired {
+		if invitation.ID == invitationID {
+			found = invitation.Status == db.SharingInvitationExpired
+		}
+	}
+	if !found {
+		t.Fatalf("expected invitation %s to expire, got %+v", invitationID, expired)
+	}
+	createPhase9Grant(t, router, ownerCookie, granteeUsername, "view")
+	if status := todayStatus(); status != http.StatusOK {
+		t.Fatalf("expected re-invited grant OK after accepting, got %d", status)
+	}
+}
+
+func TestSharingInvitationTTLFromEnv(t *testing.T) {
+	t.Setenv("SHARING_INVITATION_TTL_HOURS", "")
+	if got := sharingInvitationTTL(); got != defaultSharingInvitationTTL {
+		t.Fatalf("default ttl = %s want %s", got, defaultSharingInvitationTTL)
+	}
+	t.Setenv("SHARING_INVITATION_TTL_HOURS", "48")
+	if got := sharingInvitationTTL(); got != 48*time.Hour {
+		t.Fatalf("ttl = %s want 48h", got)
+	}
+	t.Setenv("SHARING_INVITATION_TTL_HOURS", "-3")
+	if got := sharingInvitationTTL(); got != defaultSharingInvitationTTL {
+		t.Fatalf("invalid ttl = %s want default", got)
+	}
+}
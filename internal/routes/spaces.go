@@ -0,0 +1,665 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+)
+
+type spaceRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type addSpaceMemberRequest struct {
+	User   string `json:"user"`
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type updateSpaceMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type spaceTaskAssigneeRequest struct {
+	// UserID is nil or empty to clear the assignment.
+	UserID *string `json:"user_id"`
+}
+
+func registerSpaceRoutes(router *gin.RouterGroup) {
+	router.GET("/spaces", ListSpaces)
+	router.POST("/spaces", CreateSpace)
+	router.GET("/spaces/:id", GetSpace)
+	router.PUT("/spaces/:id", UpdateSpace)
+	router.DELETE("/spaces/:id", DeleteSpace)
+	router.POST("/spaces/:id/members", AddSpaceMember)
+	router.PUT("/spaces/:id/members/:userId", UpdateSpaceMember)
+	router.DELETE("/spaces/:id/members/:userId", RemoveSpaceMember)
+	router.PUT("/spaces/:id/schedules/:scheduleId", AddScheduleToSpace)
+	router.DELETE("/spaces/:id/schedules/:scheduleId", RemoveScheduleFromSpace)
+	router.GET("/spaces/:id/tasks", GetSpaceDayTasks)
+	router.GET("/spaces/:id/progress", GetSpaceProgress)
+	router.PUT("/spaces/:id/tasks/:taskId/assignee", AssignSpaceTask)
+}
+
+func ListSpaces(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	spaces, err := db.ListUserSpaces(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar los espacios")
+		log.Printf("failed to list spaces: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"spaces": spaces}, "Espacios recuperados")
+}
+
+func CreateSpace(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	space, ok := bindSpaceRequest(c)
+	if !ok {
+		return
+	}
+	if err := db.CreateSpace(c.Request.Context(), space, authData.ID); err != nil {
+		httpx.ServerError(c, "No se pudo crear el espacio")
+		log.Printf("failed to create space: %v", err)
+		return
+	}
+
+	created, err := db.GetSpaceForMember(c.Request.Context(), space.ID, authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar el espacio")
+		log.Printf("failed to reload space: %v", err)
+		return
+	}
+
+	httpx.Created(c, gin.H{"space": created}, "Espacio creado")
+}
+
+func GetSpace(c *gin.Context) {
+	_, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+
+	members, err := db.ListSpaceMembers(c.Request.Context(), space.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar los miembros")
+		log.Printf("failed to list space members: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"space": space, "members": members}, "Espacio recuperado")
+}
+
+func UpdateSpace(c *gin.Context) {
+	authData, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	if !space.Role.CanManageMembers() {
+		spacePermissionDenied(c)
+		return
+	}
+
+	input, ok := bindSpaceRequest(c)
+	if !ok {
+		return
+	}
+	if err := db.UpdateSpace(c.Request.Context(), space.ID, input.Name, input.Description); err != nil {
+		if errors.Is(err, db.ErrSpaceNotFound) {
+			httpx.NotFound(c, "Espacio no encontrado")
+			return
+		}
+		httpx.ServerError(c, "No se pudo actualizar el espacio")
+		log.Printf("failed to update space: %v", err)
+		return
+	}
+
+	updated, err := db.GetSpaceForMember(c.Request.Context(), space.ID, authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar el espacio")
+		log.Printf("failed to reload space: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"space": updated}, "Espacio actualizado")
+}
+
+func DeleteSpace(c *gin.Context) {
+	_, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	if space.Role != db.SpaceRoleOwner {
+		spacePermissionDenied(c)
+		return
+	}
+
+	if err := db.DeleteSpace(c.Request.Context(), space.ID); err != nil {
+		if errors.Is(err, db.ErrSpaceNotFound) {
+			httpx.NotFound(c, "Espacio no encontrado")
+			return
+		}
+		httpx.ServerError(c, "No se pudo eliminar el espacio")
+		log.Printf("failed to delete space: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Espacio eliminado")
+}
+
+func AddSpaceMember(c *gin.Context) {
+	_, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+
+	var request addSpaceMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	role, ok := assignableSpaceRole(c, space, request.Role)
+	if !ok {
+		return
+	}
+
+	user, err := resolveSpaceUser(c, request)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.NotFound(c, "Usuario no encontrado")
+			return
+		}
+		httpx.ServerError(c, "No se pudo validar el usuario")
+		log.Printf("failed to resolve space member: %v", err)
+		return
+	}
+
+	added, err := db.AddSpaceMember(c.Request.Context(), space.ID, user.ID, role)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo agregar al miembro")
+		log.Printf("failed to add space member: %v", err)
+		return
+	}
+	if !added {
+		httpx.Conflict(c, "space_member_exists", "El usuario ya es miembro del espacio")
+		return
+	}
+
+	members, err := db.ListSpaceMembers(c.Request.Context(), space.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar los miembros")
+		log.Printf("failed to list space members: %v", err)
+		return
+	}
+
+	httpx.Created(c, gin.H{"members": members}, "Miembro agregado")
+}
+
+func UpdateSpaceMember(c *gin.Context) {
+	_, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	memberID, ok := spaceMemberIDParam(c)
+	if !ok {
+		return
+	}
+
+	var request updateSpaceMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	role, ok := assignableSpaceRole(c, space, request.Role)
+	if !ok {
+		return
+	}
+
+	current, ok := loadSpaceMemberRole(c, space.ID, memberID)
+	if !ok {
+		return
+	}
+	if current == db.SpaceRoleOwner {
+		httpx.BadRequest(c, "No se puede cambiar el rol del propietario")
+		return
+	}
+	if current == db.SpaceRoleManager && space.Role != db.SpaceRoleOwner {
+		spacePermissionDenied(c)
+		return
+	}
+
+	updated, err := db.UpdateSpaceMemberRole(c.Request.Context(), space.ID, memberID, role)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo actualizar el rol")
+		log.Printf("failed to update space member role: %v", err)
+		return
+	}
+	if !updated {
+		httpx.NotFound(c, "Miembro no encontrado")
+		return
+	}
+
+	members, err := db.ListSpaceMembers(c.Request.Context(), space.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar los miembros")
+		log.Printf("failed to list space members: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"members": members}, "Rol actualizado")
+}
+
+// RemoveSpaceMember lets owners and managers remove other members, and any
+// member other than the owner leave the space.
+func RemoveSpaceMember(c *gin.Context) {
+	authData, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	memberID, ok := spaceMemberIDParam(c)
+	if !ok {
+		return
+	}
+
+	current, ok := loadSpaceMemberRole(c, space.ID, memberID)
+	if !ok {
+		return
+	}
+	if current == db.SpaceRoleOwner {
+		httpx.BadRequest(c, "El propietario no puede salir del espacio")
+		return
+	}
+	if memberID != authData.ID {
+		if !space.Role.CanManageMembers() || (current == db.SpaceRoleManager && space.Role != db.SpaceRoleOwner) {
+			spacePermissionDenied(c)
+			return
+		}
+	}
+
+	removed, err := db.RemoveSpaceMember(c.Request.Context(), space.ID, memberID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo eliminar al miembro")
+		log.Printf("failed to remove space member: %v", err)
+		return
+	}
+	if !removed {
+		httpx.NotFound(c, "Miembro no encontrado")
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Miembro eliminado")
+}
+
+// AddScheduleToSpace moves one of the caller's schedules into the space.
+// Only roles that may create tasks in the space can add schedules to it.
+func AddScheduleToSpace(c *gin.Context) {
+	authData, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	if !space.Role.Allows(db.SharingPermissionCreate) {
+		spacePermissionDenied(c)
+		return
+	}
+	schedule, ok := loadSpaceSchedule(c)
+	if !ok {
+		return
+	}
+	if schedule.UserID != authData.ID {
+		spacePermissionDenied(c)
+		return
+	}
+	if schedule.SpaceID == space.ID {
+		httpx.OK(c, gin.H{"schedule": schedule}, "La agenda ya pertenece al espacio")
+		return
+	}
+
+	moved, err := db.SetScheduleSpace(c.Request.Context(), schedule.ID, authData.ID, space.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo agregar la agenda al espacio")
+		log.Printf("failed to add schedule to space: %v", err)
+		return
+	}
+	if !moved {
+		httpx.NotFound(c, "Agenda no encontrada")
+		return
+	}
+	schedule.SpaceID = space.ID
+
+	httpx.OK(c, gin.H{"schedule": schedule}, "Agenda agregada al espacio")
+}
+
+// RemoveScheduleFromSpace detaches a schedule from the space. The schedule
+// owner, managers and the space owner may do it.
+func RemoveScheduleFromSpace(c *gin.Context) {
+	authData, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	schedule, ok := loadSpaceSchedule(c)
+	if !ok {
+		return
+	}
+	if schedule.SpaceID != space.ID {
+		httpx.NotFound(c, "Agenda no encontrada")
+		return
+	}
+	if schedule.UserID != authData.ID && !space.Role.CanManageMembers() {
+		spacePermissionDenied(c)
+		return
+	}
+
+	if _, err := db.SetScheduleSpace(c.Request.Context(), schedule.ID, schedule.UserID, ""); err != nil {
+		httpx.ServerError(c, "No se pudo quitar la agenda del espacio")
+		log.Printf("failed to remove schedule from space: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Agenda retirada del espacio")
+}
+
+// GetSpaceDayTasks returns every task of the space schedules for the date,
+// generating missing instances for each schedule owner first.
+func GetSpaceDayTasks(c *gin.Context) {
+	_, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	dateStr, date, ok := spaceDateParam(c)
+	if !ok {
+		return
+	}
+	if !generateSpaceTasks(c, space.ID, date) {
+		return
+	}
+
+	tasks, err := db.GetSpaceDateDetailedTasks(c.Request.Context(), space.ID, date)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar tareas del espacio")
+		log.Printf("failed to get space day tasks: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{
+		"tasks":    tasks,
+		"date":     dateStr,
+		"space_id": space.ID,
+	}, "Tareas del espacio recuperadas")
+}
+
+func GetSpaceProgress(c *gin.Context) {
+	_, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	_, date, ok := spaceDateParam(c)
+	if !ok {
+		return
+	}
+	if !generateSpaceTasks(c, space.ID, date) {
+		return
+	}
+
+	progress, err := db.GetSpaceDayProgress(c.Request.Context(), space.ID, date)
+	if err != nil {
+		httpx.ServerError(c, "Error al calcular el progreso del espacio")
+		log.Printf("failed to get space progress: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"progress": progress}, "Progreso del espacio recuperado")
+}
+
+// AssignSpaceTask assigns a space task to a member who may act on tasks. The
+// task owner and roles with edit permission can assign. Members joined the
+// space, so unlike delegation the assignment applies without accepting it.
+func AssignSpaceTask(c *gin.Context) {
+	authData, space, ok := loadSpaceForMember(c)
+	if !ok {
+		return
+	}
+	taskID := c.Param("taskId")
+	if _, err := uuid.Parse(taskID); err != nil {
+		httpx.BadRequest(c, "ID de tarea inválido")
+		return
+	}
+
+	var request spaceTaskAssigneeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	assigneeID := ""
+	if request.UserID != nil {
+		assigneeID = strings.TrimSpace(*request.UserID)
+	}
+
+	task, err := db.GetSpaceTask(c.Request.Context(), space.ID, taskID)
+	if err != nil {
+		if errors.Is(err, db.ErrSpaceTaskNotFound) {
+			httpx.NotFound(c, "Tarea no encontrada")
+			return
+		}
+		httpx.ServerError(c, "No se pudo recuperar la tarea")
+		log.Printf("failed to get space task: %v", err)
+		return
+	}
+	if task.UserID != authData.ID && !space.Role.Allows(db.SharingPermissionEdit) {
+		spacePermissionDenied(c)
+		return
+	}
+
+	if assigneeID != "" {
+		if _, err := uuid.Parse(assigneeID); err != nil {
+			httpx.BadRequest(c, "ID de usuario inválido")
+			return
+		}
+		role, err := db.GetSpaceMemberRole(c.Request.Context(), space.ID, assigneeID)
+		if err != nil {
+			httpx.ServerError(c, "No se pudo validar al miembro")
+			log.Printf("failed to get assignee space role: %v", err)
+			return
+		}
+		if role == "" || role == db.SpaceRoleViewer {
+			httpx.BadRequest(c, "Solo se pueden asignar tareas a miembros que no sean observadores")
+			return
+		}
+	}
+
+	if err := db.SetTaskAssignee(c.Request.Context(), task.ID, assigneeID, authData.ID, db.TaskAssignmentAccepted); err != nil {
+		httpx.ServerError(c, "No se pudo asignar la tarea")
+		log.Printf("failed to assign space task: %v", err)
+		return
+	}
+
+	updated, err := db.GetSpaceTask(c.Request.Context(), space.ID, task.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar la tarea")
+		log.Printf("failed to reload space task: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"task": updated}, "Asignación actualizada")
+}
+
+// loadSpaceForMember loads the space from the :id param with the caller's
+// role. Non-members get a 404 so spaces cannot be probed.
+func loadSpaceForMember(c *gin.Context) (*auth.Auth, *db.Space, bool) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return nil, nil, false
+	}
+
+	spaceID := c.Param("id")
+	if _, err := uuid.Parse(spaceID); err != nil {
+		httpx.BadRequest(c, "ID de espacio inválido")
+		return nil, nil, false
+	}
+
+	space, err := db.GetSpaceForMember(c.Request.Context(), spaceID, authData.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrSpaceNotFound) {
+			httpx.NotFound(c, "Espacio no encontrado")
+			return nil, nil, false
+		}
+		httpx.ServerError(c, "No se pudo recuperar el espacio")
+		log.Printf("failed to get space: %v", err)
+		return nil, nil, false
+	}
+
+	return authData, space, true
+}
+
+func bindSpaceRequest(c *gin.Context) (*db.Space, bool) {
+	var request spaceRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return nil, false
+	}
+
+	name, err := db.NormalizeSpaceName(request.Name)
+	if err != nil {
+		if errors.Is(err, db.ErrSpaceNameTooLong) {
+			httpx.BadRequest(c, fmt.Sprintf("El nombre no puede exceder %d caracteres", db.MaxSpaceNameLength))
+			return nil, false
+		}
+		httpx.BadRequest(c, "El nombre del espacio es obligatorio")
+		return nil, false
+	}
+
+	return &db.Space{Name: name, Description: strings.TrimSpace(request.Description)}, true
+}
+
+// assignableSpaceRole validates a role the caller wants to give someone.
+// Nobody can hand out ownership, and only the owner can appoint managers.
+func assignableSpaceRole(c *gin.Context, space *db.Space, value string) (db.SpaceRole, bool) {
+	if !space.Role.CanManageMembers() {
+		spacePermissionDenied(c)
+		return "", false
+	}
+	role, err := db.NormalizeSpaceRole(value)
+	if err != nil || role == db.SpaceRoleOwner {
+		httpx.BadRequest(c, "Rol inválido")
+		return "", false
+	}
+	if role == db.SpaceRoleManager && space.Role != db.SpaceRoleOwner {
+		spacePermissionDenied(c)
+		return "", false
+	}
+	return role, true
+}
+
+func loadSpaceMemberRole(c *gin.Context, spaceID string, userID string) (db.SpaceRole, bool) {
+	role, err := db.GetSpaceMemberRole(c.Request.Context(), spaceID, userID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo validar al miembro")
+		log.Printf("failed to get space member role: %v", err)
+		return "", false
+	}
+	if role == "" {
+		httpx.NotFound(c, "Miembro no encontrado")
+		return "", false
+	}
+	return role, true
+}
+
+func spaceMemberIDParam(c *gin.Context) (string, bool) {
+	userID := c.Param("userId")
+	if _, err := uuid.Parse(userID); err != nil {
+		httpx.BadRequest(c, "ID de usuario inválido")
+		return "", false
+	}
+	return userID, true
+}
+
+func loadSpaceSchedule(c *gin.Context) (*db.ScheduleTask, bool) {
+	scheduleID := c.Param("scheduleId")
+	if _, err := uuid.Parse(scheduleID); err != nil {
+		httpx.BadRequest(c, "ID de agenda inválido")
+		return nil, false
+	}
+
+	schedule, err := db.GetScheduleTaskByID(c.Request.Context(), scheduleID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			httpx.NotFound(c, "Agenda no encontrada")
+			return nil, false
+		}
+		httpx.ServerError(c, "No se pudo recuperar la agenda")
+		log.Printf("failed to get space schedule: %v", err)
+		return nil, false
+	}
+	return schedule, true
+}
+
+func spaceDateParam(c *gin.Context) (string, time.Time, bool) {
+	dateStr := strings.TrimSpace(c.Query("date"))
+	if dateStr == "" {
+		dateStr = time.Now().Format("2006-01-02")
+	}
+	date, err := parseDateOnly(dateStr)
+	if err != nil {
+		httpx.BadRequest(c, "Fecha inválida, usa formato YYYY-MM-DD")
+		return "", time.Time{}, false
+	}
+	return dateStr, date, true
+}
+
+// generateSpaceTasks creates the missing task instances for date for every
+// owner of a schedule in the space.
+func generateSpaceTasks(c *gin.Context, spaceID string, date time.Time) bool {
+	ownerIDs, err := db.ListSpaceScheduleOwnerIDs(c.Request.Context(), spaceID)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar tareas del espacio")
+		log.Printf("failed to list space schedule owners: %v", err)
+		return false
+	}
+	for _, ownerID := range ownerIDs {
+		if _, err := db.CreateUserTasksForDate(c.Request.Context(), ownerID, date); err != nil {
+			httpx.ServerError(c, "Error al generar tareas del espacio")
+			log.Printf("failed to generate space tasks for %s: %v", ownerID, err)
+			return false
+		}
+	}
+	return true
+}
+
+func resolveSpaceUser(c *gin.Context, request addSpaceMemberRequest) (*db.User, error) {
+	if strings.TrimSpace(request.UserID) != "" {
+		return db.GetUserByID(c.Request.Context(), strings.TrimSpace(request.UserID))
+	}
+
+	identifier := strings.TrimSpace(request.User)
+	if identifier == "" {
+		return nil, pgx.ErrNoRows
+	}
+
+	return db.GetUserByUsernameOrEmail(c.Request.Context(), identifier)
+}
+
+func spacePermissionDenied(c *gin.Context) {
+	httpx.ErrorCode(c, http.StatusForbidden, "space_permission_denied", "No tienes permiso para realizar esta acción en el espacio")
+}
@@ -0,0 +1,188 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+type spaceTasksResponse struct {
+	Data struct {
+		Tasks []db.DetailedTask `json:"tasks"`
+	} `json:"data"`
+}
+
+func TestSpaceRolesDayViewAndAssignment(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	ownerUsername := fmt.Sprintf("spaceowner_%d", suffix)
+	memberUsername := fmt.Sprintf("spacemember_%d", suffix)
+	viewerUsername := fmt.Sprintf("spaceviewer_%d", suffix)
+	outsiderUsername := fmt.Sprintf("spaceoutsider_%d", suffix)
+	password := "Test1234"
+	usernames := []string{ownerUsername, memberUsername, viewerUsername, outsiderUsername}
+	for _, username := range usernames {
+		cleanupTaskRouteUser(t, username)
+	}
+	t.Cleanup(func() {
+		for _, username := range usernames {
+			cleanupTaskRouteUser(t, username)
+		}
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	memberCookie := registerPhase5User(t, router, memberUsername, password)
+	viewerCookie := registerPhase5User(t, router, viewerUsername, password)
+	outsiderCookie := registerPhase5User(t, router, outsiderUsername, password)
+	memberID := getPhase9UserID(t, memberUsername)
+	viewerID := getPhase9UserID(t, viewerUsername)
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/spaces", map[string]string{
+		"name": "  Casa  ",
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create space status = %d body = %s", status, body)
+	}
+	var created struct {
+		Data struct {
+			Space db.Space `json:"space"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("decode space: %v body=%s", err, body)
+	}
+	space := created.Data.Space
+	if space.Name != "Casa" || space.Role != db.SpaceRoleOwner {
+		t.Fatalf("unexpected created space: %+v", space)
+	}
+	spacePath := "/api/v1/spaces/" + space.ID
+
+	// Only the owner may appoint managers; viewers cannot invite anyone.
+	for _, member := range []struct{ username, role string }{{memberUsername, "member"}, {viewerUsername, "viewer"}} {
+		status, body, _, _ = performJSONPayload(router, http.MethodPost, spacePath+"/members", map[string]string{
+			"user": member.username,
+			"role": member.role,
+		}, []*http.Cookie{ownerCookie})
+		if status != http.StatusCreated {
+			t.Fatalf("add %s status = %d body = %s", member.role, status, body)
+		}
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, spacePath+"/members", map[string]string{
+		"user": outsiderUsername,
+		"role": "member",
+	}, []*http.Cookie{viewerCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "space_permission_denied") {
+		t.Fatalf("expected viewer invite 403, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, spacePath+"/members", map[string]string{
+		"user": ownerUsername,
+		"role": "member",
+	}, []*http.Cookie{ownerCookie})
+	if status != http.StatusConflict {
+		t.Fatalf("expected duplicate member 409, got %d body = %s", status, body)
+	}
+	if status, _, _, _ = performJSONPayload(router, http.MethodGet, spacePath, nil, []*http.Cookie{outsiderCookie}); status != http.StatusNotFound {
+		t.Fatalf("expected outsider space 404, got %d", status)
+	}
+
+	// Viewers cannot bring schedules in; owners of schedules with create can.
+	viewerSchedule := createRouteSchedule(t, router, viewerCookie, "Space viewer schedule", "07:00", "08:00")
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, spacePath+"/schedules/"+viewerSchedule.Data.Schedule.ID, nil, []*http.Cookie{viewerCookie})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected viewer schedule 403, got %d body = %s", status, body)
+	}
+	schedule := createRouteSchedule(t, router, ownerCookie, "Space dishes", "09:00", "10:00")
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, spacePath+"/schedules/"+schedule.Data.Schedule.ID, nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("add schedule status = %d body = %s", status, body)
+	}
+
+	// Every member sees the space day, outsiders do not see the task.
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, spacePath+"/tasks", nil, []*http.Cookie{viewerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("space tasks status = %d body = %s", status, body)
+	}
+	var day spaceTasksResponse
+	if err := json.Unmarshal([]byte(body), &day); err != nil {
+		t.Fatalf("decode space tasks: %v body=%s", err, body)
+	}
+	var taskID string
+	for _, task := range day.Data.Tasks {
+		if task.ScheduleTaskID == schedule.Data.Schedule.ID {
+			taskID = task.ID
+		}
+	}
+	if taskID == "" {
+		t.Fatalf("space day missing schedule task: %s", body)
+	}
+	if status, _, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/"+taskID, nil, []*http.Cookie{viewerCookie}); status != http.StatusOK {
+		t.Fatalf("expected viewer task details 200, got %d", status)
+	}
+	if status, _, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/"+taskID, nil, []*http.Cookie{outsiderCookie}); status != http.StatusForbidden {
+		t.Fatalf("expected outsider task details 403, got %d", status)
+	}
+	statsPath := "/api/v1/schedules/" + schedule.Data.Schedule.ID + "/stats"
+	if status, body, _, _ = performJSONPayload(router, http.MethodGet, statsPath, nil, []*http.Cookie{viewerCookie}); status != http.StatusOK {
+		t.Fatalf("expected viewer schedule stats 200, got %d body = %s", status, body)
+	}
+	if status, _, _, _ = performJSONPayload(router, http.MethodGet, statsPath, nil, []*http.Cookie{outsiderCookie}); status != http.StatusForbidden {
+		t.Fatalf("expected outsider schedule stats 403, got %d", status)
+	}
+
+	// Members without edit cannot assign; viewers cannot be assignees.
+	assigneePath := spacePath + "/tasks/" + taskID + "/assignee"
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user_id": memberID}, []*http.Cookie{memberCookie})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected member assign 403, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user_id": viewerID}, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected viewer assignee 400, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user_id": memberID}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"assigneeUserId":"`+memberID+`"`) {
+		t.Fatalf("assign status = %d body = %s", status, body)
+	}
+
+	// The assignee may complete the task even though members lack edit.
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/tasks/"+taskID, map[string]string{"status": "completed"}, []*http.Cookie{memberCookie})
+	if status != http.StatusOK {
+		t.Fatalf("assignee complete status = %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, spacePath+"/progress", nil, []*http.Cookie{viewerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("space progress status = %d body = %s", status, body)
+	}
+	var progress struct {
+		Data struct {
+			Progress db.SpaceDayProgress `json:"progress"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &progress); err != nil {
+		t.Fatalf("decode space progress: %v body=%s", err, body)
+	}
+	var memberProgress *db.SpaceMemberProgress
+	for _, entry := range progress.Data.Progress.Members {
+		if entry.UserID == memberID {
+			memberProgress = entry
+		}
+	}
+	if memberProgress == nil || memberProgress.Completed != 1 || memberProgress.Percentage != 100 {
+		t.Fatalf("expected member credited with the completion, got %s", body)
+	}
+
+	// Leaving the space drops access and the assignment.
+	status, body, _, _ = performJSONPayload(router, http.MethodDelete, spacePath+"/members/"+memberID, nil, []*http.Cookie{memberCookie})
+	if status != http.StatusOK {
+		t.Fatalf("leave space status = %d body = %s", status, body)
+	}
+	if status, _, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/"+taskID, nil, []*http.Cookie{memberCookie}); status != http.StatusForbidden {
+		t.Fatalf("expected former member task details 403, got %d", status)
+	}
+}
@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskStreakAndRestDays(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	username := fmt.Sprintf("streakuser_%d", suffix)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() {
+		cleanupTaskRouteUser(t, username)
+	})
+	cookie := registerPhase5User(t, router, username, "Test1234")
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPut, "/api/v1/tasks/streak/rest-days", map[string][]int{"rest_weekdays": {7}}, []*http.Cookie{cookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected invalid weekday 400, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, "/api/v1/tasks/streak/rest-days", map[string][]int{"rest_weekdays": {6, 0, 6}}, []*http.Cookie{cookie})
+	if status != http.StatusOK || !strings.Contains(body, `"rest_weekdays":[0,6]`) {
+		t.Fatalf("set rest days status = %d body = %s", status, body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/streak", nil, []*http.Cookie{cookie})
+	if status != http.StatusOK {
+		t.Fatalf("streak status = %d body = %s", status, body)
+	}
+	var response struct {
+		Data struct {
+			Streak struct {
+				RestWeekdays     []int             `json:"rest_weekdays"`
+				CurrentStreak    int               `json:"current_streak"`
+				FreezesAvailable int               `json:"freezes_available"`
+				EvaluatedThrough string            `json:"evaluated_through"`
+				Freezes          []json.RawMessage `json:"freezes"`
+			} `json:"streak"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("decode streak: %v", err)
+	}
+	streak := response.Data.Streak
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	if len(streak.RestWeekdays) != 2 || streak.CurrentStreak != 0 || streak.FreezesAvailable != 0 || streak.EvaluatedThrough != yesterday || len(streak.Freezes) != 0 {
+		t.Fatalf("unexpected new user streak: %s", body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/metrics", nil, []*http.Cookie{cookie})
+	if status != http.StatusOK || !strings.Contains(body, `"longest_streak":0`) || !strings.Contains(body, `"frozen_days":0`) {
+		t.Fatalf("metrics status = %d body = %s", status, body)
+	}
+}
@@ -0,0 +1,203 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+	"github.com/vladwithcode/tasktracker/internal/notifications"
+	tasksvc "github.com/vladwithcode/tasktracker/internal/tasks"
+)
+
+type delegateTaskRequest struct {
+	// User is a username or email; UserID wins when both are set. Leaving
+	// both empty takes the task back.
+	User   string `json:"user"`
+	UserID string `json:"user_id"`
+}
+
+func registerTaskAssignmentRoutes(router *gin.RouterGroup) {
+	router.GET("/tasks/assigned", ListTaskAssignments)
+	router.PUT("/tasks/:id/assignee", DelegateTask)
+	router.POST("/tasks/:id/assignment/accept", AcceptTaskAssignment)
+	router.POST("/tasks/:id/assignment/decline", DeclineTaskAssignment)
+}
+
+func ListTaskAssignments(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	tasks, err := service.ListAssignments(c.Request.Context(), authData.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar las tareas asignadas")
+		log.Printf("failed to list task assignments: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"tasks": tasks}, "Tareas asignadas recuperadas")
+}
+
+func DelegateTask(c *gin.Context) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+	taskID := c.Param("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		httpx.BadRequest(c, "ID de tarea inválido")
+		return
+	}
+
+	var request delegateTaskRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+
+	var assignee *db.User
+	if strings.TrimSpace(request.UserID) != "" || strings.TrimSpace(request.User) != "" {
+		assignee, err = resolveTaskAssignee(c, request)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				httpx.NotFound(c, "Usuario no encontrado")
+				return
+			}
+			httpx.ServerError(c, "No se pudo validar el usuario")
+			log.Printf("failed to resolve task assignee: %v", err)
+			return
+		}
+	}
+	assigneeID := ""
+	if assignee != nil {
+		assigneeID = assignee.ID
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	task, err := service.Delegate(c.Request.Context(), authData, taskID, assigneeID)
+	if err != nil {
+		switch {
+		case errors.Is(err, tasksvc.ErrNotFound):
+			httpx.NotFound(c, "Tarea no encontrada")
+		case errors.Is(err, tasksvc.ErrForbidden):
+			sharingPermissionDenied(c)
+		case errors.Is(err, tasksvc.ErrApprovalRequired):
+			httpx.ErrorCode(c, http.StatusForbidden, "sharing_approval_required", "Los cambios a esta tarea requieren la aprobación del propietario")
+		case errors.Is(err, tasksvc.ErrAssigneeIsOwner):
+			httpx.BadRequest(c, "La tarea ya pertenece a este usuario")
+		case errors.Is(err, tasksvc.ErrAssigneeIsDelegator):
+			httpx.BadRequest(c, "No puedes asignarte una tarea que no es tuya")
+		case errors.Is(err, tasksvc.ErrAssignmentUnchanged):
+			httpx.Conflict(c, "assignment_exists", "La tarea ya está asignada a este usuario")
+		default:
+			httpx.ServerError(c, "No se pudo asignar la tarea")
+			log.Printf("failed to delegate task: %v", err)
+		}
+		return
+	}
+
+	if assignee == nil {
+		httpx.OK(c, gin.H{"task": task}, "Asignación retirada")
+		return
+	}
+
+	delegator, err := db.GetUserByID(c.Request.Context(), authData.ID)
+	if err != nil {
+		log.Printf("failed to load task delegator for notification: %v", err)
+	} else {
+		go notifications.NotifyTaskDelegated(context.WithoutCancel(c.Request.Context()), task, delegator)
+	}
+
+	httpx.OK(c, gin.H{"task": task}, "Tarea asignada, pendiente de aceptación")
+}
+
+func AcceptTaskAssignment(c *gin.Context) {
+	respondToTaskAssignment(c, true, "Tarea aceptada")
+}
+
+func DeclineTaskAssignment(c *gin.Context) {
+	respondToTaskAssignment(c, false, "Tarea rechazada")
+}
+
+func respondToTaskAssignment(c *gin.Context, accept bool, okMsg string) {
+	authData, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+	taskID := c.Param("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		httpx.BadRequest(c, "ID de tarea inválido")
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	task, err := service.RespondToAssignment(c.Request.Context(), authData, taskID, accept)
+	if err != nil {
+		switch {
+		case errors.Is(err, tasksvc.ErrNotFound):
+			httpx.NotFound(c, "Asignación no encontrada")
+		case errors.Is(err, tasksvc.ErrAssignmentNotPending):
+			httpx.Conflict(c, "assignment_not_pending", "La asignación ya fue respondida")
+		default:
+			httpx.ServerError(c, "No se pudo responder la asignación")
+			log.Printf("failed to respond to task assignment: %v", err)
+		}
+		return
+	}
+
+	assignee, err := db.GetUserByID(c.Request.Context(), authData.ID)
+	if err != nil {
+		log.Printf("failed to load task assignee for notification: %v", err)
+	} else {
+		go notifications.NotifyTaskAssignmentOutcome(context.WithoutCancel(c.Request.Context()), task, assignee)
+	}
+
+	task.CanEdit = accept
+	httpx.OK(c, gin.H{"task": task}, okMsg)
+}
+
+// notifyDelegatedTaskCompletion tells the delegator that the caller, as the
+// accepted assignee, just completed the task. previous is the task status
+// before the update so re-sending a completion does not notify twice.
+func notifyDelegatedTaskCompletion(ctx context.Context, previous db.TaskStatus, task *db.DetailedTask, actorID string) {
+	if previous == db.TaskStatusCompleted || task.Status != db.TaskStatusCompleted {
+		return
+	}
+	if task.AssigneeUserID != actorID || task.AssignmentStatus != db.TaskAssignmentAccepted {
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		assignee, err := db.GetUserByID(ctx, actorID)
+		if err != nil {
+			log.Printf("task assignment: load assignee %s: %v", actorID, err)
+			return
+		}
+		notifications.NotifyDelegatedTaskCompleted(ctx, task, assignee)
+	}()
+}
+
+func resolveTaskAssignee(c *gin.Context, request delegateTaskRequest) (*db.User, error) {
+	if userID := strings.TrimSpace(request.UserID); userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			return nil, pgx.ErrNoRows
+		}
+		return db.GetUserByID(c.Request.Context(), userID)
+	}
+
+	return db.GetUserByUsernameOrEmail(c.Request.Context(), strings.TrimSpace(request.User))
+}
@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestTaskDelegationAcceptDeclineAndMetrics(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	suffix := time.Now().UnixNano() % 1_000_000_000
+	ownerUsername := fmt.Sprintf("delegowner_%d", suffix)
+	assigneeUsername := fmt.Sprintf("delegassignee_%d", suffix)
+	outsiderUsername := fmt.Sprintf("delegoutsider_%d", suffix)
+	password := "Test1234"
+	usernames := []string{ownerUsername, assigneeUsername, outsiderUsername}
+	for _, username := range usernames {
+		cleanupTaskRouteUser(t, username)
+	}
+	t.Cleanup(func() {
+		for _, username := range usernames {
+			cleanupTaskRouteUser(t, username)
+		}
+	})
+
+	ownerCookie := registerPhase5User(t, router, ownerUsername, password)
+	assigneeCookie := registerPhase5User(t, router, assigneeUsername, password)
+	outsiderCookie := registerPhase5User(t, router, outsiderUsername, password)
+	ownerID := getPhase9UserID(t, ownerUsername)
+	assigneeID := getPhase9UserID(t, assigneeUsername)
+
+	schedule := createRouteSchedule(t, router, ownerCookie, "Delegated errand", "09:00", "10:00")
+	status, body, _, _ := performJSONPayload(router, http.MethodGet, "/api/v1/tasks/today", nil, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("owner today status = %d body = %s", status, body)
+	}
+	var today routeTodayResponse
+	if err := json.Unmarshal([]byte(body), &today); err != nil {
+		t.Fatalf("decode today: %v body=%s", err, body)
+	}
+	task := findTaskBySchedule(t, today.Data.Tasks, schedule.Data.Schedule.ID)
+	taskPath := "/api/v1/tasks/" + task.ID
+	assigneePath := taskPath + "/assignee"
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user_id": ownerID}, []*http.Cookie{ownerCookie})
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected self assignment 400, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user": outsiderUsername}, []*http.Cookie{outsiderCookie})
+	if status != http.StatusForbidden || !strings.Contains(body, "sharing_permission_denied") {
+		t.Fatalf("expected outsider delegate 403, got %d body = %s", status, body)
+	}
+
+	// Pending assignees can look at the task but not work on it.
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user": assigneeUsername}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"assignmentStatus":"pending"`) {
+		t.Fatalf("delegate status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user_id": assigneeID}, []*http.Cookie{ownerCookie})
+	if status != http.StatusConflict {
+		t.Fatalf("expected repeated delegate 409, got %d body = %s", status, body)
+	}
+	if status, _, _, _ = performJSONPayload(router, http.MethodGet, taskPath, nil, []*http.Cookie{assigneeCookie}); status != http.StatusOK {
+		t.Fatalf("expected pending assignee task details 200, got %d", status)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath, map[string]string{"status": "completed"}, []*http.Cookie{assigneeCookie})
+	if status != http.StatusForbidden {
+		t.Fatalf("expected pending assignee update 403, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/assigned", nil, []*http.Cookie{assigneeCookie})
+	if status != http.StatusOK || !strings.Contains(body, task.ID) {
+		t.Fatalf("assigned list status = %d body = %s", status, body)
+	}
+
+	// Declining hands the task back; it can be delegated again and accepted.
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, taskPath+"/assignment/decline", nil, []*http.Cookie{assigneeCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"assignmentStatus":"declined"`) {
+		t.Fatalf("decline status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, taskPath+"/assignment/accept", nil, []*http.Cookie{assigneeCookie})
+	if status != http.StatusConflict {
+		t.Fatalf("expected accept after decline 409, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, assigneePath, map[string]string{"user_id": assigneeID}, []*http.Cookie{ownerCookie})
+	if status != http.StatusOK {
+		t.Fatalf("re-delegate status = %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, taskPath+"/assignment/accept", nil, []*http.Cookie{outsiderCookie})
+	if status != http.StatusNotFound {
+		t.Fatalf("expected outsider accept 404, got %d body = %s", status, body)
+	}
+	status, body, _, _ = performJSONPayload(router, http.MethodPost, taskPath+"/assignment/accept", nil, []*http.Cookie{assigneeCookie})
+	if status != http.StatusOK || !strings.Contains(body, `"assignmentStatus":"accepted"`) {
+		t.Fatalf("accept status = %d body = %s", status, body)
+	}
+
+	// Accepted tasks join the assignee's day view.
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/day", nil, []*http.Cookie{assigneeCookie})
+	if status != http.StatusOK {
+		t.Fatalf("assignee day status = %d body = %s", status, body)
+	}
+	var assigneeDay routeTodayResponse
+	if err := json.Unmarshal([]byte(body), &assigneeDay); err != nil {
+		t.Fatalf("decode assignee day: %v body=%s", err, body)
+	}
+	if found := findTaskBySchedule(t, assigneeDay.Data.Tasks, schedule.Data.Schedule.ID); found.ID != task.ID {
+		t.Fatalf("assignee day missing delegated task: %s", body)
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodPut, taskPath, map[string]string{"status": "completed"}, []*http.Cookie{assigneeCookie})
+	if status != http.StatusOK {
+		t.Fatalf("assignee complete status = %d body = %s", status, body)
+	}
+
+	// The completion counts for the assignee, not the owner.
+	if progress := getProgress(t, router, assigneeCookie, ""); progress.Total != 1 || progress.Completed != 1 {
+		t.Fatalf("expected assignee credited with the task, got %+v", progress)
+	}
+	if progress := getProgress(t, router, ownerCookie, ""); progress.Total != 0 {
+		t.Fatalf("expected owner progress without the delegated task, got %+v", progress)
+	}
+
+	// The owner, who delegated the task, hears about the completion.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/notifications/inbox?type=system", nil, []*http.Cookie{ownerCookie})
+		if status != http.StatusOK {
+			t.Fatalf("owner inbox status = %d body = %s", status, body)
+		}
+		if strings.Contains(body, "Tarea completada") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected completion notice in owner inbox, got %s", body)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestTaskAssignmentStatusIsActive(t *testing.T) {
+	cases := map[db.TaskAssignmentStatus]bool{
+		db.TaskAssignmentPending:  true,
+		db.TaskAssignmentAccepted: true,
+		db.TaskAssignmentDeclined: false,
+		"":                        false,
+	}
+	for status, want := range cases {
+		if got := status.IsActive(); got != want {
+			t.Errorf("%q.IsActive() = %v, want %v", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/httpx"
+	"github.com/vladwithcode/tasktracker/internal/notifications"
+	tasksvc "github.com/vladwithcode/tasktracker/internal/tasks"
+)
+
+type taskCommentRequest struct {
+	Body string `json:"body"`
+}
+
+func registerTaskCommentRoutes(router *gin.RouterGroup) {
+	router.GET("/tasks/:id/comments", ListTaskComments)
+	router.POST("/tasks/:id/comments", CreateTaskComment)
+	router.PUT("/tasks/:id/comments/:commentId", UpdateTaskComment)
+	router.DELETE("/tasks/:id/comments/:commentId", DeleteTaskComment)
+}
+
+func ListTaskComments(c *gin.Context) {
+	_, task, ok := authorizeTaskThread(c)
+	if !ok {
+		return
+	}
+
+	comments, err := db.ListTaskComments(c.Request.Context(), task.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron cargar los comentarios")
+		log.Printf("failed to list task comments: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"comments": comments}, "Comentarios recuperados")
+}
+
+func CreateTaskComment(c *gin.Context) {
+	sessionAuth, task, ok := authorizeTaskThread(c)
+	if !ok {
+		return
+	}
+
+	body, mentionIDs, ok := bindTaskCommentBody(c, task)
+	if !ok {
+		return
+	}
+
+	comment := &db.TaskComment{
+		TaskID:       task.ID,
+		AuthorUserID: sessionAuth.ID,
+		Body:         body,
+	}
+	if err := db.CreateTaskComment(c.Request.Context(), comment, mentionIDs); err != nil {
+		httpx.ServerError(c, "No se pudo publicar el comentario")
+		log.Printf("failed to create task comment: %v", err)
+		return
+	}
+
+	created, err := db.GetTaskComment(c.Request.Context(), task.ID, comment.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar el comentario")
+		log.Printf("failed to reload task comment: %v", err)
+		return
+	}
+
+	go notifyTaskCommentParticipants(context.WithoutCancel(c.Request.Context()), task, created, mentionIDs)
+
+	httpx.Created(c, gin.H{"comment": created}, "Comentario publicado")
+}
+
+func UpdateTaskComment(c *gin.Context) {
+	sessionAuth, task, ok := authorizeTaskThread(c)
+	if !ok {
+		return
+	}
+	commentID, ok := taskCommentIDParam(c)
+	if !ok {
+		return
+	}
+
+	body, mentionIDs, ok := bindTaskCommentBody(c, task)
+	if !ok {
+		return
+	}
+
+	err := db.UpdateTaskComment(c.Request.Context(), task.ID, commentID, sessionAuth.ID, body, mentionIDs)
+	if err != nil {
+		if errors.Is(err, db.ErrTaskCommentNotFound) {
+			httpx.NotFound(c, "Comentario no encontrado")
+			return
+		}
+		httpx.ServerError(c, "No se pudo editar el comentario")
+		log.Printf("failed to update task comment: %v", err)
+		return
+	}
+
+	updated, err := db.GetTaskComment(c.Request.Context(), task.ID, commentID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo recuperar el comentario")
+		log.Printf("failed to reload task comment: %v", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"comment": updated}, "Comentario editado")
+}
+
+func DeleteTaskComment(c *gin.Context) {
+	sessionAuth, task, ok := authorizeTaskThread(c)
+	if !ok {
+		return
+	}
+	commentID, ok := taskCommentIDParam(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := db.DeleteTaskComment(c.Request.Context(), task.ID, commentID, sessionAuth.ID)
+	if err != nil {
+		httpx.ServerError(c, "No se pudo eliminar el comentario")
+		log.Printf("failed to delete task comment: %v", err)
+		return
+	}
+	if !deleted {
+		httpx.NotFound(c, "Comentario no encontrado")
+		return
+	}
+
+	httpx.OK(c, gin.H{}, "Comentario eliminado")
+}
+
+// authorizeTaskThread loads the task from the :id param and checks that the
+// caller may view it, which is what every thread operation requires.
+func authorizeTaskThread(c *gin.Context) (*auth.Auth, *db.DetailedTask, bool) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return nil, nil, false
+	}
+
+	taskID := c.Param("id")
+	if _, err := uuid.Parse(taskID); err != nil {
+		httpx.BadRequest(c, "ID de tarea inválido")
+		return nil, nil, false
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	task, err := service.GetDetails(c.Request.Context(), sessionAuth, taskID)
+	if err != nil {
+		switch {
+		case errors.Is(err, tasksvc.ErrNotFound):
+			httpx.NotFound(c, "Tarea no encontrada")
+		case errors.Is(err, tasksvc.ErrForbidden):
+			sharingPermissionDenied(c)
+		default:
+			httpx.ServerError(c, "Error al validar permisos compartidos")
+			log.Printf("failed to authorize task thread: %v", err)
+		}
+		return nil, nil, false
+	}
+
+	return sessionAuth, task, true
+}
+
+func taskCommentIDParam(c *gin.Context) (string, bool) {
+	commentID := c.Param("commentId")
+	if _, err := uuid.Parse(commentID); err != nil {
+		httpx.BadRequest(c, "ID de comentario inválido")
+		return "", false
+	}
+	return commentID, true
+}
+
+// bindTaskCommentBody validates the request body and resolves its @mentions.
+// Mentions of unknown users, or of users who cannot see the task, are kept
+// as plain text.
+func bindTaskCommentBody(c *gin.Context, task *db.DetailedTask) (string, []string, bool) {
+	var request taskCommentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return "", nil, false
+	}
+
+	body, err := db.NormalizeTaskCommentBody(request.Body)
+	if err != nil {
+		if errors.Is(err, db.ErrTaskCommentTooLong) {
+			httpx.BadRequest(c, fmt.Sprintf("El comentario no puede exceder %d caracteres", db.MaxTaskCommentLength))
+			return "", nil, false
+		}
+		httpx.BadRequest(c, "El comentario no puede estar vacío")
+		return "", nil, false
+	}
+
+	mentioned, err := db.GetUsersByUsernames(c.Request.Context(), db.ExtractMentionUsernames(body))
+	if err != nil {
+		httpx.ServerError(c, "No se pudieron resolver las menciones")
+		log.Printf("failed to resolve comment mentions: %v", err)
+		return "", nil, false
+	}
+	mentionIDs := []string{}
+	for _, user := range mentioned {
+		allowed, err := db.UserHasScheduleTaskPermission(c.Request.Context(), task.UserID, user.ID, task.ScheduleTaskID, db.SharingPermissionView)
+		if err != nil {
+			httpx.ServerError(c, "Error al validar permisos compartidos")
+			log.Printf("failed to validate mention permission: %v", err)
+			return "", nil, false
+		}
+		if allowed {
+			mentionIDs = append(mentionIDs, user.ID)
+		}
+	}
+
+	return body, mentionIDs, true
+}
+
+// notifyTaskCommentParticipants sends an inbox notice and a push to everyone
+// in the thread except the author. Participants whose access was revoked
+// since they joined the thread are skipped.
+func notifyTaskCommentParticipants(ctx context.Context, task *db.DetailedTask, comment *db.TaskComment, mentionIDs []string) {
+	participants, err := db.ListTaskCommentParticipants(ctx, task.ID)
+	if err != nil {
+		log.Printf("task comment notify: list participants for %s: %v", task.ID, err)
+		return
+	}
+
+	mentioned := map[string]bool{}
+	for _, id := range mentionIDs {
+		mentioned[id] = true
+	}
+
+	author := comment.AuthorFullname
+	if author == "" {
+		author = "@" + comment.AuthorUsername
+	}
+	taskTitle := strings.TrimSpace(task.Title)
+	if taskTitle == "" {
+		taskTitle = "Tarea"
+	}
+	url := "/tasks/" + task.ID
+	body := truncateRunes(comment.Body, 140)
+
+	for _, userID := range participants {
+		if userID == comment.AuthorUserID {
+			continue
+		}
+		allowed, err := db.UserHasScheduleTaskPermission(ctx, task.UserID, userID, task.ScheduleTaskID, db.SharingPermissionView)
+		if err != nil {
+			log.Printf("task comment notify: check access for %s: %v", userID, err)
+			continue
+		}
+		if !allowed {
+			continue
+		}
+
+		title := author + " comentó en " + taskTitle
+		if mentioned[userID] {
+			title = author + " te mencionó en " + taskTitle
+		}
+		data := map[string]string{"task_id": task.ID, "comment_id": comment.ID}
+		if _, err := db.CreateInboxNotice(ctx, userID, db.InboxItemTypeComment, title, body, url, data); err != nil {
+			log.Printf("task comment notify: store notice for %s: %v", userID, err)
+		}
+
+		payload := &notifications.NotificationPayload{
+			Title:  title,
+			Body:   body,
+			Icon:   "/icon-192x192.png",
+			Badge:  "/badge-72x72.png",
+			Tag:    "task-comment-" + task.ID,
+			URL:    url,
+			TaskID: task.ID,
+			Data:   data,
+		}
+		dedupeKey := "task-comment:" + comment.ID + ":" + userID
+		if _, err := notifications.Enqueue(ctx, userID, "task_comment", dedupeKey, payload); err != nil {
+			log.Printf("task comment notify: queue push for %s: %v", userID, err)
+		}
+	}
+}
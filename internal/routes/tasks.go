@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/vladwithcode/tasktracker/internal/auth"
 	"github.com/vladwithcode/tasktracker/internal/db"
@@ -56,6 +58,13 @@ func registerTaskRoutes(router *gin.RouterGroup) {
 	router.GET("/tasks/progress", GetTaskProgress)
 	router.GET("/tasks/history", GetTaskHistory)
 	router.GET("/tasks/metrics", GetTaskMetrics)
+	router.GET("/tasks/time", GetTaskTimeAccounting)
+	router.GET("/tasks/heatmap", GetTaskHeatmap)
+	router.GET("/tasks/coach", GetCoachDashboard)
+	router.GET("/tasks/streak", GetTaskStreak)
+	router.PUT("/tasks/streak/rest-days", UpdateStreakRestDays)
+	router.GET("/tasks/score-weights", GetScoreWeights)
+	router.PUT("/tasks/score-weights", UpdateScoreWeights)
 	router.GET("/tasks/:id", GetTaskDetails)
 }
 
@@ -132,6 +141,13 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
+	var previousStatus db.TaskStatus
+	if updateInput.Status == db.TaskStatusCompleted {
+		if previous, err := db.GetTaskByID(c.Request.Context(), c.Param("id")); err == nil {
+			previousStatus = previous.Status
+		}
+	}
+
 	service := tasksvc.NewService(tasksvc.NewRepository())
 	detailedTask, err := service.Update(c.Request.Context(), sessionAuth, c.Param("id"), updateInput)
 	if err != nil {
@@ -143,10 +159,17 @@ func UpdateTask(c *gin.Context) {
 			httpx.Forbidden(c, "No tienes permisos para editar esta tarea")
 			return
 		}
+		if errors.Is(err, tasksvc.ErrApprovalRequired) {
+			proposeTaskUpdate(c, sessionAuth, c.Param("id"), updateInput)
+			return
+		}
 		httpx.ServerError(c, "Error al actualizar tarea")
 		log.Printf("failed to update task: %v\n", err)
 		return
 	}
+	if updateInput.Status == db.TaskStatusCompleted {
+		notifyDelegatedTaskCompletion(c.Request.Context(), previousStatus, detailedTask, sessionAuth.ID)
+	}
 
 	httpx.OK(c, gin.H{"task": detailedTask}, "Tarea actualizada")
 }
@@ -324,14 +347,18 @@ func GetTaskProgress(c *gin.Context) {
 	}
 
 	service := tasksvc.NewService(tasksvc.NewRepository())
-	progress, err := service.GetDayProgress(c.Request.Context(), sessionAuth.ID, day)
+	ownerUserID, ok := resolveAnalyticsOwner(c, service, sessionAuth)
+	if !ok {
+		return
+	}
+	progress, err := service.GetDayProgress(c.Request.Context(), ownerUserID, day)
 	if err != nil {
 		httpx.ServerError(c, "Error al recuperar progreso")
 		log.Printf("failed to get day progress: %v\n", err)
 		return
 	}
 
-	httpx.OK(c, gin.H{"progress": progress}, "Progreso recuperado")
+	httpx.OK(c, gin.H{"progress": progress, "owner_user_id": ownerUserID}, "Progreso recuperado")
 }
 
 func GetTaskHistory(c *gin.Context) {
@@ -342,21 +369,45 @@ func GetTaskHistory(c *gin.Context) {
 		return
 	}
 
-	from, to, err := parseTaskRange(c)
+	granularity, ok := db.ParseTimeGranularity(c.Query("granularity"))
+	if !ok {
+		httpx.BadRequest(c, "Agrupación inválida, usa day, week o month")
+		return
+	}
+	weekStart, err := parseWeekStart(c)
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+	from, to, err := parseGroupedTaskRange(c, granularity)
 	if err != nil {
 		httpx.BadRequest(c, err.Error())
 		return
 	}
 
+	var breakdown db.TaskBreakdownDimension
+	if value := strings.TrimSpace(c.Query("breakdown")); value != "" {
+		dimension, ok := db.ParseTaskBreakdownDimension(value)
+		if !ok {
+			httpx.BadRequest(c, "Desglose inválido, usa category, priority o required")
+			return
+		}
+		breakdown = dimension
+	}
+
 	service := tasksvc.NewService(tasksvc.NewRepository())
-	history, err := service.GetHistory(c.Request.Context(), sessionAuth.ID, from, to)
+	ownerUserID, ok := resolveAnalyticsOwner(c, service, sessionAuth)
+	if !ok {
+		return
+	}
+	history, err := service.GetHistory(c.Request.Context(), ownerUserID, from, to, breakdown, granularity, weekStart)
 	if err != nil {
 		httpx.ServerError(c, "Error al recuperar historial")
 		log.Printf("failed to get task history: %v\n", err)
 		return
 	}
 
-	httpx.OK(c, gin.H{"history": history}, "Historial recuperado")
+	httpx.OK(c, gin.H{"history": history, "owner_user_id": ownerUserID}, "Historial recuperado")
 }
 
 func GetTaskMetrics(c *gin.Context) {
@@ -374,17 +425,330 @@ func GetTaskMetrics(c *gin.Context) {
 	}
 
 	service := tasksvc.NewService(tasksvc.NewRepository())
-	metrics, err := service.GetMetrics(c.Request.Context(), sessionAuth.ID, from, to)
+	ownerUserID, ok := resolveAnalyticsOwner(c, service, sessionAuth)
+	if !ok {
+		return
+	}
+	metrics, err := service.GetMetrics(c.Request.Context(), ownerUserID, from, to)
 	if err != nil {
 		httpx.ServerError(c, "Error al recuperar métricas")
 		log.Printf("failed to get task metrics: %v\n", err)
 		return
 	}
 
-	httpx.OK(c, gin.H{"metrics": metrics}, "Métricas recuperadas")
+	httpx.OK(c, gin.H{"metrics": metrics, "owner_user_id": ownerUserID}, "Métricas recuperadas")
+}
+
+// GetTaskHeatmap returns a year of days with an intensity level each, like a
+// contribution graph. category and schedule_id narrow it to one slice.
+func GetTaskHeatmap(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		log.Printf("failed to get auth: %v\n", err)
+		return
+	}
+
+	year := time.Now().Year()
+	if value := strings.TrimSpace(c.Query("year")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < minHeatmapYear || parsed > time.Now().Year()+1 {
+			httpx.BadRequest(c, "Año inválido")
+			return
+		}
+		year = parsed
+	}
+	filter := db.TaskHeatmapFilter{
+		Category:   strings.TrimSpace(c.Query("category")),
+		ScheduleID: strings.TrimSpace(c.Query("schedule_id")),
+	}
+	if filter.ScheduleID != "" {
+		if _, err := uuid.Parse(filter.ScheduleID); err != nil {
+			httpx.BadRequest(c, "ID de rutina inválido")
+			return
+		}
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	ownerUserID, ok := resolveAnalyticsOwner(c, service, sessionAuth)
+	if !ok {
+		return
+	}
+	heatmap, err := service.GetHeatmap(c.Request.Context(), ownerUserID, year, filter)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar el mapa de actividad")
+		log.Printf("failed to get task heatmap: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"heatmap": heatmap, "owner_user_id": ownerUserID}, "Mapa de actividad recuperado")
+}
+
+// GetTaskTimeAccounting compares scheduled with tracked time: start delays,
+// overrun and underrun, and focused minutes per category, grouped by day,
+// week or month.
+func GetTaskTimeAccounting(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		log.Printf("failed to get auth: %v\n", err)
+		return
+	}
+
+	granularity, ok := db.ParseTimeGranularity(c.Query("granularity"))
+	if !ok {
+		httpx.BadRequest(c, "Agrupación inválida, usa day, week o month")
+		return
+	}
+	weekStart, err := parseWeekStart(c)
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+	from, to, err := parseGroupedTaskRange(c, granularity)
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	ownerUserID, ok := resolveAnalyticsOwner(c, service, sessionAuth)
+	if !ok {
+		return
+	}
+	accounting, err := service.GetTimeAccounting(c.Request.Context(), ownerUserID, from, to, granularity, weekStart)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar el tiempo")
+		log.Printf("failed to get task time accounting: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"time": accounting, "owner_user_id": ownerUserID}, "Tiempo recuperado")
 }
 
+// GetTaskStreak returns the running streak, freeze balance and rest days,
+// plus the freezes earned and used between from and to.
+func GetTaskStreak(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		log.Printf("failed to get auth: %v\n", err)
+		return
+	}
+
+	from, to, err := parseTaskRange(c)
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	ownerUserID, ok := resolveAnalyticsOwner(c, service, sessionAuth)
+	if !ok {
+		return
+	}
+	streak, err := service.GetStreak(c.Request.Context(), ownerUserID, from, to)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar la racha")
+		log.Printf("failed to get task streak: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"streak": streak, "owner_user_id": ownerUserID}, "Racha recuperada")
+}
+
+type streakRestDaysRequest struct {
+	RestWeekdays []int `json:"rest_weekdays"`
+}
+
+func UpdateStreakRestDays(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	var request streakRestDaysRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	weekdays, ok := db.NormalizeRestWeekdays(request.RestWeekdays)
+	if !ok {
+		httpx.BadRequest(c, "Los días de descanso deben estar entre 0 (domingo) y 6 (sábado)")
+		return
+	}
+	if len(weekdays) == 7 {
+		httpx.BadRequest(c, "Debe quedar al menos un día sin descanso")
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	if err := service.SetRestWeekdays(c.Request.Context(), sessionAuth.ID, weekdays); err != nil {
+		httpx.ServerError(c, "No se pudieron guardar los días de descanso")
+		log.Printf("failed to set rest weekdays: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"rest_weekdays": weekdays}, "Días de descanso guardados")
+}
+
+func GetScoreWeights(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	weights, err := service.GetScoreWeights(c.Request.Context(), sessionAuth.ID)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar los pesos de la puntuación")
+		log.Printf("failed to get score weights: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"score_weights": weights}, "Pesos de la puntuación recuperados")
+}
+
+// UpdateScoreWeights changes the fields of the scoring model present in the
+// body and keeps the rest.
+func UpdateScoreWeights(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		return
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	weights, err := service.GetScoreWeights(c.Request.Context(), sessionAuth.ID)
+	if err != nil {
+		httpx.ServerError(c, "Error al recuperar los pesos de la puntuación")
+		log.Printf("failed to get score weights: %v\n", err)
+		return
+	}
+	if err := c.ShouldBindJSON(&weights); err != nil {
+		httpx.BadRequest(c, "Información inválida")
+		return
+	}
+	if !weights.Valid() {
+		httpx.BadRequest(c, fmt.Sprintf("Los pesos deben ser mayores que 0 y como máximo %d", db.MaxTaskScoreWeight))
+		return
+	}
+
+	if err := service.SetScoreWeights(c.Request.Context(), sessionAuth.ID, weights); err != nil {
+		httpx.ServerError(c, "No se pudieron guardar los pesos de la puntuación")
+		log.Printf("failed to set score weights: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{"score_weights": weights}, "Pesos de la puntuación guardados")
+}
+
+// GetCoachDashboard compares the owners that share analytics with the caller.
+// owner_user_ids (comma separated) narrows it to some of them; from and to
+// work like in GetTaskMetrics.
+func GetCoachDashboard(c *gin.Context) {
+	sessionAuth, err := auth.GetAuth(c)
+	if err != nil {
+		httpx.Unauthorized(c, "No autorizado")
+		log.Printf("failed to get auth: %v\n", err)
+		return
+	}
+
+	from, to, err := parseTaskRange(c)
+	if err != nil {
+		httpx.BadRequest(c, err.Error())
+		return
+	}
+	var ownerUserIDs []string
+	for _, ownerUserID := range strings.Split(c.Query("owner_user_ids"), ",") {
+		if ownerUserID = strings.TrimSpace(ownerUserID); ownerUserID != "" {
+			ownerUserIDs = append(ownerUserIDs, ownerUserID)
+		}
+	}
+
+	service := tasksvc.NewService(tasksvc.NewRepository())
+	owners, err := service.ListCoachedOwners(c.Request.Context(), sessionAuth.ID, ownerUserIDs, time.Now(), from, to)
+	if err != nil {
+		if errors.Is(err, tasksvc.ErrAnalyticsHidden) {
+			sharingAnalyticsHidden(c)
+			return
+		}
+		httpx.ServerError(c, "Error al recuperar el panel")
+		log.Printf("failed to get coach dashboard: %v\n", err)
+		return
+	}
+
+	httpx.OK(c, gin.H{
+		"owners": owners,
+		"from":   from.Format("2006-01-02"),
+		"to":     to.Format("2006-01-02"),
+	}, "Panel recuperado")
+}
+
+// resolveAnalyticsOwner reads owner_user_id, defaulting to the caller, and
+// checks that the caller may read that owner's analytics.
+func resolveAnalyticsOwner(c *gin.Context, service *tasksvc.Service, sessionAuth *auth.Auth) (string, bool) {
+	ownerUserID := strings.TrimSpace(c.Query("owner_user_id"))
+	if ownerUserID == "" {
+		return sessionAuth.ID, true
+	}
+
+	if err := service.CanViewOwnerAnalytics(c.Request.Context(), sessionAuth, ownerUserID); err != nil {
+		if errors.Is(err, tasksvc.ErrForbidden) {
+			sharingPermissionDenied(c)
+			return "", false
+		}
+		if errors.Is(err, tasksvc.ErrAnalyticsHidden) {
+			sharingAnalyticsHidden(c)
+			return "", false
+		}
+		httpx.ServerError(c, "Error al validar permisos compartidos")
+		log.Printf("failed to validate shared analytics access: %v\n", err)
+		return "", false
+	}
+	return ownerUserID, true
+}
+
+// minHeatmapYear is the first year a heatmap can be asked for.
+const minHeatmapYear = 2000
+
+// Longest ranges accepted by the analytics endpoints: daily rows are capped
+// at a quarter, grouped ones at a year.
+const (
+	maxTaskRangeDays        = 90
+	maxGroupedTaskRangeDays = 366
+)
+
 func parseTaskRange(c *gin.Context) (time.Time, time.Time, error) {
+	return parseTaskRangeWithin(c, maxTaskRangeDays)
+}
+
+// parseGroupedTaskRange reads from and to like parseTaskRange, but allows up
+// to a year when the range is grouped by week or month.
+func parseGroupedTaskRange(c *gin.Context, granularity db.TimeGranularity) (time.Time, time.Time, error) {
+	if granularity == db.TimeGranularityDay {
+		return parseTaskRange(c)
+	}
+	return parseTaskRangeWithin(c, maxGroupedTaskRangeDays)
+}
+
+// parseWeekStart reads the first day of the week for weekly grouping, from
+// 0 (Sunday) to 6. Weeks start on Monday by default.
+func parseWeekStart(c *gin.Context) (time.Weekday, error) {
+	value := strings.TrimSpace(c.Query("week_start"))
+	if value == "" {
+		return time.Monday, nil
+	}
+	weekday, err := strconv.Atoi(value)
+	if err != nil || weekday < 0 || weekday > 6 {
+		return 0, errors.New("Inicio de semana inválido, usa un día entre 0 (domingo) y 6")
+	}
+	return time.Weekday(weekday), nil
+}
+
+func parseTaskRangeWithin(c *gin.Context, maxDays int) (time.Time, time.Time, error) {
 	now := time.Now()
 	to := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)
 	from := to.AddDate(0, 0, -6)
@@ -406,8 +770,8 @@ func parseTaskRange(c *gin.Context) (time.Time, time.Time, error) {
 	if from.After(to) {
 		return time.Time{}, time.Time{}, errors.New("La fecha inicial no puede ser posterior a la fecha final")
 	}
-	if to.Sub(from).Hours()/24 > 89 {
-		return time.Time{}, time.Time{}, errors.New("El rango máximo permitido es de 90 días")
+	if to.Sub(from).Hours()/24 > float64(maxDays-1) {
+		return time.Time{}, time.Time{}, fmt.Errorf("El rango máximo permitido es de %d días", maxDays)
 	}
 
 	return from, to, nil
@@ -445,7 +809,8 @@ func GetDayTasks(c *gin.Context) {
 	}
 
 	service := tasksvc.NewService(tasksvc.NewRepository())
-	if err := service.CanViewOwner(c.Request.Context(), sessionAuth, ownerUserID); err != nil {
+	grant, err := service.CanViewOwner(c.Request.Context(), sessionAuth, ownerUserID)
+	if err != nil {
 		if errors.Is(err, tasksvc.ErrForbidden) {
 			sharingPermissionDenied(c)
 			return
@@ -461,6 +826,16 @@ func GetDayTasks(c *gin.Context) {
 		log.Printf("failed to get day tasks: %v\n", err)
 		return
 	}
+	tasks = tasksvc.FilterByGrant(grant, tasks)
+	if ownerUserID == sessionAuth.ID {
+		assigned, err := service.ListAssignedByDate(c.Request.Context(), sessionAuth.ID, date)
+		if err != nil {
+			httpx.ServerError(c, "Error al recuperar tareas del día")
+			log.Printf("failed to get assigned day tasks: %v\n", err)
+			return
+		}
+		tasks = append(tasks, assigned...)
+	}
 
 	feedItems := make([]db.TaskFeedItem, 0, len(tasks))
 	for _, task := range tasks {
@@ -488,7 +863,8 @@ func GenerateTodaysTasks(c *gin.Context) {
 	}
 
 	service := tasksvc.NewService(tasksvc.NewRepository())
-	if err := service.CanViewOwner(c.Request.Context(), sessionAuth, ownerUserID); err != nil {
+	grant, err := service.CanViewOwner(c.Request.Context(), sessionAuth, ownerUserID)
+	if err != nil {
 		if errors.Is(err, tasksvc.ErrForbidden) {
 			sharingPermissionDenied(c)
 			return
@@ -504,6 +880,16 @@ func GenerateTodaysTasks(c *gin.Context) {
 		log.Printf("failed to generate today's tasks: %v\n", err)
 		return
 	}
+	tasks = tasksvc.FilterByGrant(grant, tasks)
+	if ownerUserID == sessionAuth.ID {
+		assigned, err := service.ListAssignedByDate(c.Request.Context(), sessionAuth.ID, time.Now())
+		if err != nil {
+			httpx.ServerError(c, "Failed to generate today's tasks")
+			log.Printf("failed to get assigned tasks for today: %v\n", err)
+			return
+		}
+		tasks = append(tasks, assigned...)
+	}
 
 	feedItems := make([]db.TaskFeedItem, 0, len(tasks))
 	for _, task := range tasks {
@@ -542,7 +928,7 @@ func PingTask(c *gin.Context) {
 	}
 
 	if sessionAuth.ID != task.UserID && !sessionAuth.HasAccess(auth.AccessLevelAdmin) {
-		allowed, err := db.UserHasTaskPermission(c.Request.Context(), task.UserID, sessionAuth.ID, db.SharingPermissionPing)
+		allowed, err := db.UserHasScheduleTaskPermission(c.Request.Context(), task.UserID, sessionAuth.ID, task.ScheduleTaskID, db.SharingPermissionPing)
 		if err != nil {
 			httpx.ServerError(c, "Error al validar permisos compartidos")
 			log.Printf("failed to validate ping permission: %v\n", err)
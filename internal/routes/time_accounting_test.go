@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTaskTimeAccounting(t *testing.T) {
+	router := setupAuthRouteTest(t)
+	username := fmt.Sprintf("timeacct_%d", time.Now().UnixNano()%1_000_000_000)
+	cleanupTaskRouteUser(t, username)
+	t.Cleanup(func() { cleanupTaskRouteUser(t, username) })
+	authCookie := registerPhase5User(t, router, username, "Test1234")
+
+	status, body, _, _ := performJSONPayload(router, http.MethodPost, "/api/v1/schedules", map[string]interface{}{
+		"category":            "Deep Work",
+		"frequency":           "daily",
+		"schedule_start_time": "08:00",
+		"schedule_end_time":   "09:00",
+		"title":               "Time accounting focus",
+	}, []*http.Cookie{authCookie})
+	if status != http.StatusCreated {
+		t.Fatalf("create schedule status = %d body = %s", status, body)
+	}
+	var created routeScheduleResponse
+	if err := json.Unmarshal([]byte(body), &created); err != nil {
+		t.Fatalf("decode schedule: %v", err)
+	}
+	createRouteSchedule(t, router, authCookie, "Time accounting other", "10:00", "11:00")
+
+	now := time.Now()
+	today := getRouteTodayTasks(t, router, authCookie)
+	focus := findTaskBySchedule(t, today.Data.Tasks, created.Data.Schedule.ID)
+	updatePhase7Task(t, router, authCookie, focus.ID, map[string]interface{}{
+		"actualStart": time.Date(now.Year(), now.Month(), now.Day(), 8, 10, 0, 0, time.Local).Format(time.RFC3339),
+		"actualEnd":   time.Date(now.Year(), now.Month(), now.Day(), 9, 30, 0, 0, time.Local).Format(time.RFC3339),
+		"status":      "completed",
+	})
+
+	for _, path := range []string{
+		"/api/v1/tasks/time?granularity=hour",
+		"/api/v1/tasks/time?granularity=day&from=2026-01-01&to=2026-06-01",
+		"/api/v1/tasks/time?granularity=month&from=2025-01-01&to=2026-06-01",
+	} {
+		if status, body, _, _ = performJSONPayload(router, http.MethodGet, path, nil, []*http.Cookie{authCookie}); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d body = %s", path, status, body)
+		}
+	}
+
+	status, body, _, _ = performJSONPayload(router, http.MethodGet, "/api/v1/tasks/time?granularity=week", nil, []*http.Cookie{authCookie})
+	if status != http.StatusOK {
+		t.Fatalf("time status = %d body = %s", status, body)
+	}
+	var response struct {
+		Data struct {
+			Time struct {
+				Granularity string `json:"granularity"`
+				Totals      struct {
+					Tasks                    int     `json:"tasks"`
+					TrackedTasks             int     `json:"tracked_tasks"`
+					PlannedMinutes           int     `json:"planned_minutes"`
+					FocusedMinutes           int     `json:"focused_minutes"`
+					OverrunMinutes           int     `json:"overrun_minutes"`
+					LateStarts               int     `json:"late_starts"`
+					AverageStartDelayMinutes float64 `json:"average_start_delay_minutes"`
+					Categories               []struct {
+						Category       string `json:"category"`
+						FocusedMinutes int    `json:"focused_minutes"`
+					} `json:"categories"`
+				} `json:"totals"`
+				Buckets []struct {
+					Start string `json:"start"`
+					End   string `json:"end"`
+				} `json:"buckets"`
+			} `json:"time"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatalf("decode time: %v", err)
+	}
+	accounting := response.Data.Time
+	totals := accounting.Totals
+	if accounting.Granularity != "week" || len(accounting.Buckets) == 0 || len(accounting.Buckets) > 2 {
+		t.Fatalf("unexpected buckets: %s", body)
+	}
+	if totals.Tasks != 2 || totals.TrackedTasks != 1 || totals.PlannedMinutes != 120 || totals.FocusedMinutes != 80 || totals.OverrunMinutes != 20 {
+		t.Fatalf("unexpected totals: %s", body)
+	}
+	if totals.LateStarts != 1 || totals.AverageStartDelayMinutes != 10 {
+		t.Fatalf("unexpected punctuality: %s", body)
+	}
+	if len(totals.Categories) == 0 || totals.Categories[0].Category != "deep work" || totals.Categories[0].FocusedMinutes != 80 {
+		t.Fatalf("unexpected categories: %s", body)
+	}
+}
@@ -0,0 +1,87 @@
+package schedules
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// ProposeCreateForOwner stores schedule as a change request for the grant
+// owner instead of creating it. Callers check that grant lets the requester
+// create the schedule.
+func (s *Service) ProposeCreateForOwner(ctx context.Context, grant *db.TaskAccessGrant, requesterUserID string, schedule *db.ScheduleTask) (*db.ChangeRequest, error) {
+	payload, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &db.ChangeRequest{
+		GrantID:         grant.ID,
+		OwnerUserID:     grant.OwnerUserID,
+		RequesterUserID: requesterUserID,
+		Kind:            db.ChangeRequestScheduleCreate,
+		Payload:         payload,
+		Diff:            DiffScheduleCreate(schedule),
+	}
+	if err := s.repo.CreateChangeRequest(ctx, request); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// ApplyChangeRequest creates the schedule of an approved schedule_create
+// request for its owner, credited to the requester.
+func (s *Service) ApplyChangeRequest(ctx context.Context, request *db.ChangeRequest) (*db.ScheduleTask, error) {
+	var schedule db.ScheduleTask
+	if err := json.Unmarshal(request.Payload, &schedule); err != nil {
+		return nil, err
+	}
+	schedule.Status = ""
+
+	return s.CreateForOwner(ctx, request.OwnerUserID, request.RequesterUserID, &schedule)
+}
+
+// DiffScheduleCreate lists the fields set on a proposed schedule. Every From
+// is nil because the schedule does not exist yet.
+func DiffScheduleCreate(schedule *db.ScheduleTask) []db.ChangeRequestField {
+	diff := []db.ChangeRequestField{}
+	add := func(field string, to any) {
+		diff = append(diff, db.ChangeRequestField{Field: field, To: to})
+	}
+
+	add("title", schedule.Title)
+	if schedule.Description != "" {
+		add("description", schedule.Description)
+	}
+	if schedule.Category != "" {
+		add("category", schedule.Category)
+	}
+	if schedule.Priority != "" {
+		add("priority", schedule.Priority)
+	}
+	if schedule.Frequency != "" {
+		add("frequency", schedule.Frequency)
+	}
+	if !schedule.StartTime.IsZero() {
+		add("start_time", schedule.StartTime.Format("15:04"))
+	}
+	if !schedule.EndTime.IsZero() {
+		add("end_time", schedule.EndTime.Format("15:04"))
+	}
+	if schedule.DurationMinutes > 0 {
+		add("duration_minutes", schedule.DurationMinutes)
+	}
+	if schedule.TargetCount != nil {
+		add("target_count", *schedule.TargetCount)
+	}
+	if schedule.IsRequired {
+		add("is_required", true)
+	}
+	if !schedule.StartDate.IsZero() {
+		add("start_date", schedule.StartDate.Format("2006-01-02"))
+	}
+
+	return diff
+}
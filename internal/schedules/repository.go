@@ -2,17 +2,22 @@ package schedules
 
 import (
 	"context"
+	"time"
 
 	"github.com/vladwithcode/tasktracker/internal/db"
 )
 
 type Repository interface {
 	Create(ctx context.Context, schedule *db.ScheduleTask) error
+	CreateChangeRequest(ctx context.Context, request *db.ChangeRequest) error
 	Delete(ctx context.Context, schedule *db.ScheduleTask) error
+	GetActiveTaskAccessGrantByPair(ctx context.Context, ownerUserID string, granteeUserID string) (*db.TaskAccessGrant, error)
 	GetByID(ctx context.Context, id string) (*db.ScheduleTask, error)
+	GetSpaceMemberRole(ctx context.Context, spaceID string, userID string) (db.SpaceRole, error)
+	GetStats(ctx context.Context, schedule *db.ScheduleTask, from time.Time, to time.Time) (*db.ScheduleStats, error)
 	ListByUser(ctx context.Context, userID string) ([]*db.ScheduleTask, error)
 	SetStatus(ctx context.Context, id string, userID string, status db.ScheduleTaskStatus) error
-	UserHasTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (bool, error)
+	UserHasScheduleTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, scheduleTaskID string, permission string) (bool, error)
 	Update(ctx context.Context, schedule *db.ScheduleTask) error
 }
 
@@ -26,14 +31,30 @@ func (r *DBRepository) Create(ctx context.Context, schedule *db.ScheduleTask) er
 	return db.CreateScheduleTask(ctx, schedule)
 }
 
+func (r *DBRepository) CreateChangeRequest(ctx context.Context, request *db.ChangeRequest) error {
+	return db.CreateChangeRequest(ctx, request)
+}
+
 func (r *DBRepository) Delete(ctx context.Context, schedule *db.ScheduleTask) error {
 	return db.DeleteScheduleTask(ctx, schedule)
 }
 
+func (r *DBRepository) GetActiveTaskAccessGrantByPair(ctx context.Context, ownerUserID string, granteeUserID string) (*db.TaskAccessGrant, error) {
+	return db.GetActiveTaskAccessGrantByPair(ctx, ownerUserID, granteeUserID)
+}
+
 func (r *DBRepository) GetByID(ctx context.Context, id string) (*db.ScheduleTask, error) {
 	return db.GetScheduleTaskByID(ctx, id)
 }
 
+func (r *DBRepository) GetSpaceMemberRole(ctx context.Context, spaceID string, userID string) (db.SpaceRole, error) {
+	return db.GetSpaceMemberRole(ctx, spaceID, userID)
+}
+
+func (r *DBRepository) GetStats(ctx context.Context, schedule *db.ScheduleTask, from time.Time, to time.Time) (*db.ScheduleStats, error) {
+	return db.GetScheduleStats(ctx, schedule, from, to)
+}
+
 func (r *DBRepository) ListByUser(ctx context.Context, userID string) ([]*db.ScheduleTask, error) {
 	return db.GetScheduleTasksByUserID(ctx, userID)
 }
@@ -42,8 +63,8 @@ func (r *DBRepository) SetStatus(ctx context.Context, id string, userID string,
 	return db.SetScheduleTaskStatus(ctx, id, userID, status)
 }
 
-func (r *DBRepository) UserHasTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (bool, error) {
-	return db.UserHasTaskPermission(ctx, ownerUserID, granteeUserID, permission)
+func (r *DBRepository) UserHasScheduleTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, scheduleTaskID string, permission string) (bool, error) {
+	return db.UserHasScheduleTaskPermission(ctx, ownerUserID, granteeUserID, scheduleTaskID, permission)
 }
 
 func (r *DBRepository) Update(ctx context.Context, schedule *db.ScheduleTask) error {
@@ -13,8 +13,9 @@ import (
 )
 
 var (
-	ErrForbidden = errors.New("schedule access forbidden")
-	ErrNotFound  = errors.New("schedule not found")
+	ErrForbidden       = errors.New("schedule access forbidden")
+	ErrNotFound        = errors.New("schedule not found")
+	ErrAnalyticsHidden = errors.New("schedule owner does not share analytics")
 )
 
 type Service struct {
@@ -59,13 +60,28 @@ func (s *Service) List(ctx context.Context, userID string) ([]*db.ScheduleTask,
 	return schedules, nil
 }
 
+// FilterByGrant keeps the schedules inside the grant scope. A nil grant keeps
+// everything.
+func FilterByGrant(grant *db.TaskAccessGrant, schedules []*db.ScheduleTask) []*db.ScheduleTask {
+	if grant == nil || !grant.IsScoped() {
+		return schedules
+	}
+	visible := make([]*db.ScheduleTask, 0, len(schedules))
+	for _, schedule := range schedules {
+		if grant.CoversSchedule(schedule.ID, schedule.Category) {
+			visible = append(visible, schedule)
+		}
+	}
+	return visible
+}
+
 func (s *Service) Get(ctx context.Context, authData *auth.Auth, id string) (*db.ScheduleTask, error) {
 	schedule, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, normalizeNotFound(err)
 	}
 	if !canAccessSchedule(authData, schedule.UserID) {
-		allowed, err := s.repo.UserHasTaskPermission(ctx, schedule.UserID, authData.ID, db.SharingPermissionView)
+		allowed, err := s.repo.UserHasScheduleTaskPermission(ctx, schedule.UserID, authData.ID, schedule.ID, db.SharingPermissionView)
 		if err != nil {
 			return nil, err
 		}
@@ -77,6 +93,51 @@ func (s *Service) Get(ctx context.Context, authData *auth.Auth, id string) (*db.
 	return schedule, nil
 }
 
+// Stats returns the completion stats of a schedule between from and to.
+// Members of the space the schedule is shared to see them like its tasks.
+// Grantees that may view the schedule also need a grant that shares
+// analytics; unlike the whole-user metrics, a scoped grant covering the
+// schedule is enough.
+func (s *Service) Stats(ctx context.Context, authData *auth.Auth, id string, from time.Time, to time.Time) (*db.ScheduleStats, error) {
+	schedule, err := s.Get(ctx, authData, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccessSchedule(authData, schedule.UserID) {
+		member, err := s.isSpaceViewer(ctx, schedule, authData.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !member {
+			grant, err := s.repo.GetActiveTaskAccessGrantByPair(ctx, schedule.UserID, authData.ID)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return nil, ErrForbidden
+				}
+				return nil, err
+			}
+			if !grant.ShareAnalytics {
+				return nil, ErrAnalyticsHidden
+			}
+		}
+	}
+
+	return s.repo.GetStats(ctx, schedule, from, to)
+}
+
+// isSpaceViewer reports whether userID may view schedule as a member of the
+// space it is shared to.
+func (s *Service) isSpaceViewer(ctx context.Context, schedule *db.ScheduleTask, userID string) (bool, error) {
+	if schedule.SpaceID == "" {
+		return false, nil
+	}
+	role, err := s.repo.GetSpaceMemberRole(ctx, schedule.SpaceID, userID)
+	if err != nil {
+		return false, err
+	}
+	return role.Allows(db.SharingPermissionView), nil
+}
+
 func (s *Service) Update(ctx context.Context, authData *auth.Auth, id string, input *db.ScheduleTask) (*db.ScheduleTask, error) {
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -89,6 +150,7 @@ func (s *Service) Update(ctx context.Context, authData *auth.Auth, id string, in
 	input.ID = existing.ID
 	input.UserID = existing.UserID
 	input.CreatedBy = existing.CreatedBy
+	input.SpaceID = existing.SpaceID
 	if input.Status == "" {
 		input.Status = existing.Status
 	}
@@ -0,0 +1,102 @@
+package tasks
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// CoachedOwner is one owner on a coach dashboard: today's progress next to
+// the history and metrics of the requested range.
+type CoachedOwner struct {
+	OwnerUserID string               `json:"owner_user_id"`
+	Username    string               `json:"username"`
+	Fullname    string               `json:"fullname"`
+	GrantID     string               `json:"grant_id"`
+	Today       *db.DayProgress      `json:"today"`
+	History     *db.TaskHistoryRange `json:"history"`
+	Metrics     *db.TaskMetricsRange `json:"metrics"`
+}
+
+// CanViewOwnerAnalytics checks that authData may read ownerUserID's progress,
+// history and metrics. Grantees need view access on a grant that shares
+// analytics; see db.TaskAccessGrant.AllowsAnalytics.
+func (s *Service) CanViewOwnerAnalytics(ctx context.Context, authData *auth.Auth, ownerUserID string) error {
+	grant, err := s.CanViewOwner(ctx, authData, ownerUserID)
+	if err != nil {
+		return err
+	}
+	if grant != nil && !grant.AllowsAnalytics() {
+		return ErrAnalyticsHidden
+	}
+	return nil
+}
+
+// ListCoachedOwners builds the coach dashboard of coachUserID: every owner
+// that shares analytics with them, sorted by name. A non-empty ownerUserIDs
+// narrows it to those owners and fails with ErrAnalyticsHidden when one of
+// them does not share analytics with the coach.
+func (s *Service) ListCoachedOwners(ctx context.Context, coachUserID string, ownerUserIDs []string, day time.Time, from time.Time, to time.Time) ([]*CoachedOwner, error) {
+	grants, err := s.repo.ListTaskAccessGrantsForGrantee(ctx, coachUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	followed := map[string]*db.TaskAccessGrant{}
+	for _, grant := range grants {
+		if grant.AllowsAnalytics() {
+			followed[grant.OwnerUserID] = grant
+		}
+	}
+	selected := make([]*db.TaskAccessGrant, 0, len(followed))
+	if len(ownerUserIDs) == 0 {
+		for _, grant := range followed {
+			selected = append(selected, grant)
+		}
+	} else {
+		seen := map[string]bool{}
+		for _, ownerUserID := range ownerUserIDs {
+			grant := followed[ownerUserID]
+			if grant == nil {
+				return nil, ErrAnalyticsHidden
+			}
+			if !seen[ownerUserID] {
+				seen[ownerUserID] = true
+				selected = append(selected, grant)
+			}
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].OwnerUsername < selected[j].OwnerUsername
+	})
+
+	owners := make([]*CoachedOwner, 0, len(selected))
+	for _, grant := range selected {
+		today, err := s.repo.GetUserDayProgress(ctx, grant.OwnerUserID, day)
+		if err != nil {
+			return nil, err
+		}
+		history, err := s.repo.GetUserTaskHistory(ctx, grant.OwnerUserID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		metrics, err := s.repo.GetUserTaskMetrics(ctx, grant.OwnerUserID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		owners = append(owners, &CoachedOwner{
+			OwnerUserID: grant.OwnerUserID,
+			Username:    grant.OwnerUsername,
+			Fullname:    grant.OwnerFullname,
+			GrantID:     grant.ID,
+			Today:       today,
+			History:     history,
+			Metrics:     metrics,
+		})
+	}
+
+	return owners, nil
+}
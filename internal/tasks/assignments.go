@@ -0,0 +1,95 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// Delegate hands the task to assigneeUserID, pending their acceptance, or
+// takes it back when assigneeUserID is empty. The owner and users who may
+// edit the task can delegate it, except grantees in approval mode, and those
+// users cannot delegate it to themselves: accepting it would let them edit
+// the task without going through the owner.
+func (s *Service) Delegate(ctx context.Context, authData *auth.Auth, id string, assigneeUserID string) (*db.DetailedTask, error) {
+	task, err := s.repo.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, normalizeNotFound(err)
+	}
+
+	if !canAccessTask(authData, task.UserID) {
+		allowed, err := s.repo.UserHasScheduleTaskPermission(ctx, task.UserID, authData.ID, task.ScheduleTaskID, db.SharingPermissionEdit)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, ErrForbidden
+		}
+		grant, err := s.repo.ResolveSharingGrant(ctx, task.UserID, authData.ID, db.SharingPermissionEdit)
+		if err != nil {
+			return nil, err
+		}
+		if grant != nil && grant.RequiresApproval {
+			return nil, ErrApprovalRequired
+		}
+		if assigneeUserID == authData.ID {
+			return nil, ErrAssigneeIsDelegator
+		}
+	}
+	if assigneeUserID == task.UserID {
+		return nil, ErrAssigneeIsOwner
+	}
+	if assigneeUserID != "" && assigneeUserID == task.AssigneeUserID && task.AssignmentStatus.IsActive() {
+		return nil, ErrAssignmentUnchanged
+	}
+
+	if err := s.repo.SetTaskAssignee(ctx, task.ID, assigneeUserID, authData.ID, db.TaskAssignmentPending); err != nil {
+		return nil, err
+	}
+
+	return s.GetDetails(ctx, authData, task.ID)
+}
+
+// RespondToAssignment records the caller's answer to a pending delegation.
+// Callers the task is not assigned to get ErrNotFound.
+func (s *Service) RespondToAssignment(ctx context.Context, authData *auth.Auth, id string, accept bool) (*db.DetailedTask, error) {
+	task, err := s.repo.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, normalizeNotFound(err)
+	}
+	if task.AssigneeUserID != authData.ID {
+		return nil, ErrNotFound
+	}
+
+	status := db.TaskAssignmentDeclined
+	if accept {
+		status = db.TaskAssignmentAccepted
+	}
+	responded, err := s.repo.RespondToTaskAssignment(ctx, task.ID, authData.ID, status)
+	if err != nil {
+		return nil, err
+	}
+	if !responded {
+		return nil, ErrAssignmentNotPending
+	}
+
+	return s.repo.GetTaskDetailsByID(ctx, task.ID)
+}
+
+// ListAssignments returns the tasks delegated to userID that still need them.
+func (s *Service) ListAssignments(ctx context.Context, userID string) ([]*db.DetailedTask, error) {
+	return s.repo.ListUserTaskAssignments(ctx, userID)
+}
+
+// ListAssignedByDate returns the other users' tasks that userID accepted and
+// that fall on date.
+func (s *Service) ListAssignedByDate(ctx context.Context, userID string, date time.Time) ([]*db.DetailedTask, error) {
+	tasks, err := s.repo.GetUserAssignedDetailedTasks(ctx, userID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return ensureDetailedTaskSlice(tasks), nil
+}
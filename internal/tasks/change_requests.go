@@ -0,0 +1,123 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// ProposeUpdate stores input as a change request for the task owner instead
+// of applying it. The caller needs edit access to the task through a sharing
+// grant; the owner applies the request later through ApplyChangeRequest.
+func (s *Service) ProposeUpdate(ctx context.Context, authData *auth.Auth, id string, input UpdateTaskInput) (*db.ChangeRequest, error) {
+	task, err := s.repo.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, normalizeNotFound(err)
+	}
+	if task.UserID == authData.ID || input.ApplyToSchedule {
+		return nil, ErrForbidden
+	}
+
+	allowed, err := s.repo.UserHasScheduleTaskPermission(ctx, task.UserID, authData.ID, task.ScheduleTaskID, db.SharingPermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+	grant, err := s.repo.ResolveSharingGrant(ctx, task.UserID, authData.ID, db.SharingPermissionEdit)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed || grant == nil {
+		return nil, ErrForbidden
+	}
+
+	diff := DiffTaskUpdate(task, input)
+	if len(diff) == 0 {
+		return nil, ErrNoChanges
+	}
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &db.ChangeRequest{
+		GrantID:         grant.ID,
+		OwnerUserID:     task.UserID,
+		RequesterUserID: authData.ID,
+		Kind:            db.ChangeRequestTaskUpdate,
+		TaskID:          task.ID,
+		TaskTitle:       task.Title,
+		Payload:         payload,
+		Diff:            diff,
+	}
+	if err := s.repo.CreateChangeRequest(ctx, request); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// ApplyChangeRequest runs an approved task_update request through Update on
+// behalf of the owner in authData.
+func (s *Service) ApplyChangeRequest(ctx context.Context, authData *auth.Auth, request *db.ChangeRequest) (*db.DetailedTask, error) {
+	var input UpdateTaskInput
+	if err := json.Unmarshal(request.Payload, &input); err != nil {
+		return nil, err
+	}
+	// Approval never widens what the grantee could have asked for.
+	input.ApplyToSchedule = false
+
+	return s.Update(ctx, authData, request.TaskID, input)
+}
+
+// DiffTaskUpdate lists the fields of task that input would change, the same
+// way Update reads it: zero values and nil pointers leave a field alone.
+func DiffTaskUpdate(task *db.Task, input UpdateTaskInput) []db.ChangeRequestField {
+	diff := []db.ChangeRequestField{}
+	add := func(field string, from any, to any) {
+		diff = append(diff, db.ChangeRequestField{Field: field, From: from, To: to})
+	}
+
+	if input.Status != "" && input.Status != task.Status {
+		add("status", task.Status, input.Status)
+	}
+	if !input.Date.IsZero() && input.Date.Format("2006-01-02") != task.Date.Format("2006-01-02") {
+		add("date", diffTime(task.Date, "2006-01-02"), input.Date.Format("2006-01-02"))
+	}
+	if !input.ActualStart.IsZero() && !input.ActualStart.Equal(task.ActualStart) {
+		add("actual_start", diffTime(task.ActualStart, time.RFC3339), input.ActualStart.Format(time.RFC3339))
+	}
+	if !input.ActualEnd.IsZero() && !input.ActualEnd.Equal(task.ActualEnd) {
+		add("actual_end", diffTime(task.ActualEnd, time.RFC3339), input.ActualEnd.Format(time.RFC3339))
+	}
+	if input.CurrentCount != nil && *input.CurrentCount != task.CurrentCount {
+		add("current_count", task.CurrentCount, *input.CurrentCount)
+	}
+	if input.TargetCount != nil && (task.TargetCount == nil || *task.TargetCount != *input.TargetCount) {
+		var from any
+		if task.TargetCount != nil {
+			from = *task.TargetCount
+		}
+		add("target_count", from, *input.TargetCount)
+	}
+	if input.Notes != "" && input.Notes != task.Notes {
+		add("notes", task.Notes, input.Notes)
+	}
+	if input.Title != nil && *input.Title != task.Title {
+		add("title", task.Title, *input.Title)
+	}
+	if input.Description != nil && *input.Description != task.Description {
+		add("description", task.Description, *input.Description)
+	}
+
+	return diff
+}
+
+func diffTime(value time.Time, layout string) any {
+	if value.IsZero() {
+		return nil
+	}
+	return value.Format(layout)
+}
@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestDiffTaskUpdateListsChangedFields(t *testing.T) {
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	target := 8
+	task := &db.Task{
+		Title:        "Water",
+		Status:       db.TaskStatusPending,
+		Date:         day,
+		CurrentCount: 2,
+		TargetCount:  &target,
+		Notes:        "before",
+	}
+
+	sameTitle := "Water"
+	newTitle := "Drink water"
+	count := 2
+	diff := DiffTaskUpdate(task, UpdateTaskInput{
+		Status:       db.TaskStatusCompleted,
+		Date:         day.Add(9 * time.Hour),
+		CurrentCount: &count,
+		Title:        &sameTitle,
+		Notes:        "after",
+	})
+	if len(diff) != 2 || diff[0].Field != "status" || diff[1].Field != "notes" {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	if diff[0].From != db.TaskStatusPending || diff[0].To != db.TaskStatusCompleted {
+		t.Fatalf("unexpected status change %+v", diff[0])
+	}
+
+	diff = DiffTaskUpdate(task, UpdateTaskInput{Title: &newTitle, ActualStart: day.Add(8 * time.Hour)})
+	if len(diff) != 2 || diff[0].Field != "actual_start" || diff[0].From != nil || diff[1].To != newTitle {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	if diff := DiffTaskUpdate(task, UpdateTaskInput{}); len(diff) != 0 {
+		t.Fatalf("expected empty diff, got %+v", diff)
+	}
+}
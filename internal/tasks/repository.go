@@ -10,6 +10,7 @@ import (
 type Repository interface {
 	CompleteTask(ctx context.Context, task *db.Task, completion *db.TaskCompletion) error
 	CompleteTaskAndSchedule(ctx context.Context, task *db.Task, schedule *db.ScheduleTask, completion *db.TaskCompletion) error
+	CreateChangeRequest(ctx context.Context, request *db.ChangeRequest) error
 	CreateScheduleTask(ctx context.Context, scheduleTask *db.ScheduleTask) error
 	CreateTaskCompletion(ctx context.Context, completion *db.TaskCompletion) error
 	CreateTaskForSchedule(ctx context.Context, scheduleTask *db.ScheduleTask) (*db.Task, error)
@@ -21,11 +22,25 @@ type Repository interface {
 	GetTaskDetailsByID(ctx context.Context, id string) (*db.DetailedTask, error)
 	GetTasksByUserID(ctx context.Context, userID string) ([]*db.DetailedTask, error)
 	GetUserDayProgress(ctx context.Context, userID string, day time.Time) (*db.DayProgress, error)
+	GetUserScoreWeights(ctx context.Context, userID string) (db.TaskScoreWeights, error)
 	GetUserTaskHistory(ctx context.Context, userID string, from time.Time, to time.Time) (*db.TaskHistoryRange, error)
+	GetUserTaskHistoryBreakdown(ctx context.Context, userID string, from time.Time, to time.Time, dimension db.TaskBreakdownDimension) (map[string][]db.TaskBreakdownCount, error)
+	GetUserTaskHeatmap(ctx context.Context, userID string, year int, filter db.TaskHeatmapFilter) (*db.TaskHeatmap, error)
 	GetUserTaskMetrics(ctx context.Context, userID string, from time.Time, to time.Time) (*db.TaskMetricsRange, error)
+	GetUserTimeAccounting(ctx context.Context, userID string, from time.Time, to time.Time, granularity db.TimeGranularity, weekStart time.Weekday) (*db.TimeAccountingRange, error)
 	GetUserDateDetailedTasks(ctx context.Context, userID string, date time.Time) ([]*db.DetailedTask, error)
 	GetUserTodayDetailedTasks(ctx context.Context, userID string) ([]*db.DetailedTask, error)
-	UserHasTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (bool, error)
+	GetUserAssignedDetailedTasks(ctx context.Context, userID string, date time.Time) ([]*db.DetailedTask, error)
+	ListStreakFreezeEvents(ctx context.Context, userID string, from time.Time, to time.Time) ([]*db.StreakFreezeEvent, error)
+	ListTaskAccessGrantsForGrantee(ctx context.Context, granteeUserID string) ([]*db.TaskAccessGrant, error)
+	ListUserTaskAssignments(ctx context.Context, userID string) ([]*db.DetailedTask, error)
+	RefreshUserStreak(ctx context.Context, userID string, today time.Time) (*db.UserStreak, error)
+	ResolveSharingGrant(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (*db.TaskAccessGrant, error)
+	RespondToTaskAssignment(ctx context.Context, taskID string, assigneeUserID string, status db.TaskAssignmentStatus) (bool, error)
+	SetTaskAssignee(ctx context.Context, taskID string, assigneeUserID string, assignedByUserID string, status db.TaskAssignmentStatus) error
+	SetUserRestWeekdays(ctx context.Context, userID string, weekdays []int) error
+	SetUserScoreWeights(ctx context.Context, userID string, weights db.TaskScoreWeights) error
+	UserHasScheduleTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, scheduleTaskID string, permission string) (bool, error)
 	UpdateTask(ctx context.Context, task *db.Task) error
 	UpdateTaskAndSchedule(ctx context.Context, task *db.Task, schedule *db.ScheduleTask) error
 }
@@ -44,6 +59,10 @@ func (r *DBRepository) CompleteTaskAndSchedule(ctx context.Context, task *db.Tas
 	return db.CompleteTaskAndSchedule(ctx, task, schedule, completion)
 }
 
+func (r *DBRepository) CreateChangeRequest(ctx context.Context, request *db.ChangeRequest) error {
+	return db.CreateChangeRequest(ctx, request)
+}
+
 func (r *DBRepository) CreateScheduleTask(ctx context.Context, scheduleTask *db.ScheduleTask) error {
 	return db.CreateScheduleTask(ctx, scheduleTask)
 }
@@ -88,14 +107,30 @@ func (r *DBRepository) GetUserDayProgress(ctx context.Context, userID string, da
 	return db.GetUserDayProgress(ctx, userID, day)
 }
 
+func (r *DBRepository) GetUserScoreWeights(ctx context.Context, userID string) (db.TaskScoreWeights, error) {
+	return db.GetUserScoreWeights(ctx, userID)
+}
+
 func (r *DBRepository) GetUserTaskHistory(ctx context.Context, userID string, from time.Time, to time.Time) (*db.TaskHistoryRange, error) {
 	return db.GetUserTaskHistory(ctx, userID, from, to)
 }
 
+func (r *DBRepository) GetUserTaskHistoryBreakdown(ctx context.Context, userID string, from time.Time, to time.Time, dimension db.TaskBreakdownDimension) (map[string][]db.TaskBreakdownCount, error) {
+	return db.GetUserTaskHistoryBreakdown(ctx, userID, from, to, dimension)
+}
+
+func (r *DBRepository) GetUserTaskHeatmap(ctx context.Context, userID string, year int, filter db.TaskHeatmapFilter) (*db.TaskHeatmap, error) {
+	return db.GetUserTaskHeatmap(ctx, userID, year, filter)
+}
+
 func (r *DBRepository) GetUserTaskMetrics(ctx context.Context, userID string, from time.Time, to time.Time) (*db.TaskMetricsRange, error) {
 	return db.GetUserTaskMetrics(ctx, userID, from, to)
 }
 
+func (r *DBRepository) GetUserTimeAccounting(ctx context.Context, userID string, from time.Time, to time.Time, granularity db.TimeGranularity, weekStart time.Weekday) (*db.TimeAccountingRange, error) {
+	return db.GetUserTimeAccounting(ctx, userID, from, to, granularity, weekStart)
+}
+
 func (r *DBRepository) GetUserDateDetailedTasks(ctx context.Context, userID string, date time.Time) ([]*db.DetailedTask, error) {
 	return db.GetUserDateDetailedTasks(ctx, userID, date)
 }
@@ -104,8 +139,48 @@ func (r *DBRepository) GetUserTodayDetailedTasks(ctx context.Context, userID str
 	return db.GetUserTodayDetailedTasks(ctx, userID)
 }
 
-func (r *DBRepository) UserHasTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (bool, error) {
-	return db.UserHasTaskPermission(ctx, ownerUserID, granteeUserID, permission)
+func (r *DBRepository) GetUserAssignedDetailedTasks(ctx context.Context, userID string, date time.Time) ([]*db.DetailedTask, error) {
+	return db.GetUserAssignedDetailedTasks(ctx, userID, date)
+}
+
+func (r *DBRepository) ListStreakFreezeEvents(ctx context.Context, userID string, from time.Time, to time.Time) ([]*db.StreakFreezeEvent, error) {
+	return db.ListStreakFreezeEvents(ctx, userID, from, to)
+}
+
+func (r *DBRepository) ListTaskAccessGrantsForGrantee(ctx context.Context, granteeUserID string) ([]*db.TaskAccessGrant, error) {
+	return db.ListTaskAccessGrantsForGrantee(ctx, granteeUserID)
+}
+
+func (r *DBRepository) ListUserTaskAssignments(ctx context.Context, userID string) ([]*db.DetailedTask, error) {
+	return db.ListUserTaskAssignments(ctx, userID)
+}
+
+func (r *DBRepository) RespondToTaskAssignment(ctx context.Context, taskID string, assigneeUserID string, status db.TaskAssignmentStatus) (bool, error) {
+	return db.RespondToTaskAssignment(ctx, taskID, assigneeUserID, status)
+}
+
+func (r *DBRepository) SetTaskAssignee(ctx context.Context, taskID string, assigneeUserID string, assignedByUserID string, status db.TaskAssignmentStatus) error {
+	return db.SetTaskAssignee(ctx, taskID, assigneeUserID, assignedByUserID, status)
+}
+
+func (r *DBRepository) RefreshUserStreak(ctx context.Context, userID string, today time.Time) (*db.UserStreak, error) {
+	return db.RefreshUserStreak(ctx, userID, today)
+}
+
+func (r *DBRepository) ResolveSharingGrant(ctx context.Context, ownerUserID string, granteeUserID string, permission string) (*db.TaskAccessGrant, error) {
+	return db.ResolveSharingGrant(ctx, ownerUserID, granteeUserID, permission)
+}
+
+func (r *DBRepository) SetUserRestWeekdays(ctx context.Context, userID string, weekdays []int) error {
+	return db.SetUserRestWeekdays(ctx, userID, weekdays)
+}
+
+func (r *DBRepository) SetUserScoreWeights(ctx context.Context, userID string, weights db.TaskScoreWeights) error {
+	return db.SetUserScoreWeights(ctx, userID, weights)
+}
+
+func (r *DBRepository) UserHasScheduleTaskPermission(ctx context.Context, ownerUserID string, granteeUserID string, scheduleTaskID string, permission string) (bool, error) {
+	return db.UserHasScheduleTaskPermission(ctx, ownerUserID, granteeUserID, scheduleTaskID, permission)
 }
 
 func (r *DBRepository) UpdateTask(ctx context.Context, task *db.Task) error {
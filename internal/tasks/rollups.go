@@ -0,0 +1,64 @@
+package tasks
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// Rollups are reconciled once a night, at rollupReconcileHour local time,
+// over the last rollupReconcileDays days: late edits rarely reach further
+// back.
+const (
+	rollupReconcileHour = 3
+	rollupReconcileDays = 35
+)
+
+// RollupReconciler rebuilds recent daily rollups from tasks and completions,
+// repairing any row a trigger missed.
+type RollupReconciler struct {
+	ctx context.Context
+}
+
+func NewRollupReconciler(ctx context.Context) *RollupReconciler {
+	return &RollupReconciler{ctx: ctx}
+}
+
+func (r *RollupReconciler) Start() {
+	log.Printf("daily rollup reconciler started hour=%d days=%d", rollupReconcileHour, rollupReconcileDays)
+
+	for {
+		timer := time.NewTimer(time.Until(nextRollupReconcile(time.Now())))
+		select {
+		case <-r.ctx.Done():
+			timer.Stop()
+			log.Println("daily rollup reconciler stopped")
+			return
+		case <-timer.C:
+			r.runOnce()
+		}
+	}
+}
+
+func (r *RollupReconciler) runOnce() {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)
+	from := to.AddDate(0, 0, -(rollupReconcileDays - 1))
+	rows, err := db.ReconcileTaskDailyRollups(r.ctx, from, to)
+	if err != nil {
+		log.Printf("daily rollup reconciliation failed: %v", err)
+		return
+	}
+	log.Printf("daily rollup reconciliation completed from=%s to=%s rows=%d", from.Format("2006-01-02"), to.Format("2006-01-02"), rows)
+}
+
+// nextRollupReconcile returns the first rollupReconcileHour after now.
+func nextRollupReconcile(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), rollupReconcileHour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,22 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextRollupReconcile(t *testing.T) {
+	cases := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 10, 18, 1, 30, 0, 0, time.UTC), time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), time.Date(2026, 10, 19, 3, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 10, 31, 22, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 3, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		if got := nextRollupReconcile(tc.now); !got.Equal(tc.want) {
+			t.Fatalf("nextRollupReconcile(%s) = %s, want %s", tc.now, got, tc.want)
+		}
+	}
+}
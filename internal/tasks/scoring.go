@@ -0,0 +1,19 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// GetScoreWeights returns the scoring model of userID, or the defaults when
+// the user never changed it.
+func (s *Service) GetScoreWeights(ctx context.Context, userID string) (db.TaskScoreWeights, error) {
+	return s.repo.GetUserScoreWeights(ctx, userID)
+}
+
+// SetScoreWeights stores weights, already checked with Valid, as the
+// scoring model of userID.
+func (s *Service) SetScoreWeights(ctx context.Context, userID string, weights db.TaskScoreWeights) error {
+	return s.repo.SetUserScoreWeights(ctx, userID, weights)
+}
@@ -13,8 +13,15 @@ import (
 )
 
 var (
-	ErrForbidden = errors.New("task access forbidden")
-	ErrNotFound  = errors.New("task not found")
+	ErrForbidden            = errors.New("task access forbidden")
+	ErrNotFound             = errors.New("task not found")
+	ErrAssigneeIsOwner      = errors.New("task cannot be assigned to its owner")
+	ErrAssigneeIsDelegator  = errors.New("task cannot be assigned to the editor delegating it")
+	ErrAssignmentNotPending = errors.New("task assignment is not pending")
+	ErrAssignmentUnchanged  = errors.New("task is already assigned to this user")
+	ErrApprovalRequired     = errors.New("task changes need owner approval")
+	ErrNoChanges            = errors.New("task change request has no changes")
+	ErrAnalyticsHidden      = errors.New("owner does not share analytics")
 )
 
 type Service struct {
@@ -80,15 +87,23 @@ func (s *Service) GetDetails(ctx context.Context, authData *auth.Auth, id string
 		task.CanApplyToSchedule = true
 		return task, nil
 	}
+	// Accepted assignees work on the task; pending ones may look at it
+	// before answering.
+	assignment := assignmentOf(authData, task.AssigneeUserID, task.AssignmentStatus)
+	if assignment == db.TaskAssignmentAccepted {
+		task.CanEdit = true
+		task.CanApplyToSchedule = false
+		return task, nil
+	}
 
-	allowed, err := s.repo.UserHasTaskPermission(ctx, task.UserID, authData.ID, db.SharingPermissionView)
+	allowed, err := s.repo.UserHasScheduleTaskPermission(ctx, task.UserID, authData.ID, task.ScheduleTaskID, db.SharingPermissionView)
 	if err != nil {
 		return nil, err
 	}
-	if !allowed {
+	if !allowed && assignment != db.TaskAssignmentPending {
 		return nil, ErrForbidden
 	}
-	canEdit, err := s.repo.UserHasTaskPermission(ctx, task.UserID, authData.ID, db.SharingPermissionEdit)
+	canEdit, err := s.repo.UserHasScheduleTaskPermission(ctx, task.UserID, authData.ID, task.ScheduleTaskID, db.SharingPermissionEdit)
 	if err != nil {
 		return nil, err
 	}
@@ -98,20 +113,38 @@ func (s *Service) GetDetails(ctx context.Context, authData *auth.Auth, id string
 	return task, nil
 }
 
-func (s *Service) CanViewOwner(ctx context.Context, authData *auth.Auth, ownerUserID string) error {
+// CanViewOwner checks that authData may list ownerUserID's tasks. It returns
+// the grant that allows it, or nil for the owner and admins; lists must be
+// passed through FilterByGrant so scoped grants only see their schedules.
+func (s *Service) CanViewOwner(ctx context.Context, authData *auth.Auth, ownerUserID string) (*db.TaskAccessGrant, error) {
 	if canAccessTask(authData, ownerUserID) {
-		return nil
+		return nil, nil
 	}
 
-	allowed, err := s.repo.UserHasTaskPermission(ctx, ownerUserID, authData.ID, db.SharingPermissionView)
+	grant, err := s.repo.ResolveSharingGrant(ctx, ownerUserID, authData.ID, db.SharingPermissionView)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !allowed {
-		return ErrForbidden
+	if grant == nil {
+		return nil, ErrForbidden
 	}
 
-	return nil
+	return grant, nil
+}
+
+// FilterByGrant keeps the tasks whose schedule is inside the grant scope. A
+// nil grant keeps everything.
+func FilterByGrant(grant *db.TaskAccessGrant, tasks []*db.DetailedTask) []*db.DetailedTask {
+	if grant == nil || !grant.IsScoped() {
+		return tasks
+	}
+	visible := make([]*db.DetailedTask, 0, len(tasks))
+	for _, task := range tasks {
+		if grant.CoversSchedule(task.ScheduleTaskID, task.Category) {
+			visible = append(visible, task)
+		}
+	}
+	return visible
 }
 
 func (s *Service) ListByUser(ctx context.Context, userID string) ([]*db.DetailedTask, error) {
@@ -156,13 +189,27 @@ func (s *Service) Update(ctx context.Context, authData *auth.Auth, id string, in
 	}
 
 	if !canAccessTask(authData, task.UserID) {
-		allowed, err := s.repo.UserHasTaskPermission(ctx, task.UserID, authData.ID, db.SharingPermissionEdit)
-		if err != nil {
-			return nil, err
+		allowed := assignmentOf(authData, task.AssigneeUserID, task.AssignmentStatus) == db.TaskAssignmentAccepted
+		if !allowed {
+			allowed, err = s.repo.UserHasScheduleTaskPermission(ctx, task.UserID, authData.ID, task.ScheduleTaskID, db.SharingPermissionEdit)
+			if err != nil {
+				return nil, err
+			}
 		}
 		if !allowed || input.ApplyToSchedule {
 			return nil, ErrForbidden
 		}
+		if assignmentOf(authData, task.AssigneeUserID, task.AssignmentStatus) != db.TaskAssignmentAccepted {
+			// Grants in approval mode send every edit of the grantee through
+			// ProposeUpdate instead.
+			grant, err := s.repo.ResolveSharingGrant(ctx, task.UserID, authData.ID, db.SharingPermissionEdit)
+			if err != nil {
+				return nil, err
+			}
+			if grant != nil && grant.RequiresApproval {
+				return nil, ErrApprovalRequired
+			}
+		}
 	}
 	canApplyToSchedule := canAccessTask(authData, task.UserID)
 
@@ -260,13 +307,14 @@ func (s *Service) Update(ctx context.Context, authData *auth.Auth, id string, in
 
 	if isCompletingTransition {
 		completion := &db.TaskCompletion{
-			TaskID:      task.ID,
-			UserID:      task.UserID,
-			CompletedAt: task.CompletedAt,
-			ActualStart: task.ActualStart,
-			ActualEnd:   task.ActualEnd,
-			Count:       task.CurrentCount,
-			Notes:       task.Notes,
+			TaskID:            task.ID,
+			UserID:            task.UserID,
+			CompletedAt:       task.CompletedAt,
+			ActualStart:       task.ActualStart,
+			ActualEnd:         task.ActualEnd,
+			Count:             task.CurrentCount,
+			Notes:             task.Notes,
+			CompletedByUserID: completionCreditOf(authData, task),
 		}
 		if input.ApplyToSchedule {
 			if err := s.repo.CompleteTaskAndSchedule(ctx, task, schedule, completion); err != nil {
@@ -303,14 +351,44 @@ func (s *Service) GetDayProgress(ctx context.Context, userID string, day time.Ti
 	return s.repo.GetUserDayProgress(ctx, userID, day)
 }
 
-func (s *Service) GetHistory(ctx context.Context, userID string, from time.Time, to time.Time) (*db.TaskHistoryRange, error) {
-	return s.repo.GetUserTaskHistory(ctx, userID, from, to)
+// GetHistory returns one row per day between from and to, or one per week
+// or month for a coarser granularity. A non-empty breakdown also splits every
+// row by that dimension.
+func (s *Service) GetHistory(ctx context.Context, userID string, from time.Time, to time.Time, breakdown db.TaskBreakdownDimension, granularity db.TimeGranularity, weekStart time.Weekday) (*db.TaskHistoryRange, error) {
+	history, err := s.repo.GetUserTaskHistory(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if breakdown != "" {
+		days, err := s.repo.GetUserTaskHistoryBreakdown(ctx, userID, from, to, breakdown)
+		if err != nil {
+			return nil, err
+		}
+		history.Breakdown = breakdown
+		for i := range history.Days {
+			history.Days[i].Breakdown = days[history.Days[i].Date]
+		}
+	}
+	return db.GroupTaskHistory(history, granularity, weekStart), nil
 }
 
 func (s *Service) GetMetrics(ctx context.Context, userID string, from time.Time, to time.Time) (*db.TaskMetricsRange, error) {
 	return s.repo.GetUserTaskMetrics(ctx, userID, from, to)
 }
 
+// GetHeatmap returns one cell per day of year, optionally narrowed to one
+// category or schedule.
+func (s *Service) GetHeatmap(ctx context.Context, userID string, year int, filter db.TaskHeatmapFilter) (*db.TaskHeatmap, error) {
+	return s.repo.GetUserTaskHeatmap(ctx, userID, year, filter)
+}
+
+// GetTimeAccounting compares planned with tracked time between from and to,
+// grouped by granularity.
+func (s *Service) GetTimeAccounting(ctx context.Context, userID string, from time.Time, to time.Time, granularity db.TimeGranularity, weekStart time.Weekday) (*db.TimeAccountingRange, error) {
+	return s.repo.GetUserTimeAccounting(ctx, userID, from, to, granularity, weekStart)
+}
+
 func (s *Service) Delete(ctx context.Context, authData *auth.Auth, id string) error {
 	task, err := s.repo.GetTaskByID(ctx, id)
 	if err != nil {
@@ -324,6 +402,26 @@ func (s *Service) Delete(ctx context.Context, authData *auth.Auth, id string) er
 	return s.repo.DeleteTask(ctx, task)
 }
 
+// assignmentOf returns the status of the caller's assignment to the task, or
+// "" when the task is not assigned to them.
+func assignmentOf(authData *auth.Auth, assigneeUserID string, status db.TaskAssignmentStatus) db.TaskAssignmentStatus {
+	if assigneeUserID == "" || assigneeUserID != authData.ID {
+		return ""
+	}
+	return status
+}
+
+// completionCreditOf returns who a completion by authData is credited to in
+// metrics: the accepted assignee of a delegated task finishing it. Anyone
+// else, the owner, an admin or an editor, leaves it empty and the completion
+// counts for whoever is responsible for the task.
+func completionCreditOf(authData *auth.Auth, task *db.Task) string {
+	if assignmentOf(authData, task.AssigneeUserID, task.AssignmentStatus) != db.TaskAssignmentAccepted {
+		return ""
+	}
+	return authData.ID
+}
+
 func canAccessTask(authData *auth.Auth, userID string) bool {
 	return authData.ID == userID || authData.HasAccess(auth.AccessLevelAdmin)
 }
@@ -0,0 +1,29 @@
+package tasks
+
+import (
+	"testing"
+
+	"github.com/vladwithcode/tasktracker/internal/auth"
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+func TestCompletionCreditOfOnlyCreditsAcceptedAssignee(t *testing.T) {
+	task := &db.Task{
+		UserID:           "owner",
+		AssigneeUserID:   "assignee",
+		AssignmentStatus: db.TaskAssignmentAccepted,
+	}
+	if got := completionCreditOf(&auth.Auth{ID: "assignee"}, task); got != "assignee" {
+		t.Fatalf("accepted assignee credit = %q", got)
+	}
+	for _, actor := range []string{"owner", "editor"} {
+		if got := completionCreditOf(&auth.Auth{ID: actor}, task); got != "" {
+			t.Fatalf("%s credit = %q, want none", actor, got)
+		}
+	}
+
+	task.AssignmentStatus = db.TaskAssignmentPending
+	if got := completionCreditOf(&auth.Auth{ID: "assignee"}, task); got != "" {
+		t.Fatalf("pending assignee credit = %q, want none", got)
+	}
+}
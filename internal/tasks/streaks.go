@@ -0,0 +1,42 @@
+package tasks
+
+import (
+	"context"
+	"time"
+
+	"github.com/vladwithcode/tasktracker/internal/db"
+)
+
+// StreakSummary is the running streak of a user together with the freezes
+// earned and used between From and To.
+type StreakSummary struct {
+	*db.UserStreak
+	From    string                  `json:"from"`
+	To      string                  `json:"to"`
+	Freezes []*db.StreakFreezeEvent `json:"freezes"`
+}
+
+// GetStreak brings the streak of userID up to date and returns it with the
+// freeze history of the range.
+func (s *Service) GetStreak(ctx context.Context, userID string, from time.Time, to time.Time) (*StreakSummary, error) {
+	streak, err := s.repo.RefreshUserStreak(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	freezes, err := s.repo.ListStreakFreezeEvents(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return &StreakSummary{
+		UserStreak: streak,
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		Freezes:    freezes,
+	}, nil
+}
+
+// SetRestWeekdays stores weekdays, already normalized with
+// db.NormalizeRestWeekdays, as the rest days of userID.
+func (s *Service) SetRestWeekdays(ctx context.Context, userID string, weekdays []int) error {
+	return s.repo.SetUserRestWeekdays(ctx, userID, weekdays)
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/vladwithcode/tasktracker/internal/auth"
 	"github.com/vladwithcode/tasktracker/internal/db"
+	"github.com/vladwithcode/tasktracker/internal/events"
+	"github.com/vladwithcode/tasktracker/internal/insights"
 	"github.com/vladwithcode/tasktracker/internal/notifications"
 	"github.com/vladwithcode/tasktracker/internal/routes"
 	tasksvc "github.com/vladwithcode/tasktracker/internal/tasks"
@@ -42,6 +44,23 @@ func main() {
 	scheduler := notifications.NewTaskScheduler(globalCtx)
 	go scheduler.Start()
 
+	outbox := notifications.NewOutboxWorker(globalCtx, 0)
+	go outbox.Start()
+
+	grantExpiry := notifications.NewGrantExpiryWorker(globalCtx, 0)
+	go grantExpiry.Start()
+
+	accountability := notifications.NewAccountabilityWorker(globalCtx, 0)
+	go accountability.Start()
+
+	go events.Default.Run(globalCtx)
+
+	rollups := tasksvc.NewRollupReconciler(globalCtx)
+	go rollups.Start()
+
+	insightsEngine := insights.NewEngine(globalCtx)
+	go insightsEngine.Start()
+
 	if enabled, interval := tasksvc.TaskGeneratorConfigFromEnv(); enabled {
 		generator := tasksvc.NewTaskGenerator(globalCtx, interval)
 		go generator.Start()
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_digest_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    morning_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    morning_time TIME NOT NULL DEFAULT '07:00',
+    evening_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    evening_time TIME NOT NULL DEFAULT '21:00',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_user_digest_settings_updated_at
+BEFORE UPDATE ON user_digest_settings
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- One row per user, digest kind and local day. The primary key is the
+-- deduplication guard: the scheduler never sends the same digest twice.
+CREATE TABLE digest_notifications (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('morning_agenda', 'evening_summary')),
+    digest_date DATE NOT NULL,
+    sent_count INT NOT NULL DEFAULT 0,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, kind, digest_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE digest_notifications;
+DROP TRIGGER update_user_digest_settings_updated_at ON user_digest_settings;
+DROP TABLE user_digest_settings;
+-- +goose StatementEnd
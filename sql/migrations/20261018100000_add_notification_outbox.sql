@@ -0,0 +1,63 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE notification_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    dedupe_key TEXT UNIQUE,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 6,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    sent_count INT NOT NULL DEFAULT 0,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX notification_outbox_pending_idx
+    ON notification_outbox(next_attempt_at)
+    WHERE status = 'pending';
+CREATE INDEX notification_outbox_user_created_idx
+    ON notification_outbox(user_id, created_at DESC);
+
+CREATE TRIGGER update_notification_outbox_updated_at
+BEFORE UPDATE ON notification_outbox
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Per-subscription attempt log. subscription_id is intentionally not a
+-- foreign key: expired subscriptions are deleted but their log rows stay.
+CREATE TABLE notification_deliveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    outbox_id UUID NOT NULL REFERENCES notification_outbox(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subscription_id UUID,
+    endpoint TEXT,
+    status TEXT NOT NULL CHECK (status IN ('sent', 'failed', 'expired')),
+    error TEXT,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX notification_deliveries_outbox_idx ON notification_deliveries(outbox_id);
+CREATE INDEX notification_deliveries_user_attempted_idx
+    ON notification_deliveries(user_id, attempted_at DESC);
+
+-- Digests are now queued instead of sent inline, so the dedup row points to
+-- the queued notification rather than counting devices.
+ALTER TABLE digest_notifications
+    DROP COLUMN sent_count,
+    ADD COLUMN outbox_id UUID REFERENCES notification_outbox(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE digest_notifications
+    DROP COLUMN outbox_id,
+    ADD COLUMN sent_count INT NOT NULL DEFAULT 0;
+
+DROP TABLE notification_deliveries;
+DROP TRIGGER update_notification_outbox_updated_at ON notification_outbox;
+DROP TABLE notification_outbox;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Action tokens are stateless signed JWTs; a row is written only when one is
+-- redeemed so the primary key rejects a second use of the same token.
+CREATE TABLE notification_action_tokens (
+    token_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('complete', 'snooze', 'skip')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX notification_action_tokens_expires_idx ON notification_action_tokens(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notification_action_tokens;
+-- +goose StatementEnd
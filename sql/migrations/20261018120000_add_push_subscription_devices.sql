@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- last_seen_at is bumped when the browser re-registers the subscription and
+-- last_success_at when a push is accepted; a device is idle when both are old.
+-- They are separate from updated_at, which also moves on failure bookkeeping.
+ALTER TABLE push_subscriptions
+    ADD COLUMN label TEXT,
+    ADD COLUMN user_agent TEXT,
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN last_success_at TIMESTAMPTZ,
+    ADD COLUMN last_failure_at TIMESTAMPTZ,
+    ADD COLUMN failure_count INT NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE push_subscriptions
+    DROP COLUMN failure_count,
+    DROP COLUMN last_failure_at,
+    DROP COLUMN last_success_at,
+    DROP COLUMN last_seen_at,
+    DROP COLUMN user_agent,
+    DROP COLUMN label;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+-- notify_user_event publishes a small JSON event on the user_events channel.
+-- TG_ARGV[0] is the event type and TG_ARGV[1] the column holding the user the
+-- event is for. Only ids travel through NOTIFY (payloads are capped at 8 KB);
+-- clients refetch what they need.
+CREATE OR REPLACE FUNCTION notify_user_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    rec JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := to_jsonb(OLD);
+    ELSE
+        rec := to_jsonb(NEW);
+    END IF;
+
+    PERFORM pg_notify('user_events', json_build_object(
+        'user_id', rec->>TG_ARGV[1],
+        'type', TG_ARGV[0],
+        'op', lower(TG_OP),
+        'id', rec->>'id'
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER notify_tasks_user_event
+AFTER INSERT OR UPDATE OR DELETE ON tasks
+FOR EACH ROW EXECUTE PROCEDURE notify_user_event('task', 'user_id');
+
+CREATE TRIGGER notify_schedule_tasks_user_event
+AFTER INSERT OR UPDATE OR DELETE ON schedule_tasks
+FOR EACH ROW EXECUTE PROCEDURE notify_user_event('schedule', 'user_id');
+
+CREATE TRIGGER notify_task_pings_user_event
+AFTER INSERT OR UPDATE ON task_pings
+FOR EACH ROW EXECUTE PROCEDURE notify_user_event('inbox', 'recipient_user_id');
+
+CREATE TRIGGER notify_sharing_invitations_user_event
+AFTER INSERT OR UPDATE ON sharing_invitations
+FOR EACH ROW EXECUTE PROCEDURE notify_user_event('sharing_invitation', 'grantee_user_id');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER notify_sharing_invitations_user_event ON sharing_invitations;
+DROP TRIGGER notify_task_pings_user_event ON task_pings;
+DROP TRIGGER notify_schedule_tasks_user_event ON schedule_tasks;
+DROP TRIGGER notify_tasks_user_event ON tasks;
+DROP FUNCTION notify_user_event();
+-- +goose StatementEnd
@@ -0,0 +1,127 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task_pings
+    ADD COLUMN archived_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE sharing_invitations
+    ADD COLUMN archived_at TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- Notices that have no source row of their own: system messages and copies of
+-- the daily digests.
+CREATE TABLE inbox_notices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('system', 'digest')),
+    title TEXT NOT NULL,
+    body TEXT,
+    url TEXT,
+    data JSONB,
+    read_at TIMESTAMPTZ,
+    archived_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX inbox_notices_user_created_idx ON inbox_notices(user_id, created_at DESC);
+CREATE INDEX task_pings_recipient_created_idx ON task_pings(recipient_user_id, created_at DESC);
+
+CREATE TRIGGER notify_inbox_notices_user_event
+AFTER INSERT OR UPDATE ON inbox_notices
+FOR EACH ROW EXECUTE PROCEDURE notify_user_event('inbox', 'user_id');
+
+-- inbox_items is the single read model behind /notifications/inbox. Item ids
+-- are the ids of the source rows, so read/archive/delete update the source.
+CREATE VIEW inbox_items AS
+SELECT
+    p.id,
+    p.recipient_user_id AS user_id,
+    'ping'::text AS type,
+    p.task_id,
+    COALESCE(NULLIF(t.title, ''), st.title, 'Tarea')::text AS task_title,
+    p.sender_user_id,
+    u.username::text AS sender_username,
+    u.fullname::text AS sender_fullname,
+    p.message::text AS message,
+    NULL::uuid AS grant_id,
+    NULL::text AS access_level,
+    NULL::text AS title,
+    NULL::text AS body,
+    NULL::text AS url,
+    NULL::jsonb AS data,
+    p.notification_sent,
+    p.read_at,
+    p.archived_at,
+    p.created_at
+FROM task_pings p
+INNER JOIN users u ON u.id = p.sender_user_id
+INNER JOIN tasks t ON t.id = p.task_id
+INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
+WHERE p.deleted_at IS NULL
+UNION ALL
+SELECT
+    si.id,
+    si.grantee_user_id,
+    'invitation'::text,
+    NULL::uuid,
+    NULL::text,
+    si.owner_user_id,
+    u.username::text,
+    u.fullname::text,
+    NULL::text,
+    si.grant_id,
+    g.access_level::text,
+    NULL::text,
+    NULL::text,
+    NULL::text,
+    NULL::jsonb,
+    FALSE,
+    si.read_at,
+    si.archived_at,
+    si.created_at
+FROM sharing_invitations si
+INNER JOIN users u ON u.id = si.owner_user_id
+INNER JOIN task_access_grants g ON g.id = si.grant_id
+WHERE si.deleted_at IS NULL
+  AND g.revoked_at IS NULL
+UNION ALL
+SELECT
+    n.id,
+    n.user_id,
+    n.kind,
+    NULL::uuid,
+    NULL::text,
+    NULL::uuid,
+    NULL::text,
+    NULL::text,
+    NULL::text,
+    NULL::uuid,
+    NULL::text,
+    n.title,
+    n.body,
+    n.url,
+    n.data,
+    FALSE,
+    n.read_at,
+    n.archived_at,
+    n.created_at
+FROM inbox_notices n
+WHERE n.deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW inbox_items;
+DROP TRIGGER notify_inbox_notices_user_event ON inbox_notices;
+DROP INDEX task_pings_recipient_created_idx;
+DROP TABLE inbox_notices;
+
+ALTER TABLE sharing_invitations
+    DROP COLUMN deleted_at,
+    DROP COLUMN archived_at;
+
+ALTER TABLE task_pings
+    DROP COLUMN deleted_at,
+    DROP COLUMN archived_at;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE task_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    author_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    edited_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+
+CREATE INDEX task_comments_task_created_idx ON task_comments(task_id, created_at);
+
+CREATE TABLE task_comment_mentions (
+    comment_id UUID NOT NULL REFERENCES task_comments(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (comment_id, user_id)
+);
+
+-- Comment notifications land in the inbox as their own kind so clients can
+-- link them back to the thread.
+ALTER TABLE inbox_notices DROP CONSTRAINT inbox_notices_kind_check;
+ALTER TABLE inbox_notices
+    ADD CONSTRAINT inbox_notices_kind_check CHECK (kind IN ('system', 'digest', 'comment'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM inbox_notices WHERE kind = 'comment';
+ALTER TABLE inbox_notices DROP CONSTRAINT inbox_notices_kind_check;
+ALTER TABLE inbox_notices
+    ADD CONSTRAINT inbox_notices_kind_check CHECK (kind IN ('system', 'digest'));
+
+DROP TABLE task_comment_mentions;
+DROP TABLE task_comments;
+-- +goose StatementEnd
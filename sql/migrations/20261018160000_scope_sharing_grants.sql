@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A grant with both scopes empty covers every schedule of the owner. Otherwise
+-- it covers the schedules listed in scope_schedule_ids plus every schedule
+-- whose category (lowercased) is in scope_categories.
+ALTER TABLE task_access_grants
+    ADD COLUMN scope_categories TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN scope_schedule_ids UUID[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE task_access_grants
+    DROP COLUMN scope_schedule_ids,
+    DROP COLUMN scope_categories;
+-- +goose StatementEnd
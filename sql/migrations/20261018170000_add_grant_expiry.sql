@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE task_access_grants ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX task_access_grants_pending_expiry_idx
+    ON task_access_grants(expires_at)
+    WHERE revoked_at IS NULL AND expires_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX task_access_grants_pending_expiry_idx;
+ALTER TABLE task_access_grants DROP COLUMN expires_at;
+-- +goose StatementEnd
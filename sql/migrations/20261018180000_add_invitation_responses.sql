@@ -0,0 +1,183 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Grants only take effect once the grantee accepts the invitation. Grants and
+-- invitations created before this migration were active already.
+ALTER TABLE task_access_grants ADD COLUMN accepted_at TIMESTAMPTZ;
+UPDATE task_access_grants SET accepted_at = created_at;
+
+ALTER TABLE sharing_invitations
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'accepted', 'declined', 'cancelled', 'expired')),
+    ADD COLUMN responded_at TIMESTAMPTZ,
+    ADD COLUMN expires_at TIMESTAMPTZ;
+UPDATE sharing_invitations SET status = 'accepted', responded_at = created_at;
+
+CREATE INDEX sharing_invitations_pending_expiry_idx
+    ON sharing_invitations(expires_at)
+    WHERE status = 'pending' AND expires_at IS NOT NULL;
+
+-- Invitation items carry their status so clients can offer accept/decline.
+CREATE OR REPLACE VIEW inbox_items AS
+SELECT
+    p.id,
+    p.recipient_user_id AS user_id,
+    'ping'::text AS type,
+    p.task_id,
+    COALESCE(NULLIF(t.title, ''), st.title, 'Tarea')::text AS task_title,
+    p.sender_user_id,
+    u.username::text AS sender_username,
+    u.fullname::text AS sender_fullname,
+    p.message::text AS message,
+    NULL::uuid AS grant_id,
+    NULL::text AS access_level,
+    NULL::text AS title,
+    NULL::text AS body,
+    NULL::text AS url,
+    NULL::jsonb AS data,
+    p.notification_sent,
+    p.read_at,
+    p.archived_at,
+    p.created_at
+FROM task_pings p
+INNER JOIN users u ON u.id = p.sender_user_id
+INNER JOIN tasks t ON t.id = p.task_id
+INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
+WHERE p.deleted_at IS NULL
+UNION ALL
+SELECT
+    si.id,
+    si.grantee_user_id,
+    'invitation'::text,
+    NULL::uuid,
+    NULL::text,
+    si.owner_user_id,
+    u.username::text,
+    u.fullname::text,
+    NULL::text,
+    si.grant_id,
+    g.access_level::text,
+    NULL::text,
+    NULL::text,
+    NULL::text,
+    jsonb_strip_nulls(jsonb_build_object('status', si.status, 'expires_at', si.expires_at)),
+    FALSE,
+    si.read_at,
+    si.archived_at,
+    si.created_at
+FROM sharing_invitations si
+INNER JOIN users u ON u.id = si.owner_user_id
+INNER JOIN task_access_grants g ON g.id = si.grant_id
+WHERE si.deleted_at IS NULL
+  AND g.revoked_at IS NULL
+UNION ALL
+SELECT
+    n.id,
+    n.user_id,
+    n.kind,
+    NULL::uuid,
+    NULL::text,
+    NULL::uuid,
+    NULL::text,
+    NULL::text,
+    NULL::text,
+    NULL::uuid,
+    NULL::text,
+    n.title,
+    n.body,
+    n.url,
+    n.data,
+    FALSE,
+    n.read_at,
+    n.archived_at,
+    n.created_at
+FROM inbox_notices n
+WHERE n.deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW inbox_items AS
+SELECT
+    p.id,
+    p.recipient_user_id AS user_id,
+    'ping'::text AS type,
+    p.task_id,
+    COALESCE(NULLIF(t.title, ''), st.title, 'Tarea')::text AS task_title,
+    p.sender_user_id,
+    u.username::text AS sender_username,
+    u.fullname::text AS sender_fullname,
+    p.message::text AS message,
+    NULL::uuid AS grant_id,
+    NULL::text AS access_level,
+    NULL::text AS title,
+    NULL::text AS body,
+    NULL::text AS url,
+    NULL::jsonb AS data,
+    p.notification_sent,
+    p.read_at,
+    p.archived_at,
+    p.created_at
+FROM task_pings p
+INNER JOIN users u ON u.id = p.sender_user_id
+INNER JOIN tasks t ON t.id = p.task_id
+INNER JOIN schedule_tasks st ON st.id = t.schedule_task_id
+WHERE p.deleted_at IS NULL
+UNION ALL
+SELECT
+    si.id,
+    si.grantee_user_id,
+    'invitation'::text,
+    NULL::uuid,
+    NULL::text,
+    si.owner_user_id,
+    u.username::text,
+    u.fullname::text,
+    NULL::text,
+    si.grant_id,
+    g.access_level::text,
+    NULL::text,
+    NULL::text,
+    NULL::text,
+    NULL::jsonb,
+    FALSE,
+    si.read_at,
+    si.archived_at,
+    si.created_at
+FROM sharing_invitations si
+INNER JOIN users u ON u.id = si.owner_user_id
+INNER JOIN task_access_grants g ON g.id = si.grant_id
+WHERE si.deleted_at IS NULL
+  AND g.revoked_at IS NULL
+UNION ALL
+SELECT
+    n.id,
+    n.user_id,
+    n.kind,
+    NULL::uuid,
+    NULL::text,
+    NULL::uuid,
+    NULL::text,
+    NULL::text,
+    NULL::text,
+    NULL::uuid,
+    NULL::text,
+    n.title,
+    n.body,
+    n.url,
+    n.data,
+    FALSE,
+    n.read_at,
+    n.archived_at,
+    n.created_at
+FROM inbox_notices n
+WHERE n.deleted_at IS NULL;
+
+DROP INDEX sharing_invitations_pending_expiry_idx;
+
+ALTER TABLE sharing_invitations
+    DROP COLUMN expires_at,
+    DROP COLUMN responded_at,
+    DROP COLUMN status;
+
+ALTER TABLE task_access_grants DROP COLUMN accepted_at;
+-- +goose StatementEnd
@@ -0,0 +1,131 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Spaces group schedules of several users. Members see and act on the space
+-- schedules according to their role.
+CREATE TABLE spaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    description TEXT,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_spaces_updated_at
+BEFORE UPDATE ON spaces
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+CREATE TABLE space_members (
+    space_id UUID NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'manager', 'member', 'viewer')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (space_id, user_id)
+);
+
+CREATE INDEX space_members_user_idx ON space_members(user_id);
+
+ALTER TABLE schedule_tasks ADD COLUMN space_id UUID REFERENCES spaces(id) ON DELETE SET NULL;
+CREATE INDEX schedule_tasks_space_idx ON schedule_tasks(space_id) WHERE space_id IS NOT NULL;
+
+ALTER TABLE tasks ADD COLUMN assignee_user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX tasks_assignee_idx ON tasks(assignee_user_id) WHERE assignee_user_id IS NOT NULL;
+
+CREATE OR REPLACE VIEW detailed_tasks AS
+SELECT
+    t.id,
+    t.date,
+    t.status_level AS status,
+    t.completed_at,
+    t.actual_start,
+    t.actual_end,
+    t.current_count,
+    COALESCE(t.target_count, st.target_count) AS target_count,
+    t.notes,
+    st.id AS schedule_task_id,
+    st.user_id,
+    st.created_by,
+    COALESCE(NULLIF(t.title, ''), st.title) AS title,
+    COALESCE(t.description, st.description) AS description,
+    st.schedule_start_time AS start_time,
+    st.duration_minutes AS duration,
+    st.schedule_end_time AS end_time,
+    st.start_date,
+    st.end_date,
+    st.repeating,
+    st.repeat_frequency,
+    st.repeat_weekdays,
+    st.repeat_interval,
+    st.repeat_end_date,
+    st.frequency,
+    st.frequency_config,
+    st.category,
+    st.priority_level AS priority,
+    st.is_required AS required,
+    st.is_required,
+    st.search_vector,
+    st.status_level AS schedule_status,
+    st.created_at AS schedule_created_at,
+    st.updated_at AS schedule_updated_at,
+    COALESCE(t.created_at, st.created_at) AS created_at,
+    COALESCE(t.updated_at, st.updated_at) AS updated_at,
+    st.space_id,
+    t.assignee_user_id
+FROM schedule_tasks st
+LEFT JOIN tasks t ON st.id = t.schedule_task_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW detailed_tasks;
+
+DROP INDEX tasks_assignee_idx;
+ALTER TABLE tasks DROP COLUMN assignee_user_id;
+
+DROP INDEX schedule_tasks_space_idx;
+ALTER TABLE schedule_tasks DROP COLUMN space_id;
+
+DROP TABLE space_members;
+DROP TABLE spaces;
+
+CREATE VIEW detailed_tasks AS
+SELECT
+    t.id,
+    t.date,
+    t.status_level AS status,
+    t.completed_at,
+    t.actual_start,
+    t.actual_end,
+    t.current_count,
+    COALESCE(t.target_count, st.target_count) AS target_count,
+    t.notes,
+    st.id AS schedule_task_id,
+    st.user_id,
+    st.created_by,
+    COALESCE(NULLIF(t.title, ''), st.title) AS title,
+    COALESCE(t.description, st.description) AS description,
+    st.schedule_start_time AS start_time,
+    st.duration_minutes AS duration,
+    st.schedule_end_time AS end_time,
+    st.start_date,
+    st.end_date,
+    st.repeating,
+    st.repeat_frequency,
+    st.repeat_weekdays,
+    st.repeat_interval,
+    st.repeat_end_date,
+    st.frequency,
+    st.frequency_config,
+    st.category,
+    st.priority_level AS priority,
+    st.is_required AS required,
+    st.is_required,
+    st.search_vector,
+    st.status_level AS schedule_status,
+    st.created_at AS schedule_created_at,
+    st.updated_at AS schedule_updated_at,
+    COALESCE(t.created_at, st.created_at) AS created_at,
+    COALESCE(t.updated_at, st.updated_at) AS updated_at
+FROM schedule_tasks st
+LEFT JOIN tasks t ON st.id = t.schedule_task_id;
+-- +goose StatementEnd
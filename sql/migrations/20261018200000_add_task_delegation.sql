@@ -0,0 +1,132 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Tasks can be delegated to a user other than their owner. A delegation stays
+-- pending until the assignee accepts or declines it; space assignments are
+-- accepted straight away. completed_by_user_id records who actually finished
+-- the task so metrics credit the assignee. It has no foreign key: completions
+-- are append-only, so neither a cascade nor SET NULL could ever apply.
+ALTER TABLE tasks
+    ADD COLUMN assignment_status TEXT CHECK (assignment_status IN ('pending', 'accepted', 'declined')),
+    ADD COLUMN assigned_by_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN assigned_at TIMESTAMPTZ,
+    ADD COLUMN assignment_responded_at TIMESTAMPTZ;
+
+UPDATE tasks
+SET assignment_status = 'accepted', assigned_at = updated_at, assignment_responded_at = updated_at
+WHERE assignee_user_id IS NOT NULL;
+
+ALTER TABLE task_completions
+    ADD COLUMN completed_by_user_id UUID;
+
+CREATE INDEX idx_task_completions_completed_by ON task_completions (completed_by_user_id, completed_at DESC)
+    WHERE completed_by_user_id IS NOT NULL;
+
+CREATE TRIGGER notify_tasks_assignee_user_event
+AFTER INSERT OR UPDATE ON tasks
+FOR EACH ROW WHEN (NEW.assignee_user_id IS NOT NULL)
+EXECUTE PROCEDURE notify_user_event('task', 'assignee_user_id');
+
+CREATE OR REPLACE VIEW detailed_tasks AS
+SELECT
+    t.id,
+    t.date,
+    t.status_level AS status,
+    t.completed_at,
+    t.actual_start,
+    t.actual_end,
+    t.current_count,
+    COALESCE(t.target_count, st.target_count) AS target_count,
+    t.notes,
+    st.id AS schedule_task_id,
+    st.user_id,
+    st.created_by,
+    COALESCE(NULLIF(t.title, ''), st.title) AS title,
+    COALESCE(t.description, st.description) AS description,
+    st.schedule_start_time AS start_time,
+    st.duration_minutes AS duration,
+    st.schedule_end_time AS end_time,
+    st.start_date,
+    st.end_date,
+    st.repeating,
+    st.repeat_frequency,
+    st.repeat_weekdays,
+    st.repeat_interval,
+    st.repeat_end_date,
+    st.frequency,
+    st.frequency_config,
+    st.category,
+    st.priority_level AS priority,
+    st.is_required AS required,
+    st.is_required,
+    st.search_vector,
+    st.status_level AS schedule_status,
+    st.created_at AS schedule_created_at,
+    st.updated_at AS schedule_updated_at,
+    COALESCE(t.created_at, st.created_at) AS created_at,
+    COALESCE(t.updated_at, st.updated_at) AS updated_at,
+    st.space_id,
+    t.assignee_user_id,
+    t.assignment_status,
+    t.assigned_by_user_id
+FROM schedule_tasks st
+LEFT JOIN tasks t ON st.id = t.schedule_task_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW detailed_tasks;
+
+DROP TRIGGER notify_tasks_assignee_user_event ON tasks;
+
+DROP INDEX idx_task_completions_completed_by;
+ALTER TABLE task_completions DROP COLUMN completed_by_user_id;
+
+ALTER TABLE tasks
+    DROP COLUMN assignment_responded_at,
+    DROP COLUMN assigned_at,
+    DROP COLUMN assigned_by_user_id,
+    DROP COLUMN assignment_status;
+
+CREATE VIEW detailed_tasks AS
+SELECT
+    t.id,
+    t.date,
+    t.status_level AS status,
+    t.completed_at,
+    t.actual_start,
+    t.actual_end,
+    t.current_count,
+    COALESCE(t.target_count, st.target_count) AS target_count,
+    t.notes,
+    st.id AS schedule_task_id,
+    st.user_id,
+    st.created_by,
+    COALESCE(NULLIF(t.title, ''), st.title) AS title,
+    COALESCE(t.description, st.description) AS description,
+    st.schedule_start_time AS start_time,
+    st.duration_minutes AS duration,
+    st.schedule_end_time AS end_time,
+    st.start_date,
+    st.end_date,
+    st.repeating,
+    st.repeat_frequency,
+    st.repeat_weekdays,
+    st.repeat_interval,
+    st.repeat_end_date,
+    st.frequency,
+    st.frequency_config,
+    st.category,
+    st.priority_level AS priority,
+    st.is_required AS required,
+    st.is_required,
+    st.search_vector,
+    st.status_level AS schedule_status,
+    st.created_at AS schedule_created_at,
+    st.updated_at AS schedule_updated_at,
+    COALESCE(t.created_at, st.created_at) AS created_at,
+    COALESCE(t.updated_at, st.updated_at) AS updated_at,
+    st.space_id,
+    t.assignee_user_id
+FROM schedule_tasks st
+LEFT JOIN tasks t ON st.id = t.schedule_task_id;
+-- +goose StatementEnd
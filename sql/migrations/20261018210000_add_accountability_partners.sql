@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- An accountability partner is the grantee of a sharing grant the owner asked
+-- to be alerted about missed required tasks and broken streaks. A day counts
+-- once grace_minutes have passed after it ended, so late check-ins still save
+-- the day.
+CREATE TABLE accountability_partners (
+    grant_id UUID PRIMARY KEY REFERENCES task_access_grants(id) ON DELETE CASCADE,
+    grace_minutes INT NOT NULL DEFAULT 120 CHECK (grace_minutes BETWEEN 0 AND 2880),
+    weekly_summary BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_accountability_partners_updated_at
+BEFORE UPDATE ON accountability_partners
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- One row per alert sent to a partner. subject is the task id for missed
+-- tasks and the day (or first day of the week) otherwise; the primary key is
+-- the deduplication guard.
+CREATE TABLE accountability_alerts (
+    grant_id UUID NOT NULL REFERENCES accountability_partners(grant_id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('missed_task', 'streak_broken', 'weekly_summary')),
+    subject TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (grant_id, kind, subject)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE accountability_alerts;
+DROP TRIGGER update_accountability_partners_updated_at ON accountability_partners;
+DROP TABLE accountability_partners;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Grants in approval mode turn the grantee's task edits and new schedules into
+-- change requests the owner has to approve before they are applied.
+ALTER TABLE task_access_grants ADD COLUMN requires_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- payload holds the proposed change as the service will apply it; diff is the
+-- field-by-field view shown to the owner. Requests outlive the grant they came
+-- through so the history stays readable after a revoke.
+CREATE TABLE sharing_change_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    grant_id UUID REFERENCES task_access_grants(id) ON DELETE SET NULL,
+    owner_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requester_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('task_update', 'schedule_create')),
+    task_id UUID REFERENCES tasks(id) ON DELETE CASCADE,
+    payload JSONB NOT NULL,
+    diff JSONB NOT NULL DEFAULT '[]',
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'cancelled')),
+    review_note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMPTZ,
+    CHECK ((kind = 'task_update') = (task_id IS NOT NULL))
+);
+
+CREATE INDEX sharing_change_requests_owner_pending_idx
+    ON sharing_change_requests(owner_user_id, created_at DESC)
+    WHERE status = 'pending';
+CREATE INDEX sharing_change_requests_requester_idx
+    ON sharing_change_requests(requester_user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE sharing_change_requests;
+ALTER TABLE task_access_grants DROP COLUMN requires_approval;
+-- +goose StatementEnd
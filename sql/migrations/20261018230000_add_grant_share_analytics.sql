@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Lets the owner hide progress, history and metrics from a grantee that can
+-- otherwise view their tasks. Analytics are derived from tasks the grantee can
+-- already see, so they are shared unless the owner turns them off.
+ALTER TABLE task_access_grants ADD COLUMN share_analytics BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE task_access_grants DROP COLUMN share_analytics;
+-- +goose StatementEnd
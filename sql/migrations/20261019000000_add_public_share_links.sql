@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Read-only links that show part of a user's plan to people without an
+-- account. Only a hash of the token is stored; the owner sees the token once,
+-- when the link is created.
+CREATE TABLE public_share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    label TEXT,
+    scope TEXT NOT NULL CHECK (scope IN ('day', 'range', 'category')),
+    from_date DATE NOT NULL,
+    to_date DATE NOT NULL,
+    category TEXT,
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    access_count INTEGER NOT NULL DEFAULT 0,
+    last_accessed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_date <= to_date),
+    CHECK ((scope = 'category') = (category IS NOT NULL))
+);
+
+CREATE INDEX public_share_links_owner_idx ON public_share_links(owner_user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE public_share_links;
+-- +goose StatementEnd
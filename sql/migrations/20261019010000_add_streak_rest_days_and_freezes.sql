@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Streak settings and state of a user. Rest weekdays (0 = Sunday) neither
+-- break nor extend a streak. Days up to evaluated_through have already been
+-- counted towards current_streak and the freeze balance.
+CREATE TABLE user_streaks (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    rest_weekdays INT[] NOT NULL DEFAULT '{}',
+    current_streak INTEGER NOT NULL DEFAULT 0,
+    longest_streak INTEGER NOT NULL DEFAULT 0,
+    freezes_available INTEGER NOT NULL DEFAULT 0 CHECK (freezes_available >= 0),
+    evaluated_through DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_user_streaks_updated_at
+BEFORE UPDATE ON user_streaks
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Every freeze earned by a streak and every missed day a freeze covered.
+CREATE TABLE streak_freeze_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event_date DATE NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('earned', 'used')),
+    streak INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, event_date, kind)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE streak_freeze_events;
+DROP TRIGGER update_user_streaks_updated_at ON user_streaks;
+DROP TABLE user_streaks;
+-- +goose StatementEnd
@@ -0,0 +1,257 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per responsible user, day and schedule with the task counts history
+-- and metrics read, so they no longer scan tasks and task_completions. Tasks
+-- count on the day they are due; completions on the day they happened and
+-- towards the user credited with them. Triggers keep the rows current and a
+-- nightly job reconciles recent days in case one was missed.
+CREATE TABLE task_daily_rollups (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    schedule_task_id UUID REFERENCES schedule_tasks(id) ON DELETE CASCADE,
+    category TEXT NOT NULL DEFAULT '',
+    total INT NOT NULL DEFAULT 0,
+    completed INT NOT NULL DEFAULT 0,
+    pending INT NOT NULL DEFAULT 0,
+    skipped INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    in_progress INT NOT NULL DEFAULT 0,
+    completions INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT task_daily_rollups_key UNIQUE NULLS NOT DISTINCT (user_id, day, schedule_task_id)
+);
+
+CREATE INDEX idx_task_daily_rollups_schedule ON task_daily_rollups (schedule_task_id, day);
+
+CREATE TRIGGER trigger_update_task_daily_rollups_updated_at BEFORE UPDATE ON task_daily_rollups
+    FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- The recounts look tasks and completions up by the user they count for and
+-- a range of the day, which these indexes serve; DATE(date) could not use
+-- them.
+CREATE INDEX idx_tasks_responsible_user_date
+    ON tasks ((COALESCE(CASE WHEN assignment_status = 'accepted' THEN assignee_user_id END, user_id)), date);
+CREATE INDEX idx_task_completions_credited_user_completed_at
+    ON task_completions ((COALESCE(completed_by_user_id, user_id)), completed_at);
+
+-- refresh_task_daily_rollup recounts one rollup row from the source tables
+-- and drops it once nothing is left to count.
+CREATE OR REPLACE FUNCTION refresh_task_daily_rollup(p_user_id UUID, p_day DATE, p_schedule_task_id UUID)
+RETURNS void AS $$
+BEGIN
+    IF p_user_id IS NULL OR p_day IS NULL THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO task_daily_rollups (
+        user_id, day, schedule_task_id, category,
+        total, completed, pending, skipped, failed, in_progress, completions
+    )
+    SELECT
+        p_user_id,
+        p_day,
+        p_schedule_task_id,
+        COALESCE((SELECT LOWER(TRIM(category)) FROM schedule_tasks WHERE id = p_schedule_task_id), ''),
+        t.total, t.completed, t.pending, t.skipped, t.failed, t.in_progress, c.completions
+    FROM (
+        SELECT
+            COUNT(*) AS total,
+            COUNT(*) FILTER (WHERE status_level = 'completed') AS completed,
+            COUNT(*) FILTER (WHERE status_level = 'pending') AS pending,
+            COUNT(*) FILTER (WHERE status_level = 'skipped') AS skipped,
+            COUNT(*) FILTER (WHERE status_level = 'failed') AS failed,
+            COUNT(*) FILTER (WHERE status_level = 'in_progress') AS in_progress
+        FROM tasks
+        WHERE COALESCE(CASE WHEN assignment_status = 'accepted' THEN assignee_user_id END, user_id) = p_user_id
+            AND date >= p_day::timestamptz
+            AND date < (p_day + 1)::timestamptz
+            AND schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id
+    ) t, (
+        SELECT COUNT(*) AS completions
+        FROM task_completions tc
+        INNER JOIN tasks tk ON tk.id = tc.task_id
+        WHERE COALESCE(tc.completed_by_user_id, tc.user_id) = p_user_id
+            AND tc.completed_at >= p_day::timestamptz
+            AND tc.completed_at < (p_day + 1)::timestamptz
+            AND tk.schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id
+    ) c
+    WHERE t.total > 0 OR c.completions > 0
+    ON CONFLICT ON CONSTRAINT task_daily_rollups_key DO UPDATE SET
+        category = EXCLUDED.category,
+        total = EXCLUDED.total,
+        completed = EXCLUDED.completed,
+        pending = EXCLUDED.pending,
+        skipped = EXCLUDED.skipped,
+        failed = EXCLUDED.failed,
+        in_progress = EXCLUDED.in_progress,
+        completions = EXCLUDED.completions;
+
+    IF NOT FOUND THEN
+        DELETE FROM task_daily_rollups
+        WHERE user_id = p_user_id
+            AND day = p_day
+            AND schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION refresh_task_daily_rollups_for_task()
+RETURNS trigger AS $$
+DECLARE
+    old_user_id UUID;
+    new_user_id UUID;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        new_user_id := COALESCE(CASE WHEN NEW.assignment_status = 'accepted' THEN NEW.assignee_user_id END, NEW.user_id);
+        PERFORM refresh_task_daily_rollup(new_user_id, DATE(NEW.date), NEW.schedule_task_id);
+        RETURN NULL;
+    END IF;
+
+    old_user_id := COALESCE(CASE WHEN OLD.assignment_status = 'accepted' THEN OLD.assignee_user_id END, OLD.user_id);
+    PERFORM refresh_task_daily_rollup(old_user_id, DATE(OLD.date), OLD.schedule_task_id);
+    IF TG_OP = 'UPDATE' THEN
+        new_user_id := COALESCE(CASE WHEN NEW.assignment_status = 'accepted' THEN NEW.assignee_user_id END, NEW.user_id);
+        -- A task that moved to another user, day or schedule leaves one row
+        -- and joins another.
+        IF new_user_id IS DISTINCT FROM old_user_id
+            OR DATE(NEW.date) IS DISTINCT FROM DATE(OLD.date)
+            OR NEW.schedule_task_id IS DISTINCT FROM OLD.schedule_task_id THEN
+            PERFORM refresh_task_daily_rollup(new_user_id, DATE(NEW.date), NEW.schedule_task_id);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER refresh_task_daily_rollups_on_task_write
+    AFTER INSERT OR DELETE ON tasks
+    FOR EACH ROW EXECUTE PROCEDURE refresh_task_daily_rollups_for_task();
+
+CREATE TRIGGER refresh_task_daily_rollups_on_task_update
+    AFTER UPDATE OF date, status_level, user_id, assignee_user_id, assignment_status, schedule_task_id ON tasks
+    FOR EACH ROW WHEN (
+        OLD.date IS DISTINCT FROM NEW.date
+        OR OLD.status_level IS DISTINCT FROM NEW.status_level
+        OR OLD.user_id IS DISTINCT FROM NEW.user_id
+        OR OLD.assignee_user_id IS DISTINCT FROM NEW.assignee_user_id
+        OR OLD.assignment_status IS DISTINCT FROM NEW.assignment_status
+        OR OLD.schedule_task_id IS DISTINCT FROM NEW.schedule_task_id
+    )
+    EXECUTE PROCEDURE refresh_task_daily_rollups_for_task();
+
+-- Completions are append-only, but they still go away with their task or
+-- user, or get credited elsewhere once the append-only guard is lifted.
+CREATE OR REPLACE FUNCTION refresh_task_daily_rollups_for_completion()
+RETURNS trigger AS $$
+DECLARE
+    old_user_id UUID;
+    old_schedule_task_id UUID;
+    rollup RECORD;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        old_user_id := COALESCE(OLD.completed_by_user_id, OLD.user_id);
+        SELECT schedule_task_id INTO old_schedule_task_id FROM tasks WHERE id = OLD.task_id;
+        IF FOUND THEN
+            PERFORM refresh_task_daily_rollup(old_user_id, DATE(OLD.completed_at), old_schedule_task_id);
+        ELSE
+            -- The task was deleted along with its completions, so the
+            -- schedule they counted for is unknown: recount the whole day.
+            FOR rollup IN
+                SELECT schedule_task_id
+                FROM task_daily_rollups
+                WHERE user_id = old_user_id AND day = DATE(OLD.completed_at)
+            LOOP
+                PERFORM refresh_task_daily_rollup(old_user_id, DATE(OLD.completed_at), rollup.schedule_task_id);
+            END LOOP;
+        END IF;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        PERFORM refresh_task_daily_rollup(
+            COALESCE(NEW.completed_by_user_id, NEW.user_id),
+            DATE(NEW.completed_at),
+            (SELECT schedule_task_id FROM tasks WHERE id = NEW.task_id)
+        );
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER refresh_task_daily_rollups_on_completion
+    AFTER INSERT OR UPDATE OR DELETE ON task_completions
+    FOR EACH ROW EXECUTE PROCEDURE refresh_task_daily_rollups_for_completion();
+
+CREATE OR REPLACE FUNCTION sync_task_daily_rollup_category()
+RETURNS trigger AS $$
+BEGIN
+    UPDATE task_daily_rollups
+    SET category = COALESCE(LOWER(TRIM(NEW.category)), '')
+    WHERE schedule_task_id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER sync_task_daily_rollup_category
+    AFTER UPDATE OF category ON schedule_tasks
+    FOR EACH ROW WHEN (OLD.category IS DISTINCT FROM NEW.category)
+    EXECUTE PROCEDURE sync_task_daily_rollup_category();
+
+-- Backfill every day recorded so far.
+INSERT INTO task_daily_rollups (
+    user_id, day, schedule_task_id, category,
+    total, completed, pending, skipped, failed, in_progress, completions
+)
+SELECT
+    r.user_id,
+    r.day,
+    r.schedule_task_id,
+    COALESCE(LOWER(TRIM(st.category)), ''),
+    SUM(r.total),
+    SUM(r.completed),
+    SUM(r.pending),
+    SUM(r.skipped),
+    SUM(r.failed),
+    SUM(r.in_progress),
+    SUM(r.completions)
+FROM (
+    SELECT
+        COALESCE(CASE WHEN assignment_status = 'accepted' THEN assignee_user_id END, user_id) AS user_id,
+        DATE(date) AS day,
+        schedule_task_id,
+        1 AS total,
+        (status_level = 'completed')::int AS completed,
+        (status_level = 'pending')::int AS pending,
+        (status_level = 'skipped')::int AS skipped,
+        (status_level = 'failed')::int AS failed,
+        (status_level = 'in_progress')::int AS in_progress,
+        0 AS completions
+    FROM tasks
+    UNION ALL
+    SELECT
+        COALESCE(tc.completed_by_user_id, tc.user_id),
+        DATE(tc.completed_at),
+        tk.schedule_task_id,
+        0, 0, 0, 0, 0, 0,
+        1
+    FROM task_completions tc
+    INNER JOIN tasks tk ON tk.id = tc.task_id
+) r
+INNER JOIN users u ON u.id = r.user_id
+LEFT JOIN schedule_tasks st ON st.id = r.schedule_task_id
+GROUP BY r.user_id, r.day, r.schedule_task_id, st.category;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER sync_task_daily_rollup_category ON schedule_tasks;
+DROP TRIGGER refresh_task_daily_rollups_on_completion ON task_completions;
+DROP TRIGGER refresh_task_daily_rollups_on_task_update ON tasks;
+DROP TRIGGER refresh_task_daily_rollups_on_task_write ON tasks;
+DROP FUNCTION sync_task_daily_rollup_category();
+DROP FUNCTION refresh_task_daily_rollups_for_completion();
+DROP FUNCTION refresh_task_daily_rollups_for_task();
+DROP FUNCTION refresh_task_daily_rollup(UUID, DATE, UUID);
+DROP TABLE task_daily_rollups;
+DROP INDEX idx_task_completions_credited_user_completed_at;
+DROP INDEX idx_tasks_responsible_user_date;
+-- +goose StatementEnd
@@ -0,0 +1,233 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Weights of the productivity score of a user. A task weighs its required or
+-- optional weight times its urgent or medium weight (legacy high and low count
+-- as urgent and medium). With partial_credit, an unfinished counter task earns
+-- the share of its target it reached.
+CREATE TABLE user_score_weights (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    required_weight DOUBLE PRECISION NOT NULL DEFAULT 2 CHECK (required_weight > 0),
+    optional_weight DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (optional_weight > 0),
+    urgent_weight DOUBLE PRECISION NOT NULL DEFAULT 2 CHECK (urgent_weight > 0),
+    medium_weight DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (medium_weight > 0),
+    partial_credit BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_user_score_weights_updated_at
+BEFORE UPDATE ON user_score_weights
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- partial_progress sums, over the unfinished tasks of a rollup with a
+-- target, the share of the target reached (at most 1 each).
+ALTER TABLE task_daily_rollups
+    ADD COLUMN partial_progress DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+CREATE OR REPLACE FUNCTION refresh_task_daily_rollup(p_user_id UUID, p_day DATE, p_schedule_task_id UUID)
+RETURNS void AS $$
+DECLARE
+    schedule_target INT;
+BEGIN
+    IF p_user_id IS NULL OR p_day IS NULL THEN
+        RETURN;
+    END IF;
+    SELECT target_count INTO schedule_target FROM schedule_tasks WHERE id = p_schedule_task_id;
+
+    INSERT INTO task_daily_rollups (
+        user_id, day, schedule_task_id, category,
+        total, completed, pending, skipped, failed, in_progress, completions, partial_progress
+    )
+    SELECT
+        p_user_id,
+        p_day,
+        p_schedule_task_id,
+        COALESCE((SELECT LOWER(TRIM(category)) FROM schedule_tasks WHERE id = p_schedule_task_id), ''),
+        t.total, t.completed, t.pending, t.skipped, t.failed, t.in_progress, c.completions, t.partial_progress
+    FROM (
+        SELECT
+            COUNT(*) AS total,
+            COUNT(*) FILTER (WHERE status_level = 'completed') AS completed,
+            COUNT(*) FILTER (WHERE status_level = 'pending') AS pending,
+            COUNT(*) FILTER (WHERE status_level = 'skipped') AS skipped,
+            COUNT(*) FILTER (WHERE status_level = 'failed') AS failed,
+            COUNT(*) FILTER (WHERE status_level = 'in_progress') AS in_progress,
+            COALESCE(SUM(LEAST(current_count::float8 / COALESCE(target_count, schedule_target), 1)) FILTER (
+                WHERE status_level <> 'completed' AND COALESCE(target_count, schedule_target) > 0
+            ), 0) AS partial_progress
+        FROM tasks
+        WHERE COALESCE(CASE WHEN assignment_status = 'accepted' THEN assignee_user_id END, user_id) = p_user_id
+            AND date >= p_day::timestamptz
+            AND date < (p_day + 1)::timestamptz
+            AND schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id
+    ) t, (
+        SELECT COUNT(*) AS completions
+        FROM task_completions tc
+        INNER JOIN tasks tk ON tk.id = tc.task_id
+        WHERE COALESCE(tc.completed_by_user_id, tc.user_id) = p_user_id
+            AND tc.completed_at >= p_day::timestamptz
+            AND tc.completed_at < (p_day + 1)::timestamptz
+            AND tk.schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id
+    ) c
+    WHERE t.total > 0 OR c.completions > 0
+    ON CONFLICT ON CONSTRAINT task_daily_rollups_key DO UPDATE SET
+        category = EXCLUDED.category,
+        total = EXCLUDED.total,
+        completed = EXCLUDED.completed,
+        pending = EXCLUDED.pending,
+        skipped = EXCLUDED.skipped,
+        failed = EXCLUDED.failed,
+        in_progress = EXCLUDED.in_progress,
+        completions = EXCLUDED.completions,
+        partial_progress = EXCLUDED.partial_progress;
+
+    IF NOT FOUND THEN
+        DELETE FROM task_daily_rollups
+        WHERE user_id = p_user_id
+            AND day = p_day
+            AND schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER refresh_task_daily_rollups_on_task_update ON tasks;
+
+CREATE TRIGGER refresh_task_daily_rollups_on_task_update
+    AFTER UPDATE OF date, status_level, user_id, assignee_user_id, assignment_status, schedule_task_id, current_count, target_count ON tasks
+    FOR EACH ROW WHEN (
+        OLD.date IS DISTINCT FROM NEW.date
+        OR OLD.status_level IS DISTINCT FROM NEW.status_level
+        OR OLD.user_id IS DISTINCT FROM NEW.user_id
+        OR OLD.assignee_user_id IS DISTINCT FROM NEW.assignee_user_id
+        OR OLD.assignment_status IS DISTINCT FROM NEW.assignment_status
+        OR OLD.schedule_task_id IS DISTINCT FROM NEW.schedule_task_id
+        OR OLD.current_count IS DISTINCT FROM NEW.current_count
+        OR OLD.target_count IS DISTINCT FROM NEW.target_count
+    )
+    EXECUTE PROCEDURE refresh_task_daily_rollups_for_task();
+
+-- Tasks without a target of their own use the schedule's, so changing it
+-- recounts the rollups of the schedule that have unfinished tasks.
+CREATE OR REPLACE FUNCTION refresh_task_daily_rollups_for_schedule_target()
+RETURNS trigger AS $$
+DECLARE
+    rollup RECORD;
+BEGIN
+    FOR rollup IN
+        SELECT user_id, day
+        FROM task_daily_rollups
+        WHERE schedule_task_id = NEW.id AND total > completed
+    LOOP
+        PERFORM refresh_task_daily_rollup(rollup.user_id, rollup.day, NEW.id);
+    END LOOP;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER refresh_task_daily_rollups_on_schedule_target
+    AFTER UPDATE OF target_count ON schedule_tasks
+    FOR EACH ROW WHEN (OLD.target_count IS DISTINCT FROM NEW.target_count)
+    EXECUTE PROCEDURE refresh_task_daily_rollups_for_schedule_target();
+
+UPDATE task_daily_rollups r
+SET partial_progress = p.partial_progress
+FROM (
+    SELECT
+        COALESCE(CASE WHEN t.assignment_status = 'accepted' THEN t.assignee_user_id END, t.user_id) AS user_id,
+        DATE(t.date) AS day,
+        t.schedule_task_id,
+        SUM(LEAST(t.current_count::float8 / COALESCE(t.target_count, st.target_count), 1)) AS partial_progress
+    FROM tasks t
+    LEFT JOIN schedule_tasks st ON st.id = t.schedule_task_id
+    WHERE t.status_level <> 'completed'
+        AND COALESCE(t.target_count, st.target_count) > 0
+    GROUP BY 1, 2, 3
+) p
+WHERE r.user_id = p.user_id
+    AND r.day = p.day
+    AND r.schedule_task_id IS NOT DISTINCT FROM p.schedule_task_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER refresh_task_daily_rollups_on_schedule_target ON schedule_tasks;
+DROP FUNCTION refresh_task_daily_rollups_for_schedule_target();
+
+DROP TRIGGER refresh_task_daily_rollups_on_task_update ON tasks;
+
+CREATE TRIGGER refresh_task_daily_rollups_on_task_update
+    AFTER UPDATE OF date, status_level, user_id, assignee_user_id, assignment_status, schedule_task_id ON tasks
+    FOR EACH ROW WHEN (
+        OLD.date IS DISTINCT FROM NEW.date
+        OR OLD.status_level IS DISTINCT FROM NEW.status_level
+        OR OLD.user_id IS DISTINCT FROM NEW.user_id
+        OR OLD.assignee_user_id IS DISTINCT FROM NEW.assignee_user_id
+        OR OLD.assignment_status IS DISTINCT FROM NEW.assignment_status
+        OR OLD.schedule_task_id IS DISTINCT FROM NEW.schedule_task_id
+    )
+    EXECUTE PROCEDURE refresh_task_daily_rollups_for_task();
+
+CREATE OR REPLACE FUNCTION refresh_task_daily_rollup(p_user_id UUID, p_day DATE, p_schedule_task_id UUID)
+RETURNS void AS $$
+BEGIN
+    IF p_user_id IS NULL OR p_day IS NULL THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO task_daily_rollups (
+        user_id, day, schedule_task_id, category,
+        total, completed, pending, skipped, failed, in_progress, completions
+    )
+    SELECT
+        p_user_id,
+        p_day,
+        p_schedule_task_id,
+        COALESCE((SELECT LOWER(TRIM(category)) FROM schedule_tasks WHERE id = p_schedule_task_id), ''),
+        t.total, t.completed, t.pending, t.skipped, t.failed, t.in_progress, c.completions
+    FROM (
+        SELECT
+            COUNT(*) AS total,
+            COUNT(*) FILTER (WHERE status_level = 'completed') AS completed,
+            COUNT(*) FILTER (WHERE status_level = 'pending') AS pending,
+            COUNT(*) FILTER (WHERE status_level = 'skipped') AS skipped,
+            COUNT(*) FILTER (WHERE status_level = 'failed') AS failed,
+            COUNT(*) FILTER (WHERE status_level = 'in_progress') AS in_progress
+        FROM tasks
+        WHERE COALESCE(CASE WHEN assignment_status = 'accepted' THEN assignee_user_id END, user_id) = p_user_id
+            AND date >= p_day::timestamptz
+            AND date < (p_day + 1)::timestamptz
+            AND schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id
+    ) t, (
+        SELECT COUNT(*) AS completions
+        FROM task_completions tc
+        INNER JOIN tasks tk ON tk.id = tc.task_id
+        WHERE COALESCE(tc.completed_by_user_id, tc.user_id) = p_user_id
+            AND tc.completed_at >= p_day::timestamptz
+            AND tc.completed_at < (p_day + 1)::timestamptz
+            AND tk.schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id
+    ) c
+    WHERE t.total > 0 OR c.completions > 0
+    ON CONFLICT ON CONSTRAINT task_daily_rollups_key DO UPDATE SET
+        category = EXCLUDED.category,
+        total = EXCLUDED.total,
+        completed = EXCLUDED.completed,
+        pending = EXCLUDED.pending,
+        skipped = EXCLUDED.skipped,
+        failed = EXCLUDED.failed,
+        in_progress = EXCLUDED.in_progress,
+        completions = EXCLUDED.completions;
+
+    IF NOT FOUND THEN
+        DELETE FROM task_daily_rollups
+        WHERE user_id = p_user_id
+            AND day = p_day
+            AND schedule_task_id IS NOT DISTINCT FROM p_schedule_task_id;
+    END IF;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE task_daily_rollups DROP COLUMN partial_progress;
+
+DROP TRIGGER update_user_score_weights_updated_at ON user_score_weights;
+DROP TABLE user_score_weights;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Findings of the insights engine about the habits of a user on one schedule.
+-- subject tells apart findings of the same kind, like the weekday of a skip
+-- pattern. A finding the engine stops detecting is resolved; one the user
+-- dismissed stays hidden until it is detected again after a cooldown.
+CREATE TABLE insights (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    schedule_task_id UUID NOT NULL REFERENCES schedule_tasks(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('weekday_skip', 'completion_decline', 'late_start', 'late_finish')),
+    subject TEXT NOT NULL DEFAULT '',
+    title TEXT NOT NULL,
+    body TEXT NOT NULL,
+    evidence JSONB NOT NULL DEFAULT '{}',
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMPTZ,
+    dismissed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT insights_key UNIQUE (user_id, schedule_task_id, kind, subject)
+);
+
+CREATE INDEX insights_user_active_idx ON insights (user_id, detected_at DESC)
+    WHERE resolved_at IS NULL AND dismissed_at IS NULL;
+
+CREATE TRIGGER update_insights_updated_at
+BEFORE UPDATE ON insights
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER update_insights_updated_at ON insights;
+DROP INDEX insights_user_active_idx;
+DROP TABLE insights;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rest days are recorded as the streak evaluates them, so a later change of
+-- rest weekdays does not rewrite the streak of days already counted.
+ALTER TABLE streak_freeze_events DROP CONSTRAINT streak_freeze_events_kind_check;
+ALTER TABLE streak_freeze_events
+    ADD CONSTRAINT streak_freeze_events_kind_check CHECK (kind IN ('earned', 'used', 'rest'));
+
+-- Days evaluated so far skipped the rest weekdays set today; the streak they
+-- were reached at is not known.
+INSERT INTO streak_freeze_events (user_id, event_date, kind, streak)
+SELECT s.user_id, d::date, 'rest', 0
+FROM user_streaks s
+CROSS JOIN LATERAL generate_series(s.evaluated_through - 89, s.evaluated_through, interval '1 day') d
+WHERE s.evaluated_through IS NOT NULL
+  AND EXTRACT(DOW FROM d)::int = ANY(s.rest_weekdays)
+ON CONFLICT (user_id, event_date, kind) DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM streak_freeze_events WHERE kind = 'rest';
+ALTER TABLE streak_freeze_events DROP CONSTRAINT streak_freeze_events_kind_check;
+ALTER TABLE streak_freeze_events
+    ADD CONSTRAINT streak_freeze_events_kind_check CHECK (kind IN ('earned', 'used'));
+-- +goose StatementEnd